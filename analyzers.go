@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// analyzerProtocol is the version of the input external analyzers receive
+const analyzerProtocol = 1
+
+// externalAnalyzer is a check written as a program, from the analyzers:
+// list of the config file. The command is run with sh -c, gets the graph as
+// JSON on stdin and prints its findings as JSON on stdout:
+//
+//	analyzers:
+//	  - name: secrets
+//	    command: ./scripts/find-secrets.py --strict
+type externalAnalyzer struct {
+	Name    string `yaml:"name"`
+	Command string `yaml:"command"`
+	Summary string `yaml:"summary"`
+}
+
+// analyzerInput is written to an analyzer's stdin: the task graph as the
+// json export has it, plus everything describe knows about each task
+type analyzerInput struct {
+	Version  int               `json:"version"`
+	Taskfile string            `json:"taskfile"`
+	Nodes    []taskNode        `json:"nodes"`
+	Edges    []taskEdge        `json:"edges"`
+	Tasks    []taskDescription `json:"tasks"`
+}
+
+// analyzerFinding is one finding an analyzer reports. Severity defaults to
+// warning and the position to the task's definition.
+type analyzerFinding struct {
+	Task     string `json:"task"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+func (a externalAnalyzer) check() lintCheck {
+	summary := a.Summary
+	if summary == "" {
+		summary = "external analyzer: " + a.Command
+	}
+	return lintCheck{name: a.Name, summary: summary, run: func(ws *workspace) []finding {
+		findings, err := a.run(ws)
+		if err != nil {
+			// Reported against the root Taskfile, since no task is at fault
+			root, _ := rootURI(ws.graph)
+			return []finding{{Rule: a.Name, Severity: severityError, Task: a.Name,
+				Message: "analyzer failed: " + err.Error(), Pos: sourcePos{File: root}}}
+		}
+		return findings
+	}}
+}
+
+// analyzerInput builds the document analyzers and policies are given
+func (ws *workspace) analyzerInput() (*analyzerInput, error) {
+	tg, err := buildTaskGraph(ws.graph, ws.merged, ws.idx)
+	if err != nil {
+		return nil, err
+	}
+	root, _ := rootURI(ws.graph)
+	input := &analyzerInput{Version: analyzerProtocol, Taskfile: displayPath(root), Nodes: tg.Nodes, Edges: tg.Edges}
+	for name, task := range ws.merged.Tasks.All(byName) {
+		input.Tasks = append(input.Tasks, ws.describe(name, task))
+	}
+	return input, nil
+}
+
+// run feeds the graph to the analyzer and reads back its findings, either
+// a JSON array or an object with a findings array
+func (a externalAnalyzer) run(ws *workspace) ([]finding, error) {
+	input, err := ws.analyzerInput()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", a.Command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	// Diagnostics of the analyzer itself still reach the user
+	os.Stderr.Write(stderr.Bytes())
+
+	var reported []analyzerFinding
+	out := bytes.TrimSpace(stdout.Bytes())
+	if len(out) > 0 && out[0] == '{' {
+		var wrapped struct {
+			Findings []analyzerFinding `json:"findings"`
+		}
+		err = json.Unmarshal(out, &wrapped)
+		reported = wrapped.Findings
+	} else if len(out) > 0 {
+		err = json.Unmarshal(out, &reported)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid output: %w", err)
+	}
+
+	var findings []finding
+	for _, r := range reported {
+		f := finding{Rule: a.Name, Severity: r.Severity, Task: r.Task, Message: r.Message}
+		if f.Severity == "" {
+			f.Severity = severityWarning
+		}
+		if !slices.Contains([]string{severityError, severityWarning, severityInfo}, f.Severity) {
+			return nil, fmt.Errorf("finding for %s has unknown severity %q", r.Task, r.Severity)
+		}
+		if _, task, ok := ws.resolve(r.Task); ok {
+			f.Pos = taskPos(task)
+		}
+		if r.Line > 0 {
+			f.Pos.Line, f.Pos.Column = r.Line, max(r.Column, 1)
+			if r.File != "" {
+				f.Pos.File = r.File
+			}
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile"
+)
+
+// archiveSuffixes are the archive formats --taskfile accepts
+var archiveSuffixes = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// subdirSeparator splits an archive location from the Taskfile inside it, as
+// in release.tar.gz//deploy/Taskfile.yml
+const subdirSeparator = "//"
+
+// splitArchive separates an archive location from the path inside it. The
+// // of a URL scheme is not a separator.
+func splitArchive(location string) (string, string) {
+	start := 0
+	if i := strings.Index(location, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	if i := strings.Index(location[start:], subdirSeparator); i >= 0 {
+		return location[:start+i], location[start+i+len(subdirSeparator):]
+	}
+	return location, ""
+}
+
+// archiveFormat returns the suffix naming an archive's format, or "" when
+// the location is not an archive
+func archiveFormat(location string) string {
+	location, _ = splitArchive(location)
+	location, _, _ = strings.Cut(location, "?")
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(strings.ToLower(location), suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// openArchive extracts a local or downloaded archive into a temporary
+// directory and returns the Taskfile inside it along with the directory.
+// Without an explicit path the shallowest Taskfile is used.
+func openArchive(location string) (string, string, error) {
+	format := archiveFormat(location)
+	location, inner := splitArchive(location)
+
+	data, err := readArchive(location)
+	if err != nil {
+		return "", "", err
+	}
+	tmp, err := os.MkdirTemp("", "meerkat-archive-")
+	if err != nil {
+		return "", "", err
+	}
+	if err := extractArchive(data, format, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return "", "", fmt.Errorf("failed to extract %s: %w", location, err)
+	}
+
+	path := filepath.Join(tmp, filepath.FromSlash(inner))
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path, err = findTaskfile(path)
+		if err != nil {
+			os.RemoveAll(tmp)
+			return "", "", fmt.Errorf("%s: %w", location, err)
+		}
+	}
+	return path, tmp, nil
+}
+
+// readArchive reads an archive from disk, or over HTTPS through the default
+// client so --timeout and the fetch transport apply
+func readArchive(location string) ([]byte, error) {
+	if !taskfile.IsRemoteEntrypoint(location) {
+		return os.ReadFile(location)
+	}
+	if !strings.HasPrefix(location, "https://") {
+		return nil, fmt.Errorf("archives can only be downloaded over https: %s", location)
+	}
+	req, err := http.NewRequestWithContext(withTaskfileFetch(context.Background()), http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", location, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// extractArchive unpacks an archive of the given format under dest
+func extractArchive(data []byte, format, dest string) error {
+	switch format {
+	case ".zip":
+		return extractZip(data, dest)
+	case ".tar":
+		return extractTar(bytes.NewReader(data), dest)
+	default:
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return extractTar(gz, dest)
+	}
+}
+
+// extractPath is where an archive entry goes under dest, refusing entries
+// that would land outside it, by name or by writing through a symlink an
+// earlier entry made
+func extractPath(dest, name string) (string, error) {
+	dest = filepath.Clean(dest)
+	target := filepath.Join(dest, name)
+	if target != dest && !strings.HasPrefix(target, dest+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%s escapes the extraction directory", name)
+	}
+	rel, _ := filepath.Rel(dest, target)
+	p := dest
+	for _, elem := range strings.Split(rel, string(os.PathSeparator)) {
+		p = filepath.Join(p, elem)
+		info, err := os.Lstat(p)
+		if err != nil {
+			break
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%s writes through the symlink %s", name, elem)
+		}
+	}
+	return target, nil
+}
+
+// checkLink refuses a symlink target that could lead outside dest from the
+// link at target: an absolute one, one climbing above dest, or one with ..
+// after a name, which may itself be a link that resolves elsewhere than the
+// name suggests. Since every link is checked, and nothing is written through
+// one, the others resolve under dest.
+func checkLink(dest, target, linkname string) error {
+	if filepath.IsAbs(linkname) || strings.HasPrefix(linkname, "/") {
+		return fmt.Errorf("symlink %s points outside the extraction directory, to %s", target, linkname)
+	}
+	rel, _ := filepath.Rel(filepath.Clean(dest), filepath.Dir(target))
+	depth := 0
+	if rel != "." {
+		depth = len(strings.Split(rel, string(os.PathSeparator)))
+	}
+	climbing := true
+	for _, elem := range strings.Split(filepath.ToSlash(linkname), "/") {
+		switch {
+		case elem == "" || elem == ".":
+		case elem == "..":
+			if depth--; !climbing || depth < 0 {
+				return fmt.Errorf("symlink %s points outside the extraction directory, to %s", target, linkname)
+			}
+		default:
+			climbing = false
+			depth++
+		}
+	}
+	return nil
+}
+
+// extractTar writes the directories, files and links of a tar stream under
+// dest, refusing links that lead outside it
+func extractTar(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := extractPath(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(target, tr, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := checkLink(dest, target, hdr.Linkname); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			// A hard link names another entry of the archive
+			existing, err := extractPath(dest, hdr.Linkname)
+			if err != nil {
+				return err
+			}
+			// Linking a symlink would move it, and with it where it leads
+			if info, err := os.Lstat(existing); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				return fmt.Errorf("%s hard links the symlink %s", hdr.Name, hdr.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Link(existing, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// extractZip writes the directories and files of a zip archive under dest
+func extractZip(data []byte, dest string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		target, err := extractPath(dest, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(target, rc, f.Mode().Perm())
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile creates a file and its parent directories from r
+func writeFile(target string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// findTaskfile returns the shallowest Taskfile under dir, by go-task's
+// default names. Several at the same depth are ambiguous and must be chosen
+// with archive//path.
+func findTaskfile(dir string) (string, error) {
+	var found []string
+	depth := -1
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !slices.Contains(taskfile.DefaultTaskfiles, d.Name()) {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		n := strings.Count(rel, string(os.PathSeparator))
+		switch {
+		case depth < 0 || n < depth:
+			found, depth = []string{path}, n
+		case n == depth:
+			found = append(found, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Several names in one directory resolve the way go-task does, by the
+	// order of its default names
+	dirs := make(map[string]bool)
+	for _, f := range found {
+		dirs[filepath.Dir(f)] = true
+	}
+	switch {
+	case len(found) == 0:
+		return "", fmt.Errorf("no Taskfile found")
+	case len(dirs) > 1:
+		var rels []string
+		for _, f := range found {
+			rel, _ := filepath.Rel(dir, f)
+			rels = append(rels, filepath.ToSlash(rel))
+		}
+		return "", fmt.Errorf("several Taskfiles found, pick one with %spath: %s", subdirSeparator, strings.Join(rels, ", "))
+	}
+	slices.SortFunc(found, func(a, b string) int {
+		return slices.Index(taskfile.DefaultTaskfiles, filepath.Base(a)) - slices.Index(taskfile.DefaultTaskfiles, filepath.Base(b))
+	})
+	return found[0], nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarEntry is an entry of a tar built for a test: a file when it has no
+// link, and otherwise a symlink, or a hard link when hard is set
+type tarEntry struct {
+	name, body, link string
+	hard             bool
+}
+
+func buildTar(t *testing.T, entries []tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		switch {
+		case e.hard:
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeLink, e.link, 0
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractTar(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		wantErr bool
+	}{
+		{"files", []tarEntry{{name: "Taskfile.yml", body: "version: '3'\n"}, {name: "sub/Taskfile.yml", body: "x"}}, false},
+		{"link within", []tarEntry{{name: "sub/a.yml", body: "x"}, {name: "sub/b.yml", link: "a.yml"}, {name: "c.yml", link: "sub/a.yml"}}, false},
+		{"link up within", []tarEntry{{name: "a.yml", body: "x"}, {name: "sub/b.yml", link: "../a.yml"}}, false},
+		{"hard link within", []tarEntry{{name: "a.yml", body: "x"}, {name: "b.yml", link: "a.yml", hard: true}}, false},
+		{"dotdot name", []tarEntry{{name: "../pwned.txt", body: "x"}}, true},
+		{"absolute link", []tarEntry{{name: "link", link: "/outside"}}, true},
+		{"link climbing out", []tarEntry{{name: "sub/link", link: "../../outside"}}, true},
+		{"dotdot after a link", []tarEntry{{name: "here", link: "."}, {name: "up", link: "here/.."}}, true},
+		{"write through link", []tarEntry{{name: "dir", body: ""}, {name: "link", link: "dir"}, {name: "link/pwned.txt", body: "x"}}, true},
+		{"overwrite link", []tarEntry{{name: "a.yml", body: "x"}, {name: "b.yml", link: "a.yml"}, {name: "b.yml", body: "y"}}, true},
+		{"hard link outside", []tarEntry{{name: "h", link: "../outside", hard: true}}, true},
+		{"hard link of a link", []tarEntry{{name: "a/b/l", link: "../x"}, {name: "h", link: "a/b/l", hard: true}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dest := filepath.Join(root, "dest")
+			if err := os.Mkdir(dest, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			err := extractTar(bytes.NewReader(buildTar(t, tt.entries)), dest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractTar() error = %v, want error %v", err, tt.wantErr)
+			}
+			if _, err := os.Lstat(filepath.Join(root, "pwned.txt")); err == nil {
+				t.Error("an entry was written outside the extraction directory")
+			}
+		})
+	}
+}
+
+func TestExtractTarSymlinkEscape(t *testing.T) {
+	root := t.TempDir()
+	dest, outside := filepath.Join(root, "dest"), filepath.Join(root, "outside")
+	for _, dir := range []string{dest, outside} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data := buildTar(t, []tarEntry{{name: "link", link: outside}, {name: "link/pwned.txt", body: "x"}})
+	if err := extractTar(bytes.NewReader(data), dest); err == nil {
+		t.Error("extractTar() accepted a symlink out of the extraction directory")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "pwned.txt")); err == nil {
+		t.Error("pwned.txt was written outside the extraction directory")
+	}
+}
+
+func TestExtractPath(t *testing.T) {
+	dest := t.TempDir()
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"Taskfile.yml", false},
+		{"a/../b.yml", false},
+		{"../x", true},
+		{"a/../../x", true},
+	}
+	for _, tt := range tests {
+		_, err := extractPath(dest, tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("extractPath(%q) error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
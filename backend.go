@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// rpcTaskNotFound is the application error returned for unknown task names
+const rpcTaskNotFound = -32001
+
+// runRPC keeps one Taskfile graph loaded and answers JSON-RPC requests about
+// it on stdin and stdout, using the same framing as the language server, so
+// editor extensions do not re-read remote includes for every question
+func runRPC(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("rpc", flag.ExitOnError)
+	opts.register(fs)
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if opts.taskfileURL == "-" {
+		return fmt.Errorf("--taskfile - cannot be used here: stdin carries the protocol messages")
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	ws, err := openWorkspace(l)
+	if err != nil {
+		return err
+	}
+
+	conn := newRPCConn(os.Stdin, os.Stdout)
+	for {
+		req, err := conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			if err := conn.reply(nil, nil, rpcErr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var result any
+		switch req.Method {
+		case "reload":
+			var fresh *workspace
+			if fresh, err = openWorkspace(l); err == nil {
+				ws = fresh
+			}
+		case "exit":
+			return nil
+		default:
+			result, err = ws.call(req.Method, req.Params)
+		}
+		if len(req.ID) > 0 {
+			if err := conn.reply(req.ID, result, err); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// call runs one query method against the workspace
+func (ws *workspace) call(method string, raw json.RawMessage) (any, error) {
+	var p struct {
+		Name     string `json:"name"`
+		MaxDepth int    `json:"maxDepth"`
+	}
+	if len(raw) > 0 {
+		if err := decodeParams(raw, &p); err != nil {
+			return nil, err
+		}
+	}
+
+	switch method {
+	case "listTasks":
+		var names []string
+		for name := range ws.merged.Tasks.All(byName) {
+			names = append(names, name)
+		}
+		return names, nil
+	case "describeTask", "findReferences", "treeForTask":
+	default:
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: "unknown method " + method}
+	}
+
+	name, task, ok := ws.resolve(p.Name)
+	if !ok {
+		return nil, &rpcError{Code: rpcTaskNotFound, Message: "task not found: " + p.Name}
+	}
+	switch method {
+	case "describeTask":
+		return ws.describe(name, task), nil
+	case "findReferences":
+		return ws.references(name), nil
+	default:
+		return ws.tree(name, p.MaxDepth, make(map[string]bool)), nil
+	}
+}
+
+// taskReference is one place a task is called from
+type taskReference struct {
+	Task     string `json:"task"`
+	Kind     string `json:"kind"`
+	Location string `json:"location"`
+}
+
+// references lists every dep and task call that reaches a task, directly or
+// through one of its aliases
+func (ws *workspace) references(name string) []taskReference {
+	refs := []taskReference{}
+	for from, task := range ws.merged.Tasks.All(byName) {
+		for i, dep := range task.Deps {
+			if target, _, _ := ws.resolve(dep.Task); target == name {
+				refs = append(refs, taskReference{Task: from, Kind: edgeDep, Location: ws.idx.depPos(task, i).String()})
+			}
+		}
+		for i, cmd := range task.Cmds {
+			if cmd.Task == "" {
+				continue
+			}
+			if target, _, _ := ws.resolve(cmd.Task); target == name {
+				kind := edgeCall
+				if cmd.Defer {
+					kind = edgeDefer
+				}
+				refs = append(refs, taskReference{Task: from, Kind: kind, Location: ws.idx.cmdPos(task, i).String()})
+			}
+		}
+	}
+	return refs
+}
+
+// taskTree is one node of a task's dependency tree. Cycle marks a task that
+// already appears higher up on the same branch, which is not expanded again;
+// Truncated marks one whose children were cut off by the depth limit.
+// Children is always present, so clients can tell a leaf from a node still
+// to be fetched by Truncated alone.
+type taskTree struct {
+	Name      string     `json:"name"`
+	Desc      string     `json:"desc,omitempty"`
+	Missing   bool       `json:"missing,omitempty"`
+	Cycle     bool       `json:"cycle,omitempty"`
+	Truncated bool       `json:"truncated,omitempty"`
+	Children  []taskTree `json:"children"`
+}
+
+// tree expands the deps and task calls below a task, down to maxDepth levels
+// when it is positive
+func (ws *workspace) tree(name string, maxDepth int, branch map[string]bool) taskTree {
+	name, task, ok := ws.resolve(name)
+	node := taskTree{Name: name, Children: []taskTree{}}
+	if !ok {
+		node.Missing = true
+		return node
+	}
+	node.Desc = task.Desc
+	if branch[name] {
+		node.Cycle = true
+		return node
+	}
+
+	var children []string
+	for _, dep := range task.Deps {
+		children = append(children, dep.Task)
+	}
+	for _, cmd := range task.Cmds {
+		if cmd.Task != "" {
+			children = append(children, cmd.Task)
+		}
+	}
+	if maxDepth == 1 {
+		node.Truncated = len(children) > 0
+		return node
+	}
+
+	branch[name] = true
+	defer delete(branch, name)
+	for _, child := range children {
+		node.Children = append(node.Children, ws.tree(child, max(maxDepth-1, 0), branch))
+	}
+	return node
+}
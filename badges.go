@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// badgeNames are the badges written by the badges command and served by
+// serve under /badges/NAME.svg
+var badgeNames = []string{"tasks", "depth", "lint"}
+
+// Badge colors, as shields.io names them
+const (
+	badgeBlue   = "#007ec6"
+	badgeGreen  = "#4c1"
+	badgeYellow = "#dfb317"
+	badgeRed    = "#e05d44"
+)
+
+// badge is one shields-style label: message pair
+type badge struct {
+	Label, Message, Color string
+}
+
+// runBadges writes an SVG badge per Taskfile metric, for READMEs to embed
+func runBadges(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("badges", flag.ExitOnError)
+	opts.register(fs)
+	out := fs.String("out", ".", "Directory to write NAME.svg badges to")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	ws, err := openWorkspace(l)
+	if err != nil {
+		return err
+	}
+	tg, err := buildTaskGraph(ws.graph, ws.merged, ws.idx)
+	if err != nil {
+		return err
+	}
+
+	checks, err := loadChecks(opts.configFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	badges := taskfileBadges(ws, tg, checks)
+	for _, name := range badgeNames {
+		path := filepath.Join(*out, name+".svg")
+		if err := os.WriteFile(path, badges[name].svg(), 0o644); err != nil {
+			return err
+		}
+		fmt.Println(path)
+	}
+	return nil
+}
+
+// taskfileBadges computes every badge of a loaded Taskfile: how many tasks
+// it has, the longest chain of tasks calling tasks, and what lint found
+func taskfileBadges(ws *workspace, tg *taskGraph, checks []lintCheck) map[string]badge {
+	tasks := 0
+	for _, n := range tg.Nodes {
+		if !n.Missing {
+			tasks++
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, f := range ws.lint(checks) {
+		counts[f.Severity]++
+	}
+	lint := badge{Label: "lint", Message: "passing", Color: badgeGreen}
+	switch {
+	case counts[severityError] > 0:
+		lint.Message, lint.Color = plural(counts[severityError], "error"), badgeRed
+	case counts[severityWarning] > 0:
+		lint.Message, lint.Color = plural(counts[severityWarning], "warning"), badgeYellow
+	}
+
+	return map[string]badge{
+		"tasks": {Label: "tasks", Message: fmt.Sprint(tasks), Color: badgeBlue},
+		"depth": {Label: "max depth", Message: fmt.Sprint(tg.maxDepth()), Color: badgeBlue},
+		"lint":  lint,
+	}
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// maxDepth is the number of tasks on the longest chain of references, the
+// depth of the deepest dependency tree. References closing a cycle are
+// not followed.
+func (tg *taskGraph) maxDepth() int {
+	next := make(map[string][]string)
+	for _, e := range tg.Edges {
+		if e.Kind != edgeInferred {
+			next[e.From] = append(next[e.From], e.To)
+		}
+	}
+	depth := make(map[string]int)
+	onPath := make(map[string]bool)
+	var visit func(name string) int
+	visit = func(name string) int {
+		if d, ok := depth[name]; ok {
+			return d
+		}
+		if onPath[name] {
+			return 0
+		}
+		onPath[name] = true
+		d := 0
+		for _, to := range next[name] {
+			d = max(d, visit(to))
+		}
+		delete(onPath, name)
+		depth[name] = d + 1
+		return d + 1
+	}
+	deepest := 0
+	for _, n := range tg.Nodes {
+		deepest = max(deepest, visit(n.Name))
+	}
+	return deepest
+}
+
+// svg renders the badge in the flat shields.io style. Text widths are
+// estimated, since the fonts are the viewer's.
+func (b badge) svg() []byte {
+	lw := textWidth(b.Label)
+	mw := textWidth(b.Message)
+	w := lw + mw
+	label, message := html.EscapeString(b.Label), html.EscapeString(b.Message)
+	return fmt.Appendf(nil, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[7]d" y="14">%[4]s</text>
+<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[8]d" y="14">%[5]s</text>
+</g>
+</svg>
+`, w, lw, mw, label, message, html.EscapeString(b.Color), lw/2, lw+mw/2)
+}
+
+// textWidth approximates the width of 11px Verdana plus padding
+func textWidth(s string) int {
+	return utf8.RuneCountInString(s)*7 + 10
+}
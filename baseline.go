@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-task/task/v3/taskfile"
+)
+
+// baselineVersion is written to every baseline file, so the format can
+// change later without misreading old files
+const baselineVersion = 1
+
+// baseline is a recorded set of accepted findings. Findings are matched by
+// rule, file, task and message but not by line, so edits elsewhere in a
+// Taskfile do not bring them back.
+type baseline struct {
+	Version  int             `json:"version"`
+	Findings []baselineEntry `json:"findings"`
+}
+
+type baselineEntry struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	File     string `json:"file"` // relative to the root Taskfile's directory
+	Task     string `json:"task"`
+	Message  string `json:"message"`
+}
+
+// key identifies the finding an entry records; severity is left out so a
+// check raising or lowering it does not count as a new finding
+func (e baselineEntry) key() string {
+	return e.Rule + "\x00" + e.File + "\x00" + e.Task + "\x00" + e.Message
+}
+
+// baselineEntries records findings with their files made relative to the
+// root Taskfile, so the baseline holds wherever the tree is checked out
+func (ws *workspace) baselineEntries(findings []finding) []baselineEntry {
+	root, _ := rootURI(ws.graph)
+	entries := make([]baselineEntry, 0, len(findings))
+	for _, f := range findings {
+		file := f.Pos.File
+		if !taskfile.IsRemoteEntrypoint(file) && root != "" {
+			if rel, err := filepath.Rel(filepath.Dir(root), file); err == nil {
+				file = filepath.ToSlash(rel)
+			}
+		}
+		entries = append(entries, baselineEntry{Rule: f.Rule, Severity: f.Severity, File: file, Task: f.Task, Message: f.Message})
+	}
+	return entries
+}
+
+func readBaseline(path string) (*baseline, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("baseline %s does not exist; create it with --update-baseline", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var b baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid baseline %s: %w", path, err)
+	}
+	if b.Version != baselineVersion {
+		return nil, fmt.Errorf("baseline %s has version %d, want %d", path, b.Version, baselineVersion)
+	}
+	return &b, nil
+}
+
+func writeBaseline(path string, entries []baselineEntry) error {
+	data, err := json.MarshalIndent(baseline{Version: baselineVersion, Findings: entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// newFindings drops the findings the baseline already records. Each entry
+// absorbs one finding, so a second copy of a recorded problem is still new.
+func (ws *workspace) newFindings(b *baseline, findings []finding) []finding {
+	known := make(map[string]int)
+	for _, e := range b.Findings {
+		known[e.key()]++
+	}
+	var fresh []finding
+	for i, e := range ws.baselineEntries(findings) {
+		if known[e.key()] > 0 {
+			known[e.key()]--
+			continue
+		}
+		fresh = append(fresh, findings[i])
+	}
+	return fresh
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// benchPhases are the timings bench reports, in the order a run goes
+// through them. go-task's reader fetches and parses each Taskfile in turn,
+// so read covers both; fetch sums the remote requests on their own and
+// parse times parsing every file of the graph again once it is read.
+var benchPhases = []string{"fetch", "parse", "read", "merge", "analysis", "total"}
+
+// benchRun is the timings of one run by phase, plus how many remote
+// requests it made
+type benchRun struct {
+	times    map[string]time.Duration
+	requests int
+}
+
+// runBench loads and analyzes a Taskfile --runs times with the remote cache
+// bypassed, then --runs times with it warm, and prints each phase's median
+// and range
+func runBench(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	opts.register(fs)
+	runs := fs.Int("runs", 5, "Runs to time with a cold and with a warm cache")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if *runs < 1 {
+		return fmt.Errorf("--runs must be at least 1")
+	}
+	if opts.taskfileURL == "-" {
+		return fmt.Errorf("--taskfile - cannot be used here: stdin can only be read once")
+	}
+	checks, err := loadChecks(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	// Cold runs fill the cache the warm runs then read from
+	results := map[bool][]benchRun{}
+	for _, cold := range []bool{true, false} {
+		for range *runs {
+			run, err := benchOnce(opts, cold, checks)
+			if err != nil {
+				return err
+			}
+			results[cold] = append(results[cold], run)
+		}
+	}
+
+	fmt.Printf("%s: %s with a cold and a warm cache\n", opts.taskfileURL, plural(*runs, "run"))
+	fmt.Printf("remote requests per run: %d cold, %d warm\n\n", results[true][0].requests, results[false][0].requests)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "PHASE\tCOLD\tWARM\n")
+	for _, phase := range benchPhases {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", phase, benchSpread(results[true], phase), benchSpread(results[false], phase))
+	}
+	return tw.Flush()
+}
+
+// benchOnce times one run on a fresh loader, so nothing is kept in
+// process between runs
+func benchOnce(opts options, cold bool, checks []lintCheck) (benchRun, error) {
+	opts.noCache = cold
+	run := benchRun{times: make(map[string]time.Duration)}
+	start := time.Now()
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return run, err
+	}
+	defer l.Close()
+	g, merged, err := l.load()
+	if err != nil {
+		return run, err
+	}
+	run.times["read"], run.times["merge"] = l.timings["read"], l.timings["merge"]
+	run.requests, run.times["fetch"] = l.fetch.fetched()
+
+	parse, err := timeParse(l, g)
+	if err != nil {
+		return run, err
+	}
+	run.times["parse"] = parse
+
+	analysisStart := time.Now()
+	ws, err := newWorkspace(l, g, merged)
+	if err != nil {
+		return run, err
+	}
+	if _, err := buildTaskGraph(g, merged, ws.idx); err != nil {
+		return run, err
+	}
+	ws.lint(checks)
+	run.times["analysis"] = time.Since(analysisStart)
+
+	run.times["total"] = time.Since(start)
+	return run, nil
+}
+
+// timeParse parses every Taskfile of the graph the way the reader does,
+// from the bytes already read
+func timeParse(l *loader, g *ast.TaskfileGraph) (time.Duration, error) {
+	adjacency, err := g.AdjacencyMap()
+	if err != nil {
+		return 0, err
+	}
+	var sources [][]byte
+	for uri := range adjacency {
+		src, err := l.readSource(uri)
+		if err != nil {
+			return 0, err
+		}
+		sources = append(sources, src)
+	}
+	start := time.Now()
+	for _, src := range sources {
+		var tf ast.Taskfile
+		if err := yaml.Unmarshal(src, &tf); err != nil {
+			return 0, err
+		}
+	}
+	return time.Since(start), nil
+}
+
+// benchSpread formats the median of a phase across runs with its range
+func benchSpread(runs []benchRun, phase string) string {
+	var times []time.Duration
+	for _, r := range runs {
+		times = append(times, r.times[phase])
+	}
+	slices.Sort(times)
+	round := func(d time.Duration) time.Duration { return d.Round(10 * time.Microsecond) }
+	median := times[len(times)/2]
+	if len(times) == 1 {
+		return round(median).String()
+	}
+	return fmt.Sprintf("%s (%s..%s)", round(median), round(times[0]), round(times[len(times)-1]))
+}
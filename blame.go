@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// runBlame prints, for every task of the merged Taskfile, the file that
+// defines it and the chain of include namespaces it was merged through.
+// Task names given as arguments limit the report to those tasks.
+func runBlame(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("blame", flag.ExitOnError)
+	opts.register(fs)
+	var selected selectors
+	fs.Var(&selected, "select", "Only list tasks whose labels match, as in tier=critical (repeatable)")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	only := fs.Args()
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	g, merged, err := l.load()
+	if err != nil {
+		return err
+	}
+	scopes, err := includeScopes(g)
+	if err != nil {
+		return err
+	}
+
+	idx := newSourceIndex(l)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "TASK\tDEFINED IN\tVIA\n")
+	found := make(map[string]bool)
+	for name, task := range merged.Tasks.All(byName) {
+		if len(only) > 0 {
+			matched := false
+			for _, n := range append([]string{name}, task.Aliases...) {
+				if slices.Contains(only, n) {
+					found[n], matched = true, true
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		if !selected.matches(idx.labels(task)) {
+			continue
+		}
+		via := "(root)"
+		if scope, ok := taskScope(name, task, scopes); ok && len(scope.namespaces) > 0 {
+			via = namespaceChain(scope)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, taskPos(task), via)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, n := range only {
+		if !found[n] {
+			missing = append(missing, n)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("task not found: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// namespaceChain renders the namespaces of a scope from the root inwards,
+// marking flattened levels
+func namespaceChain(scope includeScope) string {
+	chain := make([]string, 0, len(scope.namespaces))
+	for _, ns := range slices.Backward(scope.namespaces) {
+		if ns == "" {
+			ns = "(flattened)"
+		}
+		chain = append(chain, ns)
+	}
+	return strings.Join(chain, " > ")
+}
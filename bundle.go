@@ -0,0 +1,305 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// runBundle writes the whole Taskfile graph as one self-contained Taskfile
+// with every include inlined under its namespaced task names
+func runBundle(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("bundle", flag.ExitOnError)
+	opts.register(fs)
+	out := fs.String("out", "-", "Path of the bundled Taskfile (- for stdout)")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	g, _, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	bundled, err := bundleTaskfile(l, g)
+	if err != nil {
+		return err
+	}
+
+	if *out == "-" {
+		_, err = os.Stdout.Write(bundled)
+		return err
+	}
+	return os.WriteFile(*out, bundled, 0o644)
+}
+
+// includeScope describes how the tasks of one included file end up in the
+// merged Taskfile: the namespaces wrapping it (innermost first, "" for a
+// flattened level) and the include settings inherited from its parents
+type includeScope struct {
+	namespaces []string
+	internal   bool
+	dir        string
+	vars       []*yaml.Node
+}
+
+// qualify rewrites a task reference made inside the scope to its merged name,
+// the same way merging applies each include's namespace in turn
+func (s includeScope) qualify(name string) string {
+	for _, ns := range s.namespaces {
+		if ns == "" {
+			continue
+		}
+		if after, ok := strings.CutPrefix(name, ast.NamespaceSeparator); ok {
+			name = after
+		} else {
+			name = ns + ast.NamespaceSeparator + name
+		}
+	}
+	return name
+}
+
+// prefix is the namespace prefix the scope adds to a plain task name
+func (s includeScope) prefix() string {
+	return strings.TrimSuffix(s.qualify("_"), "_")
+}
+
+// bundler accumulates tasks and globals from every file in the graph
+type bundler struct {
+	l     *loader
+	edges map[string][]includeEdge
+	tasks *yaml.Node
+	vars  *yaml.Node
+	env   *yaml.Node
+}
+
+// bundleTaskfile inlines every include of the graph into its root Taskfile
+func bundleTaskfile(l *loader, g *ast.TaskfileGraph) ([]byte, error) {
+	edges, err := includeEdges(g)
+	if err != nil {
+		return nil, err
+	}
+	root, err := rootURI(g)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := l.readSource(root)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, err
+	}
+	top := documentRoot(&doc)
+
+	b := &bundler{
+		l:     l,
+		edges: make(map[string][]includeEdge),
+		tasks: &yaml.Node{Kind: yaml.MappingNode},
+		vars:  mappingOrNew(mappingValue(top, "vars")),
+		env:   mappingOrNew(mappingValue(top, "env")),
+	}
+	for _, e := range edges {
+		b.edges[e.Parent] = append(b.edges[e.Parent], e)
+	}
+
+	if err := b.addFile(root, top, includeScope{}, nil); err != nil {
+		return nil, err
+	}
+
+	deleteMappingKey(top, "includes")
+	if len(b.vars.Content) > 0 {
+		setMappingValue(top, "vars", b.vars)
+	}
+	if len(b.env.Content) > 0 {
+		setMappingValue(top, "env", b.env)
+	}
+	setMappingValue(top, "tasks", b.tasks)
+
+	return encodeYAML(&doc)
+}
+
+// addFile copies the tasks of one parsed Taskfile into the bundle and then
+// recurses into its includes
+func (b *bundler) addFile(uri string, top *yaml.Node, scope includeScope, include *ast.Include) error {
+	if tasks := mappingValue(top, "tasks"); tasks != nil && tasks.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(tasks.Content); i += 2 {
+			name := tasks.Content[i].Value
+			if include != nil && slices.Contains(include.Excludes, name) {
+				continue
+			}
+			task := b.bundleTask(tasks.Content[i+1], scope, include, name)
+			b.tasks.Content = append(b.tasks.Content, scalarNode(scope.qualify(name)), task)
+		}
+	}
+
+	// Globals of included files are added only where the root does not define them
+	if include != nil {
+		mergeMissing(b.vars, mappingValue(top, "vars"))
+		mergeMissing(b.env, mappingValue(top, "env"))
+	}
+
+	includes := mappingValue(top, "includes")
+	for _, e := range b.edges[uri] {
+		child := childScope(scope, e.Include, mappingValue(includes, e.Include.Namespace))
+
+		src, err := b.l.readSource(e.Child)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", e.Child, err)
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(src, &doc); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", e.Child, err)
+		}
+		if err := b.addFile(e.Child, documentRoot(&doc), child, e.Include); err != nil {
+			return err
+		}
+
+		// Mirror the alias merging adds so `ns` still runs `ns:default`
+		b.addDefaultAlias(child, e.Include)
+	}
+
+	return nil
+}
+
+// childScope derives the scope of an included file from its include stanza
+func childScope(parent includeScope, include *ast.Include, stanza *yaml.Node) includeScope {
+	ns := include.Namespace
+	if include.Flatten {
+		ns = ""
+	}
+	child := includeScope{
+		namespaces: append([]string{ns}, parent.namespaces...),
+		internal:   parent.internal || include.Internal,
+		dir:        parent.dir,
+		vars:       parent.vars,
+	}
+	if stanza != nil && stanza.Kind == yaml.MappingNode {
+		if dir := mappingValue(stanza, "dir"); dir != nil && dir.Value != "" {
+			child.dir = joinTaskDir(parent.dir, dir.Value)
+		}
+		if vars := mappingValue(stanza, "vars"); vars != nil {
+			child.vars = append([]*yaml.Node{vars}, parent.vars...)
+		}
+	}
+	return child
+}
+
+// bundleTask copies a task node and rewrites its references for the scope
+func (b *bundler) bundleTask(node *yaml.Node, scope includeScope, include *ast.Include, name string) *yaml.Node {
+	if len(scope.namespaces) == 0 {
+		return node
+	}
+
+	// Shorthand tasks (a single command or a list of commands) become mappings
+	// so include settings can be attached to them
+	task := node
+	if node.Kind != yaml.MappingNode {
+		task = &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(task, "cmds", node)
+	}
+
+	rewriteTaskRefs(task, scope.qualify)
+
+	if include != nil && !include.Flatten {
+		aliases := sequenceOrNew(mappingValue(task, "aliases"))
+		for _, alias := range include.Aliases {
+			alias := includeScope{namespaces: append([]string{alias}, scope.namespaces[1:]...)}
+			aliases.Content = append(aliases.Content, scalarNode(alias.qualify(name)))
+		}
+		if len(aliases.Content) > 0 {
+			setMappingValue(task, "aliases", aliases)
+		}
+	}
+
+	if scope.internal {
+		setMappingValue(task, "internal", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+	}
+	if scope.dir != "" {
+		dir := scope.dir
+		if own := mappingValue(task, "dir"); own != nil {
+			dir = joinTaskDir(scope.dir, own.Value)
+		}
+		setMappingValue(task, "dir", scalarNode(dir))
+	}
+	if len(scope.vars) > 0 {
+		vars := mappingOrNew(mappingValue(task, "vars"))
+		for _, inherited := range scope.vars {
+			mergeMissing(vars, inherited)
+		}
+		setMappingValue(task, "vars", vars)
+	}
+
+	return task
+}
+
+// addDefaultAlias gives an included default task the namespace as an alias,
+// unless a task with that name already exists
+func (b *bundler) addDefaultAlias(scope includeScope, include *ast.Include) {
+	if include.Flatten {
+		return
+	}
+	nsName := includeScope{namespaces: scope.namespaces[1:]}.qualify(include.Namespace)
+	defaultName := scope.qualify("default")
+	if mappingValue(b.tasks, nsName) != nil {
+		return
+	}
+	task := mappingValue(b.tasks, defaultName)
+	if task == nil || task.Kind != yaml.MappingNode {
+		return
+	}
+	aliases := sequenceOrNew(mappingValue(task, "aliases"))
+	aliases.Content = append(aliases.Content, scalarNode(nsName))
+	setMappingValue(task, "aliases", aliases)
+}
+
+// joinTaskDir resolves a task directory relative to an include directory
+func joinTaskDir(base, dir string) string {
+	if base == "" || path.IsAbs(dir) || strings.HasPrefix(dir, "{{") {
+		return dir
+	}
+	return path.Join(base, dir)
+}
+
+// mergeMissing copies the entries of src that dst does not define yet
+func mergeMissing(dst, src *yaml.Node) {
+	if src == nil || src.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		if mappingValue(dst, src.Content[i].Value) == nil {
+			dst.Content = append(dst.Content, src.Content[i], src.Content[i+1])
+		}
+	}
+}
+
+// mappingOrNew returns n if it is a mapping node, or a new empty mapping
+func mappingOrNew(n *yaml.Node) *yaml.Node {
+	if n != nil && n.Kind == yaml.MappingNode {
+		return n
+	}
+	return &yaml.Node{Kind: yaml.MappingNode}
+}
+
+// sequenceOrNew returns n if it is a sequence node, or a new empty sequence
+func sequenceOrNew(n *yaml.Node) *yaml.Node {
+	if n != nil && n.Kind == yaml.SequenceNode {
+		return n
+	}
+	return &yaml.Node{Kind: yaml.SequenceNode}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// storeVersion is written to the cache index, so its format can change
+// later without misreading old caches
+const storeVersion = 1
+
+// remoteStore is meerkat's cache of remote Taskfiles. Contents live once
+// each under blobs/, named by their SHA-256, and index.json maps the
+// reader's cache keys to them with when each was fetched and last used.
+//
+// go-task's reader keeps its own cache in a directory of key-named files.
+// It is given a private work directory, filled before each read with the
+// entries its root Taskfile used last time, and whatever it downloaded or
+// re-validated there is taken back into the store after the read.
+type remoteStore struct {
+	dir  string
+	work string // the reader's cache directory, removed on Close
+}
+
+type storeIndex struct {
+	Version int                     `json:"version"`
+	Entries map[string]*storeEntry  `json:"entries"`          // reader cache key -> entry
+	Roots   map[string][]string     `json:"roots"`            // root Taskfile -> keys its last load read
+	Graphs  map[string]*storedGraph `json:"graphs,omitempty"` // root Taskfile -> files its last load read
+}
+
+type storeEntry struct {
+	URL      string    `json:"url,omitempty"`
+	Blob     string    `json:"blob"` // SHA-256 of the content
+	Size     int64     `json:"size"`
+	Checksum string    `json:"checksum,omitempty"` // checksum the download was trusted with
+	Redirect string    `json:"redirect,omitempty"` // where the URL redirected to when last downloaded
+	Fetched  time.Time `json:"fetched"`
+	Used     time.Time `json:"used"`
+}
+
+// defaultCacheDir is where the store lives unless --cache-dir says otherwise
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "meerkat")
+}
+
+// openRemoteStore opens the store in dir, with the reader's work directory
+// under temp, or the system's temporary directory when it is ""
+func openRemoteStore(dir, temp string) (*remoteStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "blobs"), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache: %w", err)
+	}
+	work, err := os.MkdirTemp(temp, "meerkat-cache-")
+	if err != nil {
+		return nil, err
+	}
+	return &remoteStore{dir: dir, work: work}, nil
+}
+
+// Close removes the reader's work directory; the store itself stays
+func (s *remoteStore) Close() {
+	os.RemoveAll(s.work)
+}
+
+func (s *remoteStore) blobPath(hash string) string {
+	return filepath.Join(s.dir, "blobs", hash)
+}
+
+// readIndex reads the index, or starts an empty one
+func (s *remoteStore) readIndex() (*storeIndex, error) {
+	idx := &storeIndex{Version: storeVersion, Entries: map[string]*storeEntry{}, Roots: map[string][]string{}, Graphs: map[string]*storedGraph{}}
+	data, err := os.ReadFile(filepath.Join(s.dir, "index.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("invalid cache index in %s: %w", s.dir, err)
+	}
+	if idx.Version != storeVersion {
+		return nil, fmt.Errorf("cache index in %s has version %d, want %d", s.dir, idx.Version, storeVersion)
+	}
+	if idx.Entries == nil {
+		idx.Entries = map[string]*storeEntry{}
+	}
+	if idx.Roots == nil {
+		idx.Roots = map[string][]string{}
+	}
+	if idx.Graphs == nil {
+		idx.Graphs = map[string]*storedGraph{}
+	}
+	return idx, nil
+}
+
+// writeIndex replaces the index in one rename, so a concurrent run reads
+// either the old index or the new one
+func (s *remoteStore) writeIndex(idx *storeIndex) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, "index-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, "index.json"))
+}
+
+// workFile is a file of the reader's cache for key, such as its .yaml
+func (s *remoteStore) workFile(key, suffix string) string {
+	return filepath.Join(s.work, "remote", key+"."+suffix)
+}
+
+// materialize gives the reader the entries root used last time. Files the
+// reader already has, from an earlier load of the same run, are kept.
+func (s *remoteStore) materialize(root string) error {
+	idx, err := s.readIndex()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(s.work, "remote"), 0o755); err != nil {
+		return err
+	}
+	for _, key := range idx.Roots[root] {
+		e, ok := idx.Entries[key]
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(s.workFile(key, "yaml")); err == nil {
+			continue
+		}
+		data, err := os.ReadFile(s.blobPath(e.Blob))
+		if err != nil {
+			// An evicted or damaged blob is fetched again
+			continue
+		}
+		files := map[string][]byte{
+			"yaml":      data,
+			"checksum":  []byte(e.Checksum),
+			"timestamp": []byte(e.Fetched.UTC().Format(time.RFC3339)),
+		}
+		for suffix, content := range files {
+			if err := os.WriteFile(s.workFile(key, suffix), content, 0o644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ingest stores what the reader left in its cache directory and records
+// the keys a successful read of root used. keys maps the keys of the graph
+// to their URLs, and is nil when the read failed.
+func (s *remoteStore) ingest(root string, keys, redirects map[string]string) error {
+	idx, err := s.readIndex()
+	if err != nil {
+		return err
+	}
+	paths, err := filepath.Glob(filepath.Join(s.work, "remote", "*.yaml"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 && len(keys) == 0 && idx.Roots[root] == nil {
+		// A graph of local Taskfiles leaves nothing to store
+		return nil
+	}
+	now := time.Now().UTC()
+	for _, path := range paths {
+		key := strings.TrimSuffix(filepath.Base(path), ".yaml")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		if _, err := os.Stat(s.blobPath(hash)); errors.Is(err, os.ErrNotExist) {
+			if err := writeBlob(s.blobPath(hash), data); err != nil {
+				return err
+			}
+		}
+
+		e, ok := idx.Entries[key]
+		if !ok {
+			e = &storeEntry{}
+			idx.Entries[key] = e
+		}
+		e.Blob, e.Size = hash, int64(len(data))
+		if checksum, err := os.ReadFile(s.workFile(key, "checksum")); err == nil {
+			e.Checksum = string(checksum)
+		}
+		downloaded := false
+		if stamp, err := os.ReadFile(s.workFile(key, "timestamp")); err == nil {
+			if t, err := time.Parse(time.RFC3339, string(stamp)); err == nil {
+				downloaded = !t.Equal(e.Fetched)
+				e.Fetched = t
+			}
+		}
+		if url, used := keys[key]; used {
+			e.URL, e.Used = url, now
+			if downloaded {
+				e.Redirect = redirects[url]
+			}
+		}
+	}
+	switch {
+	case len(keys) > 0:
+		idx.Roots[root] = slices.Sorted(maps.Keys(keys))
+	case keys != nil:
+		delete(idx.Roots, root)
+	}
+	return s.writeIndex(idx)
+}
+
+// redirects are where the remote Taskfiles in the store redirected to, by
+// URL, for the ones that did
+func (s *remoteStore) redirects() map[string]string {
+	redirects := make(map[string]string)
+	idx, err := s.readIndex()
+	if err != nil {
+		return redirects
+	}
+	for _, e := range idx.Entries {
+		if e.Redirect != "" {
+			redirects[e.URL] = e.Redirect
+		}
+	}
+	return redirects
+}
+
+// writeBlob writes a blob under a temporary name first, so a blob that
+// exists is always complete
+func writeBlob(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// graphCacheKeys maps the reader's cache key of every remote Taskfile in
+// the graph to its URL
+func graphCacheKeys(g *ast.TaskfileGraph, insecure bool) map[string]string {
+	keys := make(map[string]string)
+	adjacency, err := g.AdjacencyMap()
+	if err != nil {
+		return keys
+	}
+	for uri := range adjacency {
+		if !taskfile.IsRemoteEntrypoint(uri) {
+			continue
+		}
+		node, err := taskfile.NewNode(uri, "", insecure)
+		if err != nil {
+			continue
+		}
+		if remote, ok := node.(taskfile.RemoteNode); ok {
+			keys[remote.CacheKey()] = uri
+		}
+	}
+	return keys
+}
+
+// gcResult is what a collection removed, or would remove
+type gcResult struct {
+	entries []string
+	graphs  int
+	blobs   int
+	bytes   int64
+}
+
+// gc evicts the entries and graphs no load has used since cutoff and
+// deletes the blobs no remaining entry refers to
+func (s *remoteStore) gc(cutoff time.Time, dryRun bool) (gcResult, error) {
+	var res gcResult
+	idx, err := s.readIndex()
+	if err != nil {
+		return res, err
+	}
+	for key, e := range idx.Entries {
+		if e.Used.Before(cutoff) {
+			res.entries = append(res.entries, key)
+			delete(idx.Entries, key)
+		}
+	}
+	slices.Sort(res.entries)
+	for root, sg := range idx.Graphs {
+		if sg.Used.Before(cutoff) {
+			res.graphs++
+			delete(idx.Graphs, root)
+		}
+	}
+	for root, keys := range idx.Roots {
+		keys = slices.DeleteFunc(keys, func(key string) bool { return idx.Entries[key] == nil })
+		if len(keys) == 0 {
+			delete(idx.Roots, root)
+		} else {
+			idx.Roots[root] = keys
+		}
+	}
+
+	referenced := make(map[string]bool)
+	for _, e := range idx.Entries {
+		referenced[e.Blob] = true
+	}
+	blobs, err := os.ReadDir(filepath.Join(s.dir, "blobs"))
+	if err != nil {
+		return res, err
+	}
+	for _, b := range blobs {
+		if referenced[b.Name()] {
+			continue
+		}
+		info, err := b.Info()
+		if err != nil {
+			return res, err
+		}
+		res.blobs++
+		res.bytes += info.Size()
+		if !dryRun {
+			if err := os.Remove(s.blobPath(b.Name())); err != nil {
+				return res, err
+			}
+		}
+	}
+	if dryRun {
+		return res, nil
+	}
+	return res, s.writeIndex(idx)
+}
+
+// runCache manages the remote Taskfile cache: list shows its entries and gc
+// evicts the ones not used within --older-than
+func runCache(args []string) error {
+	if len(args) == 0 || (args[0] != "gc" && args[0] != "list") {
+		return fmt.Errorf("usage: cache gc|list [flags]")
+	}
+	var opts options
+	fs := flag.NewFlagSet("cache "+args[0], flag.ExitOnError)
+	opts.register(fs)
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "With gc, evict entries no load has used for this long")
+	dryRun := fs.Bool("dry-run", false, "With gc, report what would be removed without removing it")
+	if err := parseFlags(fs, &opts, args[1:]); err != nil {
+		return err
+	}
+
+	s := &remoteStore{dir: opts.cacheDir}
+	if args[0] == "list" {
+		idx, err := s.readIndex()
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "URL\tSIZE\tFETCHED\tUSED\tBLOB\n")
+		for _, key := range slices.Sorted(maps.Keys(idx.Entries)) {
+			e := idx.Entries[key]
+			url := e.URL
+			if url == "" {
+				url = key
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%.12s\n", url, e.Size, e.Fetched.Format(time.DateTime), e.Used.Format(time.DateTime), e.Blob)
+		}
+		return tw.Flush()
+	}
+
+	res, err := s.gc(time.Now().Add(-*olderThan), *dryRun)
+	if err != nil {
+		return err
+	}
+	verb := "removed"
+	if *dryRun {
+		verb = "would remove"
+	}
+	for _, key := range res.entries {
+		fmt.Printf("evict %s\n", key)
+	}
+	entries := "entries"
+	if len(res.entries) == 1 {
+		entries = "entry"
+	}
+	fmt.Printf("%s %d %s, %s and %s (%d bytes) from %s\n", verb, len(res.entries), entries, plural(res.graphs, "graph"), plural(res.blobs, "blob"), res.bytes, opts.cacheDir)
+	return nil
+}
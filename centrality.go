@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// taskCentrality is how central one task is to the graph. In and out count
+// the distinct tasks calling it and called by it, Dependents every task
+// that reaches it, and Betweenness how many shortest paths between other
+// tasks pass through it.
+type taskCentrality struct {
+	Name        string  `json:"name"`
+	In          int     `json:"in"`
+	Out         int     `json:"out"`
+	Dependents  int     `json:"dependents"`
+	Betweenness float64 `json:"betweenness"`
+}
+
+// centralityOrders are the metrics --sort ranks by, ties broken by the next
+var centralityOrders = map[string]func(a, b taskCentrality) bool{
+	"betweenness": func(a, b taskCentrality) bool {
+		if a.Betweenness != b.Betweenness {
+			return a.Betweenness > b.Betweenness
+		}
+		return a.Dependents > b.Dependents
+	},
+	"dependents": func(a, b taskCentrality) bool {
+		if a.Dependents != b.Dependents {
+			return a.Dependents > b.Dependents
+		}
+		return a.Betweenness > b.Betweenness
+	},
+	"degree": func(a, b taskCentrality) bool {
+		if a.In+a.Out != b.In+b.Out {
+			return a.In+a.Out > b.In+b.Out
+		}
+		return a.Betweenness > b.Betweenness
+	},
+}
+
+// runHubs ranks tasks by centrality, the hubs whose change ripples widest
+// and the bottlenecks most paths through the graph go through
+func runHubs(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("hubs", flag.ExitOnError)
+	opts.register(fs)
+	top := fs.Int("top", 10, "Tasks to report (0 reports every task)")
+	order := fs.String("sort", "betweenness", "Metric to rank by: betweenness, dependents or degree")
+	format := fs.String("format", "text", "Output format: text or json")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	less, ok := centralityOrders[*order]
+	if !ok {
+		return fmt.Errorf("unknown sort %q", *order)
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	g, merged, err := l.load()
+	if err != nil {
+		return err
+	}
+	tg, err := buildTaskGraph(g, merged, nil)
+	if err != nil {
+		return err
+	}
+
+	ranked := tg.centrality()
+	sort.SliceStable(ranked, func(i, j int) bool { return less(ranked[i], ranked[j]) })
+	if *top > 0 && len(ranked) > *top {
+		ranked = ranked[:*top]
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ranked)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "TASK\tBETWEENNESS\tDEPENDENTS\tIN\tOUT\n")
+	for _, c := range ranked {
+		fmt.Fprintf(tw, "%s\t%.2f\t%d\t%d\t%d\n", c.Name, c.Betweenness, c.Dependents, c.In, c.Out)
+	}
+	return tw.Flush()
+}
+
+// centrality measures every task of the graph, in the graph's order. Edges
+// of every kind count once however often the reference is made.
+func (tg *taskGraph) centrality() []taskCentrality {
+	index := make(map[string]int)
+	for i, n := range tg.Nodes {
+		index[n.Name] = i
+	}
+	out := make([][]int, len(tg.Nodes))
+	in := make([][]int, len(tg.Nodes))
+	seen := make(map[[2]int]bool)
+	for _, e := range tg.Edges {
+		edge := [2]int{index[e.From], index[e.To]}
+		if seen[edge] || edge[0] == edge[1] {
+			continue
+		}
+		seen[edge] = true
+		out[edge[0]] = append(out[edge[0]], edge[1])
+		in[edge[1]] = append(in[edge[1]], edge[0])
+	}
+
+	// Brandes' algorithm, one breadth-first search per source on its own
+	// worker, summed afterwards
+	deltas := parallelMap(len(tg.Nodes), func(s int) []float64 {
+		return pathDependency(out, s)
+	})
+	result := make([]taskCentrality, len(tg.Nodes))
+	for i, n := range tg.Nodes {
+		result[i] = taskCentrality{Name: n.Name, In: len(in[i]), Out: len(out[i]), Dependents: reachCount(in, i)}
+	}
+	for s, delta := range deltas {
+		for v, d := range delta {
+			if v != s {
+				result[v].Betweenness += d
+			}
+		}
+	}
+	return result
+}
+
+// pathDependency is the dependency of source s on every node: the share of
+// shortest paths from s through the node
+func pathDependency(adj [][]int, s int) []float64 {
+	n := len(adj)
+	sigma := make([]float64, n)
+	dist := make([]int, n)
+	for i := range dist {
+		dist[i] = -1
+	}
+	preds := make([][]int, n)
+	sigma[s], dist[s] = 1, 0
+	queue := []int{s}
+	var visited []int
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		visited = append(visited, v)
+		for _, w := range adj[v] {
+			if dist[w] < 0 {
+				dist[w] = dist[v] + 1
+				queue = append(queue, w)
+			}
+			if dist[w] == dist[v]+1 {
+				sigma[w] += sigma[v]
+				preds[w] = append(preds[w], v)
+			}
+		}
+	}
+	delta := make([]float64, n)
+	for i := len(visited) - 1; i >= 0; i-- {
+		w := visited[i]
+		for _, v := range preds[w] {
+			delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+		}
+	}
+	return delta
+}
+
+// reachCount counts the nodes reachable from start, not counting itself
+func reachCount(adj [][]int, start int) int {
+	seen := map[int]bool{start: true}
+	stack := []int{start}
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, w := range adj[v] {
+			if !seen[w] {
+				seen[w] = true
+				stack = append(stack, w)
+			}
+		}
+	}
+	return len(seen) - 1
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// ciSide is one version of the Taskfile as ci compares it: its graphs and
+// its lint findings, with findings also keyed the way a baseline keys them
+// so they match across checkouts
+type ciSide struct {
+	diffSide
+	findings []finding
+	entries  []baselineEntry
+}
+
+// ciReport is what ci found: the structural delta from the base and the
+// findings the base did not have
+type ciReport struct {
+	Base        string    `json:"base"`
+	Changes     graphDiff `json:"changes"`
+	NewFindings []finding `json:"newFindings"`
+	Regressions int       `json:"regressions"`
+}
+
+// runCI checks a change to the Taskfile against a base ref for pull
+// requests: it prints only what changed in the graph and the findings the
+// change introduced, and fails when any of those findings fail --fail-on
+func runCI(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("ci", flag.ExitOnError)
+	opts.register(fs)
+	base := fs.String("base", "", "Git ref to compare the working tree with, such as origin/main")
+	only := fs.String("checks", "", "Comma-separated lint checks to run (default all)")
+	failOn := fs.String("fail-on", "warning", "Comma-separated classes of new findings that fail the check: error, warning (or worse), any-finding, cycle")
+	format := fs.String("format", "text", "Output format: text or json")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if *base == "" {
+		return fmt.Errorf("usage: ci --base REF [flags]")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	policy, err := parseFailPolicy(*failOn)
+	if err != nil {
+		return err
+	}
+	available, err := loadChecks(opts.configFile)
+	if err != nil {
+		return err
+	}
+	checks, err := selectChecks(*only, available)
+	if err != nil {
+		return err
+	}
+
+	baseOpts := opts
+	baseOpts.gitRef = *base
+	before, err := loadCISide(baseOpts, checks)
+	if err != nil {
+		return fmt.Errorf("base %s: %w", *base, err)
+	}
+	after, err := loadCISide(opts, checks)
+	if err != nil {
+		return err
+	}
+
+	r := ciReport{Base: *base, Changes: diffGraphs(before.diffSide, after.diffSide), NewFindings: []finding{}}
+	// A finding the base has absorbs one copy at HEAD, as a baseline does
+	known := make(map[string]int)
+	for _, e := range before.entries {
+		known[e.key()]++
+	}
+	for i, e := range after.entries {
+		if known[e.key()] > 0 {
+			known[e.key()]--
+			continue
+		}
+		f := after.findings[i]
+		r.NewFindings = append(r.NewFindings, f)
+		if policy.fails(f) {
+			r.Regressions++
+		}
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Changes since %s:\n", *base)
+		r.Changes.writeText(os.Stdout)
+		if len(r.NewFindings) > 0 {
+			fmt.Printf("\nNew findings:\n")
+			writeFindings(os.Stdout, r.NewFindings)
+		} else {
+			fmt.Printf("\nNo new findings\n")
+		}
+	}
+	if r.Regressions > 0 {
+		return &failure{msg: fmt.Sprintf("%s against %s", plural(r.Regressions, "regression"), *base)}
+	}
+	return nil
+}
+
+func loadCISide(o options, checks []lintCheck) (ciSide, error) {
+	l, err := newLoader(&o)
+	if err != nil {
+		return ciSide{}, err
+	}
+	// Closing removes a checked-out ref, so everything is read before then
+	defer l.Close()
+	ws, err := openWorkspace(l)
+	if err != nil {
+		return ciSide{}, err
+	}
+	tg, err := buildTaskGraph(ws.graph, ws.merged, ws.idx)
+	if err != nil {
+		return ciSide{}, err
+	}
+	ig, err := buildIncludeGraph(ws.graph)
+	if err != nil {
+		return ciSide{}, err
+	}
+	findings := suppress(ws.lint(checks), ws.suppressions())
+	return ciSide{
+		diffSide: diffSide{tasks: tg, includes: ig},
+		findings: findings,
+		entries:  ws.baselineEntries(findings),
+	}, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCIReportJSON(t *testing.T) {
+	empty := diffSide{tasks: &taskGraph{}, includes: &includeGraph{}}
+	r := ciReport{
+		Base:    "origin/main",
+		Changes: diffGraphs(empty, empty),
+		NewFindings: []finding{
+			{Rule: "portability", Severity: severityWarning, Task: "build", Message: "sed -i differs on macOS", Pos: sourcePos{File: "/src/Taskfile.yml", Line: 4, Column: 9}},
+			{Rule: "custom", Severity: severityError, Task: "deploy", Message: "no position"},
+		},
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Changes     map[string]json.RawMessage `json:"changes"`
+		NewFindings []map[string]any           `json:"newFindings"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, list := range got.Changes {
+		if string(list) != "[]" {
+			t.Errorf("changes.%s = %s, want []", name, list)
+		}
+	}
+	if len(got.Changes) != 6 {
+		t.Errorf("changes has %d lists, want 6: %s", len(got.Changes), data)
+	}
+	if loc := got.NewFindings[0]["location"]; loc != "/src/Taskfile.yml:4:9" {
+		t.Errorf("location = %v, want /src/Taskfile.yml:4:9", loc)
+	}
+	if _, ok := got.NewFindings[1]["location"]; ok || strings.Contains(string(data), `"Pos"`) {
+		t.Errorf("a finding without a position has one: %s", data)
+	}
+}
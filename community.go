@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// weightedGraph is an undirected graph whose weights are stored in both
+// directions; a self-loop holds twice the weight inside an aggregated node
+type weightedGraph []map[int]float64
+
+func (g weightedGraph) degree(i int) float64 {
+	k := 0.0
+	for _, w := range g[i] {
+		k += w
+	}
+	return k
+}
+
+// louvain assigns every node a community, numbered from 0 in node order,
+// by the Louvain method: nodes move to the neighbouring community that
+// raises modularity most, then each community becomes one node and the
+// moves repeat, until nothing moves. Nodes are visited in order, a tie
+// keeps a node where it is or else goes to the lower community, so the
+// result is the same on every run.
+func louvain(g weightedGraph) []int {
+	membership := make([]int, len(g))
+	for i := range membership {
+		membership[i] = i
+	}
+	for {
+		comm, moved := louvainLevel(g)
+		if !moved {
+			break
+		}
+		// Renumber in order of first member and fold each community into a node
+		renumber := make(map[int]int)
+		for _, c := range comm {
+			if _, ok := renumber[c]; !ok {
+				renumber[c] = len(renumber)
+			}
+		}
+		next := make(weightedGraph, len(renumber))
+		for i := range next {
+			next[i] = make(map[int]float64)
+		}
+		for i, edges := range g {
+			for j, w := range edges {
+				next[renumber[comm[i]]][renumber[comm[j]]] += w
+			}
+		}
+		for i, c := range membership {
+			membership[i] = renumber[comm[c]]
+		}
+		g = next
+	}
+	// Number communities by their first task
+	renumber := make(map[int]int)
+	for i, c := range membership {
+		if _, ok := renumber[c]; !ok {
+			renumber[c] = len(renumber)
+		}
+		membership[i] = renumber[c]
+	}
+	return membership
+}
+
+// louvainLevel runs the moving phase once over g
+func louvainLevel(g weightedGraph) ([]int, bool) {
+	comm := make([]int, len(g))
+	tot := make([]float64, len(g))
+	degree := make([]float64, len(g))
+	m2 := 0.0
+	for i := range g {
+		comm[i] = i
+		degree[i] = g.degree(i)
+		tot[i] = degree[i]
+		m2 += degree[i]
+	}
+	if m2 == 0 {
+		return comm, false
+	}
+
+	moved := false
+	for improved := true; improved; {
+		improved = false
+		for i := range g {
+			old := comm[i]
+			tot[old] -= degree[i]
+			links := make(map[int]float64)
+			for j, w := range g[i] {
+				if j != i {
+					links[comm[j]] += w
+				}
+			}
+			best, bestGain := old, links[old]-tot[old]*degree[i]/m2
+			candidates := make([]int, 0, len(links))
+			for c := range links {
+				candidates = append(candidates, c)
+			}
+			slices.Sort(candidates)
+			for _, c := range candidates {
+				if gain := links[c] - tot[c]*degree[i]/m2; gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+			comm[i] = best
+			tot[best] += degree[i]
+			if best != old {
+				improved, moved = true, true
+			}
+		}
+	}
+	return comm, moved
+}
+
+// modularity scores a partition of g: the share of weight inside
+// communities less what random wiring of the same degrees would put there
+func modularity(g weightedGraph, comm []int) float64 {
+	inside := make(map[int]float64)
+	tot := make(map[int]float64)
+	m2 := 0.0
+	for i, edges := range g {
+		for j, w := range edges {
+			m2 += w
+			tot[comm[i]] += w
+			if comm[i] == comm[j] {
+				inside[comm[i]] += w
+			}
+		}
+	}
+	if m2 == 0 {
+		return 0
+	}
+	q := 0.0
+	for c, t := range tot {
+		q += inside[c]/m2 - (t/m2)*(t/m2)
+	}
+	return q
+}
+
+// groupByCommunity assigns tasks to the communities of the dependency graph
+// instead of their name prefixes. Communities of one task stay in the root
+// file. Each community is named after the name prefix most of its tasks
+// share, or else after its most connected task.
+func (p *splitPlan) groupByCommunity(includes func(string) bool) {
+	index := make(map[string]int)
+	for i, name := range p.order {
+		index[name] = i
+	}
+	g := make(weightedGraph, len(p.order))
+	for i := range g {
+		g[i] = make(map[int]float64)
+	}
+	for _, from := range p.order {
+		for _, to := range p.deps[from] {
+			j, ok := index[to]
+			if !ok || to == from {
+				continue
+			}
+			g[index[from]][j]++
+			g[j][index[from]]++
+		}
+	}
+	comm := louvain(g)
+	p.modularity = modularity(g, comm)
+
+	members := make(map[int][]int)
+	var communities []int
+	for i, c := range comm {
+		if members[c] == nil {
+			communities = append(communities, c)
+		}
+		members[c] = append(members[c], i)
+	}
+	used := make(map[string]bool)
+	for _, c := range communities {
+		tasks := members[c]
+		if len(tasks) < 2 {
+			continue
+		}
+		name := p.communityName(tasks, comm, g)
+		base := name
+		for n := 2; used[name] || includes(name); n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[name] = true
+		for _, i := range tasks {
+			task := p.order[i]
+			p.group[task] = name
+			prefix, _, _ := strings.Cut(task, ast.NamespaceSeparator)
+			if !strings.Contains(task, ast.NamespaceSeparator) || prefix != name {
+				p.moved[task] = true
+			}
+		}
+	}
+}
+
+// communityName is the prefix most tasks of a community share, falling
+// back to the task with the most edges inside it
+func (p *splitPlan) communityName(tasks []int, comm []int, g weightedGraph) string {
+	prefixes := make(map[string]int)
+	for _, i := range tasks {
+		if prefix, _, ok := strings.Cut(p.order[i], ast.NamespaceSeparator); ok {
+			prefixes[prefix]++
+		}
+	}
+	best, count := "", 0
+	for _, prefix := range slices.Sorted(maps.Keys(prefixes)) {
+		if prefixes[prefix] > count {
+			best, count = prefix, prefixes[prefix]
+		}
+	}
+	if 2*count > len(tasks) {
+		return best
+	}
+
+	hub, links := tasks[0], -1.0
+	for _, i := range tasks {
+		inside := 0.0
+		for j, w := range g[i] {
+			if comm[j] == comm[i] {
+				inside += w
+			}
+		}
+		if inside > links {
+			hub, links = i, inside
+		}
+	}
+	return strings.ReplaceAll(p.order[hub], ast.NamespaceSeparator, "-")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// defaultConfigFile is read from the working directory when --config is not given
+const defaultConfigFile = "meerkat.yaml"
+
+// applyConfigFile sets every flag named in the YAML config file that was not
+// already set on the command line. Keys mirror flag names, so
+// `cache-expiry: 1h` in the file is equivalent to `--cache-expiry 1h`. Keys
+// that are flags of another command are ignored, so one file can serve every
+// subcommand; ones no command knows, likely misspelled, get a warning.
+func applyConfigFile(fs *flag.FlagSet, path string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// A missing default config file is not an error
+		if errors.Is(err, os.ErrNotExist) && !explicit["config"] {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	var known map[string]bool
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		value := values[name]
+		if fs.Lookup(name) == nil {
+			if known == nil {
+				known = configKeys()
+			}
+			if !known[name] {
+				fmt.Fprintf(os.Stderr, "warning: config file %s: unknown key %q\n", path, name)
+			}
+			continue
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("config file %s: invalid value for %q: %w", path, name, err)
+		}
+	}
+
+	return nil
+}
+
+// configSections are the keys of the config file that are not flags
+var configSections = []string{"rules", "analyzers"}
+
+// commandArgs are the arguments that get a command as far as its flags, for
+// the ones with subcommands of their own
+var commandArgs = map[string][][]string{
+	"cache":    {{"gc"}, {"list"}},
+	"policy":   {{"eval"}},
+	"refactor": {{"rename"}},
+	"track":    {nil, {"report"}},
+}
+
+// collectFlags, while set, gets the flags of a command at parseFlags, which
+// then stops it with errFlagsCollected
+var collectFlags func(fs *flag.FlagSet)
+
+var errFlagsCollected = errors.New("flags collected")
+
+// configKeys are the keys a config file may have: the flags of the report
+// and of every command, and the sections of rules and analyzers. Each
+// command is run only as far as defining its flags.
+func configKeys() map[string]bool {
+	known := make(map[string]bool)
+	for _, name := range configSections {
+		known[name] = true
+	}
+	collectFlags = func(fs *flag.FlagSet) {
+		fs.VisitAll(func(f *flag.Flag) {
+			known[f.Name] = true
+		})
+	}
+	defer func() { collectFlags = nil }()
+
+	runReport(nil)
+	for name, run := range commands {
+		argSets, ok := commandArgs[name]
+		if !ok {
+			argSets = [][]string{nil}
+		}
+		for _, args := range argSets {
+			run(args)
+		}
+	}
+	return known
+}
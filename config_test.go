@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigKeys(t *testing.T) {
+	known := configKeys()
+	for _, key := range []string{"cache-expiry", "timeout", "start", "older-than", "min-tokens", "policy", "db", "rules", "analyzers"} {
+		if !known[key] {
+			t.Errorf("configKeys() lacks %q", key)
+		}
+	}
+	if known["cache-expiy"] {
+		t.Error("configKeys() knows a misspelled key")
+	}
+	if collectFlags != nil {
+		t.Error("configKeys() left flag collection on")
+	}
+}
+
+// stderrOf runs f and returns what it writes to stderr
+func stderrOf(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+	f()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meerkat.yaml")
+	config := "cache-expiry: 2h\ntimeout: 5s\ncache-expiy: 1h\nolder-than: 1h\nrules: []\n"
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var o options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.register(fs)
+	if err := fs.Parse([]string{"--timeout", "9s"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	warnings := stderrOf(t, func() { err = applyConfigFile(fs, path) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.cacheExpiry != 2*time.Hour {
+		t.Errorf("cache-expiry = %v, want 2h from the file", o.cacheExpiry)
+	}
+	if o.timeout != 9*time.Second {
+		t.Errorf("timeout = %v, want 9s from the command line", o.timeout)
+	}
+	if !strings.Contains(warnings, `unknown key "cache-expiy"`) {
+		t.Errorf("no warning about the misspelled key: %q", warnings)
+	}
+	for _, key := range []string{"older-than", "rules"} {
+		if strings.Contains(warnings, key) {
+			t.Errorf("warned about %s, which another command or section knows: %q", key, warnings)
+		}
+	}
+}
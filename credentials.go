@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// credentialHelper runs --credential-helper for the Authorization of each
+// host remote Taskfiles are fetched from, at its first fetch, so short-lived
+// tokens come from a vault or an OIDC flow rather than flags or env. It
+// speaks git's credential protocol: protocol= and host= lines in, and
+// username= and password=, or authtype= and credential=, lines out.
+type credentialHelper struct {
+	command string
+
+	mu     sync.Mutex
+	byHost map[string]string // Authorization by scheme://host, "" for none
+	failed error
+}
+
+func newCredentialHelper(command string) *credentialHelper {
+	if command == "" {
+		return nil
+	}
+	return &credentialHelper{command: command, byHost: make(map[string]string)}
+}
+
+// authorization is the Authorization header for a host reached over a
+// scheme, or "" when the helper has no credentials for it, or there is no
+// helper
+func (h *credentialHelper) authorization(scheme, host string) (string, error) {
+	if h == nil {
+		return "", nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// The helper is asked for a protocol too, so its answer holds for that
+	// protocol alone
+	key := scheme + "://" + host
+	if auth, ok := h.byHost[key]; ok {
+		return auth, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", h.command+` "$@"`, "sh", "get")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=%s\nhost=%s\n\n", scheme, host))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		if h.failed == nil {
+			h.failed = fmt.Errorf("--credential-helper failed for %s: %w", host, err)
+		}
+		return "", h.failed
+	}
+
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			fields[key] = value
+		}
+	}
+	var auth string
+	switch {
+	case fields["authtype"] != "" && fields["credential"] != "":
+		auth = fields["authtype"] + " " + fields["credential"]
+	case fields["password"] != "":
+		auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(fields["username"]+":"+fields["password"]))
+	}
+	h.byHost[key] = auth
+	return auth, nil
+}
+
+// reset forgets the credentials and failure of the last load, so a
+// long-running mode asks again for tokens that may have expired
+func (h *credentialHelper) reset() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.byHost, h.failed = make(map[string]string), nil
+}
+
+// err is why the helper failed during the current load, if it did
+func (h *credentialHelper) err() error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.failed
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCredentialHelper(t *testing.T) {
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("u:p"))
+	tests := []struct {
+		name, command, want string
+		wantErr             bool
+	}{
+		{"username and password", `printf 'username=u\npassword=p\n'`, basic, false},
+		{"authtype", `printf 'authtype=Bearer\ncredential=tok\n'`, "Bearer tok", false},
+		{"nothing", "true", "", false},
+		{"failure", "echo denied >&2; exit 3", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newCredentialHelper(tt.command)
+			got, err := h.authorization("https", "h:8443")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("authorization() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("authorization() = %q, want %q", got, tt.want)
+			}
+			if tt.wantErr && !strings.Contains(h.err().Error(), "denied") {
+				t.Errorf("err() = %v, want the helper's message", h.err())
+			}
+		})
+	}
+}
+
+func TestCredentialHelperCache(t *testing.T) {
+	// The helper answers with the protocol and host it is asked about, and
+	// logs each time it runs
+	dir := t.TempDir()
+	log, helper := filepath.Join(dir, "calls"), filepath.Join(dir, "helper")
+	writeFiles(t, dir, map[string]string{"helper": `read p; read host; echo "$1 $p $host" >> ` + log + `
+printf 'authtype=Token\ncredential=%s\n' "${p#protocol=}-${host#host=}"
+`})
+	h := newCredentialHelper("sh " + helper)
+
+	for _, c := range []struct{ scheme, host, want string }{
+		{"https", "a", "Token https-a"},
+		{"http", "a", "Token http-a"},
+		{"https", "b", "Token https-b"},
+		{"https", "a", "Token https-a"},
+	} {
+		got, err := h.authorization(c.scheme, c.host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("authorization(%s, %s) = %q, want %q", c.scheme, c.host, got, c.want)
+		}
+	}
+	calls, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(calls), "\n"); n != 3 {
+		t.Errorf("helper ran %d times, want once per scheme and host:\n%s", n, calls)
+	}
+	if !strings.HasPrefix(string(calls), "get ") {
+		t.Errorf("helper was not asked to get: %s", calls)
+	}
+
+	h.reset()
+	if _, err := h.authorization("https", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if calls, _ := os.ReadFile(log); strings.Count(string(calls), "\n") != 4 {
+		t.Error("reset() did not forget the cached credentials")
+	}
+}
+
+func TestNilCredentialHelper(t *testing.T) {
+	var h *credentialHelper
+	if auth, err := h.authorization("https", "h"); auth != "" || err != nil {
+		t.Errorf("authorization() = %q, %v, want nothing", auth, err)
+	}
+	h.reset()
+	if h.err() != nil {
+		t.Error("err() of no helper is not nil")
+	}
+}
+
+func TestCredentialHelperOnlyForTaskfileFetches(t *testing.T) {
+	dir := t.TempDir()
+	log, helper := filepath.Join(dir, "calls"), filepath.Join(dir, "helper")
+	writeFiles(t, dir, map[string]string{"helper": `read host; echo "$host" >> ` + log + `
+printf 'authtype=Bearer\ncredential=tok\n'
+`})
+	tr := &fetchTransport{creds: newCredentialHelper("sh " + helper)}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "https://hooks.example.com/notify", nil),
+		httptest.NewRequest(http.MethodGet, "https://auth.example.com/token", nil),
+	} {
+		if auth, err := tr.authorization(req); auth != "" || err != nil {
+			t.Errorf("authorization(%s %s) = %q, %v, want nothing", req.Method, req.URL, auth, err)
+		}
+	}
+	if _, err := os.Stat(log); err == nil {
+		t.Error("helper ran for a request that fetches no Taskfile")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "https://tasks.example.com/Taskfile.yml", nil)
+	req = req.WithContext(withTaskfileFetch(req.Context()))
+	if auth, err := tr.authorization(req); auth != "Bearer tok" || err != nil {
+		t.Errorf("authorization() of a Taskfile fetch = %q, %v, want the helper's token", auth, err)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	taskerrors "github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// cycleStep is one include on the way around an include cycle, or any
+// include rawIncludes finds, with the error of one the reader can't resolve
+type cycleStep struct {
+	uri       string
+	namespace string
+	optional  bool
+	err       error
+}
+
+// explainIncludeCycle turns the reader's cycle error, which only names the
+// two files of the closing edge, into one that spells out the whole chain of
+// includes forming the loop. Any other error is returned unchanged.
+func (l *loader) explainIncludeCycle(err error) error {
+	var cycle taskerrors.TaskfileCycleError
+	if !errors.As(err, &cycle) {
+		return err
+	}
+
+	// The closing edge runs from Source back to Destination, which is already
+	// in the graph, so the rest of the loop leads from Destination to Source
+	path := l.includePath(cycle.Destination, cycle.Source, make(map[string]bool))
+	if path == nil {
+		return err
+	}
+	namespace := ""
+	src, readErr := l.readSource(cycle.Source)
+	if readErr == nil {
+		for _, step := range l.rawIncludes(cycle.Source, src) {
+			if step.uri == cycle.Destination {
+				namespace = step.namespace
+			}
+		}
+	}
+	path = append(path, cycleStep{uri: cycle.Destination, namespace: namespace})
+
+	var b strings.Builder
+	b.WriteString(displayPath(path[0].uri))
+	for _, step := range path[1:] {
+		fmt.Fprintf(&b, " -> %s", displayPath(step.uri))
+		if step.namespace != "" {
+			fmt.Fprintf(&b, " (as %s)", step.namespace)
+		}
+	}
+	return &includeCycleError{TaskfileCycleError: cycle, chain: b.String()}
+}
+
+// includeCycleError is the reader's cycle error with the whole chain of
+// includes spelled out. It still unwraps to the reader's error, whose exit
+// code and JSON code it keeps.
+type includeCycleError struct {
+	taskerrors.TaskfileCycleError
+	chain string
+}
+
+func (e *includeCycleError) Error() string {
+	return "include cycle: " + e.chain
+}
+
+func (e *includeCycleError) Unwrap() error {
+	return e.TaskfileCycleError
+}
+
+// includePath finds a chain of includes leading from one Taskfile to
+// another by reading the files directly, since the reader discards its
+// partial graph on error. The first step is from itself.
+func (l *loader) includePath(from, to string, visited map[string]bool) []cycleStep {
+	if from == to {
+		return []cycleStep{{uri: from}}
+	}
+	if visited[from] {
+		return nil
+	}
+	visited[from] = true
+
+	src, err := l.readSource(from)
+	if err != nil {
+		return nil
+	}
+	for _, step := range l.rawIncludes(from, src) {
+		if step.err != nil {
+			continue
+		}
+		if rest := l.includePath(step.uri, to, visited); rest != nil {
+			rest[0].namespace = step.namespace
+			return append([]cycleStep{{uri: from}}, rest...)
+		}
+	}
+	return nil
+}
+
+// rawIncludes resolves the includes of one Taskfile to the URIs the reader
+// would load. Includes whose location is templated are skipped, and one
+// whose node can't be created keeps its entrypoint and the error.
+func (l *loader) rawIncludes(uri string, src []byte) []cycleStep {
+	var tf ast.Taskfile
+	if err := yaml.Unmarshal(src, &tf); err != nil || tf.Includes == nil {
+		return nil
+	}
+	node, err := taskfile.NewNode(uri, "", l.opts.insecure())
+	if err != nil {
+		return nil
+	}
+
+	var steps []cycleStep
+	for namespace, include := range tf.Includes.All() {
+		if strings.Contains(include.Taskfile, "{{") {
+			continue
+		}
+		entrypoint, err := node.ResolveEntrypoint(include.Taskfile)
+		if err != nil {
+			continue
+		}
+		step := cycleStep{uri: entrypoint, namespace: namespace, optional: include.Optional}
+		if child, err := taskfile.NewNode(entrypoint, "", l.opts.insecure()); err != nil {
+			step.err = err
+		} else {
+			step.uri = child.Location()
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// checkTaskCycles reports tasks that reach themselves again through deps and
+// task calls, which go-task only stops at runtime after its call limit. Each
+// loop is reported once, at the first of its tasks by name.
+func checkTaskCycles(ws *workspace) []finding {
+	var names []string
+	for name := range ws.merged.Tasks.All(byName) {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+	seen := make(map[string]bool)
+	var findings []finding
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = onStack
+		stack = append(stack, name)
+		_, task, _ := ws.resolve(name)
+		var next []string
+		for _, dep := range task.Deps {
+			next = append(next, dep.Task)
+		}
+		for _, cmd := range task.Cmds {
+			if cmd.Task != "" {
+				next = append(next, cmd.Task)
+			}
+		}
+		for _, call := range next {
+			target, _, ok := ws.resolve(call)
+			if !ok {
+				continue
+			}
+			switch state[target] {
+			case unvisited:
+				visit(target)
+			case onStack:
+				loop := slices.Clone(stack[slices.Index(stack, target):])
+				// Start the loop at its first task by name, so it reads the
+				// same whichever task the search entered it from
+				first := slices.Index(loop, slices.Min(loop))
+				loop = append(loop[first:], loop[:first]...)
+				key := strings.Join(loop, "\x00")
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				_, start, _ := ws.resolve(loop[0])
+				findings = append(findings, finding{
+					Rule:     "cycles",
+					Severity: severityError,
+					Task:     loop[0],
+					Message:  "calls itself through " + strings.Join(append(loop, loop[0]), " -> "),
+					Pos:      taskPos(start),
+				})
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+	}
+	for _, name := range names {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"strings"
+)
+
+// writeDAG draws the tasks reachable from the starts as a graph in the
+// style of git log --graph: one line per task, each task once, with the
+// lines of every task that depends on it converging onto it. Several starts
+// make one graph with a line heading down from each. Inferred edges are left
+// out, as in the indented tree.
+func writeDAG(w io.Writer, tg *taskGraph, starts ...string) error {
+	children := make(map[string][]string)
+	for _, e := range tg.Edges {
+		if e.Kind != edgeInferred && !slices.Contains(children[e.From], e.To) {
+			children[e.From] = append(children[e.From], e.To)
+		}
+	}
+	nodes := make(map[string]taskNode)
+	for _, n := range tg.Nodes {
+		nodes[n.Name] = n
+	}
+
+	// Reverse postorder puts every task below all the tasks reaching it.
+	// Children are visited last to first so the first ends up leftmost.
+	var order []string
+	visited := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		visited[name] = true
+		kids := children[name]
+		for i := len(kids) - 1; i >= 0; i-- {
+			if !visited[kids[i]] {
+				visit(kids[i])
+			}
+		}
+		order = append(order, name)
+	}
+	for i := len(starts) - 1; i >= 0; i-- {
+		if !visited[starts[i]] {
+			visit(starts[i])
+		}
+	}
+	slices.Reverse(order)
+
+	// Each column is an edge waiting for the task it leads to; a column
+	// whose edge has arrived stays empty until a new edge reuses it, so
+	// lines never have to shift sideways
+	var b strings.Builder
+	var columns []string
+	drawn := make(map[string]bool)
+	trim := func() {
+		for len(columns) > 0 && columns[len(columns)-1] == "" {
+			columns = columns[:len(columns)-1]
+		}
+	}
+	for _, name := range order {
+		var arriving []int
+		for c, target := range columns {
+			if target == name {
+				arriving = append(arriving, c)
+			}
+		}
+		if len(arriving) == 0 {
+			// A start, or only reachable through a cycle's closing edge
+			arriving = append(arriving, len(columns))
+			columns = append(columns, name)
+		}
+		at := arriving[0]
+		if len(arriving) > 1 {
+			b.WriteString(dagJoin(columns, at, arriving[1:], "┘", "┴"))
+			for _, c := range arriving[1:] {
+				columns[c] = ""
+			}
+			trim()
+		}
+
+		n := nodes[name]
+		mark := "●"
+		if n.Missing {
+			mark = "○"
+		}
+		for c, target := range columns {
+			switch {
+			case c == at:
+				b.WriteString(mark)
+			case target != "":
+				b.WriteString("│")
+			default:
+				b.WriteString(" ")
+			}
+			if c < len(columns)-1 {
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("  " + name)
+		if n.Missing {
+			b.WriteString(" (not found)")
+		} else if n.Desc != "" {
+			b.WriteString(" - " + n.Desc)
+		}
+		drawn[name] = true
+
+		// A task drawn already is above this one: a cycle
+		var next, loops []string
+		for _, kid := range children[name] {
+			if drawn[kid] {
+				loops = append(loops, kid)
+			} else {
+				next = append(next, kid)
+			}
+		}
+		if len(loops) > 0 {
+			b.WriteString(" (loops back to " + strings.Join(loops, ", ") + ")")
+		}
+		b.WriteString("\n")
+
+		if len(next) == 0 {
+			columns[at] = ""
+		} else {
+			columns[at] = next[0]
+			var opened []int
+			for _, kid := range next[1:] {
+				c := slices.Index(columns, "")
+				if c < 0 {
+					c = len(columns)
+					columns = append(columns, "")
+				}
+				columns[c] = kid
+				opened = append(opened, c)
+			}
+			if len(opened) > 0 {
+				b.WriteString(dagJoin(columns, at, opened, "┐", "┬"))
+			}
+		}
+		trim()
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dagJoin draws the row where the lines of the others columns meet column
+// at, either branching out of it or converging into it. The others are to
+// its right unless a free column on the left was reused.
+func dagJoin(columns []string, at int, others []int, end, middle string) string {
+	lo, hi := at, at
+	for _, c := range others {
+		lo, hi = min(lo, c), max(hi, c)
+	}
+	var b strings.Builder
+	for c := range columns {
+		inside := c >= lo && c <= hi
+		switch {
+		case c == at && lo < at && hi > at:
+			b.WriteString("┼")
+		case c == at && lo < at:
+			b.WriteString("┤")
+		case c == at:
+			b.WriteString("├")
+		case slices.Contains(others, c) && c == lo:
+			// A line reaching at from its left
+			b.WriteString(map[string]string{"┐": "┌", "┘": "└"}[end])
+		case slices.Contains(others, c) && c == hi:
+			b.WriteString(end)
+		case slices.Contains(others, c):
+			b.WriteString(middle)
+		case inside && columns[c] != "":
+			b.WriteString("┼")
+		case inside:
+			b.WriteString("─")
+		case columns[c] != "":
+			b.WriteString("│")
+		default:
+			b.WriteString(" ")
+		}
+		if c < len(columns)-1 {
+			if c >= lo && c < hi {
+				b.WriteString("─")
+			} else {
+				b.WriteString(" ")
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), " ") + "\n"
+}
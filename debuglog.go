@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debugLog writes structured JSONL records for reader debug messages and
+// phase timings. A nil *debugLog discards everything.
+type debugLog struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// debugRecord is a single line in the debug log
+type debugRecord struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"`
+	Message    string    `json:"msg,omitempty"`
+	Phase      string    `json:"phase,omitempty"`
+	DurationMS float64   `json:"duration_ms,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// openDebugLog creates the debug log at path, or returns nil if path is empty
+func openDebugLog(path string) (*debugLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &debugLog{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Debug records a debug message from the Taskfile reader
+func (l *debugLog) Debug(msg string) {
+	l.write(debugRecord{Kind: "debug", Message: strings.TrimSpace(msg)})
+}
+
+// Phase records how long a named phase took since start, and its error if any
+func (l *debugLog) Phase(name string, start time.Time, err error) {
+	rec := debugRecord{
+		Kind:       "phase",
+		Phase:      name,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	l.write(rec)
+}
+
+// Close flushes and closes the underlying file
+func (l *debugLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+func (l *debugLog) write(rec debugRecord) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rec.Time = time.Now().UTC()
+	_ = l.enc.Encode(rec)
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	sprig "github.com/go-task/slim-sprig/v3"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// runDescribe prints everything known about one task of the merged Taskfile
+func runDescribe(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("describe", flag.ExitOnError)
+	opts.register(fs)
+	format := fs.String("format", "text", "Output format: text or json")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: describe [flags] TASK")
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	ws, err := openWorkspace(l)
+	if err != nil {
+		return err
+	}
+	name, task, ok := ws.resolve(fs.Arg(0))
+	if !ok {
+		return fmt.Errorf("task %q not found%s", fs.Arg(0), didYouMean(fs.Arg(0), ws.merged.Tasks))
+	}
+	d := ws.describe(name, task)
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	case "text":
+		d.print()
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+// taskDescription is everything describe reports about one task
+type taskDescription struct {
+	Name          string            `json:"name"`
+	Desc          string            `json:"desc,omitempty"`
+	Location      string            `json:"location"`
+	Namespace     string            `json:"namespace,omitempty"`
+	Internal      bool              `json:"internal,omitempty"`
+	Dir           string            `json:"dir,omitempty"`
+	Aliases       []string          `json:"aliases,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Requires      []requirement     `json:"requires,omitempty"`
+	Platforms     []string          `json:"platforms,omitempty"`
+	IncludeVars   []includeVar      `json:"include_vars,omitempty"`
+	Vars          []resolvedVar     `json:"vars,omitempty"`
+	Env           []resolvedVar     `json:"env,omitempty"`
+	Deps          []string          `json:"deps,omitempty"`
+	Preconditions []string          `json:"preconditions,omitempty"`
+	Cmds          []string          `json:"cmds,omitempty"`
+	Dependents    []string          `json:"dependents,omitempty"`
+}
+
+// resolvedVar is a variable with its value as far as it can be known without
+// running anything. Dynamic (sh) variables show their command instead.
+type resolvedVar struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Dynamic bool   `json:"dynamic,omitempty"`
+}
+
+// describe collects the details of one merged task
+func (ws *workspace) describe(name string, task *ast.Task) taskDescription {
+	d := taskDescription{
+		Name:      name,
+		Desc:      task.Desc,
+		Location:  taskPos(task).String(),
+		Namespace: taskNamespace(name, task, ws.scopes),
+		Internal:  task.Internal,
+		Dir:       task.Dir,
+		Aliases:   task.Aliases,
+		Labels:    ws.idx.labels(task),
+		Requires:  taskRequirements(task),
+	}
+	for _, p := range task.Platforms {
+		d.Platforms = append(d.Platforms, strings.Trim(p.OS+"/"+p.Arch, "/"))
+	}
+
+	r, vars, env := ws.taskVars(name, task)
+	d.Vars, d.Env = vars, env
+	d.IncludeVars = includeVars(ws.merged, name, task)
+
+	for _, dep := range task.Deps {
+		d.Deps = append(d.Deps, dep.Task)
+	}
+	for _, p := range task.Preconditions {
+		line := r.expand(p.Sh)
+		if p.Msg != "" {
+			line += " (" + r.expand(p.Msg) + ")"
+		}
+		d.Preconditions = append(d.Preconditions, line)
+	}
+	for _, cmd := range task.Cmds {
+		var line string
+		switch {
+		case cmd.Task != "":
+			line = "task: " + cmd.Task
+		case cmd.Cmd != "":
+			line = r.expand(cmd.Cmd)
+		default:
+			continue
+		}
+		if cmd.Defer {
+			line = "defer " + line
+		}
+		if n := loopCount(cmd.For); n > 1 {
+			line += fmt.Sprintf(" (x%d)", n)
+		}
+		d.Cmds = append(d.Cmds, line)
+	}
+	for _, ref := range ws.references(name) {
+		if !slices.Contains(d.Dependents, ref.Task) {
+			d.Dependents = append(d.Dependents, ref.Task)
+		}
+	}
+	return d
+}
+
+// taskVars resolves the vars and env a task sees, returning a resolver
+// that expands templates against them
+func (ws *workspace) taskVars(name string, task *ast.Task) (*varResolver, []resolvedVar, []resolvedVar) {
+	// Env is visible to templates as well, so it is resolved first
+	r := newVarResolver(name)
+	globalEnv := r.add(ws.merged.Env)
+	vars := overrideVars(
+		r.add(ws.merged.Vars),
+		r.add(task.IncludeVars),
+		r.add(task.IncludedTaskfileVars),
+		r.add(task.Vars),
+	)
+	return r, vars, overrideVars(globalEnv, r.add(task.Env))
+}
+
+// includeVar is a variable the include of a task's Taskfile passes it
+type includeVar struct {
+	resolvedVar
+	Overridden bool `json:"overridden,omitempty"` // by the task's own vars
+}
+
+// includeVars are the values the vars: of its include give a task of an
+// included Taskfile, resolved against the global ones as the task compiler
+// does. The same Taskfile included twice gets different values under each
+// namespace.
+func includeVars(tf *ast.Taskfile, name string, task *ast.Task) []includeVar {
+	r := newVarResolver(name)
+	r.add(tf.Env)
+	r.add(tf.Vars)
+	var vars []includeVar
+	for _, v := range r.add(task.IncludeVars) {
+		_, overridden := task.Vars.Get(v.Name)
+		vars = append(vars, includeVar{resolvedVar: v, Overridden: overridden})
+	}
+	return vars
+}
+
+// formatIncludeVars lists include vars on one line, as in A=1, B=$(date)
+func formatIncludeVars(vars []includeVar) string {
+	parts := make([]string, len(vars))
+	for i, v := range vars {
+		parts[i] = v.Name + "=" + formatVarValue(v.resolvedVar)
+		if v.Overridden {
+			parts[i] += " (overridden by the task)"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (d taskDescription) print() {
+	fmt.Printf("Task: %s", d.Name)
+	if d.Desc != "" {
+		fmt.Printf(" - %s", d.Desc)
+	}
+	fmt.Printf("\n")
+	fmt.Printf("Defined in: %s\n", d.Location)
+	if d.Namespace != "" {
+		fmt.Printf("Namespace: %s\n", d.Namespace)
+	}
+	if d.Internal {
+		fmt.Printf("Internal: true\n")
+	}
+	if d.Dir != "" {
+		fmt.Printf("Dir: %s\n", d.Dir)
+	}
+	printList("Aliases", d.Aliases)
+	if len(d.Labels) > 0 {
+		fmt.Printf("Labels: %s\n", formatLabels(d.Labels))
+	}
+	printList("Platforms", d.Platforms)
+	var requires []string
+	for _, r := range d.Requires {
+		requires = append(requires, r.Class+" ("+r.Command+")")
+	}
+	printList("Requires", requires)
+	if len(d.IncludeVars) > 0 {
+		fmt.Printf("Include vars: %s\n", formatIncludeVars(d.IncludeVars))
+	}
+	printVars("Vars", d.Vars)
+	printVars("Env", d.Env)
+	printList("Deps", d.Deps)
+	printList("Preconditions", d.Preconditions)
+	printList("Commands", d.Cmds)
+	printList("Dependents", d.Dependents)
+}
+
+func printList(title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Printf("%s:\n", title)
+	for _, item := range items {
+		fmt.Printf("  - %s\n", item)
+	}
+}
+
+func printVars(title string, vars []resolvedVar) {
+	if len(vars) == 0 {
+		return
+	}
+	fmt.Printf("%s:\n", title)
+	for _, v := range vars {
+		fmt.Printf("  %s = %s\n", v.Name, formatVarValue(v))
+	}
+}
+
+// varResolver resolves variables in the order the task compiler applies
+// them, later definitions overriding earlier ones, and expands templates
+// against the static values known so far
+type varResolver struct {
+	values map[string]any
+}
+
+func newVarResolver(taskName string) *varResolver {
+	return &varResolver{values: map[string]any{"TASK": taskName}}
+}
+
+// add resolves a set of variables and returns them in declaration order
+func (r *varResolver) add(vars *ast.Vars) []resolvedVar {
+	if vars == nil {
+		return nil
+	}
+	var resolved []resolvedVar
+	for name, v := range vars.All() {
+		rv := resolvedVar{Name: name}
+		switch {
+		case v.Sh != nil:
+			// Never run commands just to describe a task
+			rv.Value, rv.Dynamic = r.expand(*v.Sh), true
+			delete(r.values, name)
+		case v.Ref != "":
+			rv.Value = r.expand("{{" + v.Ref + "}}")
+			r.values[name] = rv.Value
+		default:
+			if s, ok := v.Value.(string); ok {
+				rv.Value = r.expand(s)
+				r.values[name] = rv.Value
+			} else {
+				rv.Value = fmt.Sprint(v.Value)
+				r.values[name] = v.Value
+			}
+		}
+		resolved = append(resolved, rv)
+	}
+	return resolved
+}
+
+// overrideVars combines sets of variables, later sets overriding the values
+// of earlier ones while keeping each name where it first appeared
+func overrideVars(sets ...[]resolvedVar) []resolvedVar {
+	var combined []resolvedVar
+	index := make(map[string]int)
+	for _, set := range sets {
+		for _, v := range set {
+			if i, ok := index[v.Name]; ok {
+				combined[i] = v
+				continue
+			}
+			index[v.Name] = len(combined)
+			combined = append(combined, v)
+		}
+	}
+	return combined
+}
+
+// expand renders s as a Go template against the known values. Anything that
+// cannot be rendered, such as a reference to a dynamic variable, is left as
+// written.
+func (r *varResolver) expand(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	t, err := template.New("").Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return s
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, r.values); err != nil {
+		return s
+	}
+	return b.String()
+}
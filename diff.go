@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// prCommentMarker opens every pr-comment so a bot can find and update its
+// earlier comment instead of adding another
+const prCommentMarker = "<!-- meerkat-diff -->"
+
+// prCommentLimit caps each list of a pr-comment; GitHub rejects comments
+// over 65536 characters
+const prCommentLimit = 50
+
+// runDiff compares two versions of a Taskfile graph: the tasks, the edges
+// between them and the remote Taskfiles they include
+func runDiff(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	opts.register(fs)
+	base := fs.String("base", "", "Compare --taskfile as of this git ref with the working tree")
+	format := fs.String("format", "text", "Output format: text, json, or pr-comment for Markdown to post on a pull request")
+	nt := registerNotify(fs)
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+
+	oldOpts, newOpts := opts, opts
+	switch {
+	case *base != "" && fs.NArg() == 0:
+		oldOpts.gitRef = *base
+	case *base == "" && fs.NArg() == 2:
+		oldOpts.taskfileURL, newOpts.taskfileURL = fs.Arg(0), fs.Arg(1)
+	default:
+		return fmt.Errorf("usage: diff [flags] OLD NEW, or diff --base REF [flags]")
+	}
+
+	before, err := loadDiffSide(oldOpts)
+	if err != nil {
+		return fmt.Errorf("old version: %w", err)
+	}
+	after, err := loadDiffSide(newOpts)
+	if err != nil {
+		return fmt.Errorf("new version: %w", err)
+	}
+	d := diffGraphs(before, after)
+
+	switch *format {
+	case "text":
+		d.writeText(os.Stdout)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	case "pr-comment":
+		d.writePRComment(os.Stdout)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if d.empty() {
+		return nil
+	}
+	var items strings.Builder
+	d.writeText(&items)
+	lines := strings.Split(strings.TrimSpace(items.String()), "\n")
+	return nt.notify(notification{
+		Command:  "diff",
+		Taskfile: displayPath(newOpts.taskfileURL),
+		Summary:  d.summary(),
+		Items:    lines[:len(lines)-1],
+		Results:  d,
+	})
+}
+
+// diffSide is what one version of the graph contributes to a diff
+type diffSide struct {
+	tasks    *taskGraph
+	includes *includeGraph
+}
+
+func loadDiffSide(o options) (diffSide, error) {
+	l, err := newLoader(&o)
+	if err != nil {
+		return diffSide{}, err
+	}
+	defer l.Close()
+
+	g, merged, err := l.load()
+	if err != nil {
+		return diffSide{}, err
+	}
+	tg, err := buildTaskGraph(g, merged, newSourceIndex(l))
+	if err != nil {
+		return diffSide{}, err
+	}
+	ig, err := buildIncludeGraph(g)
+	if err != nil {
+		return diffSide{}, err
+	}
+	return diffSide{tasks: tg, includes: ig}, nil
+}
+
+// graphDiff lists what one version of the graph has that the other lacks
+type graphDiff struct {
+	AddedTasks      []taskNode `json:"addedTasks"`
+	RemovedTasks    []taskNode `json:"removedTasks"`
+	AddedEdges      []taskEdge `json:"addedEdges"`
+	RemovedEdges    []taskEdge `json:"removedEdges"`
+	AddedIncludes   []string   `json:"addedRemoteIncludes"`
+	RemovedIncludes []string   `json:"removedRemoteIncludes"`
+}
+
+// diffGraphs compares two versions. Tasks are matched by merged name and
+// edges by their ends and kind, so a reference written more often is not a
+// change.
+func diffGraphs(before, after diffSide) graphDiff {
+	// Empty lists, not null, for JSON consumers
+	d := graphDiff{
+		AddedTasks: []taskNode{}, RemovedTasks: []taskNode{},
+		AddedEdges: []taskEdge{}, RemovedEdges: []taskEdge{},
+		AddedIncludes: []string{}, RemovedIncludes: []string{},
+	}
+	tasks := func(s diffSide) map[string]taskNode {
+		m := make(map[string]taskNode)
+		for _, n := range s.tasks.Nodes {
+			if !n.Missing {
+				m[n.Name] = n
+			}
+		}
+		return m
+	}
+	oldTasks, newTasks := tasks(before), tasks(after)
+	for _, n := range after.tasks.Nodes {
+		if _, ok := oldTasks[n.Name]; !ok && !n.Missing {
+			d.AddedTasks = append(d.AddedTasks, n)
+		}
+	}
+	for _, n := range before.tasks.Nodes {
+		if _, ok := newTasks[n.Name]; !ok && !n.Missing {
+			d.RemovedTasks = append(d.RemovedTasks, n)
+		}
+	}
+
+	edges := func(s diffSide) map[string]bool {
+		m := make(map[string]bool)
+		for _, e := range s.tasks.Edges {
+			m[edgeKey(e)] = true
+		}
+		return m
+	}
+	oldEdges, newEdges := edges(before), edges(after)
+	for _, e := range after.tasks.Edges {
+		if !oldEdges[edgeKey(e)] {
+			d.AddedEdges = append(d.AddedEdges, e)
+		}
+	}
+	for _, e := range before.tasks.Edges {
+		if !newEdges[edgeKey(e)] {
+			d.RemovedEdges = append(d.RemovedEdges, e)
+		}
+	}
+
+	remotes := func(s diffSide) map[string]bool {
+		m := make(map[string]bool)
+		for _, n := range s.includes.Nodes {
+			if n.Remote && !n.Root {
+				m[n.URI] = true
+			}
+		}
+		return m
+	}
+	oldRemotes, newRemotes := remotes(before), remotes(after)
+	for uri := range newRemotes {
+		if !oldRemotes[uri] {
+			d.AddedIncludes = append(d.AddedIncludes, uri)
+		}
+	}
+	for uri := range oldRemotes {
+		if !newRemotes[uri] {
+			d.RemovedIncludes = append(d.RemovedIncludes, uri)
+		}
+	}
+	sort.Strings(d.AddedIncludes)
+	sort.Strings(d.RemovedIncludes)
+	return d
+}
+
+func edgeKey(e taskEdge) string {
+	return e.From + "\x00" + e.To + "\x00" + e.Kind
+}
+
+// empty reports whether the versions have the same tasks, edges and remote
+// includes
+func (d graphDiff) empty() bool {
+	return len(d.AddedTasks)+len(d.RemovedTasks)+len(d.AddedEdges)+len(d.RemovedEdges)+
+		len(d.AddedIncludes)+len(d.RemovedIncludes) == 0
+}
+
+// summary counts the changes in one line
+func (d graphDiff) summary() string {
+	if d.empty() {
+		return "no changes to tasks, edges or remote includes"
+	}
+	var parts []string
+	add := func(n int, noun, what string) {
+		if n > 0 {
+			parts = append(parts, plural(n, noun)+" "+what)
+		}
+	}
+	add(len(d.AddedTasks), "task", "added")
+	add(len(d.RemovedTasks), "task", "removed")
+	add(len(d.AddedEdges)+len(d.RemovedEdges), "edge", "changed")
+	add(len(d.AddedIncludes), "remote include", "added")
+	add(len(d.RemovedIncludes), "remote include", "removed")
+	return strings.Join(parts, ", ")
+}
+
+func edgeString(e taskEdge) string {
+	return fmt.Sprintf("%s -> %s (%s)", e.From, e.To, e.Kind)
+}
+
+func (d graphDiff) writeText(w io.Writer) {
+	for _, n := range d.AddedTasks {
+		fmt.Fprintf(w, "+ task %s\n", n.Name)
+	}
+	for _, n := range d.RemovedTasks {
+		fmt.Fprintf(w, "- task %s\n", n.Name)
+	}
+	for _, e := range d.AddedEdges {
+		fmt.Fprintf(w, "+ edge %s\n", edgeString(e))
+	}
+	for _, e := range d.RemovedEdges {
+		fmt.Fprintf(w, "- edge %s\n", edgeString(e))
+	}
+	for _, uri := range d.AddedIncludes {
+		fmt.Fprintf(w, "+ remote include %s\n", uri)
+	}
+	for _, uri := range d.RemovedIncludes {
+		fmt.Fprintf(w, "- remote include %s\n", uri)
+	}
+	fmt.Fprintf(w, "%s\n", d.summary())
+}
+
+// writePRComment writes the diff as a Markdown pull request comment: the
+// summary up front and each list folded into a details block
+func (d graphDiff) writePRComment(w io.Writer) {
+	fmt.Fprintf(w, "%s\n### Taskfile changes\n\n", prCommentMarker)
+	if d.empty() {
+		fmt.Fprintf(w, "No changes to tasks, edges or remote includes.\n")
+		return
+	}
+	summary := d.summary()
+	fmt.Fprintf(w, "%s.\n", strings.ToUpper(summary[:1])+summary[1:])
+
+	tasks := func(nodes []taskNode) []string {
+		items := make([]string, len(nodes))
+		for i, n := range nodes {
+			items[i] = "`" + n.Name + "`"
+			if n.Desc != "" {
+				items[i] += " — " + n.Desc
+			}
+		}
+		return items
+	}
+	edges := func(change string, list []taskEdge) []string {
+		items := make([]string, len(list))
+		for i, e := range list {
+			items[i] = "`" + e.From + "` → `" + e.To + "` (" + e.Kind + ", " + change + ")"
+		}
+		return items
+	}
+	code := func(list []string) []string {
+		items := make([]string, len(list))
+		for i, s := range list {
+			items[i] = "`" + s + "`"
+		}
+		return items
+	}
+
+	writeDetails(w, "Added tasks", tasks(d.AddedTasks))
+	writeDetails(w, "Removed tasks", tasks(d.RemovedTasks))
+	writeDetails(w, "Changed edges", append(edges("added", d.AddedEdges), edges("removed", d.RemovedEdges)...))
+	if len(d.AddedIncludes) > 0 {
+		// New remote code is what reviewers most need to see, so it is not
+		// folded away
+		fmt.Fprintf(w, "\n**New remote includes**\n\n")
+		writeItems(w, code(d.AddedIncludes))
+	}
+	writeDetails(w, "Removed remote includes", code(d.RemovedIncludes))
+}
+
+// writeDetails writes a collapsed list, or nothing when it is empty
+func writeDetails(w io.Writer, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n<details>\n<summary>%s (%d)</summary>\n\n", title, len(items))
+	writeItems(w, items)
+	fmt.Fprintf(w, "\n</details>\n")
+}
+
+func writeItems(w io.Writer, items []string) {
+	for i, item := range items {
+		if i == prCommentLimit {
+			fmt.Fprintf(w, "- … and %d more\n", len(items)-i)
+			break
+		}
+		fmt.Fprintf(w, "- %s\n", item)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rootNamespace is how the index lists tasks of the root Taskfile
+const rootNamespace = "(root)"
+
+// runDocs writes the merged Taskfile as a static HTML site: an index of
+// namespaces and one page per task, ready to publish as-is. With --format
+// obsidian it writes a vault of linked Markdown notes instead.
+func runDocs(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("docs", flag.ExitOnError)
+	opts.register(fs)
+	site := fs.String("site", "", "Directory to write the site to")
+	format := fs.String("format", "html", "Output format: html, or obsidian for one Markdown note per task with wikilinks")
+	open := fs.Bool("open", false, "Open the site's index in the default browser, writing it to a temporary directory unless --site is given")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if *format != "html" && *format != "obsidian" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if *open && *format != "html" {
+		return fmt.Errorf("--open only opens the html site")
+	}
+	if *site == "" && *open {
+		dir, err := os.MkdirTemp("", "meerkat-docs-")
+		if err != nil {
+			return err
+		}
+		*site = dir
+	}
+	if *site == "" {
+		return fmt.Errorf("usage: docs --site DIR [flags]")
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	ws, err := openWorkspace(l)
+	if err != nil {
+		return err
+	}
+	tg, err := buildTaskGraph(ws.graph, ws.merged, ws.idx)
+	if err != nil {
+		return err
+	}
+
+	if *format == "obsidian" {
+		notes, err := writeNotes(*site, displayPath(opts.taskfileURL), ws, tg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "wrote %d task notes to %s\n", notes, *site)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Join(*site, "tasks"), 0o755); err != nil {
+		return err
+	}
+	// GitHub Pages would otherwise run the site through Jekyll
+	if err := os.WriteFile(filepath.Join(*site, ".nojekyll"), nil, 0o644); err != nil {
+		return err
+	}
+
+	index := docsIndex{Root: displayPath(opts.taskfileURL)}
+	var b strings.Builder
+	writeMermaid(&b, tg)
+	index.Mermaid = b.String()
+	pages := 0
+	for _, ns := range append([]string{""}, tg.namespaces()...) {
+		group := docsNamespace{Name: ns}
+		if ns == "" {
+			group.Name = rootNamespace
+		}
+		for _, n := range tg.Nodes {
+			if n.Missing || n.Namespace != ns {
+				continue
+			}
+			group.Tasks = append(group.Tasks, n)
+
+			name, task, _ := ws.resolve(n.Name)
+			tree := ws.tree(name, 0, map[string]bool{})
+			page := docsTask{
+				Root:        index.Root,
+				Task:        ws.describe(name, task),
+				Tree:        tree,
+				Mermaid:     treeMermaid(tree),
+				HasChildren: len(tree.Children) > 0,
+			}
+			if err := writeDocsPage(filepath.Join(*site, "tasks", taskPage(name)), docsTaskTemplate, page); err != nil {
+				return err
+			}
+			pages++
+		}
+		if len(group.Tasks) > 0 {
+			index.Namespaces = append(index.Namespaces, group)
+		}
+	}
+	if err := writeDocsPage(filepath.Join(*site, "index.html"), docsIndexTemplate, index); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d task pages to %s\n", pages, *site)
+	if *open {
+		return openBrowser(filepath.Join(*site, "index.html"))
+	}
+	return nil
+}
+
+// docsIndex is the front page of the site
+type docsIndex struct {
+	Root       string
+	Mermaid    string
+	Namespaces []docsNamespace
+}
+
+type docsNamespace struct {
+	Name  string
+	Tasks []taskNode
+}
+
+// docsTask is the page of one task
+type docsTask struct {
+	Root        string
+	Task        taskDescription
+	Tree        taskTree
+	Mermaid     string
+	HasChildren bool
+}
+
+func writeDocsPage(path string, t *template.Template, data any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := t.Execute(f, data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to render %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// taskPage names the page of a task. Colons are kept out of file names so
+// the site can be checked out on Windows.
+func taskPage(name string) string {
+	return strings.ReplaceAll(name, ":", "__") + ".html"
+}
+
+// treeMermaid draws a task's dependency tree as a Mermaid flowchart, each
+// task once however many branches reach it
+func treeMermaid(root taskTree) string {
+	ids := make(map[string]string)
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	id := func(t taskTree) string {
+		if s, ok := ids[t.Name]; ok {
+			return s
+		}
+		s := fmt.Sprintf("n%d", len(ids))
+		ids[t.Name] = s
+		fmt.Fprintf(&b, "  %s[%s]\n", s, mermaidLabel(t.Name))
+		if t.Missing {
+			fmt.Fprintf(&b, "  style %s stroke:red,stroke-dasharray:4\n", s)
+		}
+		return s
+	}
+	edges := make(map[string]bool)
+	var walk func(t taskTree)
+	walk = func(t taskTree) {
+		from := id(t)
+		for _, c := range t.Children {
+			edge := from + " --> " + id(c)
+			if !edges[edge] {
+				edges[edge] = true
+				fmt.Fprintf(&b, "  %s\n", edge)
+			}
+			walk(c)
+		}
+	}
+	walk(root)
+	return b.String()
+}
+
+var docsFuncs = template.FuncMap{
+	"page":   taskPage,
+	"labels": formatLabels,
+}
+
+var docsIndexTemplate = template.Must(template.New("index").Funcs(docsFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+` + pageHead + `<title>{{.Root}}</title>
+</head>
+<body>
+<h1>{{.Root}}</h1>
+{{range .Namespaces}}<h2>{{.Name}}</h2>
+<table>
+<tr><th>Task</th><th>Description</th><th>Labels</th></tr>
+{{range .Tasks}}<tr><td><a href="tasks/{{page .Name}}">{{.Name}}</a></td><td>{{.Desc}}</td><td>{{labels .Labels}}</td></tr>
+{{end}}</table>
+{{end}}<h2>Task graph</h2>
+<pre class="mermaid">{{.Mermaid}}</pre>
+</body>
+</html>
+`))
+
+var docsTaskTemplate = template.Must(template.New("task").Funcs(docsFuncs).Parse(`{{define "tree"}}<li>{{if .Missing}}<span class="missing">{{.Name}} (missing)</span>{{else}}<a href="{{page .Name}}">{{.Name}}</a>{{end}}{{if .Cycle}} (cycle){{end}}
+{{if .Children}}<ul>
+{{range .Children}}{{template "tree" .}}{{end}}</ul>
+{{end}}</li>
+{{end}}<!DOCTYPE html>
+<html>
+<head>
+` + pageHead + `<title>{{.Task.Name}} - {{.Root}}</title>
+</head>
+<body>
+<p><a href="../index.html">{{.Root}}</a></p>
+{{with .Task}}<h1>{{.Name}}</h1>
+{{if .Desc}}<p>{{.Desc}}</p>{{end}}
+<table>
+<tr><th>Defined in</th><td>{{.Location}}</td></tr>
+{{- if .Namespace}}<tr><th>Namespace</th><td>{{.Namespace}}</td></tr>{{end}}
+{{- if .Internal}}<tr><th>Internal</th><td>true</td></tr>{{end}}
+{{- if .Dir}}<tr><th>Dir</th><td>{{.Dir}}</td></tr>{{end}}
+{{- if .Aliases}}<tr><th>Aliases</th><td>{{range $i, $a := .Aliases}}{{if $i}}, {{end}}{{$a}}{{end}}</td></tr>{{end}}
+{{- if .Labels}}<tr><th>Labels</th><td>{{labels .Labels}}</td></tr>{{end}}
+{{- if .Platforms}}<tr><th>Platforms</th><td>{{range $i, $p := .Platforms}}{{if $i}}, {{end}}{{$p}}{{end}}</td></tr>{{end}}
+</table>
+{{if .Vars}}<h2>Vars</h2>
+<table>
+{{range .Vars}}<tr><th>{{.Name}}</th><td><code>{{.Value}}</code>{{if .Dynamic}} (sh){{end}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Preconditions}}<h2>Preconditions</h2>
+<ul>
+{{range .Preconditions}}<li><code>{{.}}</code></li>
+{{end}}</ul>
+{{end}}{{if .Cmds}}<h2>Commands</h2>
+<pre>{{range .Cmds}}{{.}}
+{{end}}</pre>
+{{end}}{{if .Dependents}}<h2>Used by</h2>
+<ul>
+{{range .Dependents}}<li><a href="{{page .}}">{{.}}</a></li>
+{{end}}</ul>
+{{end}}{{end}}{{if .HasChildren}}<h2>Dependency tree</h2>
+<ul>
+{{template "tree" .Tree}}</ul>
+<pre class="mermaid">{{.Mermaid}}</pre>
+{{end}}</body>
+</html>
+`))
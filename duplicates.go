@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Variables are replaced by placeholders before commands are compared, so
+// go build -o {{.OUT}} and go build -o $BIN count as the same command
+var (
+	templateVar = regexp.MustCompile(`\{\{.*?\}\}`)
+	shellVar    = regexp.MustCompile(`\$\{[^}]*\}|\$\w+`)
+)
+
+// trivialCommands are too common to be worth sharing through a task
+var trivialCommands = map[string]bool{"echo": true, "printf": true, "true": true, "false": true, "exit": true, "cd": true}
+
+// normalizeCommand reduces a command to what it does: whitespace collapsed
+// and every variable alike. Commands too small to extract normalize to "".
+func normalizeCommand(cmd string) string {
+	cmd = templateVar.ReplaceAllString(cmd, "$")
+	cmd = shellVar.ReplaceAllString(cmd, "$")
+	words := strings.Fields(cmd)
+	if len(words) < 2 || trivialCommands[words[0]] {
+		return ""
+	}
+	return strings.Join(words, " ")
+}
+
+// checkDuplicates reports commands that tasks of different Taskfiles each
+// run, as candidates for one shared task. Each cluster is reported once, at
+// its first task by name, listing the others.
+func checkDuplicates(ws *workspace) []finding {
+	clusters := make(map[string][]taskCmd)
+	var order []string
+	for _, c := range ws.shellCmds() {
+		key := normalizeCommand(c.cmd.Cmd)
+		if key == "" {
+			continue
+		}
+		if clusters[key] == nil {
+			order = append(order, key)
+		}
+		// A task repeating a command counts once
+		if !slices.ContainsFunc(clusters[key], func(u taskCmd) bool { return u.name == c.name }) {
+			clusters[key] = append(clusters[key], c)
+		}
+	}
+
+	var findings []finding
+	for _, key := range order {
+		uses := clusters[key]
+		files := make(map[string]bool)
+		for _, u := range uses {
+			files[u.pos.File] = true
+		}
+		if len(files) < 2 {
+			continue
+		}
+		first := strings.Join(strings.Fields(uses[0].cmd.Cmd), " ")
+		var others []string
+		for _, u := range uses[1:] {
+			others = append(others, fmt.Sprintf("%s (%s)", u.name, u.pos))
+		}
+		findings = append(findings, finding{
+			Rule:     "duplicates",
+			Severity: severityInfo,
+			Task:     uses[0].name,
+			Message:  fmt.Sprintf("runs %q, as do %s; extract the command into a shared task", first, strings.Join(others, ", ")),
+			Pos:      uses[0].pos,
+		})
+	}
+	return findings
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// runEnvDiff shows which vars and environment variables two tasks see
+// differently, as far as they can be resolved without running anything
+func runEnvDiff(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("env-diff", flag.ExitOnError)
+	opts.register(fs)
+	all := fs.Bool("all", false, "Also list the variables both tasks agree on")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: env-diff [flags] TASK_A TASK_B")
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	ws, err := openWorkspace(l)
+	if err != nil {
+		return err
+	}
+	var described [2]taskDescription
+	for i, arg := range fs.Args() {
+		name, task, ok := ws.resolve(arg)
+		if !ok {
+			return fmt.Errorf("task %q not found%s", arg, didYouMean(arg, ws.merged.Tasks))
+		}
+		described[i] = ws.describe(name, task)
+	}
+	a, b := described[0], described[1]
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	differences := diffVars(w, "Vars", a.Name, b.Name, a.Vars, b.Vars, *all)
+	differences += diffVars(w, "Env", a.Name, b.Name, a.Env, b.Env, *all)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if differences == 0 {
+		fmt.Printf("%s and %s resolve the same vars and env\n", a.Name, b.Name)
+	}
+	return nil
+}
+
+// diffVars writes one section comparing two resolved variable sets and
+// returns how many variables differ
+func diffVars(w *tabwriter.Writer, title, nameA, nameB string, a, b []resolvedVar, all bool) int {
+	values := func(vars []resolvedVar) map[string]string {
+		m := make(map[string]string, len(vars))
+		for _, v := range vars {
+			m[v.Name] = formatVarValue(v)
+		}
+		return m
+	}
+	valuesA, valuesB := values(a), values(b)
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, v := range overrideVars(a, b) {
+		if !seen[v.Name] {
+			seen[v.Name] = true
+			names = append(names, v.Name)
+		}
+	}
+
+	differences := 0
+	header := false
+	for _, name := range names {
+		va, okA := valuesA[name]
+		vb, okB := valuesB[name]
+		same := okA && okB && va == vb
+		if same && !all {
+			continue
+		}
+		if !same {
+			differences++
+		}
+		if !header {
+			fmt.Fprintf(w, "=== %s ===\n", title)
+			fmt.Fprintf(w, "NAME\t%s\t%s\t\n", nameA, nameB)
+			header = true
+		}
+		if !okA {
+			va = "(unset)"
+		}
+		if !okB {
+			vb = "(unset)"
+		}
+		marker := ""
+		if !same {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, va, vb, marker)
+	}
+	if header {
+		fmt.Fprintf(w, "\n")
+	}
+	return differences
+}
+
+// formatVarValue shows dynamic variables by the command that computes them
+func formatVarValue(v resolvedVar) string {
+	if v.Dynamic {
+		return "$(" + v.Value + ")"
+	}
+	return v.Value
+}
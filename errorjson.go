@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	taskerrors "github.com/go-task/task/v3/errors"
+)
+
+// errorCodes name go-task's exit codes for the JSON error report
+var errorCodes = map[int]string{
+	taskerrors.CodeTaskRCNotFoundError:          "TASKRC_NOT_FOUND",
+	taskerrors.CodeTaskfileNotFound:             "TASKFILE_NOT_FOUND",
+	taskerrors.CodeTaskfileDecode:               "DECODE_FAILED",
+	taskerrors.CodeTaskfileFetchFailed:          "FETCH_FAILED",
+	taskerrors.CodeTaskfileNotTrusted:           "NOT_TRUSTED",
+	taskerrors.CodeTaskfileNotSecure:            "NOT_SECURE",
+	taskerrors.CodeTaskfileCacheNotFound:        "CACHE_NOT_FOUND",
+	taskerrors.CodeTaskfileVersionCheckError:    "VERSION_CHECK_FAILED",
+	taskerrors.CodeTaskfileNetworkTimeout:       "NETWORK_TIMEOUT",
+	taskerrors.CodeTaskfileInvalid:              "TASKFILE_INVALID",
+	taskerrors.CodeTaskfileCycle:                "INCLUDE_CYCLE",
+	taskerrors.CodeTaskfileDoesNotMatchChecksum: "CHECKSUM_MISMATCH",
+	taskerrors.CodeTaskNotFound:                 "TASK_NOT_FOUND",
+	taskerrors.CodeTaskNameConflict:             "TASK_NAME_CONFLICT",
+}
+
+// errorReport is a failure as --format json reports it on stderr
+type errorReport struct {
+	Code     string `json:"code"`
+	URL      string `json:"url,omitempty"`
+	Cause    string `json:"cause"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// describeError classifies err by the go-task error it wraps, if any. A
+// failure is a finding rather than an error and is coded as one.
+func describeError(err error) errorReport {
+	r := errorReport{Code: "ERROR", Cause: err.Error(), ExitCode: 1}
+	var f *failure
+	if errors.As(err, &f) {
+		r.Code = "CHECK_FAILED"
+		return r
+	}
+	var te taskerrors.TaskError
+	if errors.As(err, &te) {
+		r.ExitCode = te.Code()
+		if code, ok := errorCodes[te.Code()]; ok {
+			r.Code = code
+		}
+	}
+	r.URL = errorURL(err)
+	return r
+}
+
+// errorURL is the Taskfile a go-task error is about
+func errorURL(err error) string {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch e := err.(type) {
+		case taskerrors.TaskfileNotFoundError:
+			return e.URI
+		case taskerrors.TaskfileInvalidError:
+			return e.URI
+		case taskerrors.TaskfileFetchFailedError:
+			return e.URI
+		case *taskerrors.TaskfileNotTrustedError:
+			return e.URI
+		case *taskerrors.TaskfileNotSecureError:
+			return e.URI
+		case *taskerrors.TaskfileCacheNotFoundError:
+			return e.URI
+		case *taskerrors.TaskfileVersionCheckError:
+			return e.URI
+		case *taskerrors.TaskfileNetworkTimeoutError:
+			return e.URI
+		case *taskerrors.TaskfileDoesNotMatchChecksum:
+			return e.URI
+		case taskerrors.TaskfileCycleError:
+			return e.Destination
+		}
+	}
+	return ""
+}
+
+// jsonErrors tells whether the command line asks for JSON, and so for its
+// errors as JSON too
+func jsonErrors(args []string) bool {
+	return flagValue(args, "--format", "-format") == "json"
+}
+
+// writeErrorJSON reports err as {"error": {...}}
+func writeErrorJSON(w io.Writer, err error) {
+	b, jerr := json.Marshal(map[string]errorReport{"error": describeError(err)})
+	if jerr != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	fmt.Fprintf(w, "%s\n", b)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	taskerrors "github.com/go-task/task/v3/errors"
+)
+
+func TestDescribeError(t *testing.T) {
+	cycle := taskerrors.TaskfileCycleError{Source: "/p/b.yml", Destination: "/p/Taskfile.yml"}
+	tests := []struct {
+		name     string
+		err      error
+		code     string
+		url      string
+		exitCode int
+	}{
+		{"plain", errors.New("boom"), "ERROR", "", 1},
+		{"failure", &failure{"2 findings"}, "CHECK_FAILED", "", 1},
+		{"not found", fmt.Errorf("failed to read Taskfile: %w", taskerrors.TaskfileNotFoundError{URI: "/p/Taskfile.yml"}), "TASKFILE_NOT_FOUND", "/p/Taskfile.yml", taskerrors.CodeTaskfileNotFound},
+		{"fetch", taskerrors.TaskfileFetchFailedError{URI: "https://h/x.yml", HTTPStatusCode: 404}, "FETCH_FAILED", "https://h/x.yml", taskerrors.CodeTaskfileFetchFailed},
+		{"cycle", cycle, "INCLUDE_CYCLE", "/p/Taskfile.yml", taskerrors.CodeTaskfileCycle},
+		{"explained cycle", fmt.Errorf("failed to read Taskfile: %w", &includeCycleError{TaskfileCycleError: cycle, chain: "a -> b -> a"}), "INCLUDE_CYCLE", "/p/Taskfile.yml", taskerrors.CodeTaskfileCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describeError(tt.err)
+			if got.Code != tt.code || got.URL != tt.url || got.ExitCode != tt.exitCode {
+				t.Errorf("describeError() = %+v, want code %s, url %q, exit code %d", got, tt.code, tt.url, tt.exitCode)
+			}
+			if got.Cause != tt.err.Error() {
+				t.Errorf("cause = %q, want %q", got.Cause, tt.err.Error())
+			}
+		})
+	}
+}
+
+func TestIncludeCycleErrorCode(t *testing.T) {
+	l := newTestLoader(t, map[string]string{
+		"Taskfile.yml": "version: '3'\nincludes:\n  b: ./b.yml\n",
+		"b.yml":        "version: '3'\nincludes:\n  a: ./Taskfile.yml\n",
+	})
+	_, _, err := l.load()
+	if err == nil {
+		t.Fatal("load() succeeded on an include cycle")
+	}
+	if got := describeError(err); got.Code != "INCLUDE_CYCLE" || got.ExitCode != taskerrors.CodeTaskfileCycle {
+		t.Errorf("describeError() = %+v, want INCLUDE_CYCLE", got)
+	}
+
+	var buf bytes.Buffer
+	writeErrorJSON(&buf, err)
+	var report map[string]errorReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report["error"].Code != "INCLUDE_CYCLE" {
+		t.Errorf("JSON report = %s, want code INCLUDE_CYCLE", buf.String())
+	}
+}
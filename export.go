@@ -0,0 +1,402 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// clusterColors are the fill colors given to namespace clusters, in order
+var clusterColors = []string{
+	"#e3f2fd", "#e8f5e9", "#fff3e0", "#f3e5f5", "#e0f7fa",
+	"#fffde7", "#fce4ec", "#f1f8e9", "#ede7f6", "#efebe9",
+}
+
+// clusterColor picks the fill color of the i-th namespace cluster
+func clusterColor(i int) string {
+	return clusterColors[i%len(clusterColors)]
+}
+
+// writeTaskGraph writes the task graph in one of the export formats
+func writeTaskGraph(w io.Writer, tg *taskGraph, format string) error {
+	switch format {
+	case "dot":
+		return writeDOT(w, tg)
+	case "mermaid":
+		return writeMermaid(w, tg)
+	case "json":
+		return writeJSON(w, tg)
+	case "graphml":
+		return writeGraphML(w, tg)
+	case "cytoscape":
+		return writeCytoscape(w, tg)
+	case "gexf":
+		return writeGEXF(w, tg)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// writeDOT renders the graph as Graphviz DOT with one cluster per namespace
+func writeDOT(w io.Writer, tg *taskGraph) error {
+	var b strings.Builder
+	b.WriteString("digraph tasks {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=white];\n")
+
+	for i, ns := range tg.namespaces() {
+		fmt.Fprintf(&b, "  subgraph %s {\n", dotID("cluster_"+ns))
+		fmt.Fprintf(&b, "    label=%s;\n", dotID(ns))
+		fmt.Fprintf(&b, "    style=filled;\n    color=%s;\n    fillcolor=%s;\n", dotID("#90a4ae"), dotID(clusterColor(i)))
+		for _, n := range tg.Nodes {
+			if n.Namespace == ns {
+				fmt.Fprintf(&b, "    %s%s;\n", dotID(n.Name), dotNodeAttrs(n))
+			}
+		}
+		b.WriteString("  }\n")
+	}
+	for _, n := range tg.Nodes {
+		if n.Namespace == "" {
+			fmt.Fprintf(&b, "  %s%s;\n", dotID(n.Name), dotNodeAttrs(n))
+		}
+	}
+
+	for _, e := range tg.Edges {
+		attrs := fmt.Sprintf("kind=%s, style=%s, weight=%d", dotID(e.Kind), dotEdgeStyle(e.Kind), e.Weight)
+		if e.Weight > 1 {
+			attrs += fmt.Sprintf(", label=%s, penwidth=%d", dotID(fmt.Sprintf("x%d", e.Weight)), min(e.Weight, 5))
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotID(e.From), dotID(e.To), attrs)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotNodeAttrs(n taskNode) string {
+	var attrs []string
+	if n.Desc != "" {
+		attrs = append(attrs, "tooltip="+dotID(n.Desc))
+	}
+	switch {
+	case n.Unresolved:
+		attrs = append(attrs, `style="dashed"`, `color="orange"`)
+	case n.Missing:
+		attrs = append(attrs, `style="dashed"`, `color="red"`)
+	}
+	// Labels become custom attributes, prefixed so they cannot clash with
+	// the ones Graphviz understands
+	for _, k := range sortedKeys(n.Labels) {
+		attrs = append(attrs, dotID(labelMarker+k)+"="+dotID(n.Labels[k]))
+	}
+	if len(n.Requires) > 0 {
+		attrs = append(attrs, "requires="+dotID(strings.Join(n.Requires, ",")))
+	}
+	if len(attrs) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(attrs, ", ") + "]"
+}
+
+// dotEdgeStyle gives each edge kind its own line style
+func dotEdgeStyle(kind string) string {
+	switch kind {
+	case edgeCall:
+		return "dashed"
+	case edgeDefer:
+		return "dotted"
+	case edgeInferred:
+		return "bold"
+	default:
+		return "solid"
+	}
+}
+
+// dotID quotes a string as a DOT identifier
+func dotID(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// writeMermaid renders the graph as a Mermaid flowchart with one subgraph per
+// namespace
+func writeMermaid(w io.Writer, tg *taskGraph) error {
+	ids := make(map[string]string, len(tg.Nodes))
+	for i, n := range tg.Nodes {
+		ids[n.Name] = fmt.Sprintf("n%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	namespaces := tg.namespaces()
+	for i, ns := range namespaces {
+		fmt.Fprintf(&b, "  subgraph ns%d[%s]\n", i, mermaidLabel(ns))
+		for _, n := range tg.Nodes {
+			if n.Namespace == ns {
+				fmt.Fprintf(&b, "    %s%s\n", ids[n.Name], mermaidNode(n))
+			}
+		}
+		b.WriteString("  end\n")
+	}
+	for _, n := range tg.Nodes {
+		if n.Namespace == "" {
+			fmt.Fprintf(&b, "  %s%s\n", ids[n.Name], mermaidNode(n))
+		}
+	}
+
+	for _, e := range tg.Edges {
+		fmt.Fprintf(&b, "  %s %s %s\n", ids[e.From], mermaidArrow(e.Kind, e.Weight), ids[e.To])
+	}
+
+	for i := range namespaces {
+		fmt.Fprintf(&b, "  style ns%d fill:%s,stroke:#90a4ae\n", i, clusterColor(i))
+	}
+	for _, n := range tg.Nodes {
+		if n.Missing {
+			fmt.Fprintf(&b, "  style %s stroke:red,stroke-dasharray:4\n", ids[n.Name])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidArrow gives each edge kind its own line style and labels repeated
+// references with their count
+func mermaidArrow(kind string, weight int) string {
+	var label []string
+	if kind == edgeDefer {
+		label = append(label, "defer")
+	}
+	if weight > 1 {
+		label = append(label, fmt.Sprintf("x%d", weight))
+	}
+
+	arrow := "-->"
+	switch kind {
+	case edgeCall, edgeDefer:
+		arrow = "-.->"
+	case edgeInferred:
+		arrow = "==>"
+	}
+	if len(label) > 0 {
+		arrow += "|" + strings.Join(label, " ") + "|"
+	}
+	return arrow
+}
+
+func mermaidNode(n taskNode) string {
+	return "[" + mermaidLabel(n.Name) + "]"
+}
+
+// mermaidLabel quotes a label so colons and brackets survive
+func mermaidLabel(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+// writeJSON writes the graph as {"nodes": [...], "edges": [...]}
+func writeJSON(w io.Writer, tg *taskGraph) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Nodes []taskNode `json:"nodes"`
+		Edges []taskEdge `json:"edges"`
+	}{tg.Nodes, tg.Edges})
+}
+
+// cytoscapeElement is a node or edge as Cytoscape.js loads it, with its
+// fields in data and its styling hooks in classes
+type cytoscapeElement struct {
+	Data    map[string]any `json:"data"`
+	Classes []string       `json:"classes,omitempty"`
+}
+
+// writeCytoscape writes the graph as the elements JSON cy.json() reads.
+// Nodes are classed as missing and by their requirement classes, edges by
+// their kind, so a stylesheet can tell them apart.
+func writeCytoscape(w io.Writer, tg *taskGraph) error {
+	var doc struct {
+		Elements struct {
+			Nodes []cytoscapeElement `json:"nodes"`
+			Edges []cytoscapeElement `json:"edges"`
+		} `json:"elements"`
+	}
+	doc.Elements.Nodes = []cytoscapeElement{}
+	doc.Elements.Edges = []cytoscapeElement{}
+	for _, n := range tg.Nodes {
+		data := map[string]any{"id": n.Name, "label": n.Name}
+		for k, v := range map[string]string{"desc": n.Desc, "namespace": n.Namespace, "taskfile": n.Taskfile, "location": n.Location} {
+			if v != "" {
+				data[k] = v
+			}
+		}
+		if len(n.Labels) > 0 {
+			data["labels"] = n.Labels
+		}
+		if len(n.Requires) > 0 {
+			data["requires"] = n.Requires
+		}
+		var classes []string
+		if n.Missing {
+			data["missing"] = true
+			classes = append(classes, "missing")
+		}
+		if n.Unresolved {
+			data["unresolved"] = true
+			classes = append(classes, "unresolved")
+		}
+		for _, r := range n.Requires {
+			classes = append(classes, "requires-"+r)
+		}
+		doc.Elements.Nodes = append(doc.Elements.Nodes, cytoscapeElement{Data: data, Classes: classes})
+	}
+	for i, e := range tg.Edges {
+		data := map[string]any{"id": fmt.Sprintf("e%d", i), "source": e.From, "target": e.To, "kind": e.Kind, "weight": e.Weight}
+		if e.Location != "" {
+			data["location"] = e.Location
+		}
+		doc.Elements.Edges = append(doc.Elements.Edges, cytoscapeElement{Data: data, Classes: []string{e.Kind}})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// writeGraphML writes the graph as GraphML with node and edge attributes
+func writeGraphML(w io.Writer, tg *taskGraph) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	b.WriteString(`  <key id="desc" for="node" attr.name="desc" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="namespace" for="node" attr.name="namespace" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="taskfile" for="node" attr.name="taskfile" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="location" for="all" attr.name="location" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="missing" for="node" attr.name="missing" attr.type="boolean"/>` + "\n")
+	b.WriteString(`  <key id="requires" for="node" attr.name="requires" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="kind" for="edge" attr.name="kind" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="weight" for="edge" attr.name="weight" attr.type="int"/>` + "\n")
+	labelKeys := tg.labelKeys()
+	for _, k := range labelKeys {
+		fmt.Fprintf(&b, "  <key id=\"%s\" for=\"node\" attr.name=\"%s\" attr.type=\"string\"/>\n", xmlEscape(labelMarker+k), xmlEscape(labelMarker+k))
+	}
+	b.WriteString(`  <graph id="tasks" edgedefault="directed">` + "\n")
+	for _, n := range tg.Nodes {
+		fmt.Fprintf(&b, "    <node id=\"%s\">\n", xmlEscape(n.Name))
+		writeGraphMLData(&b, "desc", n.Desc)
+		writeGraphMLData(&b, "namespace", n.Namespace)
+		writeGraphMLData(&b, "taskfile", n.Taskfile)
+		writeGraphMLData(&b, "location", n.Location)
+		for _, k := range labelKeys {
+			writeGraphMLData(&b, labelMarker+k, n.Labels[k])
+		}
+		writeGraphMLData(&b, "requires", strings.Join(n.Requires, ","))
+		if n.Missing {
+			writeGraphMLData(&b, "missing", "true")
+		}
+		b.WriteString("    </node>\n")
+	}
+	for i, e := range tg.Edges {
+		fmt.Fprintf(&b, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\">\n", i, xmlEscape(e.From), xmlEscape(e.To))
+		writeGraphMLData(&b, "kind", e.Kind)
+		writeGraphMLData(&b, "weight", fmt.Sprint(e.Weight))
+		writeGraphMLData(&b, "location", e.Location)
+		b.WriteString("    </edge>\n")
+	}
+	b.WriteString("  </graph>\n</graphml>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeGEXF writes the graph as GEXF for Gephi. Besides what GraphML
+// carries, nodes have their fan-in and fan-out in distinct tasks and a
+// complexity: the commands they run plus the references they make.
+func writeGEXF(w io.Writer, tg *taskGraph) error {
+	fanIn := make(map[string]int)
+	fanOut := make(map[string]int)
+	references := make(map[string]int)
+	linked := make(map[[2]string]bool)
+	for _, e := range tg.Edges {
+		references[e.From] += e.Weight
+		// A dep and a call between the same tasks link them once
+		if pair := [2]string{e.From, e.To}; !linked[pair] {
+			linked[pair] = true
+			fanIn[e.To]++
+			fanOut[e.From]++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<gexf xmlns="http://gexf.net/1.3" version="1.3">` + "\n")
+	b.WriteString("  <meta>\n    <creator>meerkat</creator>\n  </meta>\n")
+	b.WriteString(`  <graph defaultedgetype="directed" mode="static">` + "\n")
+	b.WriteString(`    <attributes class="node">` + "\n")
+	for _, a := range [][2]string{
+		{"desc", "string"}, {"namespace", "string"}, {"taskfile", "string"}, {"location", "string"},
+		{"complexity", "integer"}, {"fan_in", "integer"}, {"fan_out", "integer"},
+		{"requires", "string"}, {"missing", "boolean"},
+	} {
+		fmt.Fprintf(&b, "      <attribute id=\"%s\" title=\"%s\" type=\"%s\"/>\n", a[0], a[0], a[1])
+	}
+	labelKeys := tg.labelKeys()
+	for _, k := range labelKeys {
+		fmt.Fprintf(&b, "      <attribute id=\"%s\" title=\"%s\" type=\"string\"/>\n", xmlEscape(labelMarker+k), xmlEscape(labelMarker+k))
+	}
+	b.WriteString("    </attributes>\n")
+	b.WriteString(`    <attributes class="edge">` + "\n")
+	b.WriteString(`      <attribute id="kind" title="kind" type="string"/>` + "\n")
+	b.WriteString(`      <attribute id="location" title="location" type="string"/>` + "\n")
+	b.WriteString("    </attributes>\n")
+
+	b.WriteString("    <nodes>\n")
+	for _, n := range tg.Nodes {
+		fmt.Fprintf(&b, "      <node id=\"%s\" label=\"%s\">\n        <attvalues>\n", xmlEscape(n.Name), xmlEscape(n.Name))
+		writeGEXFValue(&b, "desc", n.Desc)
+		writeGEXFValue(&b, "namespace", n.Namespace)
+		writeGEXFValue(&b, "taskfile", n.Taskfile)
+		writeGEXFValue(&b, "location", n.Location)
+		writeGEXFValue(&b, "complexity", fmt.Sprint(n.Commands+references[n.Name]))
+		writeGEXFValue(&b, "fan_in", fmt.Sprint(fanIn[n.Name]))
+		writeGEXFValue(&b, "fan_out", fmt.Sprint(fanOut[n.Name]))
+		writeGEXFValue(&b, "requires", strings.Join(n.Requires, ","))
+		writeGEXFValue(&b, "missing", fmt.Sprint(n.Missing))
+		for _, k := range labelKeys {
+			writeGEXFValue(&b, labelMarker+k, n.Labels[k])
+		}
+		b.WriteString("        </attvalues>\n      </node>\n")
+	}
+	b.WriteString("    </nodes>\n    <edges>\n")
+	for i, e := range tg.Edges {
+		fmt.Fprintf(&b, "      <edge id=\"e%d\" source=\"%s\" target=\"%s\" weight=\"%d\">\n        <attvalues>\n", i, xmlEscape(e.From), xmlEscape(e.To), e.Weight)
+		writeGEXFValue(&b, "kind", e.Kind)
+		writeGEXFValue(&b, "location", e.Location)
+		b.WriteString("        </attvalues>\n      </edge>\n")
+	}
+	b.WriteString("    </edges>\n  </graph>\n</gexf>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeGEXFValue(b *strings.Builder, key, value string) {
+	if value != "" {
+		fmt.Fprintf(b, "          <attvalue for=\"%s\" value=\"%s\"/>\n", xmlEscape(key), xmlEscape(value))
+	}
+}
+
+func writeGraphMLData(b *strings.Builder, key, value string) {
+	if value != "" {
+		fmt.Fprintf(b, "      <data key=\"%s\">%s</data>\n", key, xmlEscape(value))
+	}
+}
+
+// xmlEscape escapes text for use in XML content and attribute values
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
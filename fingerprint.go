@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// runFingerprint prints a hash of the task graph's structure: its tasks and
+// the references between them. Descriptions, commands, positions and
+// formatting do not enter it, so it only changes when the graph does.
+func runFingerprint(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("fingerprint", flag.ExitOnError)
+	opts.register(fs)
+	expect := fs.String("expect", "", "Fail unless the fingerprint equals this one")
+	canonical := fs.Bool("canonical", false, "Print the canonical form that is hashed instead of the hash")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	g, merged, err := l.load()
+	if err != nil {
+		return err
+	}
+	tg, err := buildTaskGraph(g, merged, nil)
+	if err != nil {
+		return err
+	}
+
+	form := tg.canonicalForm()
+	if *canonical {
+		fmt.Print(form)
+		return nil
+	}
+	fingerprint := tg.fingerprint()
+	fmt.Println(fingerprint)
+	if *expect != "" && *expect != fingerprint {
+		return &failure{msg: "task graph changed, expected " + *expect}
+	}
+	return nil
+}
+
+// fingerprint hashes the canonical form of the graph
+func (tg *taskGraph) fingerprint() string {
+	sum := sha256.Sum256([]byte(tg.canonicalForm()))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// canonicalForm writes the graph one line per task and per reference, in
+// the order buildTaskGraph sorts them. Tasks carry their namespace, and
+// referenced tasks that do not exist are marked, since both change what a
+// name runs.
+func (tg *taskGraph) canonicalForm() string {
+	var b strings.Builder
+	b.WriteString("meerkat-fingerprint 1\n")
+	for _, n := range tg.Nodes {
+		if n.Missing {
+			fmt.Fprintf(&b, "missing %q\n", n.Name)
+		} else {
+			fmt.Fprintf(&b, "task %q namespace %q\n", n.Name, n.Namespace)
+		}
+	}
+	for _, e := range tg.Edges {
+		fmt.Fprintf(&b, "edge %q %q %s %d\n", e.From, e.To, e.Kind, e.Weight)
+	}
+	return b.String()
+}
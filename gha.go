@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ghaJob and ghaStep are the parts of a GitHub Actions workflow the export
+// writes, in the order the keys are usually written
+type ghaJob struct {
+	Name   string    `yaml:"name,omitempty"`
+	Needs  []string  `yaml:"needs,omitempty"`
+	RunsOn string    `yaml:"runs-on"`
+	Steps  []ghaStep `yaml:"steps"`
+}
+
+type ghaStep struct {
+	Name             string            `yaml:"name,omitempty"`
+	Uses             string            `yaml:"uses,omitempty"`
+	WorkingDirectory string            `yaml:"working-directory,omitempty"`
+	Env              map[string]string `yaml:"env,omitempty"`
+	Run              string            `yaml:"run,omitempty"`
+}
+
+// ghaJobID names the job of a task; job ids allow no colons, so namespaces
+// are joined with dashes as for just
+func ghaJobID(name string) string {
+	return justRecipe(name)
+}
+
+// writeWorkflow writes the tasks the starts reach through deps as a GitHub
+// Actions workflow, one job per task with needs for its deps, so each wave
+// of the graph runs in parallel on its own runners. Task calls in cmds run
+// inline through task, like delegated tasks, which run their deps again in
+// their own job.
+func writeWorkflow(w io.Writer, plan exportPlan, runsOn string) error {
+	recipes := make(map[string]exportRecipe)
+	for _, r := range plan.Recipes {
+		recipes[r.Name] = r
+	}
+	// Jobs in waves: each after every job it needs, by name within a wave
+	wave := make(map[string]int)
+	var measure func(name string, path []string) (int, error)
+	measure = func(name string, path []string) (int, error) {
+		if slices.Contains(path, name) {
+			return 0, fmt.Errorf("deps loop through %s, which a workflow cannot express", strings.Join(append(path, name), " -> "))
+		}
+		if n, ok := wave[name]; ok {
+			return n, nil
+		}
+		n := 0
+		for _, dep := range recipes[name].Deps {
+			d, err := measure(dep, append(path, name))
+			if err != nil {
+				return 0, err
+			}
+			n = max(n, d+1)
+		}
+		wave[name] = n
+		return n, nil
+	}
+	for _, start := range plan.Starts {
+		if _, err := measure(start, nil); err != nil {
+			return err
+		}
+	}
+	names := slices.Sorted(maps.Keys(wave))
+	slices.SortStableFunc(names, func(a, b string) int { return wave[a] - wave[b] })
+
+	owners := make(map[string]string)
+	jobs := &yaml.Node{Kind: yaml.MappingNode}
+	for _, name := range names {
+		r := recipes[name]
+		id := ghaJobID(name)
+		if other, ok := owners[id]; ok {
+			return fmt.Errorf("tasks %s and %s would both be job %s", other, name, id)
+		}
+		owners[id] = name
+		job := ghaJob{Name: name, RunsOn: runsOn, Steps: []ghaStep{{Uses: "actions/checkout@v4"}}}
+		for _, dep := range r.Deps {
+			job.Needs = append(job.Needs, ghaJobID(dep))
+		}
+		job.Steps = append(job.Steps, ghaSteps(r, plan.Env)...)
+		var node yaml.Node
+		if err := node.Encode(job); err != nil {
+			return err
+		}
+		jobs.Content = append(jobs.Content, scalarNode(id), &node)
+	}
+
+	workflow := &yaml.Node{Kind: yaml.MappingNode}
+	workflow.HeadComment = "Generated by meerkat export from the Taskfile; regenerate rather than edit."
+	on := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle, Content: []*yaml.Node{scalarNode("push"), scalarNode("workflow_dispatch")}}
+	workflow.Content = append(workflow.Content,
+		scalarNode("name"), scalarNode(strings.Join(plan.Starts, ", ")),
+		scalarNode("on"), on)
+	var static []resolvedVar
+	for _, v := range plan.Env {
+		if !v.Dynamic {
+			static = append(static, v)
+		}
+	}
+	if len(static) > 0 {
+		env := &yaml.Node{Kind: yaml.MappingNode}
+		for _, v := range static {
+			env.Content = append(env.Content, scalarNode(v.Name), scalarNode(v.Value))
+		}
+		workflow.Content = append(workflow.Content, scalarNode("env"), env)
+	}
+	workflow.Content = append(workflow.Content, scalarNode("jobs"), jobs)
+
+	out, err := encodeYAML(workflow)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// ghaSteps are the steps after checkout running one task: Task is set up
+// when anything runs through it, and the commands run as one script that
+// stops at the first failure, as a task's commands do
+func ghaSteps(r exportRecipe, global []resolvedVar) []ghaStep {
+	needsTask := r.Delegate || slices.ContainsFunc(r.Steps, func(s exportStep) bool { return s.Task != "" })
+	var steps []ghaStep
+	if needsTask {
+		steps = append(steps, ghaStep{Uses: "go-task/setup-task@v1"})
+	}
+	if r.Delegate {
+		return append(steps, ghaStep{Name: "task " + r.Name + " (" + r.Why + ")", Run: "task " + shellQuote(r.Name)})
+	}
+	if len(r.Steps) == 0 {
+		return steps
+	}
+
+	s := ghaStep{Name: r.Name, WorkingDirectory: r.Dir, Env: make(map[string]string)}
+	var lines, body, deferred []string
+	// Static global env is the workflow's; the rest is set here
+	for _, v := range append(slices.Clone(global), r.Env...) {
+		switch {
+		case v.Dynamic:
+			lines = append(lines, "export "+v.Name+`="$(`+v.Value+`)"`)
+		case !slices.Contains(global, v):
+			s.Env[v.Name] = v.Value
+		}
+	}
+	for _, step := range r.Steps {
+		line := step.Cmd
+		if step.Task != "" {
+			line = "task " + shellQuote(step.Task)
+		}
+		if step.Defer {
+			deferred = append(deferred, line)
+		} else {
+			body = append(body, line)
+		}
+	}
+	// Task runs deferred commands last in first out, whatever fails
+	if len(deferred) > 0 {
+		slices.Reverse(deferred)
+		lines = append(lines, "trap "+shellQuote(strings.Join(deferred, "\n"))+" EXIT")
+	}
+	s.Run = strings.Join(append(lines, body...), "\n") + "\n"
+	return append(steps, s)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-task/task/v3/taskfile"
+)
+
+// checkoutGitRef writes the tree of a git ref, read from the repository with
+// go-git, into a temporary directory, without touching the working tree or
+// needing git installed, and returns the path that corresponds to the local
+// Taskfile path inside it. The reader then sees the Taskfile and all of its
+// local includes as they were at that ref.
+func checkoutGitRef(path, ref string) (string, string, error) {
+	if path == "-" || taskfile.IsRemoteEntrypoint(path) {
+		return "", "", fmt.Errorf("--git-ref needs --taskfile to be a path inside a local git repository")
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	// The path itself may not exist in the working tree at all
+	dir, file := abs, ""
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		dir, file = filepath.Dir(abs), filepath.Base(abs)
+	}
+	repo, top, err := openRepository(dir)
+	if err != nil {
+		return "", "", err
+	}
+
+	// The top level may be reached through symlinks, so resolve both sides
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = real
+	}
+	if real, err := filepath.EvalSymlinks(top); err == nil {
+		top = real
+	}
+	rel, err := filepath.Rel(top, dir)
+	if err != nil {
+		return "", "", err
+	}
+	rel = filepath.Join(rel, file)
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return "", "", fmt.Errorf("unknown revision %s: %w", ref, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return "", "", err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", "", err
+	}
+	tmp, err := os.MkdirTemp("", "meerkat-ref-")
+	if err != nil {
+		return "", "", err
+	}
+	if err := writeGitTree(tree, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return "", "", fmt.Errorf("failed to extract %s: %w", ref, err)
+	}
+	return filepath.Join(tmp, rel), tmp, nil
+}
+
+// openRepository opens the git repository holding dir and returns it with
+// the top level of its working tree
+func openRepository(dir string) (*git.Repository, string, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			return nil, "", fmt.Errorf("%s is not inside a git repository", dir)
+		}
+		return nil, "", err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, "", err
+	}
+	return repo, wt.Filesystem.Root(), nil
+}
+
+// writeGitTree writes the files and symlinks of a tree under dest, through
+// the same checks as the entries of an archive. Submodules are left out, as
+// git archive leaves them.
+func writeGitTree(tree *object.Tree, dest string) error {
+	return tree.Files().ForEach(func(f *object.File) error {
+		target, err := extractPath(dest, f.Name)
+		if err != nil {
+			return err
+		}
+		switch f.Mode {
+		case filemode.Symlink:
+			link, err := f.Contents()
+			if err != nil {
+				return err
+			}
+			if err := checkLink(dest, target, link); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case filemode.Regular, filemode.Executable, filemode.Deprecated:
+			r, err := f.Reader()
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			perm := os.FileMode(0o644)
+			if f.Mode == filemode.Executable {
+				perm = 0o755
+			}
+			return writeFile(target, r, perm)
+		}
+		return nil
+	})
+}
+
+// gitHead is the commit checked out in the repository holding dir
+func gitHead(dir string) (string, error) {
+	repo, _, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFiles writes files into the work tree of repo and commits them all
+func commitFiles(t *testing.T, repo *git.Repository, dir string, files map[string]string) {
+	t.Helper()
+	writeFiles(t, dir, files)
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.AddGlob("."); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	if _, err := wt.Commit("commit", &git.CommitOptions{Author: sig}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckoutGitRef(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, dir, map[string]string{
+		"sub/Taskfile.yml": "version: '3'\nincludes:\n  lib: ./lib.yml\n",
+		"sub/lib.yml":      "old\n",
+	})
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("v1", head.Hash(), nil); err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, dir, map[string]string{"sub/lib.yml": "new\n"})
+
+	for _, ref := range []string{"v1", "HEAD~1", head.Hash().String()[:8]} {
+		t.Run(ref, func(t *testing.T) {
+			path, tree, err := checkoutGitRef(filepath.Join(dir, "sub", "Taskfile.yml"), ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tree)
+			if want := filepath.Join(tree, "sub", "Taskfile.yml"); path != want {
+				t.Errorf("path = %s, want %s", path, want)
+			}
+			lib, err := os.ReadFile(filepath.Join(tree, "sub", "lib.yml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(lib) != "old\n" {
+				t.Errorf("lib.yml at %s = %q, want the old content", ref, lib)
+			}
+		})
+	}
+
+	if _, _, err := checkoutGitRef(filepath.Join(dir, "sub"), "no-such-ref"); err == nil {
+		t.Error("checkoutGitRef() accepted an unknown ref")
+	}
+	if got, err := gitHead(filepath.Join(dir, "sub")); err != nil || got == head.Hash().String() {
+		t.Errorf("gitHead() = %s, %v, want the second commit", got, err)
+	}
+}
+
+func TestCheckoutGitRefOutsideRepository(t *testing.T) {
+	if _, _, err := checkoutGitRef(t.TempDir(), "HEAD"); err == nil {
+		t.Error("checkoutGitRef() succeeded outside a git repository")
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dominikbraun/graph"
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// storedGraph is a Taskfile graph as the store keeps it between runs: the
+// SHA-256 of every file a load of its root read. A later run whose files all
+// still have that content builds the graph from them without the reader,
+// so no include is fetched, revalidated or templated again. go-task's
+// syntax tree cannot be serialized, so each file is still parsed.
+type storedGraph struct {
+	Insecure bool              `json:"insecure,omitempty"`
+	Files    map[string]string `json:"files"` // Taskfile URI -> SHA-256 of the content
+	Loaded   time.Time         `json:"loaded"`
+	Used     time.Time         `json:"used"`
+}
+
+// newStoredGraph records a graph just read, or returns nil for one that
+// cannot be rebuilt from its files alone: one with a templated include,
+// whose location can depend on the environment of the run
+func newStoredGraph(g *ast.TaskfileGraph, insecure bool, read func(uri string) ([]byte, error)) *storedGraph {
+	adjacency, err := g.AdjacencyMap()
+	if err != nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	sg := &storedGraph{Insecure: insecure, Files: make(map[string]string), Loaded: now, Used: now}
+	for uri := range adjacency {
+		vertex, err := g.Vertex(uri)
+		if err != nil {
+			return nil
+		}
+		for include := range vertex.Taskfile.Includes.Values() {
+			if strings.Contains(include.Taskfile+include.Dir, "{{") {
+				return nil
+			}
+		}
+		data, err := read(uri)
+		if err != nil {
+			return nil
+		}
+		sg.Files[uri] = contentHash(data)
+	}
+	return sg
+}
+
+// build reads the graph from root again as the reader would, or returns nil
+// if any file has changed or is gone, an include now resolves to a file the
+// graph did not have, or a remote file is past the cache expiry
+func (sg *storedGraph) build(root taskfile.Node, insecure bool, expiry time.Duration, read func(uri string) ([]byte, error)) *ast.TaskfileGraph {
+	if sg == nil || sg.Insecure != insecure {
+		return nil
+	}
+	for uri := range sg.Files {
+		if taskfile.IsRemoteEntrypoint(uri) && time.Since(sg.Loaded) >= expiry {
+			return nil
+		}
+	}
+
+	g := ast.NewTaskfileGraph()
+	var add func(node taskfile.Node) bool
+	add = func(node taskfile.Node) bool {
+		uri := node.Location()
+		if _, err := g.Vertex(uri); err == nil {
+			return true
+		}
+		data, err := read(uri)
+		if err != nil || sg.Files[uri] != contentHash(data) {
+			return false
+		}
+		tf := parseTaskfile(uri, data)
+		if tf == nil || g.AddVertex(&ast.TaskfileVertex{URI: uri, Taskfile: tf}) != nil {
+			return false
+		}
+
+		for _, include := range tf.Includes.All() {
+			if strings.Contains(include.Taskfile+include.Dir, "{{") {
+				return false
+			}
+			entrypoint, err := node.ResolveEntrypoint(include.Taskfile)
+			if err != nil {
+				return false
+			}
+			dir, err := node.ResolveDir(include.Dir)
+			if err != nil {
+				return false
+			}
+			child, err := taskfile.NewNode(entrypoint, dir, insecure,
+				taskfile.WithParent(node),
+				taskfile.WithChecksum(include.Checksum),
+			)
+			if err != nil {
+				if include.Optional {
+					continue
+				}
+				return false
+			}
+			if !add(child) {
+				return false
+			}
+
+			// The edge carries the include with its directory resolved, as
+			// the reader's does
+			resolved := *include
+			resolved.Dir = dir
+			edge, err := g.Edge(uri, child.Location())
+			if errors.Is(err, graph.ErrEdgeNotFound) {
+				err = g.AddEdge(uri, child.Location(),
+					graph.EdgeData([]*ast.Include{&resolved}),
+					graph.EdgeWeight(1),
+				)
+			} else if err == nil {
+				data := append(edge.Properties.Data.([]*ast.Include), &resolved)
+				err = g.UpdateEdge(uri, child.Location(), graph.EdgeData(data), graph.EdgeWeight(len(data)))
+			}
+			if err != nil {
+				return false
+			}
+		}
+		return true
+	}
+	if !add(root) {
+		return nil
+	}
+	if order, err := g.Order(); err != nil || order != len(sg.Files) {
+		return nil
+	}
+	return g
+}
+
+// parseTaskfile decodes one Taskfile and sets the locations the reader
+// sets, or returns nil if it is not one
+func parseTaskfile(uri string, data []byte) *ast.Taskfile {
+	var tf ast.Taskfile
+	if err := yaml.Unmarshal(data, &tf); err != nil || tf.Version == nil {
+		return nil
+	}
+	tf.Location = uri
+	for task := range tf.Tasks.Values(nil) {
+		if task != nil && task.Location.Taskfile == "" {
+			task.Location.Taskfile = uri
+		}
+	}
+	return &tf
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readGraph returns the graph stored for root, or nil
+func (s *remoteStore) readGraph(root string) *storedGraph {
+	idx, err := s.readIndex()
+	if err != nil {
+		return nil
+	}
+	return idx.Graphs[root]
+}
+
+// writeGraph stores the graph of root, replacing the one stored before
+func (s *remoteStore) writeGraph(root string, sg *storedGraph) error {
+	idx, err := s.readIndex()
+	if err != nil {
+		return err
+	}
+	idx.Graphs[root] = sg
+	return s.writeIndex(idx)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-task/task/v3/taskfile"
+)
+
+func TestStoredGraph(t *testing.T) {
+	files := map[string]string{
+		"Taskfile.yml": `version: '3'
+includes:
+  lib:
+    taskfile: ./lib
+    dir: ./lib
+  opt:
+    taskfile: ./opt.yml
+    optional: true
+tasks:
+  build:
+    deps: [lib:gen]
+`,
+		"lib/Taskfile.yml": `version: '3'
+tasks:
+  gen: echo gen
+`,
+	}
+	l, _, merged := loadTest(t, files)
+	dir := filepath.Dir(l.opts.taskfileURL)
+	root, lib := filepath.Join(dir, "Taskfile.yml"), filepath.Join(dir, "lib", "Taskfile.yml")
+	stored := l.store.readGraph(root)
+	if stored == nil {
+		t.Fatal("readGraph() = nil after a load")
+	}
+	if got, want := slices.Sorted(maps.Keys(stored.Files)), []string{root, lib}; !slices.Equal(got, want) {
+		t.Errorf("stored files = %v, want %v", got, want)
+	}
+
+	// A later run builds the same graph from the store
+	o := *l.opts
+	next, err := newLoader(&o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer next.Close()
+	node, err := taskfile.NewRootNode(root, "", false, o.timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := next.store.readGraph(root).build(node, false, o.cacheExpiry, next.readSource)
+	if g == nil {
+		t.Fatal("build() = nil with no file changed")
+	}
+	rebuilt, err := g.Merge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want []string
+	for name := range rebuilt.Tasks.Keys(nil) {
+		got = append(got, name)
+	}
+	for name := range merged.Tasks.Keys(nil) {
+		want = append(want, name)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("rebuilt tasks = %v, want %v", got, want)
+	}
+	gen, ok := rebuilt.Tasks.Get("lib:gen")
+	if !ok || gen.Dir != filepath.Join(dir, "lib") || gen.Location.Taskfile != lib {
+		t.Errorf("rebuilt lib:gen = %+v, want it in lib", gen)
+	}
+
+	tests := []struct {
+		name   string
+		change func() error
+	}{
+		{"included file edited", func() error { return os.WriteFile(lib, []byte("version: '3'\ntasks:\n  gen: echo other\n"), 0o644) }},
+		{"included file removed", func() error { return os.Remove(lib) }},
+		{"optional include created", func() error { return os.WriteFile(filepath.Join(dir, "opt.yml"), []byte("version: '3'\n"), 0o644) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				os.Remove(filepath.Join(dir, "opt.yml"))
+				writeFiles(t, dir, files)
+			}()
+			if g := stored.build(node, false, o.cacheExpiry, next.readSource); g != nil {
+				t.Error("build() = graph, want nil")
+			}
+		})
+	}
+	if g := stored.build(node, false, o.cacheExpiry, next.readSource); g == nil {
+		t.Error("build() = nil with the files restored")
+	}
+	if g := stored.build(node, true, o.cacheExpiry, next.readSource); g != nil {
+		t.Error("build() when insecure = graph, want nil")
+	}
+}
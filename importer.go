@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dominikbraun/graph"
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// importFormat names the build file another runner reads, which --taskfile
+// takes in place of a Taskfile, or "" for a path that is not one
+func importFormat(path string) string {
+	if path == "-" || taskfile.IsRemoteEntrypoint(path) {
+		return ""
+	}
+	switch base := filepath.Base(path); {
+	case base == "Makefile" || base == "makefile" || base == "GNUmakefile" || strings.HasSuffix(base, ".mk"):
+		return "make"
+	case base == "package.json":
+		return "npm"
+	case strings.EqualFold(base, "justfile") || strings.EqualFold(base, ".justfile") || strings.HasSuffix(base, ".just"):
+		return "just"
+	}
+	return ""
+}
+
+// importers parse the build file of each format into tasks
+var importers = map[string]func(src []byte) []importedTask{
+	"make": parseMakefile,
+	"npm":  parsePackageJSON,
+	"just": parseJustfile,
+}
+
+// importedTask is a task of another runner's build file, on the line it is
+// defined on there
+type importedTask struct {
+	Name     string
+	Line     int
+	Desc     string
+	Internal bool
+	Aliases  []string
+	Deps     []string
+	Sources  []string
+	Cmds     []importedCmd
+}
+
+// importedCmd is a command of an imported task, or a call of another one,
+// where it is written in the build file; 0 is the line of the task
+type importedCmd struct {
+	Cmd          string
+	Task         string
+	IgnoreError  bool
+	Line, Column int
+}
+
+// importNode is a build file of another runner as the root Taskfile. It
+// reads as a Taskfile translated from it, so the graph, analyses and exports
+// work on it unchanged, and keeps its location and dir.
+type importNode struct {
+	*taskfile.StdinNode
+	path string
+	src  []byte
+}
+
+func (n *importNode) Location() string {
+	return n.path
+}
+
+func (n *importNode) Read() ([]byte, error) {
+	return n.src, nil
+}
+
+// translate reads a build file as a Taskfile, afresh on every load so the
+// long-running modes see its edits, and keeps it for readSource
+func (l *loader) translate(path string) (string, []byte, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	src := importedTaskfile(importers[importFormat(path)](data))
+	if l.imported == nil {
+		l.imported = make(map[string][]byte)
+	}
+	l.imported[path] = src
+	return path, src, nil
+}
+
+// newImportNode is the build file at --taskfile as the root Taskfile
+func (l *loader) newImportNode() (taskfile.Node, error) {
+	path, src, err := l.translate(l.opts.taskfileURL)
+	if err != nil {
+		return nil, err
+	}
+	node, err := taskfile.NewStdinNode(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	return &importNode{StdinNode: node, path: path, src: src}, nil
+}
+
+// buildFile is a build file of another runner given with --with, whose
+// tasks join the graph as if the root Taskfile included it
+type buildFile struct {
+	namespace, path string
+}
+
+// buildFiles collects --with, each use NS=PATH or a PATH whose tasks go under
+// the name of its format, as in make:build
+type buildFiles []buildFile
+
+func (b *buildFiles) String() string {
+	var parts []string
+	for _, f := range *b {
+		parts = append(parts, f.namespace+"="+f.path)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (b *buildFiles) Set(value string) error {
+	namespace, path, ok := strings.Cut(value, "=")
+	if !ok {
+		path = value
+		namespace = importFormat(path)
+	}
+	if importFormat(path) == "" {
+		return fmt.Errorf("%s is not a Makefile, package.json or justfile", path)
+	}
+	*b = append(*b, buildFile{namespace: namespace, path: path})
+	return nil
+}
+
+// graftBuildFiles adds the --with build files to a graph just read, each as
+// an include of the root Taskfile under its namespace, so they merge, and
+// show in the inclusion graph, as Taskfiles would
+func (l *loader) graftBuildFiles(g *ast.TaskfileGraph, root string) error {
+	if len(l.opts.with) == 0 {
+		return nil
+	}
+	rootVertex, err := g.Vertex(root)
+	if err != nil {
+		return err
+	}
+	for _, f := range l.opts.with {
+		if _, taken := rootVertex.Taskfile.Includes.Get(f.namespace); taken {
+			return fmt.Errorf("--with %s: the root Taskfile already includes namespace %s", f.path, f.namespace)
+		}
+		path, src, err := l.translate(f.path)
+		if err != nil {
+			return err
+		}
+		var tf ast.Taskfile
+		if err := yaml.Unmarshal(src, &tf); err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+		tf.Location = path
+		for task := range tf.Tasks.Values(nil) {
+			task.Location.Taskfile = path
+		}
+		if err := g.AddVertex(&ast.TaskfileVertex{URI: path, Taskfile: &tf}); err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+		include := &ast.Include{Namespace: f.namespace, Taskfile: path, Dir: filepath.Dir(path)}
+		if err := g.AddEdge(root, path, graph.EdgeData([]*ast.Include{include}), graph.EdgeWeight(1)); err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+		rootVertex.Taskfile.Includes.Set(f.namespace, include)
+	}
+	return nil
+}
+
+// importedTaskfile writes the tasks as a Taskfile whose every task key and
+// command is on the line of the build file it came from, so positions
+// reported anywhere point into that file. That takes flow style, one mapping
+// spanning the lines with blank lines filling the gaps. Anything out of line
+// order goes where the last item ended.
+func importedTaskfile(tasks []importedTask) []byte {
+	var b strings.Builder
+	b.WriteString(`{version: "3", tasks: {`)
+	line, column := 1, b.Len()+1
+	// write puts s at the line and column given, if still ahead
+	write := func(s string, atLine, atColumn int) {
+		if atLine > line {
+			b.WriteString(strings.Repeat("\n", atLine-line))
+			line, column = atLine, 1
+		}
+		if atColumn > column {
+			b.WriteString(strings.Repeat(" ", atColumn-column))
+			column = atColumn
+		} else if column > 1 {
+			b.WriteString(" ")
+			column++
+		}
+		b.WriteString(s)
+		column += len(s)
+	}
+	for _, t := range tasks {
+		head := strconv.Quote(t.Name) + ": {"
+		if t.Desc != "" {
+			head += "desc: " + strconv.Quote(t.Desc) + ", "
+		}
+		if t.Internal {
+			head += "internal: true, "
+		}
+		if len(t.Aliases) > 0 {
+			head += "aliases: " + flowList(t.Aliases) + ", "
+		}
+		if len(t.Deps) > 0 {
+			head += "deps: " + flowList(t.Deps) + ", "
+		}
+		if len(t.Sources) > 0 {
+			head += "sources: " + flowList(t.Sources) + ", "
+		}
+		write(head+"cmds: [", t.Line, 1)
+		for _, c := range t.Cmds {
+			var item string
+			switch {
+			case c.Task != "":
+				item = "{task: " + strconv.Quote(c.Task) + "}"
+			case c.IgnoreError:
+				item = "{cmd: " + strconv.Quote(c.Cmd) + ", ignore_error: true}"
+			default:
+				item = strconv.Quote(c.Cmd)
+			}
+			write(item+",", c.Line, c.Column)
+		}
+		write("]},", 0, 0)
+	}
+	b.WriteString("\n}}\n")
+	return []byte(b.String())
+}
+
+// flowList is a YAML flow sequence of quoted strings
+func flowList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = strconv.Quote(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
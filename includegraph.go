@@ -0,0 +1,344 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// includeGraph is the file-level inclusion graph shared by the exporters,
+// with the optional includes the load skipped
+type includeGraph struct {
+	Nodes   []fileNode
+	Edges   []includeLink
+	Skipped []skippedInclude
+}
+
+// fileNode is one Taskfile of the graph
+type fileNode struct {
+	URI    string `json:"uri"`
+	Root   bool   `json:"root,omitempty"`
+	Remote bool   `json:"remote,omitempty"`
+}
+
+// includeLink is one include stanza with the settings that change how the
+// child is merged
+type includeLink struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Namespace string `json:"namespace"`
+	Optional  bool   `json:"optional,omitempty"`
+	Flatten   bool   `json:"flatten,omitempty"`
+	Internal  bool   `json:"internal,omitempty"`
+}
+
+// label is the namespace followed by any include settings that are on
+func (e includeLink) label() string {
+	var flags []string
+	if e.Optional {
+		flags = append(flags, "optional")
+	}
+	if e.Flatten {
+		flags = append(flags, "flatten")
+	}
+	if e.Internal {
+		flags = append(flags, "internal")
+	}
+	if len(flags) == 0 {
+		return e.Namespace
+	}
+	return fmt.Sprintf("%s (%s)", e.Namespace, strings.Join(flags, ", "))
+}
+
+// buildIncludeGraph builds the exportable inclusion graph
+func buildIncludeGraph(g *ast.TaskfileGraph) (*includeGraph, error) {
+	edges, err := includeEdges(g)
+	if err != nil {
+		return nil, err
+	}
+	root, err := rootURI(g)
+	if err != nil {
+		return nil, err
+	}
+	adjacency, err := g.AdjacencyMap()
+	if err != nil {
+		return nil, err
+	}
+
+	ig := &includeGraph{}
+	for uri := range adjacency {
+		ig.Nodes = append(ig.Nodes, fileNode{URI: uri, Root: uri == root, Remote: taskfile.IsRemoteEntrypoint(uri)})
+	}
+	sort.Slice(ig.Nodes, func(i, j int) bool { return ig.Nodes[i].URI < ig.Nodes[j].URI })
+
+	for _, e := range edges {
+		ig.Edges = append(ig.Edges, includeLink{
+			From:      e.Parent,
+			To:        e.Child,
+			Namespace: e.Include.Namespace,
+			Optional:  e.Include.Optional,
+			Flatten:   e.Include.Flatten,
+			Internal:  e.Include.Internal,
+		})
+	}
+	return ig, nil
+}
+
+// writeIncludeGraph writes the inclusion graph in one of the export formats
+func writeIncludeGraph(w io.Writer, ig *includeGraph, format string) error {
+	switch format {
+	case "text":
+		return writeIncludeText(w, ig)
+	case "dot":
+		return writeIncludeDOT(w, ig)
+	case "mermaid":
+		return writeIncludeMermaid(w, ig)
+	case "json":
+		return writeIncludeJSON(w, ig)
+	case "graphml":
+		return writeIncludeGraphML(w, ig)
+	case "gexf":
+		return writeIncludeGEXF(w, ig)
+	case "cytoscape":
+		return writeIncludeCytoscape(w, ig)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// writeIncludeText lists every Taskfile with the includes it declares
+func writeIncludeText(w io.Writer, ig *includeGraph) error {
+	var b strings.Builder
+	for _, n := range ig.Nodes {
+		b.WriteString(n.URI)
+		if n.Root {
+			b.WriteString(" (root)")
+		}
+		b.WriteString("\n")
+		for _, e := range ig.Edges {
+			if e.From == n.URI {
+				fmt.Fprintf(&b, "  - %s: %s\n", e.label(), e.To)
+			}
+		}
+		for _, s := range ig.Skipped {
+			if s.From == n.URI {
+				fmt.Fprintf(&b, "  - %s (skipped): %s: %s\n", s.Namespace, s.URI, s.Reason)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeIncludeDOT renders the inclusion graph as Graphviz DOT
+func writeIncludeDOT(w io.Writer, ig *includeGraph) error {
+	var b strings.Builder
+	b.WriteString("digraph includes {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=note, style=filled, fillcolor=white];\n")
+	for _, n := range ig.Nodes {
+		var attrs []string
+		if n.Root {
+			attrs = append(attrs, "penwidth=2")
+		}
+		if n.Remote {
+			attrs = append(attrs, "fillcolor="+dotID(clusterColor(0)))
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, "  %s [%s];\n", dotID(n.URI), strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(&b, "  %s;\n", dotID(n.URI))
+		}
+	}
+	for _, e := range ig.Edges {
+		attrs := "label=" + dotID(e.label())
+		if e.Optional {
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotID(e.From), dotID(e.To), attrs)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeIncludeMermaid renders the inclusion graph as a Mermaid flowchart
+func writeIncludeMermaid(w io.Writer, ig *includeGraph) error {
+	ids := make(map[string]string, len(ig.Nodes))
+	for i, n := range ig.Nodes {
+		ids[n.URI] = fmt.Sprintf("f%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, n := range ig.Nodes {
+		fmt.Fprintf(&b, "  %s[%s]\n", ids[n.URI], mermaidLabel(n.URI))
+	}
+	for _, e := range ig.Edges {
+		arrow := "-->"
+		if e.Optional {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s|%s| %s\n", ids[e.From], arrow, mermaidLabel(e.label()), ids[e.To])
+	}
+	for _, n := range ig.Nodes {
+		if n.Remote {
+			fmt.Fprintf(&b, "  style %s fill:%s\n", ids[n.URI], clusterColor(0))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeIncludeJSON writes the inclusion graph as {"nodes": [...], "edges": [...]}
+func writeIncludeJSON(w io.Writer, ig *includeGraph) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Nodes   []fileNode       `json:"nodes"`
+		Edges   []includeLink    `json:"edges"`
+		Skipped []skippedInclude `json:"skipped,omitempty"`
+	}{ig.Nodes, ig.Edges, ig.Skipped})
+}
+
+// writeIncludeGraphML writes the inclusion graph as GraphML
+func writeIncludeGraphML(w io.Writer, ig *includeGraph) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	b.WriteString(`  <key id="root" for="node" attr.name="root" attr.type="boolean"/>` + "\n")
+	b.WriteString(`  <key id="remote" for="node" attr.name="remote" attr.type="boolean"/>` + "\n")
+	b.WriteString(`  <key id="namespace" for="edge" attr.name="namespace" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="optional" for="edge" attr.name="optional" attr.type="boolean"/>` + "\n")
+	b.WriteString(`  <key id="flatten" for="edge" attr.name="flatten" attr.type="boolean"/>` + "\n")
+	b.WriteString(`  <key id="internal" for="edge" attr.name="internal" attr.type="boolean"/>` + "\n")
+	b.WriteString(`  <graph id="includes" edgedefault="directed">` + "\n")
+	for _, n := range ig.Nodes {
+		fmt.Fprintf(&b, "    <node id=\"%s\">\n", xmlEscape(n.URI))
+		writeGraphMLData(&b, "root", graphMLBool(n.Root))
+		writeGraphMLData(&b, "remote", graphMLBool(n.Remote))
+		b.WriteString("    </node>\n")
+	}
+	for i, e := range ig.Edges {
+		fmt.Fprintf(&b, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\">\n", i, xmlEscape(e.From), xmlEscape(e.To))
+		writeGraphMLData(&b, "namespace", e.Namespace)
+		writeGraphMLData(&b, "optional", graphMLBool(e.Optional))
+		writeGraphMLData(&b, "flatten", graphMLBool(e.Flatten))
+		writeGraphMLData(&b, "internal", graphMLBool(e.Internal))
+		b.WriteString("    </edge>\n")
+	}
+	b.WriteString("  </graph>\n</graphml>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeIncludeCytoscape writes the inclusion graph as Cytoscape.js elements.
+// Nodes are classed root and remote, edges include and by the settings that
+// are on, as the task graph's elements are classed by kind.
+func writeIncludeCytoscape(w io.Writer, ig *includeGraph) error {
+	var doc struct {
+		Elements struct {
+			Nodes []cytoscapeElement `json:"nodes"`
+			Edges []cytoscapeElement `json:"edges"`
+		} `json:"elements"`
+	}
+	doc.Elements.Nodes = []cytoscapeElement{}
+	doc.Elements.Edges = []cytoscapeElement{}
+	for _, n := range ig.Nodes {
+		data := map[string]any{"id": n.URI, "label": n.URI}
+		var classes []string
+		if n.Root {
+			data["root"] = true
+			classes = append(classes, "root")
+		}
+		if n.Remote {
+			data["remote"] = true
+			classes = append(classes, "remote")
+		}
+		doc.Elements.Nodes = append(doc.Elements.Nodes, cytoscapeElement{Data: data, Classes: classes})
+	}
+	for i, e := range ig.Edges {
+		data := map[string]any{"id": fmt.Sprintf("e%d", i), "source": e.From, "target": e.To, "namespace": e.Namespace, "label": e.label()}
+		classes := []string{"include"}
+		for _, setting := range []struct {
+			name string
+			on   bool
+		}{{"optional", e.Optional}, {"flatten", e.Flatten}, {"internal", e.Internal}} {
+			if setting.on {
+				data[setting.name] = true
+				classes = append(classes, setting.name)
+			}
+		}
+		doc.Elements.Edges = append(doc.Elements.Edges, cytoscapeElement{Data: data, Classes: classes})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// writeIncludeGEXF writes the inclusion graph as GEXF for Gephi, with each
+// Taskfile's includes and includers counted
+func writeIncludeGEXF(w io.Writer, ig *includeGraph) error {
+	includes := make(map[string]int)
+	includers := make(map[string]int)
+	for _, e := range ig.Edges {
+		includes[e.From]++
+		includers[e.To]++
+	}
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<gexf xmlns="http://gexf.net/1.3" version="1.3">` + "\n")
+	b.WriteString("  <meta>\n    <creator>meerkat</creator>\n  </meta>\n")
+	b.WriteString(`  <graph defaultedgetype="directed" mode="static">` + "\n")
+	b.WriteString(`    <attributes class="node">` + "\n")
+	for _, a := range [][2]string{{"root", "boolean"}, {"remote", "boolean"}, {"includes", "integer"}, {"included_by", "integer"}} {
+		fmt.Fprintf(&b, "      <attribute id=\"%s\" title=\"%s\" type=\"%s\"/>\n", a[0], a[0], a[1])
+	}
+	b.WriteString("    </attributes>\n")
+	b.WriteString(`    <attributes class="edge">` + "\n")
+	for _, a := range [][2]string{{"namespace", "string"}, {"optional", "boolean"}, {"flatten", "boolean"}, {"internal", "boolean"}} {
+		fmt.Fprintf(&b, "      <attribute id=\"%s\" title=\"%s\" type=\"%s\"/>\n", a[0], a[0], a[1])
+	}
+	b.WriteString("    </attributes>\n")
+
+	b.WriteString("    <nodes>\n")
+	for _, n := range ig.Nodes {
+		fmt.Fprintf(&b, "      <node id=\"%s\" label=\"%s\">\n        <attvalues>\n", xmlEscape(n.URI), xmlEscape(n.URI))
+		writeGEXFValue(&b, "root", fmt.Sprint(n.Root))
+		writeGEXFValue(&b, "remote", fmt.Sprint(n.Remote))
+		writeGEXFValue(&b, "includes", fmt.Sprint(includes[n.URI]))
+		writeGEXFValue(&b, "included_by", fmt.Sprint(includers[n.URI]))
+		b.WriteString("        </attvalues>\n      </node>\n")
+	}
+	b.WriteString("    </nodes>\n    <edges>\n")
+	for i, e := range ig.Edges {
+		fmt.Fprintf(&b, "      <edge id=\"e%d\" source=\"%s\" target=\"%s\" label=\"%s\">\n        <attvalues>\n", i, xmlEscape(e.From), xmlEscape(e.To), xmlEscape(e.label()))
+		writeGEXFValue(&b, "namespace", e.Namespace)
+		writeGEXFValue(&b, "optional", fmt.Sprint(e.Optional))
+		writeGEXFValue(&b, "flatten", fmt.Sprint(e.Flatten))
+		writeGEXFValue(&b, "internal", fmt.Sprint(e.Internal))
+		b.WriteString("        </attvalues>\n      </edge>\n")
+	}
+	b.WriteString("    </edges>\n  </graph>\n</gexf>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// graphMLBool renders true as "true" and leaves false out entirely
+func graphMLBool(v bool) string {
+	if v {
+		return "true"
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testIncludeGraph is a root including a local file, optionally, and a
+// remote one
+var testIncludeGraph = &includeGraph{
+	Nodes: []fileNode{
+		{URI: "/repo/Taskfile.yml", Root: true},
+		{URI: "/repo/ci/Taskfile.yml"},
+		{URI: "https://example.com/Taskfile.yml?ref=a&b", Remote: true},
+	},
+	Edges: []includeLink{
+		{From: "/repo/Taskfile.yml", To: "/repo/ci/Taskfile.yml", Namespace: "ci", Optional: true},
+		{From: "/repo/Taskfile.yml", To: "https://example.com/Taskfile.yml?ref=a&b", Namespace: "shared", Flatten: true},
+	},
+}
+
+func TestWriteIncludeGEXF(t *testing.T) {
+	var b strings.Builder
+	if err := writeIncludeGraph(&b, testIncludeGraph, "gexf"); err != nil {
+		t.Fatal(err)
+	}
+	type attvalue struct {
+		For   string `xml:"for,attr"`
+		Value string `xml:"value,attr"`
+	}
+	var doc struct {
+		Nodes []struct {
+			ID     string     `xml:"id,attr"`
+			Values []attvalue `xml:"attvalues>attvalue"`
+		} `xml:"graph>nodes>node"`
+		Edges []struct {
+			Source string     `xml:"source,attr"`
+			Target string     `xml:"target,attr"`
+			Label  string     `xml:"label,attr"`
+			Values []attvalue `xml:"attvalues>attvalue"`
+		} `xml:"graph>edges>edge"`
+	}
+	if err := xml.Unmarshal([]byte(b.String()), &doc); err != nil {
+		t.Fatalf("invalid GEXF: %v\n%s", err, b.String())
+	}
+	values := func(vs []attvalue) map[string]string {
+		m := make(map[string]string)
+		for _, v := range vs {
+			m[v.For] = v.Value
+		}
+		return m
+	}
+
+	if len(doc.Nodes) != 3 || len(doc.Edges) != 2 {
+		t.Fatalf("GEXF has %d nodes and %d edges, want 3 and 2", len(doc.Nodes), len(doc.Edges))
+	}
+	root := values(doc.Nodes[0].Values)
+	if doc.Nodes[0].ID != "/repo/Taskfile.yml" || root["root"] != "true" || root["includes"] != "2" || root["included_by"] != "0" {
+		t.Errorf("root node = %s %v", doc.Nodes[0].ID, root)
+	}
+	if remote := values(doc.Nodes[2].Values); doc.Nodes[2].ID != "https://example.com/Taskfile.yml?ref=a&b" || remote["remote"] != "true" || remote["included_by"] != "1" {
+		t.Errorf("remote node = %s %v", doc.Nodes[2].ID, remote)
+	}
+	e := doc.Edges[0]
+	if ev := values(e.Values); e.Source != "/repo/Taskfile.yml" || e.Target != "/repo/ci/Taskfile.yml" || e.Label != "ci (optional)" || ev["namespace"] != "ci" || ev["optional"] != "true" || ev["flatten"] != "false" {
+		t.Errorf("edge = %+v", e)
+	}
+}
+
+func TestWriteIncludeCytoscape(t *testing.T) {
+	var b strings.Builder
+	if err := writeIncludeGraph(&b, testIncludeGraph, "cytoscape"); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Elements struct {
+			Nodes []cytoscapeElement `json:"nodes"`
+			Edges []cytoscapeElement `json:"edges"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal([]byte(b.String()), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, b.String())
+	}
+	nodes, edges := doc.Elements.Nodes, doc.Elements.Edges
+	if len(nodes) != 3 || len(edges) != 2 {
+		t.Fatalf("got %d nodes and %d edges, want 3 and 2", len(nodes), len(edges))
+	}
+	if nodes[0].Data["id"] != "/repo/Taskfile.yml" || !reflect.DeepEqual(nodes[0].Classes, []string{"root"}) {
+		t.Errorf("root node = %+v", nodes[0])
+	}
+	if !reflect.DeepEqual(nodes[2].Classes, []string{"remote"}) {
+		t.Errorf("remote node = %+v", nodes[2])
+	}
+	tests := []struct {
+		edge    cytoscapeElement
+		source  string
+		label   string
+		classes []string
+	}{
+		{edges[0], "/repo/Taskfile.yml", "ci (optional)", []string{"include", "optional"}},
+		{edges[1], "/repo/Taskfile.yml", "shared (flatten)", []string{"include", "flatten"}},
+	}
+	for _, tt := range tests {
+		if tt.edge.Data["source"] != tt.source || tt.edge.Data["label"] != tt.label || !reflect.DeepEqual(tt.edge.Classes, tt.classes) {
+			t.Errorf("edge = %+v, want from %s labeled %q with classes %v", tt.edge, tt.source, tt.label, tt.classes)
+		}
+	}
+}
+
+func TestWriteIncludeGraphFormats(t *testing.T) {
+	// The include graph comes in every format the task graph does
+	tg := &taskGraph{}
+	for _, format := range []string{"dot", "mermaid", "json", "graphml", "cytoscape", "gexf"} {
+		if err := writeTaskGraph(io.Discard, tg, format); err != nil {
+			t.Fatalf("writeTaskGraph(%s) = %v", format, err)
+		}
+		if err := writeIncludeGraph(io.Discard, testIncludeGraph, format); err != nil {
+			t.Errorf("writeIncludeGraph(%s) = %v", format, err)
+		}
+	}
+}
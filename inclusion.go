@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// includeEdge is one include stanza linking a parent Taskfile to a child
+type includeEdge struct {
+	Parent  string
+	Child   string
+	Include *ast.Include
+}
+
+// includeEdges lists every include in the graph, ordered by parent then namespace
+func includeEdges(g *ast.TaskfileGraph) ([]includeEdge, error) {
+	adjacency, err := g.AdjacencyMap()
+	if err != nil {
+		return nil, err
+	}
+
+	var edges []includeEdge
+	for parent, children := range adjacency {
+		for child, edge := range children {
+			includes, _ := edge.Properties.Data.([]*ast.Include)
+			for _, include := range includes {
+				edges = append(edges, includeEdge{Parent: parent, Child: child, Include: include})
+			}
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Parent != edges[j].Parent {
+			return edges[i].Parent < edges[j].Parent
+		}
+		return edges[i].Include.Namespace < edges[j].Include.Namespace
+	})
+	return edges, nil
+}
+
+// rootURI returns the URI of the root Taskfile in the graph
+func rootURI(g *ast.TaskfileGraph) (string, error) {
+	predecessors, err := g.PredecessorMap()
+	if err != nil {
+		return "", err
+	}
+	for uri, parents := range predecessors {
+		if len(parents) == 0 {
+			return uri, nil
+		}
+	}
+	return "", nil
+}
+
+// includeScopes walks the graph from the root and returns every scope each
+// Taskfile is merged under. Files included more than once have several.
+func includeScopes(g *ast.TaskfileGraph) (map[string][]includeScope, error) {
+	edges, err := includeEdges(g)
+	if err != nil {
+		return nil, err
+	}
+	root, err := rootURI(g)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[string][]includeEdge)
+	for _, e := range edges {
+		children[e.Parent] = append(children[e.Parent], e)
+	}
+
+	scopes := make(map[string][]includeScope)
+	var visit func(uri string, scope includeScope)
+	visit = func(uri string, scope includeScope) {
+		scopes[uri] = append(scopes[uri], scope)
+		for _, e := range children[uri] {
+			visit(e.Child, childScope(scope, e.Include, nil))
+		}
+	}
+	visit(root, includeScope{})
+	return scopes, nil
+}
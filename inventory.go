@@ -0,0 +1,405 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// runInventory reports what the Taskfile's commands expect from the machine
+// they run on
+func runInventory(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("inventory", flag.ExitOnError)
+	opts.register(fs)
+	format := fs.String("format", "text", "Output format: text, json or cyclonedx")
+	byOS := fs.Bool("by-os", false, "Report only the packages installed, consolidated per OS")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if *byOS && *format == "cyclonedx" {
+		return fmt.Errorf("--by-os cannot be combined with --format cyclonedx")
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	ws, err := openWorkspace(l)
+	if err != nil {
+		return err
+	}
+	inv := collectInventory(ws)
+
+	if *byOS {
+		pkgs := packagesByOS(inv.Packages)
+		if *format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(pkgs)
+		}
+		if *format != "text" {
+			return fmt.Errorf("unknown format %q", *format)
+		}
+		return writePackagesByOS(os.Stdout, pkgs)
+	}
+
+	switch *format {
+	case "text":
+		return inv.writeText(os.Stdout)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(inv)
+	case "cyclonedx":
+		return inv.writeCycloneDX(os.Stdout, opts.taskfileURL)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+// inventory collects the external requirements of every task
+type inventory struct {
+	Tools      []toolUse         `json:"tools"`
+	Packages   []packageUse      `json:"packages"`
+	Conflicts  []versionConflict `json:"conflicts"`
+	Containers []containerUse    `json:"containers"`
+}
+
+// toolUse is an external program and the tasks that run it
+type toolUse struct {
+	Name  string   `json:"name"`
+	Core  bool     `json:"core,omitempty"` // part of a standard POSIX userland
+	Tasks []string `json:"tasks"`
+}
+
+// containerUse is one container runtime invocation. Image is empty when the
+// command needs a runtime without naming an image, as docker compose does.
+type containerUse struct {
+	Task     string `json:"task"`
+	Runtime  string `json:"runtime"`
+	Action   string `json:"action"`
+	Image    string `json:"image,omitempty"`
+	Pinning  string `json:"pinning,omitempty"`
+	Location string `json:"location"`
+}
+
+// containerRuntimes are the CLIs that run containers
+var containerRuntimes = []string{"docker", "podman", "nerdctl", "docker-compose", "podman-compose"}
+
+// containerValueFlags are run/create options that take a separate value,
+// which must be skipped to find the image argument
+var containerValueFlags = map[string]bool{
+	"-e": true, "--env": true, "--env-file": true, "-v": true, "--volume": true,
+	"-p": true, "--publish": true, "-w": true, "--workdir": true, "--name": true,
+	"--network": true, "--net": true, "--entrypoint": true, "-u": true, "--user": true,
+	"--platform": true, "--mount": true, "-l": true, "--label": true, "--cpus": true,
+	"-m": true, "--memory": true, "--add-host": true, "--device": true, "-h": true,
+	"--hostname": true, "--restart": true, "--pull": true, "--cap-add": true,
+	"--cap-drop": true, "--dns": true, "--tmpfs": true, "--ulimit": true,
+	"--security-opt": true, "--gpus": true, "--log-driver": true, "--shm-size": true,
+	"--network-alias": true, "--pid": true, "--ipc": true, "--link": true,
+	"--expose": true, "--runtime": true, "--volumes-from": true, "-a": true,
+	"--attach": true, "--cidfile": true, "--group-add": true, "--label-file": true,
+}
+
+// shellBuiltins run inside the shell and need nothing installed
+var shellBuiltins = map[string]bool{
+	"echo": true, "cd": true, "export": true, "set": true, "unset": true, "test": true,
+	"[": true, "true": true, "false": true, "printf": true, "read": true, "exit": true,
+	"return": true, "source": true, ".": true, "eval": true, "shift": true, "pwd": true,
+	"wait": true, "trap": true, "umask": true, "alias": true, "type": true, "local": true,
+	"declare": true, "readonly": true, "if": true, "then": true, "else": true, "elif": true,
+	"fi": true, "for": true, "while": true, "until": true, "do": true, "done": true,
+	"case": true, "esac": true, "in": true, "break": true, "continue": true, "{": true,
+	"}": true, "!": true, ":": true, "[[": true,
+}
+
+// coreTools ship with every POSIX userland, so provisioning rarely needs them
+var coreTools = map[string]bool{
+	"cat": true, "cp": true, "mv": true, "rm": true, "mkdir": true, "rmdir": true,
+	"ls": true, "ln": true, "chmod": true, "chown": true, "touch": true, "head": true,
+	"tail": true, "grep": true, "sed": true, "awk": true, "cut": true, "sort": true,
+	"uniq": true, "tr": true, "wc": true, "find": true, "xargs": true, "tee": true,
+	"date": true, "sleep": true, "env": true, "basename": true, "dirname": true,
+	"uname": true, "id": true, "whoami": true, "kill": true, "ps": true, "tar": true,
+	"gzip": true, "gunzip": true, "diff": true, "stat": true, "readlink": true,
+	"realpath": true, "mktemp": true, "which": true, "sh": true, "install": true,
+}
+
+// collectInventory scans the shell commands of every task
+func collectInventory(ws *workspace) *inventory {
+	// Commands are parsed on a worker each and their uses gathered in order
+	cmds := ws.shellCmds()
+	scanned := parallelMap(len(cmds), func(i int) *inventory {
+		c := cmds[i]
+		found := &inventory{}
+		for _, words := range splitCommands(c.cmd.Cmd) {
+			for _, cmd := range expandCommand(words) {
+				tool, args := commandTool(cmd)
+				if !isExternalTool(tool) {
+					continue
+				}
+				found.Tools = append(found.Tools, toolUse{Name: tool})
+				if slices.Contains(containerRuntimes, tool) {
+					found.Containers = append(found.Containers, containerInvocation(c, tool, args))
+				}
+				found.Packages = append(found.Packages, packageInstalls(c, tool, args)...)
+			}
+		}
+		return found
+	})
+
+	inv := &inventory{Tools: []toolUse{}, Packages: []packageUse{}, Containers: []containerUse{}}
+	toolTasks := make(map[string][]string)
+	for i, found := range scanned {
+		for _, t := range found.Tools {
+			if !slices.Contains(toolTasks[t.Name], cmds[i].name) {
+				toolTasks[t.Name] = append(toolTasks[t.Name], cmds[i].name)
+			}
+		}
+		inv.Containers = append(inv.Containers, found.Containers...)
+		inv.Packages = append(inv.Packages, found.Packages...)
+	}
+	inv.Conflicts = versionConflicts(inv.Packages)
+
+	for name, tasks := range toolTasks {
+		inv.Tools = append(inv.Tools, toolUse{Name: name, Core: coreTools[name], Tasks: tasks})
+	}
+	sort.Slice(inv.Tools, func(i, j int) bool { return inv.Tools[i].Name < inv.Tools[j].Name })
+	return inv
+}
+
+// expandCommand returns a simple command together with the commands it runs
+// on behalf of the caller: the script of sh -c, the command xargs runs and
+// the one find -exec runs
+func expandCommand(words []string) [][]string {
+	commands := [][]string{words}
+	tool, args := commandTool(words)
+	switch tool {
+	case "sh", "bash", "zsh":
+		for i, a := range args {
+			if a == "-c" && i+1 < len(args) {
+				for _, inner := range splitCommands(args[i+1]) {
+					commands = append(commands, expandCommand(inner)...)
+				}
+				break
+			}
+		}
+	case "xargs":
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "-n", "-I", "-P", "-L", "-d", "-E", "-s":
+				// Options taking a value, as in xargs -n 1 or xargs -I {}
+				i++
+				continue
+			}
+			if !strings.HasPrefix(args[i], "-") {
+				commands = append(commands, expandCommand(args[i:])...)
+				break
+			}
+		}
+	case "find":
+		for i, a := range args {
+			if (a == "-exec" || a == "-execdir") && i+1 < len(args) {
+				commands = append(commands, expandCommand(args[i+1:])...)
+			}
+		}
+	}
+	return commands
+}
+
+// isExternalTool reports whether a command word names a program that must be
+// installed: not a builtin, a redirection, a templated name or a script of
+// the project
+func isExternalTool(tool string) bool {
+	return tool != "" && !shellBuiltins[tool] && !strings.ContainsAny(tool[:1], "<>$0123456789") &&
+		!strings.Contains(tool, "{{") && !strings.Contains(tool, "/")
+}
+
+// containerInvocation works out what a container runtime command does and
+// which image it pulls
+func containerInvocation(c taskCmd, runtime string, args []string) containerUse {
+	use := containerUse{Task: c.name, Runtime: runtime, Location: c.pos.String()}
+	if strings.HasSuffix(runtime, "-compose") {
+		use.Action = "compose"
+		return use
+	}
+
+	// Global options come before the subcommand
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "--context", "-c", "-H", "--host", "--config", "--log-level", "--namespace", "-n":
+			args = args[1:]
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return use
+	}
+	use.Action, args = args[0], args[1:]
+	if use.Action == "container" || use.Action == "image" {
+		if len(args) == 0 {
+			return use
+		}
+		use.Action, args = args[0], args[1:]
+	}
+
+	switch use.Action {
+	case "run", "create", "pull":
+		for i := 0; i < len(args); i++ {
+			a := args[i]
+			if strings.HasPrefix(a, "-") {
+				if containerValueFlags[a] {
+					i++
+				}
+				continue
+			}
+			use.Image = a
+			use.Pinning = imagePinning(a)
+			break
+		}
+	}
+	return use
+}
+
+// imagePinning says how firmly an image reference is pinned: by digest, by
+// tag, or not at all (no tag, or latest)
+func imagePinning(image string) string {
+	if strings.Contains(image, "@sha256:") {
+		return "digest"
+	}
+	// A colon after the last slash separates the tag; one before it is a
+	// registry port
+	name := image[strings.LastIndex(image, "/")+1:]
+	if _, tag, ok := strings.Cut(name, ":"); ok && tag != "latest" {
+		return "tag"
+	}
+	return "unpinned"
+}
+
+func (inv *inventory) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "=== External Tools ===\n")
+	if len(inv.Tools) == 0 {
+		fmt.Fprintf(tw, "No external tools\n")
+	} else {
+		fmt.Fprintf(tw, "TOOL\tCORE\tUSED BY\n")
+		for _, t := range inv.Tools {
+			core := ""
+			if t.Core {
+				core = "yes"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", t.Name, core, strings.Join(t.Tasks, ", "))
+		}
+	}
+
+	// The same inventory, task by task
+	byTask := make(map[string][]string)
+	var order []string
+	for _, t := range inv.Tools {
+		for _, task := range t.Tasks {
+			if byTask[task] == nil {
+				order = append(order, task)
+			}
+			byTask[task] = append(byTask[task], t.Name)
+		}
+	}
+	sort.Strings(order)
+	if len(order) > 0 {
+		fmt.Fprintf(tw, "\nTASK\tTOOLS\n")
+		for _, task := range order {
+			fmt.Fprintf(tw, "%s\t%s\n", task, strings.Join(byTask[task], ", "))
+		}
+	}
+
+	fmt.Fprintf(tw, "\n=== Package Versions ===\n")
+	if len(inv.Packages) == 0 {
+		fmt.Fprintf(tw, "No task installs packages\n")
+	} else {
+		fmt.Fprintf(tw, "MANAGER\tPACKAGE\tVERSION\tTASK\tLOCATION\n")
+		for _, p := range inv.Packages {
+			version := p.Version
+			if version == "" {
+				version = "(unpinned)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.Manager, p.Name, version, p.Task, p.Location)
+		}
+		for _, c := range inv.Conflicts {
+			versions := make([]string, 0, len(c.Versions))
+			for v := range c.Versions {
+				versions = append(versions, v)
+			}
+			sort.Strings(versions)
+			fmt.Fprintf(tw, "\nCONFLICT: %s package %s is pinned to different versions\n", c.Manager, c.Name)
+			for _, v := range versions {
+				fmt.Fprintf(tw, "  %s\t%s\n", v, strings.Join(c.Versions[v], ", "))
+			}
+		}
+	}
+
+	fmt.Fprintf(tw, "\n=== Container Usage ===\n")
+	if len(inv.Containers) == 0 {
+		fmt.Fprintf(tw, "No task runs containers\n")
+	} else {
+		fmt.Fprintf(tw, "TASK\tRUNTIME\tACTION\tIMAGE\tLOCATION\n")
+		for _, u := range inv.Containers {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", u.Task, u.Runtime, u.Action, u.Image, u.Location)
+		}
+
+		// Each image once, with the tasks using it
+		users := make(map[string][]string)
+		pinning := make(map[string]string)
+		for _, u := range inv.Containers {
+			if u.Image != "" && !slices.Contains(users[u.Image], u.Task) {
+				users[u.Image] = append(users[u.Image], u.Task)
+				pinning[u.Image] = u.Pinning
+			}
+		}
+		images := make([]string, 0, len(users))
+		for image := range users {
+			images = append(images, image)
+		}
+		sort.Strings(images)
+		if len(images) > 0 {
+			fmt.Fprintf(tw, "\nIMAGE\tPINNING\tUSED BY\n")
+			for _, image := range images {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", image, pinning[image], strings.Join(users[image], ", "))
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// writePackagesByOS answers what running every task installs on each OS,
+// grouped by package manager
+func writePackagesByOS(w io.Writer, byOS map[string][]osPackage) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(byOS) == 0 {
+		fmt.Fprintf(tw, "No task installs packages\n")
+	}
+	for i, target := range slices.Sorted(maps.Keys(byOS)) {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "=== %s ===\n", target)
+		fmt.Fprintf(tw, "MANAGER\tPACKAGE\tVERSION\tTASKS\n")
+		for _, p := range byOS[target] {
+			version := p.Version
+			if version == "" {
+				version = "(unpinned)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Manager, p.Name, version, strings.Join(p.Tasks, ", "))
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// justRecipe names the recipe of a task; just allows no colons, so
+// namespaces are joined with dashes as in web-css
+func justRecipe(name string) string {
+	return strings.ReplaceAll(name, ":", "-")
+}
+
+// writeJustfile writes the recipes as a justfile, deps as dependencies and
+// descriptions as doc comments. Each recipe is a shebang script that stops
+// at the first failure, so multi-line commands and a cd into the task's dir
+// carry over as they do in Task; deferred commands become an EXIT trap.
+func writeJustfile(w io.Writer, plan exportPlan) error {
+	recipes := slices.Clone(plan.Recipes)
+	owners := make(map[string]string)
+	for _, r := range recipes {
+		if other, ok := owners[justRecipe(r.Name)]; ok {
+			return fmt.Errorf("tasks %s and %s would both be recipe %s", other, r.Name, justRecipe(r.Name))
+		}
+		owners[justRecipe(r.Name)] = r.Name
+	}
+	// just runs the first recipe when given none
+	slices.SortStableFunc(recipes, func(a, b exportRecipe) int {
+		switch {
+		case a.Name == "default" && b.Name != "default":
+			return -1
+		case b.Name == "default" && a.Name != "default":
+			return 1
+		}
+		return 0
+	})
+
+	var b strings.Builder
+	b.WriteString("# Generated by meerkat export from the Taskfile; regenerate rather than edit.\n")
+	b.WriteString("task := env_var_or_default(\"TASK\", \"task\")\n")
+	for _, v := range plan.Env {
+		value := strconv.Quote(v.Value)
+		if v.Dynamic {
+			value = "`" + v.Value + "`"
+		}
+		fmt.Fprintf(&b, "export %s := %s\n", v.Name, value)
+	}
+
+	for _, r := range recipes {
+		b.WriteString("\n")
+		if r.Desc != "" {
+			fmt.Fprintf(&b, "# %s\n", r.Desc)
+		}
+		if r.Internal {
+			b.WriteString("[private]\n")
+		}
+		// A delegated task runs its own deps, so they are only noted
+		if r.Delegate {
+			fmt.Fprintf(&b, "%s:\n", justRecipe(r.Name))
+			fmt.Fprintf(&b, "    # Runs through task: %s\n", r.Why)
+			fmt.Fprintf(&b, "    {{task}} %s\n", shellQuote(r.Name))
+			continue
+		}
+		deps := make([]string, len(r.Deps))
+		for i, dep := range r.Deps {
+			deps[i] = justRecipe(dep)
+		}
+		fmt.Fprintf(&b, "%s:%s\n", justRecipe(r.Name), strings.TrimRight(" "+strings.Join(deps, " "), " "))
+		if len(r.Steps) == 0 {
+			continue
+		}
+
+		lines := []string{"#!/bin/sh", "set -e"}
+		if r.Dir != "" {
+			lines = append(lines, "cd "+shellQuote(r.Dir))
+		}
+		for _, v := range r.Env {
+			value := shellQuote(v.Value)
+			if v.Dynamic {
+				value = `"$(` + v.Value + `)"`
+			}
+			lines = append(lines, "export "+v.Name+"="+value)
+		}
+		var body, deferred []string
+		for _, s := range r.Steps {
+			line := strings.ReplaceAll(s.Cmd, "{{", "{{{{")
+			if s.Task != "" {
+				line = `"{{just_executable()}}" -f "{{justfile()}}" ` + justRecipe(s.Task)
+			}
+			if s.Defer {
+				deferred = append(deferred, line)
+			} else {
+				body = append(body, line)
+			}
+		}
+		// Task runs deferred commands last in first out, whatever fails
+		if len(deferred) > 0 {
+			slices.Reverse(deferred)
+			lines = append(lines, "trap "+shellQuote(strings.Join(deferred, "\n"))+" EXIT")
+		}
+		for _, line := range append(lines, body...) {
+			b.WriteString("    " + strings.ReplaceAll(line, "\n", "\n    ") + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// justHeader matches the line starting a recipe: attributes aside, a name,
+// parameters and a colon not followed by =, which would be an assignment
+var justHeader = regexp.MustCompile(`^(@?)([A-Za-z_][A-Za-z0-9_-]*)((?:\s+[^:]*)?):([^=].*|)$`)
+
+// justAlias matches alias b := build
+var justAlias = regexp.MustCompile(`^alias\s+([A-Za-z_][A-Za-z0-9_-]*)\s*:=\s*([A-Za-z_][A-Za-z0-9_-]*)\s*$`)
+
+// parseJustfile reads the recipes of a justfile as tasks: dependencies as
+// deps, the ones after && as calls once the body has run, and the body as
+// commands, with a just of other recipes as calls; a shebang recipe is one
+// command. A comment or [doc] above the recipe is the description, [private]
+// and a leading _ make it internal, and aliases carry over. The first recipe
+// becomes the default task unless there is one.
+func parseJustfile(src []byte) []importedTask {
+	var tasks []*importedTask
+	byName := make(map[string]*importedTask)
+	aliases := make(map[string][]string)
+	after := make(map[*importedTask][]string)
+	var current *importedTask    // the recipe whose body is being read
+	shebang, indent := false, "" // a shebang body keeps its indentation past the first line's
+	var comment string
+	private := false
+
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		start := i + 1
+		trimmed := strings.TrimSpace(line)
+		indented := line != "" && (line[0] == ' ' || line[0] == '\t')
+		column := len(line) - len(strings.TrimLeft(line, " \t")) + 1
+
+		switch {
+		case current != nil && shebang && (indented || trimmed == ""):
+			last := &current.Cmds[len(current.Cmds)-1]
+			last.Cmd += "\n" + strings.TrimRight(strings.TrimPrefix(line, indent), " \t")
+			continue
+		case current != nil && indented:
+			switch {
+			case strings.HasPrefix(trimmed, "#!") && len(current.Cmds) == 0:
+				current.Cmds = append(current.Cmds, importedCmd{Cmd: trimmed, Line: start, Column: column})
+				shebang, indent = true, line[:column-1]
+			case !strings.HasPrefix(trimmed, "#"):
+				c := makeCommand(trimmed)
+				c.Line, c.Column = start, len(line)-len(strings.TrimLeft(line, " \t@-"))+1
+				current.Cmds = append(current.Cmds, c)
+			}
+			continue
+		case current != nil && trimmed == "":
+			// A blank line inside a body does not end it
+			continue
+		}
+		if current != nil && shebang {
+			last := &current.Cmds[len(current.Cmds)-1]
+			last.Cmd = strings.TrimRight(last.Cmd, "\n")
+		}
+		current, shebang = nil, false
+
+		switch {
+		case trimmed == "":
+			comment, private = "", false
+		case strings.HasPrefix(trimmed, "#"):
+			comment = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		case strings.HasPrefix(trimmed, "["):
+			// Attributes, as in [private] or [group('ci'), doc('Build it')]
+			if strings.Contains(trimmed, "private") {
+				private = true
+			}
+			if _, doc, ok := strings.Cut(trimmed, "doc("); ok {
+				doc, _, _ = strings.Cut(doc, ")")
+				comment = strings.Trim(doc, `'"`)
+			}
+		case justAlias.MatchString(trimmed):
+			m := justAlias.FindStringSubmatch(trimmed)
+			aliases[m[2]] = append(aliases[m[2]], m[1])
+			comment, private = "", false
+		default:
+			m := justHeader.FindStringSubmatch(line)
+			if m == nil {
+				// Settings, assignments, imports and modules
+				comment, private = "", false
+				continue
+			}
+			name := m[2]
+			// A later definition replaces a recipe, as with allow-duplicate-recipes
+			if byName[name] == nil {
+				byName[name] = &importedTask{}
+				tasks = append(tasks, byName[name])
+			}
+			t := byName[name]
+			*t = importedTask{Name: name, Line: start, Desc: comment, Internal: private || strings.HasPrefix(name, "_")}
+			list, _, _ := strings.Cut(m[4], "#")
+			deps, later, _ := strings.Cut(list, "&&")
+			t.Deps, after[t] = justDeps(deps), justDeps(later)
+			current, comment, private = t, "", false
+		}
+	}
+	if current != nil && shebang {
+		last := &current.Cmds[len(current.Cmds)-1]
+		last.Cmd = strings.TrimRight(last.Cmd, "\n")
+	}
+
+	out := make([]importedTask, 0, len(tasks)+1)
+	for _, t := range tasks {
+		for i, c := range t.Cmds {
+			t.Cmds[i] = justCall(c, byName)
+		}
+		for _, name := range after[t] {
+			t.Cmds = append(t.Cmds, importedCmd{Task: name})
+		}
+		t.Aliases = aliases[t.Name]
+		out = append(out, *t)
+	}
+	if len(out) > 0 && byName["default"] == nil {
+		// just runs the first recipe when given none, as task runs default
+		def := importedTask{Name: "default", Line: out[0].Line, Desc: "First recipe of the justfile", Deps: []string{out[0].Name}}
+		out = slices.Insert(out, 0, def)
+	}
+	return out
+}
+
+// justCall turns a body line running just on a recipe of the same justfile
+// into a call of its task
+func justCall(c importedCmd, recipes map[string]*importedTask) importedCmd {
+	fields := strings.Fields(c.Cmd)
+	if len(fields) != 2 || c.IgnoreError {
+		return c
+	}
+	switch strings.Trim(fields[0], `"`) {
+	case "just", "{{just_executable()}}":
+		if recipes[fields[1]] != nil {
+			c.Cmd, c.Task = "", fields[1]
+		}
+	}
+	return c
+}
+
+// justDeps are the recipes a dependency list names, as in build (test "x")
+func justDeps(list string) []string {
+	var deps []string
+	for list = strings.TrimSpace(list); list != ""; list = strings.TrimSpace(list) {
+		if list[0] == '(' {
+			end := strings.IndexByte(list, ')')
+			if end < 0 {
+				end = len(list) - 1
+			}
+			if fields := strings.Fields(list[1:end]); len(fields) > 0 {
+				deps = append(deps, fields[0])
+			}
+			list = list[end+1:]
+			continue
+		}
+		name, rest, _ := strings.Cut(list, " ")
+		deps = append(deps, name)
+		list = rest
+	}
+	return slices.Compact(deps)
+}
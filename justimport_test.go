@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJustfile(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []importedTask
+	}{
+		{"empty", "", []importedTask{}},
+		{
+			"recipe",
+			"# Build it\nbuild:\n    go build\n",
+			[]importedTask{
+				{Name: "default", Line: 2, Desc: "First recipe of the justfile", Deps: []string{"build"}},
+				{Name: "build", Line: 2, Desc: "Build it", Cmds: []importedCmd{{Cmd: "go build", Line: 3, Column: 5}}},
+			},
+		},
+		{
+			"deps, params and later calls",
+			"test: build (lint \"x\") && clean\n\t@go test\nbuild target=\"app\":\n\t-go build {{target}}\nlint arg:\nclean:\n",
+			[]importedTask{
+				{Name: "default", Line: 1, Desc: "First recipe of the justfile", Deps: []string{"test"}},
+				{Name: "test", Line: 1, Deps: []string{"build", "lint"}, Cmds: []importedCmd{{Cmd: "go test", Line: 2, Column: 3}, {Task: "clean"}}},
+				{Name: "build", Line: 3, Cmds: []importedCmd{{Cmd: "go build {{target}}", IgnoreError: true, Line: 4, Column: 3}}},
+				{Name: "lint", Line: 5},
+				{Name: "clean", Line: 6},
+			},
+		},
+		{
+			"attributes, aliases and just calls",
+			"default: b\n[private]\nhelper:\n  echo help\n[doc('Build it')]\nb:\n  just helper\n  just missing\n_hidden:\nalias bb := b\n",
+			[]importedTask{
+				{Name: "default", Line: 1, Deps: []string{"b"}},
+				{Name: "helper", Line: 3, Internal: true, Cmds: []importedCmd{{Cmd: "echo help", Line: 4, Column: 3}}},
+				{Name: "b", Line: 6, Desc: "Build it", Aliases: []string{"bb"}, Cmds: []importedCmd{{Task: "helper", Line: 7, Column: 3}, {Cmd: "just missing", Line: 8, Column: 3}}},
+				{Name: "_hidden", Line: 9, Internal: true},
+			},
+		},
+		{
+			"shebang recipe",
+			"script:\n  #!/usr/bin/env bash\n  set -e\n\n    echo indented\nafter:\n",
+			[]importedTask{
+				{Name: "default", Line: 1, Desc: "First recipe of the justfile", Deps: []string{"script"}},
+				{Name: "script", Line: 1, Cmds: []importedCmd{{Cmd: "#!/usr/bin/env bash\nset -e\n\n  echo indented", Line: 2, Column: 3}}},
+				{Name: "after", Line: 6},
+			},
+		},
+		{
+			"settings and assignments left out",
+			"set shell := [\"bash\", \"-c\"]\nversion := \"1.0\"\nexport FOO := \"bar\"\nimport 'other.just'\nbuild:\n  echo {{version}}\n",
+			[]importedTask{
+				{Name: "default", Line: 5, Desc: "First recipe of the justfile", Deps: []string{"build"}},
+				{Name: "build", Line: 5, Cmds: []importedCmd{{Cmd: "echo {{version}}", Line: 6, Column: 3}}},
+			},
+		},
+		{
+			"later definition wins",
+			"a:\n  echo one\na:\n  echo two\n",
+			[]importedTask{
+				{Name: "default", Line: 3, Desc: "First recipe of the justfile", Deps: []string{"a"}},
+				{Name: "a", Line: 3, Cmds: []importedCmd{{Cmd: "echo two", Line: 4, Column: 3}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseJustfile([]byte(tt.src)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseJustfile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJustDeps(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"", nil},
+		{"a b", []string{"a", "b"}},
+		{`(test "x" "y") b`, []string{"test", "b"}},
+		{"a a b", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		if got := justDeps(tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("justDeps(%q) = %q, want %q", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestJustCall(t *testing.T) {
+	recipes := map[string]*importedTask{"build": {Name: "build"}}
+	tests := []struct {
+		c    importedCmd
+		want importedCmd
+	}{
+		{importedCmd{Cmd: "just build"}, importedCmd{Task: "build"}},
+		{importedCmd{Cmd: `"{{just_executable()}}" build`}, importedCmd{Task: "build"}},
+		{importedCmd{Cmd: "just other"}, importedCmd{Cmd: "just other"}},
+		{importedCmd{Cmd: "just build --verbose"}, importedCmd{Cmd: "just build --verbose"}},
+		{importedCmd{Cmd: "just build", IgnoreError: true}, importedCmd{Cmd: "just build", IgnoreError: true}},
+	}
+	for _, tt := range tests {
+		if got := justCall(tt.c, recipes); got != tt.want {
+			t.Errorf("justCall(%+v) = %+v, want %+v", tt.c, got, tt.want)
+		}
+	}
+}
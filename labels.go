@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// labelMarker starts a comment carrying task metadata, written above a task
+// or after its key:
+//
+//	# meerkat: owner=platform-team, tier=critical
+//	build:
+//	  cmds: [go build ./...]
+const labelMarker = "meerkat:"
+
+// labels returns the metadata annotations of a merged task. Later comments
+// override earlier ones for the same key.
+func (x *sourceIndex) labels(task *ast.Task) map[string]string {
+	src := x.source(task)
+	if src.key == nil {
+		return nil
+	}
+	return parseLabels(src.key.HeadComment + "\n" + src.key.LineComment)
+}
+
+// parseLabels reads key=value pairs from the meerkat: lines of a comment.
+// A key without a value is a flag and gets "true".
+func parseLabels(comment string) map[string]string {
+	var labels map[string]string
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		rest, ok := strings.CutPrefix(line, labelMarker)
+		if !ok || strings.HasPrefix(line, ignoreMarker) {
+			continue
+		}
+		for _, pair := range strings.Split(rest, ",") {
+			key, value, found := strings.Cut(pair, "=")
+			key = strings.TrimSpace(key)
+			if key == "" {
+				continue
+			}
+			if !found {
+				value = "true"
+			}
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels[key] = strings.TrimSpace(value)
+		}
+	}
+	return labels
+}
+
+// labelKeys returns the distinct label keys used by any node, sorted
+func (tg *taskGraph) labelKeys() []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, n := range tg.Nodes {
+		for k := range n.Labels {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedKeys returns the keys of a label set in order
+func sortedKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatLabels prints labels as key=value pairs in key order
+func formatLabels(labels map[string]string) string {
+	keys := sortedKeys(labels)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + labels[k]
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// labelSelector is one --select term: key=value, key!=value, or a bare key
+// that must be present
+type labelSelector struct {
+	key, value string
+	negate     bool
+	exists     bool
+}
+
+func (s labelSelector) matches(labels map[string]string) bool {
+	value, ok := labels[s.key]
+	switch {
+	case s.exists:
+		return ok
+	case s.negate:
+		return value != s.value
+	default:
+		return ok && value == s.value
+	}
+}
+
+// selectors is the value of the repeatable --select flag. Terms of one flag
+// are comma-separated and, like repeated flags, must all match.
+type selectors []labelSelector
+
+func (ss *selectors) String() string {
+	terms := make([]string, len(*ss))
+	for i, s := range *ss {
+		switch {
+		case s.exists:
+			terms[i] = s.key
+		case s.negate:
+			terms[i] = s.key + "!=" + s.value
+		default:
+			terms[i] = s.key + "=" + s.value
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+func (ss *selectors) Set(value string) error {
+	for _, term := range strings.Split(value, ",") {
+		term = strings.TrimSpace(term)
+		var s labelSelector
+		if key, v, ok := strings.Cut(term, "!="); ok {
+			s = labelSelector{key: key, value: v, negate: true}
+		} else if key, v, ok := strings.Cut(term, "="); ok {
+			s = labelSelector{key: key, value: v}
+		} else {
+			s = labelSelector{key: term, exists: true}
+		}
+		s.key = strings.TrimSpace(s.key)
+		s.value = strings.TrimSpace(s.value)
+		if s.key == "" {
+			return fmt.Errorf("invalid selector %q", term)
+		}
+		*ss = append(*ss, s)
+	}
+	return nil
+}
+
+// matches reports whether labels satisfy every selector; no selectors match
+// everything
+func (ss selectors) matches(labels map[string]string) bool {
+	for _, s := range ss {
+		if !s.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectNodes keeps the tasks whose labels match, and the edges between them
+func (tg *taskGraph) selectNodes(ss selectors) *taskGraph {
+	if len(ss) == 0 {
+		return tg
+	}
+	kept := &taskGraph{}
+	keep := make(map[string]bool)
+	for _, n := range tg.Nodes {
+		if ss.matches(n.Labels) {
+			keep[n.Name] = true
+			kept.Nodes = append(kept.Nodes, n)
+		}
+	}
+	for _, e := range tg.Edges {
+		if keep[e.From] && keep[e.To] {
+			kept.Edges = append(kept.Edges, e)
+		}
+	}
+	return kept
+}
+
+// noLabel groups the tasks that lack the label being grouped by
+const noLabel = "(none)"
+
+// groupByLabel splits task names by their value of one label key, in
+// value order with unlabelled tasks last
+func groupByLabel(names []string, labelsOf func(string) map[string]string, key string) ([]string, map[string][]string) {
+	groups := make(map[string][]string)
+	for _, name := range names {
+		value, ok := labelsOf(name)[key]
+		if !ok {
+			value = noLabel
+		}
+		groups[value] = append(groups[value], name)
+	}
+	values := make([]string, 0, len(groups))
+	for v := range groups {
+		if v != noLabel {
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	if groups[noLabel] != nil {
+		values = append(values, noLabel)
+	}
+	return values, groups
+}
+
+// printGroups prints how many tasks carry each value of a label
+func printGroups(tg *taskGraph, key string) {
+	var names []string
+	labels := make(map[string]map[string]string)
+	for _, n := range tg.Nodes {
+		if !n.Missing {
+			names = append(names, n.Name)
+			labels[n.Name] = n.Labels
+		}
+	}
+	values, groups := groupByLabel(names, func(name string) map[string]string { return labels[name] }, key)
+	fmt.Printf("Tasks by %s:\n", key)
+	for _, v := range values {
+		fmt.Printf("  %s: %d (%s)\n", v, len(groups[v]), strings.Join(groups[v], ", "))
+	}
+}
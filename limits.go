@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// readLimits bound how much one load may read, so a broken or malicious
+// remote Taskfile cannot include its way into downloading forever. Zero
+// disables a limit.
+type readLimits struct {
+	maxDepth int   // longest chain of includes below the root Taskfile
+	maxFiles int   // Taskfiles in the graph, local and remote
+	maxBytes int64 // bytes of remote Taskfiles downloaded
+}
+
+// downloadBudget enforces the file and byte limits as remote Taskfiles are
+// downloaded, ahead of the reader or the object mirror following their
+// includes. It remembers the first limit broken, since the reader reports a
+// failed download without its cause.
+type downloadBudget struct {
+	limits readLimits
+
+	mu       sync.Mutex
+	files    map[string]bool
+	bytes    int64
+	exceeded error
+}
+
+// reset starts the budget over for the next load
+func (b *downloadBudget) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.files, b.bytes, b.exceeded = make(map[string]bool), 0, nil
+}
+
+// err is the limit the current load broke, if any
+func (b *downloadBudget) err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.exceeded
+}
+
+func (b *downloadBudget) fail(err error) error {
+	if b.exceeded == nil {
+		b.exceeded = err
+	}
+	return err
+}
+
+// admit counts a download of the file at url, refusing it once more files
+// than allowed have been downloaded
+func (b *downloadBudget) admit(url string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.files == nil {
+		b.files = make(map[string]bool)
+	}
+	if b.files[url] {
+		return nil
+	}
+	b.files[url] = true
+	if b.limits.maxFiles > 0 && len(b.files) > b.limits.maxFiles {
+		return b.fail(fmt.Errorf("downloading %s would exceed %s (--max-files)", url, plural(b.limits.maxFiles, "remote Taskfile")))
+	}
+	return nil
+}
+
+// meter counts the bytes of the body downloaded from url against the byte
+// limit, failing the read that goes over it. A declared length, -1 when
+// unknown, already over the limit is refused before anything is read.
+func (b *downloadBudget) meter(url string, body io.ReadCloser, length int64) (io.ReadCloser, error) {
+	if b.limits.maxBytes <= 0 {
+		return body, nil
+	}
+	b.mu.Lock()
+	over := length > 0 && b.bytes+length > b.limits.maxBytes
+	b.mu.Unlock()
+	if over {
+		body.Close()
+		return nil, b.over(url)
+	}
+	return &meteredBody{ReadCloser: body, budget: b, url: url}, nil
+}
+
+func (b *downloadBudget) over(url string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.fail(fmt.Errorf("downloading %s would exceed %d bytes of remote Taskfiles (--max-download-bytes)", url, b.limits.maxBytes))
+}
+
+// meteredBody is a download read against a budget
+type meteredBody struct {
+	io.ReadCloser
+	budget *downloadBudget
+	url    string
+}
+
+func (m *meteredBody) Read(p []byte) (int, error) {
+	n, err := m.ReadCloser.Read(p)
+	m.budget.mu.Lock()
+	m.budget.bytes += int64(n)
+	over := m.budget.bytes > m.budget.limits.maxBytes
+	m.budget.mu.Unlock()
+	if over {
+		return n, m.budget.over(m.url)
+	}
+	return n, err
+}
+
+// checkGraphLimits applies the depth and file limits to a graph once it is
+// read. Local includes are not downloaded, so this is where they count.
+func checkGraphLimits(g *ast.TaskfileGraph, limits readLimits) error {
+	adjacency, err := g.AdjacencyMap()
+	if err != nil {
+		return err
+	}
+	if limits.maxFiles > 0 && len(adjacency) > limits.maxFiles {
+		return fmt.Errorf("the graph has %d Taskfiles, more than %d (--max-files)", len(adjacency), limits.maxFiles)
+	}
+	if limits.maxDepth <= 0 {
+		return nil
+	}
+
+	// The reader rejects include cycles, so the longest chain is finite
+	depth := make(map[string]int)
+	var longest func(uri string) int
+	longest = func(uri string) int {
+		if d, ok := depth[uri]; ok {
+			return d
+		}
+		d := 0
+		for child := range adjacency[uri] {
+			d = max(d, longest(child)+1)
+		}
+		depth[uri] = d
+		return d
+	}
+	root, err := rootURI(g)
+	if err != nil {
+		return err
+	}
+	if d := longest(root); d > limits.maxDepth {
+		return fmt.Errorf("%s includes Taskfiles %d levels deep, more than %d (--max-include-depth)", displayPath(root), d, limits.maxDepth)
+	}
+	return nil
+}
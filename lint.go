@@ -0,0 +1,314 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// Finding severities, from most to least serious
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+// finding is one problem a check reports about a task
+type finding struct {
+	Rule     string    `json:"rule"`
+	Severity string    `json:"severity"`
+	Task     string    `json:"task"`
+	Message  string    `json:"message"`
+	Pos      sourcePos `json:"location,omitzero"`
+}
+
+// lintCheck is one named check run by the lint command
+type lintCheck struct {
+	name    string
+	summary string
+	run     func(ws *workspace) []finding
+}
+
+// lintChecks lists every check in the order they run
+var lintChecks = []lintCheck{
+	{"portability", "OS-specific tools and GNU/BSD-only flags on platforms a task claims to support", checkPortability},
+	{"windows", "POSIX paths, shell scripts and bash-isms in tasks that may run on Windows", checkWindows},
+	{"cycles", "Tasks that call themselves again through deps and task calls", checkTaskCycles},
+	{"duplicates", "The same command, up to whitespace and variables, run by tasks of different Taskfiles", checkDuplicates},
+	{"transport", "Includes fetched over plain HTTP or through a redirect, to another host as a warning", checkTransport},
+}
+
+// runLint runs the selected checks over the merged Taskfile and prints
+// every finding with its location
+func runLint(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	opts.register(fs)
+	only := fs.String("checks", "", "Comma-separated checks to run (default all)")
+	list := fs.Bool("list-checks", false, "List the available checks and exit")
+	format := fs.String("format", "text", "Output format: text, checkstyle, junit for JUnit XML or tap for a TAP stream, the last two with a test per check and Taskfile")
+	failOn := fs.String("fail-on", "", "Comma-separated finding classes that make lint exit non-zero: error, warning (or worse), any-finding, cycle")
+	baselinePath := fs.String("baseline", "", "Only report findings not recorded in this baseline file, and fail on them unless --fail-on says otherwise")
+	listSuppressions := fs.Bool("list-suppressions", false, "List every meerkat:ignore comment and what it silenced instead of the findings")
+	updateBaseline := fs.Bool("update-baseline", false, "Record the current findings in the --baseline file and exit")
+	nt := registerNotify(fs)
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if *updateBaseline && *baselinePath == "" {
+		return fmt.Errorf("--update-baseline needs --baseline FILE")
+	}
+
+	available, err := loadChecks(opts.configFile)
+	if err != nil {
+		return err
+	}
+	if *list {
+		for _, c := range available {
+			fmt.Printf("%-12s %s\n", c.name, c.summary)
+		}
+		return nil
+	}
+	selected, err := selectChecks(*only, available)
+	if err != nil {
+		return err
+	}
+	policy, err := parseFailPolicy(*failOn)
+	if err != nil {
+		return err
+	}
+	var base *baseline
+	if *baselinePath != "" && !*updateBaseline {
+		if base, err = readBaseline(*baselinePath); err != nil {
+			return err
+		}
+		if *failOn == "" {
+			policy["any-finding"] = true
+		}
+	}
+
+	root := displayPath(opts.taskfileURL)
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	ws, err := openWorkspace(l)
+	if err != nil {
+		return err
+	}
+
+	suppressions := ws.suppressions()
+	findings := suppress(ws.lint(selected), suppressions)
+	if *listSuppressions {
+		writeSuppressions(os.Stdout, suppressions, available)
+		return nil
+	}
+	if *updateBaseline {
+		if err := writeBaseline(*baselinePath, ws.baselineEntries(findings)); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "recorded %s in %s\n", plural(len(findings), "finding"), *baselinePath)
+		return nil
+	}
+	if base != nil {
+		total := len(findings)
+		findings = ws.newFindings(base, findings)
+		if known := total - len(findings); known > 0 {
+			fmt.Fprintf(os.Stderr, "%s already in baseline %s\n", plural(known, "finding"), *baselinePath)
+		}
+	}
+	switch *format {
+	case "text":
+		writeFindings(os.Stdout, findings)
+	case "junit":
+		if err := writeJUnit(os.Stdout, selected, ws.lintedFiles(), findings); err != nil {
+			return err
+		}
+	case "checkstyle":
+		if err := writeCheckstyle(os.Stdout, ws.lintedFiles(), findings); err != nil {
+			return err
+		}
+	case "tap":
+		if err := writeTAP(os.Stdout, selected, ws.lintedFiles(), findings); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	type located struct {
+		finding
+		Location string `json:"location"`
+	}
+	n := notification{Command: "lint", Taskfile: root, Summary: findingsSummary(findings)}
+	var results []located
+	for _, f := range findings {
+		n.Items = append(n.Items, fmt.Sprintf("%s: %s: %s: %s [%s]", f.Pos, f.Severity, f.Task, f.Message, f.Rule))
+		results = append(results, located{f, f.Pos.String()})
+	}
+	n.Results = results
+	if err := nt.notify(n); err != nil {
+		return err
+	}
+
+	failing := 0
+	for _, f := range findings {
+		if policy.fails(f) {
+			failing++
+		}
+	}
+	if failing > 0 {
+		if *failOn == "" {
+			return &failure{fmt.Sprintf("%s not in baseline %s", plural(failing, "finding"), *baselinePath)}
+		}
+		return &failure{fmt.Sprintf("%s match --fail-on %s", plural(failing, "finding"), *failOn)}
+	}
+	return nil
+}
+
+// failPolicy is the set of finding classes --fail-on names
+type failPolicy map[string]bool
+
+// failClasses are the values --fail-on accepts
+var failClasses = []string{"error", "warning", "any-finding", "cycle"}
+
+func parseFailPolicy(s string) (failPolicy, error) {
+	policy := make(failPolicy)
+	if s == "" {
+		return policy, nil
+	}
+	for _, class := range strings.Split(s, ",") {
+		class = strings.TrimSpace(class)
+		if !slices.Contains(failClasses, class) {
+			return nil, fmt.Errorf("unknown --fail-on class %q (want %s)", class, strings.Join(failClasses, ", "))
+		}
+		policy[class] = true
+	}
+	return policy, nil
+}
+
+// fails reports whether a finding belongs to a class of the policy. Each
+// class includes the more serious ones, so warning also fails on errors.
+func (p failPolicy) fails(f finding) bool {
+	switch {
+	case p["any-finding"]:
+		return true
+	case p["cycle"] && f.Rule == "cycles":
+		return true
+	case p["warning"] && (f.Severity == severityWarning || f.Severity == severityError):
+		return true
+	case p["error"] && f.Severity == severityError:
+		return true
+	}
+	return false
+}
+
+// selectChecks resolves a comma-separated list of check names among the
+// available ones; an empty list selects them all
+func selectChecks(names string, available []lintCheck) ([]lintCheck, error) {
+	if names == "" {
+		return available, nil
+	}
+	var selected []lintCheck
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, c := range available {
+			if c.name == name {
+				selected = append(selected, c)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown check %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// lint runs checks and returns their findings ordered by location
+func (ws *workspace) lint(checks []lintCheck) []finding {
+	// Checks only read the workspace, so they all run at once
+	var findings []finding
+	for _, found := range parallelMap(len(checks), func(i int) []finding { return checks[i].run(ws) }) {
+		findings = append(findings, found...)
+	}
+	sortFindings(findings)
+	return findings
+}
+
+// sortFindings orders findings by location
+func sortFindings(findings []finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Pos, findings[j].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		// Findings at one position by check, then task and message
+		x, y := findings[i], findings[j]
+		if x.Rule != y.Rule {
+			return x.Rule < y.Rule
+		}
+		if x.Task != y.Task {
+			return x.Task < y.Task
+		}
+		return x.Message < y.Message
+	})
+}
+
+// writeFindings prints findings one per line in the file:line:col form
+// compilers use, followed by a summary
+func writeFindings(w io.Writer, findings []finding) {
+	for _, f := range findings {
+		fmt.Fprintf(w, "%s: %s: %s: %s [%s]\n", f.Pos, f.Severity, f.Task, f.Message, f.Rule)
+	}
+	fmt.Fprintf(w, "%s\n", findingsSummary(findings))
+}
+
+// findingsSummary counts findings by severity
+func findingsSummary(findings []finding) string {
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	return fmt.Sprintf("%d findings (%d errors, %d warnings, %d info)",
+		len(findings), counts[severityError], counts[severityWarning], counts[severityInfo])
+}
+
+// taskCmd is one shell command of a merged task, with where it is written
+type taskCmd struct {
+	name string
+	task *ast.Task
+	cmd  *ast.Cmd
+	pos  sourcePos
+}
+
+// shellCmds lists the shell commands of every task; task calls are skipped
+func (ws *workspace) shellCmds() []taskCmd {
+	var cmds []taskCmd
+	for name, task := range ws.merged.Tasks.All(byName) {
+		for i, cmd := range task.Cmds {
+			if cmd.Cmd != "" {
+				cmds = append(cmds, taskCmd{name: name, task: task, cmd: cmd, pos: ws.idx.cmdPos(task, i)})
+			}
+		}
+	}
+	return cmds
+}
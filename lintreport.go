@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// lintedFiles lists the Taskfiles that define merged tasks, the files the
+// checks looked at, in the order findings are sorted
+func (ws *workspace) lintedFiles() []string {
+	var files []string
+	for _, task := range ws.merged.Tasks.All(byName) {
+		if f := taskPos(task).File; f != "" && !slices.Contains(files, f) {
+			files = append(files, f)
+		}
+	}
+	slices.Sort(files)
+	return files
+}
+
+// lintCase is one check applied to one Taskfile, the unit the test-report
+// formats pass or fail
+type lintCase struct {
+	file, check string
+}
+
+func findingsByCase(findings []finding) map[lintCase][]finding {
+	byCase := make(map[lintCase][]finding)
+	for _, f := range findings {
+		c := lintCase{f.Pos.File, f.Rule}
+		byCase[c] = append(byCase[c], f)
+	}
+	return byCase
+}
+
+// writeJUnit writes findings as JUnit XML: a suite per Taskfile and a test
+// case per check within it, failing when the check reported an error or
+// warning there. Info findings are attached as output of a passing case.
+func writeJUnit(w io.Writer, checks []lintCheck, files []string, findings []finding) error {
+	byCase := findingsByCase(findings)
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	totalFailures := 0
+	var suites strings.Builder
+	for _, file := range files {
+		name := xmlEscape(displayPath(file))
+		failures := 0
+		var cases strings.Builder
+		for _, c := range checks {
+			var failed, info []string
+			for _, f := range byCase[lintCase{file, c.name}] {
+				line := fmt.Sprintf("%s: %s: %s: %s", f.Pos, f.Severity, f.Task, f.Message)
+				if f.Severity == severityInfo {
+					info = append(info, line)
+				} else {
+					failed = append(failed, line)
+				}
+			}
+			fmt.Fprintf(&cases, "    <testcase classname=\"%s\" name=\"%s\"", name, xmlEscape(c.name))
+			if len(failed) == 0 && len(info) == 0 {
+				cases.WriteString("/>\n")
+				continue
+			}
+			cases.WriteString(">\n")
+			if len(failed) > 0 {
+				failures++
+				fmt.Fprintf(&cases, "      <failure message=\"%s\" type=\"%s\">%s</failure>\n",
+					xmlEscape(plural(len(failed), "finding")), xmlEscape(c.name), xmlEscape(strings.Join(failed, "\n")))
+			}
+			if len(info) > 0 {
+				fmt.Fprintf(&cases, "      <system-out>%s</system-out>\n", xmlEscape(strings.Join(info, "\n")))
+			}
+			cases.WriteString("    </testcase>\n")
+		}
+		totalFailures += failures
+		fmt.Fprintf(&suites, "  <testsuite name=\"%s\" tests=\"%d\" failures=\"%d\" errors=\"0\">\n", name, len(checks), failures)
+		suites.WriteString(cases.String())
+		suites.WriteString("  </testsuite>\n")
+	}
+	fmt.Fprintf(&b, "<testsuites name=\"meerkat lint\" tests=\"%d\" failures=\"%d\" errors=\"0\">\n", len(files)*len(checks), totalFailures)
+	b.WriteString(suites.String())
+	b.WriteString("</testsuites>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeTAP writes findings as a TAP version 13 stream with a test point per
+// check and Taskfile. A failing point carries its findings in a YAML
+// diagnostic block; info findings are comments on a passing one.
+func writeTAP(w io.Writer, checks []lintCheck, files []string, findings []finding) error {
+	byCase := findingsByCase(findings)
+	var b strings.Builder
+	b.WriteString("TAP version 13\n")
+	fmt.Fprintf(&b, "1..%d\n", len(files)*len(checks))
+	n := 0
+	for _, file := range files {
+		for _, c := range checks {
+			n++
+			var failed, info []finding
+			for _, f := range byCase[lintCase{file, c.name}] {
+				if f.Severity == severityInfo {
+					info = append(info, f)
+				} else {
+					failed = append(failed, f)
+				}
+			}
+			status := "ok"
+			if len(failed) > 0 {
+				status = "not ok"
+			}
+			fmt.Fprintf(&b, "%s %d - %s %s\n", status, n, c.name, displayPath(file))
+			if len(failed) > 0 {
+				b.WriteString("  ---\n  findings:\n")
+				for _, f := range failed {
+					fmt.Fprintf(&b, "    - location: %s\n      severity: %s\n      task: %s\n      message: %s\n",
+						yamlQuote(f.Pos.String()), f.Severity, yamlQuote(f.Task), yamlQuote(f.Message))
+				}
+				b.WriteString("  ...\n")
+			}
+			for _, f := range info {
+				fmt.Fprintf(&b, "# %s: %s: %s\n", f.Pos, f.Task, f.Message)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// yamlQuote writes s as a double-quoted YAML scalar; every escape Go
+// uses is also a YAML one
+func yamlQuote(s string) string {
+	return strconv.Quote(s)
+}
+
+// writeCheckstyle writes findings in the Checkstyle XML format reviewdog and
+// danger read, a file element per Taskfile with an error element per
+// finding. Rules are reported as sources under a meerkat. prefix.
+func writeCheckstyle(w io.Writer, files []string, findings []finding) error {
+	byFile := make(map[string][]finding)
+	for _, f := range findings {
+		byFile[f.Pos.File] = append(byFile[f.Pos.File], f)
+	}
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString("<checkstyle version=\"8.0\">\n")
+	for _, file := range files {
+		fmt.Fprintf(&b, "  <file name=\"%s\">\n", xmlEscape(displayPath(file)))
+		for _, f := range byFile[file] {
+			fmt.Fprintf(&b, "    <error line=\"%d\" column=\"%d\" severity=\"%s\" message=\"%s\" source=\"%s\"/>\n",
+				f.Pos.Line, f.Pos.Column, f.Severity, xmlEscape(f.Task+": "+f.Message), xmlEscape("meerkat."+f.Rule))
+		}
+		b.WriteString("  </file>\n")
+	}
+	b.WriteString("</checkstyle>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// listRow is one task as list reports it. Deps and fan-in count distinct
+// tasks named in deps and task calls, Depth the longest chain of them below
+// the task; inferred edges are left out, as in the dependency tree.
+type listRow struct {
+	Name     string
+	Desc     string
+	NS       string
+	Deps     int
+	Depth    int
+	FanIn    int
+	Location string
+}
+
+// listColumns are the columns --columns picks from
+var listColumns = map[string]func(r listRow) any{
+	"name":     func(r listRow) any { return r.Name },
+	"desc":     func(r listRow) any { return r.Desc },
+	"ns":       func(r listRow) any { return r.NS },
+	"deps":     func(r listRow) any { return r.Deps },
+	"depth":    func(r listRow) any { return r.Depth },
+	"fanin":    func(r listRow) any { return r.FanIn },
+	"location": func(r listRow) any { return r.Location },
+}
+
+// listOrders rank the rows for --sort; every order but name puts the largest
+// first and breaks ties by name
+var listOrders = map[string]func(r listRow) int{
+	"name":  nil,
+	"deps":  func(r listRow) int { return r.Deps },
+	"depth": func(r listRow) int { return r.Depth },
+	"fanin": func(r listRow) int { return r.FanIn },
+}
+
+// runList lists the tasks with the columns and order asked for, the listing
+// task --list cannot sort or reshape
+func runList(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	opts.register(fs)
+	order := fs.String("sort", "name", "Order by name, deps, depth or fanin")
+	columnList := fs.String("columns", "name,desc,ns,deps", "Comma-separated columns: name, desc, ns, deps, depth, fanin, location")
+	format := fs.String("format", "table", "Output format: table, json or csv")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	key, ok := listOrders[*order]
+	if !ok {
+		return fmt.Errorf("unknown sort %q", *order)
+	}
+	columns := strings.Split(*columnList, ",")
+	for i, c := range columns {
+		columns[i] = strings.TrimSpace(c)
+		if _, ok := listColumns[columns[i]]; !ok {
+			return fmt.Errorf("unknown column %q; want %s", columns[i], strings.Join(slices.Sorted(maps.Keys(listColumns)), ", "))
+		}
+	}
+	if *format != "table" && *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	g, merged, err := l.load()
+	if err != nil {
+		return err
+	}
+	tg, err := buildTaskGraph(g, merged, newSourceIndex(l))
+	if err != nil {
+		return err
+	}
+
+	rows := tg.listRows()
+	if key != nil {
+		sort.SliceStable(rows, func(i, j int) bool { return key(rows[i]) > key(rows[j]) })
+	}
+
+	switch *format {
+	case "json":
+		out := make([]map[string]any, len(rows))
+		for i, r := range rows {
+			out[i] = make(map[string]any)
+			for _, c := range columns {
+				out[i][c] = listColumns[c](r)
+			}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write(columns)
+		for _, r := range rows {
+			record := make([]string, len(columns))
+			for i, c := range columns {
+				record[i] = fmt.Sprint(listColumns[c](r))
+			}
+			w.Write(record)
+		}
+		w.Flush()
+		return w.Error()
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, r := range rows {
+		record := make([]string, len(columns))
+		for i, c := range columns {
+			record[i] = fmt.Sprint(listColumns[c](r))
+		}
+		fmt.Fprintln(tw, strings.Join(record, "\t"))
+	}
+	return tw.Flush()
+}
+
+// listRows measures every task of the graph, in name order; tasks that are
+// only referenced are left out
+func (tg *taskGraph) listRows() []listRow {
+	deps := make(map[string][]string)
+	fanIn := make(map[string]int)
+	for _, e := range tg.Edges {
+		if e.Kind == edgeInferred || e.From == e.To || slices.Contains(deps[e.From], e.To) {
+			continue
+		}
+		deps[e.From] = append(deps[e.From], e.To)
+		fanIn[e.To]++
+	}
+
+	// Longest chain below each task; a task on the current path counts as a
+	// leaf so cycles end
+	depth := make(map[string]int)
+	onPath := make(map[string]bool)
+	var measure func(name string) int
+	measure = func(name string) int {
+		if d, ok := depth[name]; ok {
+			return d
+		}
+		if onPath[name] {
+			return 0
+		}
+		onPath[name] = true
+		d := 0
+		for _, dep := range deps[name] {
+			d = max(d, measure(dep)+1)
+		}
+		delete(onPath, name)
+		depth[name] = d
+		return d
+	}
+
+	var rows []listRow
+	for _, n := range tg.Nodes {
+		if n.Missing {
+			continue
+		}
+		rows = append(rows, listRow{
+			Name:     n.Name,
+			Desc:     n.Desc,
+			NS:       n.Namespace,
+			Deps:     len(deps[n.Name]),
+			Depth:    measure(n.Name),
+			FanIn:    fanIn[n.Name],
+			Location: n.Location,
+		})
+	}
+	return rows
+}
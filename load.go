@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// loader reads and merges a Taskfile graph according to the shared options
+type loader struct {
+	opts  *options
+	dlog  *debugLog
+	prof  *profiler
+	fetch *fetchTransport
+	redir *redirectPolicy
+	sign  *signatureVerifier
+	store *remoteStore
+
+	// timings holds how long each phase of the last load took
+	timings map[string]time.Duration
+
+	// logOut receives reader debug and prompt messages. It is stderr unless
+	// a command prints a human-readable report where they belong inline.
+	logOut io.Writer
+
+	// tree is the temporary copy of a --git-ref tree, an extracted archive
+	// or mirrored object storage, removed on Close
+	tree string
+
+	// stdin holds the root Taskfile once read from stdin for --taskfile -
+	stdin []byte
+
+	// imported holds the Taskfiles translated from a Makefile or other build
+	// file, given as --taskfile or --with, by path as of the last load
+	imported map[string][]byte
+
+	// cache is the last graph loaded, reused while no Taskfile in it has
+	// changed, and sources the task keys of each file parsed so far, by
+	// content hash
+	cache     *loadCache
+	sources   map[[sha256.Size]byte]map[[2]int]taskSource
+	sourcesMu sync.Mutex
+
+	// skipped are the optional includes the last load left out, and
+	// placeholders the cache keys of the empty Taskfiles standing in for the
+	// ones that failed to download, by URI, while it reads
+	skipped      []skippedInclude
+	placeholders map[string]string
+
+	// root, when set, is read in place of the root Taskfile, such as one
+	// left with only the includes a subgraph needs
+	root *prunedNode
+}
+
+// loadCache is a loaded graph with the SHA-256 of every local file in it.
+// go-task's syntax tree cannot be serialized, so it lives as long as the
+// process: the long-running modes reload without reading or merging
+// anything when an edit leaves the graph's files as they were. Between runs
+// the store keeps the files' hashes instead, as a storedGraph.
+type loadCache struct {
+	root   string
+	hashes map[string][sha256.Size]byte // local Taskfile -> content hash
+	remote bool                         // the graph has remote Taskfiles
+	loaded time.Time
+	graph  *ast.TaskfileGraph
+	merged *ast.Taskfile
+}
+
+// newLoadCache hashes the local files of a freshly loaded graph
+func newLoadCache(root string, g *ast.TaskfileGraph, merged *ast.Taskfile) *loadCache {
+	adjacency, err := g.AdjacencyMap()
+	if err != nil {
+		return nil
+	}
+	c := &loadCache{root: root, hashes: make(map[string][sha256.Size]byte), loaded: time.Now(), graph: g, merged: merged}
+	for uri := range adjacency {
+		if taskfile.IsRemoteEntrypoint(uri) {
+			c.remote = true
+			continue
+		}
+		data, err := os.ReadFile(uri)
+		if err != nil {
+			return nil
+		}
+		c.hashes[uri] = sha256.Sum256(data)
+	}
+	return c
+}
+
+// fresh reports whether the cached graph still describes root: every local
+// file has the content it was loaded with and remote files are within the
+// cache expiry the reader would have honored
+func (c *loadCache) fresh(root string, expiry time.Duration) bool {
+	if c == nil || c.root != root {
+		return false
+	}
+	if c.remote && time.Since(c.loaded) >= expiry {
+		return false
+	}
+	for uri, hash := range c.hashes {
+		data, err := os.ReadFile(uri)
+		if err != nil || sha256.Sum256(data) != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// newLoader prepares the environment for reading Taskfiles: the debug log,
+// the remote Taskfiles experiment and the HTTP transport
+func newLoader(o *options) (*loader, error) {
+	// Open the structured debug log, if requested
+	dlog, err := openDebugLog(o.debugFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open debug log: %w", err)
+	}
+
+	// Experiments come from the environment, .env and the Task settings of
+	// the Taskfile's directory, as when task runs there
+	if err := setupExperiments(o); err != nil {
+		return nil, err
+	}
+
+	// Remote HTTP nodes use the default client, so its timeout bounds every
+	// individual fetch of the root Taskfile and its includes
+	http.DefaultClient.Timeout = o.timeout
+
+	// Report progress of, and throttle, remote downloads through the default client
+	progressReporter, err := newProgressReporter(o.progress, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --progress: %w", err)
+	}
+	fetch := &fetchTransport{
+		progress: progressReporter,
+		limiter:  newRateLimiter(o.rateLimit),
+		perHost:  o.maxPerHost,
+		budget:   &downloadBudget{limits: o.limits},
+		creds:    newCredentialHelper(o.credHelper),
+		netrc:    readNetrc(),
+	}
+	if fetch.base, err = tlsTransport(o); err != nil {
+		return nil, err
+	}
+	installFetchTransport(fetch)
+	redir, err := newRedirectPolicy(o.maxRedirects, o.redirects)
+	if err != nil {
+		return nil, err
+	}
+	http.DefaultClient.CheckRedirect = redir.check
+
+	sign, err := newSignatureVerifier(o.cosignKey, o.requireSig || o.strict && o.cosignKey != "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Analyze a historical version by pointing the reader at a copy of its tree
+	var tree string
+	if o.gitRef != "" {
+		o.taskfileURL, tree, err = checkoutGitRef(o.taskfileURL, o.gitRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read git ref %s: %w", o.gitRef, err)
+		}
+	} else if archiveFormat(o.taskfileURL) != "" {
+		// Archives are unpacked and the Taskfile inside them analyzed in place
+		o.taskfileURL, tree, err = openArchive(o.taskfileURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+	} else if objectScheme(o.taskfileURL) != "" {
+		// The reader has no S3, GCS or OCI support, so it reads a local mirror
+		o.taskfileURL, tree, err = mirrorObjects(o.taskfileURL, o.timeout, o.limits, sign)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	temp, err := taskTempDir(o)
+	if err != nil {
+		return nil, err
+	}
+	store, err := openRemoteStore(o.cacheDir, temp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Profiling starts last, so a failed setup leaves nothing running
+	prof, err := startProfiles(o.cpuProfile, o.memProfile)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	return &loader{opts: o, dlog: dlog, prof: prof, fetch: fetch, redir: redir, sign: sign, store: store, logOut: os.Stderr, tree: tree}, nil
+}
+
+// Close writes any profiles and releases the debug log, the reader's cache
+// directory and any extracted git ref or archive tree
+func (l *loader) Close() error {
+	if l.tree != "" {
+		os.RemoveAll(l.tree)
+	}
+	l.store.Close()
+	if err := l.prof.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	return l.dlog.Close()
+}
+
+// load reads the Taskfile graph (including remote includes) and merges it
+func (l *loader) load() (*ast.TaskfileGraph, *ast.Taskfile, error) {
+	// Reader debug messages go to the debug log when one is open
+	debugFunc := func(msg string) {
+		fmt.Fprintf(l.logOut, "DEBUG: %s\n", msg)
+	}
+	if l.dlog != nil {
+		debugFunc = l.dlog.Debug
+	}
+
+	// Nothing to do when no Taskfile of the last graph has changed
+	cacheStart := time.Now()
+	if l.root == nil && !l.opts.noCache && l.cache.fresh(l.opts.taskfileURL, l.opts.cacheExpiry) {
+		l.phase("cache", cacheStart, nil)
+		if err := l.checkStrict(l.cache.graph, l.cache.merged); err != nil {
+			return nil, nil, err
+		}
+		return l.cache.graph, l.cache.merged, nil
+	}
+
+	// Create a root node for the Taskfile
+	var node taskfile.Node
+	var err error
+	switch {
+	case l.root != nil:
+		node = l.root
+	case l.opts.taskfileURL == "-":
+		node, err = l.newStdinNode()
+	case importFormat(l.opts.taskfileURL) != "":
+		node, err = l.newImportNode()
+	default:
+		node, err = taskfile.NewRootNode(l.opts.taskfileURL, "", l.opts.insecure(), l.opts.timeout)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create root node: %w", err)
+	}
+
+	// Create a reader with remote-specific options, afresh for every read
+	newReader := func() *taskfile.Reader {
+		return taskfile.NewReader(append(readerOptions(l.opts),
+			taskfile.WithDownload(l.opts.noCache), // Force download if no-cache is set
+			taskfile.WithTempDir(l.store.work),
+			taskfile.WithCacheExpiryDuration(l.opts.cacheExpiry),
+			taskfile.WithDebugFunc(debugFunc),
+			taskfile.WithPromptFunc(func(prompt string) error {
+				fmt.Fprintf(l.logOut, "PROMPT: %s\n", prompt)
+				// Auto-accept prompts for demo purposes
+				// In production, you'd want to prompt the user
+				return nil
+			}),
+		)...)
+	}
+
+	// Bound the whole read phase if an overall deadline was requested
+	ctx := withTaskfileFetch(context.Background())
+	if l.opts.readTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.opts.readTimeout)
+		defer cancel()
+	}
+
+	// Read the Taskfile graph (including remote includes)
+	// The reader sees the stored copies of what this root read last time
+	if err := l.store.materialize(node.Location()); err != nil {
+		return nil, nil, fmt.Errorf("failed to prepare cache: %w", err)
+	}
+
+	// An optional include that fails to download is skipped, and the read
+	// repeated without it, as is any remote one under --keep-going
+	readStart := time.Now()
+	l.skipped = nil
+	l.fetch.creds.reset()
+	// A graph whose files a run before read with the same content is built
+	// from them without the reader
+	var stored *storedGraph
+	if l.storesGraph() && !l.opts.noCache {
+		stored = l.store.readGraph(node.Location())
+	}
+	taskfileGraph := stored.build(node, l.opts.insecure(), l.opts.cacheExpiry, l.readSource)
+	if taskfileGraph == nil {
+		stored = nil
+	}
+	for taskfileGraph == nil {
+		l.fetch.budget.reset()
+		l.redir.reset()
+		taskfileGraph, err = newReader().Read(ctx, node)
+		if err == nil || !l.skipFailed(node.Location(), err) {
+			break
+		}
+	}
+	l.phase("read", readStart, err)
+	var keys map[string]string
+	if err == nil {
+		err = l.dropSkipped(taskfileGraph)
+	}
+	l.clearPlaceholders()
+	if err == nil {
+		keys = graphCacheKeys(taskfileGraph, l.opts.insecure())
+		err = l.graftBuildFiles(taskfileGraph, node.Location())
+	}
+	if ierr := l.store.ingest(node.Location(), keys, l.redir.targets()); ierr != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to update cache: %v\n", ierr)
+	}
+	if err != nil {
+		// A download refused for breaking a limit fails without saying why
+		if exceeded := l.fetch.budget.err(); exceeded != nil {
+			err = exceeded
+		}
+		// So does one that redirects against --redirects or --max-redirects
+		if refused := l.redir.err(); refused != nil {
+			err = refused
+		}
+		// And one whose credential helper failed
+		if failed := l.fetch.creds.err(); failed != nil {
+			err = failed
+		}
+		return nil, nil, fmt.Errorf("failed to read Taskfile: %w", l.explainIncludeCycle(err))
+	}
+	if err := checkGraphLimits(taskfileGraph, l.opts.limits); err != nil {
+		return nil, nil, fmt.Errorf("failed to read Taskfile: %w", err)
+	}
+	if err := l.sign.checkGraph(l, taskfileGraph); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify Taskfile: %w", err)
+	}
+	if stored == nil && l.storesGraph() {
+		stored = newStoredGraph(taskfileGraph, l.opts.insecure(), l.readSource)
+	}
+
+	// Get the merged Taskfile
+	mergeStart := time.Now()
+	mergedTaskfile, err := taskfileGraph.Merge()
+	l.phase("merge", mergeStart, err)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to merge Taskfile: %w", err)
+	}
+
+	if l.root == nil && l.opts.taskfileURL != "-" {
+		l.cache = newLoadCache(l.opts.taskfileURL, taskfileGraph, mergedTaskfile)
+	}
+	if stored != nil {
+		stored.Used = time.Now().UTC()
+		if err := l.store.writeGraph(node.Location(), stored); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to update cache: %v\n", err)
+		}
+	}
+	if err := l.checkStrict(taskfileGraph, mergedTaskfile); err != nil {
+		return nil, nil, err
+	}
+	return taskfileGraph, mergedTaskfile, nil
+}
+
+// storesGraph reports whether the graph of this load can be kept between
+// runs: one read from the Taskfile itself, not stdin, a translated build
+// file or a temporary tree, and left whole
+func (l *loader) storesGraph() bool {
+	return l.root == nil && l.tree == "" && l.opts.taskfileURL != "-" &&
+		importFormat(l.opts.taskfileURL) == "" && len(l.opts.with) == 0
+}
+
+// phase records the duration of a load phase in the debug log and timings
+func (l *loader) phase(name string, start time.Time, err error) {
+	l.dlog.Phase(name, start, err)
+	if l.timings == nil {
+		l.timings = make(map[string]time.Duration)
+	}
+	l.timings[name] = time.Since(start)
+}
+
+// readSource returns the raw bytes of the Taskfile at uri. Remote files are
+// read from the reader's cache directory, which load has just populated.
+func (l *loader) readSource(uri string) ([]byte, error) {
+	if uri == stdinLocation && l.stdin != nil {
+		return l.stdin, nil
+	}
+	if src, ok := l.imported[uri]; ok {
+		return src, nil
+	}
+	if !taskfile.IsRemoteEntrypoint(uri) {
+		return os.ReadFile(uri)
+	}
+	node, err := taskfile.NewNode(uri, "", l.opts.insecure())
+	if err != nil {
+		return nil, err
+	}
+	remote, ok := node.(taskfile.RemoteNode)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a remote Taskfile", uri)
+	}
+	return taskfile.NewCacheNode(remote, l.store.work).Read()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// writeFiles writes files, by path relative to dir, creating directories
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newTestLoader is a loader of the Taskfile.yml among files, written to a
+// temporary directory with a cache of its own
+func newTestLoader(t *testing.T, files map[string]string, args ...string) *loader {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	var o options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.register(fs)
+	args = append([]string{"--taskfile", filepath.Join(dir, "Taskfile.yml"), "--cache-dir", t.TempDir()}, args...)
+	if err := parseFlags(fs, &o, args); err != nil {
+		t.Fatal(err)
+	}
+	l, err := newLoader(&o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.logOut = io.Discard
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// loadTest loads the Taskfile.yml among files
+func loadTest(t *testing.T, files map[string]string, args ...string) (*loader, *ast.TaskfileGraph, *ast.Taskfile) {
+	t.Helper()
+	l := newTestLoader(t, files, args...)
+	g, merged, err := l.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, g, merged
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// runLSP serves the Language Server Protocol over stdio. Without an explicit
+// --taskfile the first document the editor opens is taken as the root.
+func runLSP(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	opts.register(fs)
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if opts.taskfileURL == "-" {
+		return fmt.Errorf("--taskfile - cannot be used here: stdin carries the protocol messages")
+	}
+
+	checks, err := loadChecks(opts.configFile)
+	if err != nil {
+		return err
+	}
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	s := &lspServer{
+		conn:     newRPCConn(os.Stdin, os.Stdout),
+		l:        l,
+		checks:   checks,
+		haveRoot: opts.taskfileURL != defaultTaskfileURL,
+		docs:     make(map[string]string),
+	}
+	return s.serve()
+}
+
+// lspServer holds the open documents and the workspace they belong to
+type lspServer struct {
+	conn     *rpcConn
+	l        *loader
+	haveRoot bool
+	checks   []lintCheck
+	ws       *workspace
+	findings []finding         // of the checks on ws, as of its load
+	docs     map[string]string // document URI -> current text
+}
+
+// LSP wire types, limited to the fields used here
+type (
+	lspPosition struct {
+		Line      int `json:"line"`
+		Character int `json:"character"`
+	}
+	lspRange struct {
+		Start lspPosition `json:"start"`
+		End   lspPosition `json:"end"`
+	}
+	lspLocation struct {
+		URI   string   `json:"uri"`
+		Range lspRange `json:"range"`
+	}
+	lspDiagnostic struct {
+		Range    lspRange `json:"range"`
+		Severity int      `json:"severity"`
+		Source   string   `json:"source"`
+		Message  string   `json:"message"`
+	}
+	lspTextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text,omitempty"`
+	}
+	lspPositionParams struct {
+		TextDocument lspTextDocument `json:"textDocument"`
+		Position     lspPosition     `json:"position"`
+	}
+)
+
+// Diagnostic severities
+const (
+	lspError       = 1
+	lspWarning     = 2
+	lspInformation = 3
+)
+
+func (s *lspServer) serve() error {
+	for {
+		req, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			if err := s.conn.reply(nil, nil, rpcErr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err := s.handle(req)
+		if len(req.ID) > 0 {
+			if err := s.conn.reply(req.ID, result, err); err != nil {
+				return err
+			}
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+	}
+}
+
+func (s *lspServer) handle(req *rpcRequest) (any, error) {
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   map[string]any{"openClose": true, "change": 1, "save": true},
+				"definitionProvider": true,
+				"hoverProvider":      true,
+			},
+			"serverInfo": map[string]any{"name": "meerkat"},
+		}, nil
+
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		if s.ws == nil {
+			s.reload(p.TextDocument.URI)
+		}
+		return nil, s.publish(p.TextDocument.URI, nil)
+
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument   lspTextDocument `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[n-1].Text
+		}
+		return nil, s.publish(p.TextDocument.URI, nil)
+
+	case "textDocument/didSave":
+		var p struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		loadErr := s.reload(p.TextDocument.URI)
+		for uri := range s.docs {
+			var err error
+			if uri == p.TextDocument.URI {
+				err = loadErr
+			}
+			if err := s.publish(uri, err); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+
+	case "textDocument/didClose":
+		var p struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		delete(s.docs, p.TextDocument.URI)
+		return nil, s.conn.notify("textDocument/publishDiagnostics", map[string]any{
+			"uri": p.TextDocument.URI, "diagnostics": []lspDiagnostic{},
+		})
+
+	case "textDocument/definition":
+		var p lspPositionParams
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		_, task, ok := s.taskAt(p)
+		if !ok || task.Location == nil {
+			return nil, nil
+		}
+		pos := lspPosition{Line: task.Location.Line - 1, Character: task.Location.Column - 1}
+		return lspLocation{URI: pathToURI(task.Location.Taskfile), Range: lspRange{Start: pos, End: pos}}, nil
+
+	case "textDocument/hover":
+		var p lspPositionParams
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		name, task, ok := s.taskAt(p)
+		if !ok {
+			return nil, nil
+		}
+		return map[string]any{
+			"contents": map[string]any{"kind": "markdown", "value": hoverText(name, task)},
+		}, nil
+
+	case "initialized", "shutdown", "exit", "$/cancelRequest":
+		return nil, nil
+
+	default:
+		if len(req.ID) > 0 {
+			return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not supported: " + req.Method}
+		}
+		return nil, nil
+	}
+}
+
+// reload reads the Taskfile graph again, keeping the previous workspace when
+// the new one fails to load
+func (s *lspServer) reload(docURI string) error {
+	if !s.haveRoot {
+		s.l.opts.taskfileURL = uriToPath(docURI)
+		s.haveRoot = true
+	}
+	ws, err := openWorkspace(s.l)
+	if err != nil {
+		s.conn.notify("window/logMessage", map[string]any{"type": lspError, "message": err.Error()})
+		return err
+	}
+	// Lint runs the external analyzers too, so it runs once per load
+	// rather than on every edit, which could not change its findings
+	s.ws, s.findings = ws, ws.lint(s.checks)
+	return nil
+}
+
+// text is the current content of a document, open or not
+func (s *lspServer) text(docURI string) []byte {
+	if text, ok := s.docs[docURI]; ok {
+		return []byte(text)
+	}
+	src, _ := s.l.readSource(uriToPath(docURI))
+	return src
+}
+
+// taskAt resolves the task named at a cursor position
+func (s *lspServer) taskAt(p lspPositionParams) (string, *ast.Task, bool) {
+	if s.ws == nil {
+		return "", nil, false
+	}
+	uri := p.TextDocument.URI
+	ref, ok := s.ws.refAt(uriToPath(uri), s.text(uri), p.Position.Line+1, p.Position.Character+1)
+	if !ok {
+		return "", nil, false
+	}
+	return s.ws.resolve(ref.name)
+}
+
+// publish sends the diagnostics of one document, plus loadErr if the graph
+// failed to load
+func (s *lspServer) publish(docURI string, loadErr error) error {
+	diagnostics := []lspDiagnostic{}
+	if loadErr != nil {
+		diagnostics = append(diagnostics, lspDiagnostic{Severity: lspError, Source: "meerkat", Message: loadErr.Error()})
+	}
+	if s.ws != nil {
+		diagnostics = append(diagnostics, s.ws.diagnostics(uriToPath(docURI), s.text(docURI), s.findings)...)
+	}
+	return s.conn.notify("textDocument/publishDiagnostics", map[string]any{
+		"uri": docURI, "diagnostics": diagnostics,
+	})
+}
+
+// diagnostics reports the problems found in one file of the workspace: task
+// calls naming a task that does not exist, and the lint findings located in
+// the file. Lint positions come from the loaded graph, so they follow the
+// saved file rather than unsaved edits.
+func (ws *workspace) diagnostics(uri string, src []byte, findings []finding) []lspDiagnostic {
+	var diagnostics []lspDiagnostic
+	for _, ref := range ws.fileRefs(uri, src) {
+		if ref.key || strings.Contains(ref.node.Value, "{{") {
+			continue
+		}
+		if _, _, ok := ws.resolve(ref.name); !ok {
+			diagnostics = append(diagnostics, lspDiagnostic{
+				Range:    nodeRange(ref.node),
+				Severity: lspWarning,
+				Source:   "meerkat",
+				Message:  fmt.Sprintf("task %q not found%s", ref.name, didYouMean(ref.name, ws.merged.Tasks)),
+			})
+		}
+	}
+	for _, f := range findings {
+		if f.Pos.File != uri || f.Pos.Line == 0 {
+			continue
+		}
+		pos := lspPosition{Line: f.Pos.Line - 1, Character: f.Pos.Column - 1}
+		diagnostics = append(diagnostics, lspDiagnostic{
+			Range:    lspRange{Start: pos, End: pos},
+			Severity: lspSeverity(f.Severity),
+			Source:   "meerkat/" + f.Rule,
+			Message:  f.Message,
+		})
+	}
+	return diagnostics
+}
+
+// lspSeverity maps a finding severity to the LSP diagnostic severity
+func lspSeverity(severity string) int {
+	switch severity {
+	case severityError:
+		return lspError
+	case severityWarning:
+		return lspWarning
+	default:
+		return lspInformation
+	}
+}
+
+// nodeRange is the LSP range covering a scalar's value
+func nodeRange(n *yaml.Node) lspRange {
+	start := n.Column - 1
+	if n.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0 {
+		start++
+	}
+	return lspRange{
+		Start: lspPosition{Line: n.Line - 1, Character: start},
+		End:   lspPosition{Line: n.Line - 1, Character: start + len([]rune(n.Value))},
+	}
+}
+
+// hoverText describes a task in markdown: its description, where it is
+// defined, and its deps and commands as they are after merging
+func hoverText(name string, task *ast.Task) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**", name)
+	if task.Desc != "" {
+		fmt.Fprintf(&b, " — %s", task.Desc)
+	}
+	fmt.Fprintf(&b, "\n\nDefined at `%s`\n", taskPos(task))
+	if len(task.Deps) > 0 {
+		b.WriteString("\nDeps:\n")
+		for _, dep := range task.Deps {
+			fmt.Fprintf(&b, "- %s\n", dep.Task)
+		}
+	}
+	if len(task.Cmds) > 0 {
+		b.WriteString("\n```sh\n")
+		for _, cmd := range task.Cmds {
+			prefix := ""
+			if cmd.Defer {
+				prefix = "defer "
+			}
+			if cmd.Task != "" {
+				fmt.Fprintf(&b, "%stask: %s\n", prefix, cmd.Task)
+			} else {
+				fmt.Fprintf(&b, "%s%s\n", prefix, cmd.Cmd)
+			}
+		}
+		b.WriteString("```\n")
+	}
+	return b.String()
+}
+
+// uriToPath turns a file:// document URI into the path the graph uses.
+// Remote URIs are already graph keys.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
+
+// pathToURI is the inverse of uriToPath
+func pathToURI(uri string) string {
+	if taskfile.IsRemoteEntrypoint(uri) {
+		return uri
+	}
+	return (&url.URL{Scheme: "file", Path: uri}).String()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLSPLintsOncePerLoad(t *testing.T) {
+	l := newTestLoader(t, map[string]string{"Taskfile.yml": "version: '3'\ntasks:\n  build:\n    cmds:\n      - go build\n"})
+	root := l.opts.taskfileURL
+
+	// The check stands in for an external analyzer, which is expensive to run
+	runs := 0
+	check := lintCheck{name: "slow", run: func(ws *workspace) []finding {
+		runs++
+		return []finding{{Rule: "slow", Severity: severityWarning, Task: "build", Message: "slow build", Pos: sourcePos{File: root, Line: 3, Column: 3}}}
+	}}
+	var out strings.Builder
+	s := &lspServer{
+		conn:     newRPCConn(strings.NewReader(""), &out),
+		l:        l,
+		checks:   []lintCheck{check},
+		haveRoot: true,
+		docs:     make(map[string]string),
+	}
+	send := func(method string, params any) {
+		t.Helper()
+		raw, err := json.Marshal(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.handle(&rpcRequest{Method: method, Params: raw}); err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+	}
+	doc := map[string]any{"uri": pathToURI(root), "text": "version: '3'\ntasks:\n  build:\n"}
+
+	send("textDocument/didOpen", map[string]any{"textDocument": doc})
+	for range 3 {
+		send("textDocument/didChange", map[string]any{"textDocument": doc, "contentChanges": []map[string]any{{"text": doc["text"]}}})
+	}
+	if runs != 1 {
+		t.Errorf("lint ran %d times for one load and some edits, want 1", runs)
+	}
+	if n := strings.Count(out.String(), "slow build"); n != 4 {
+		t.Errorf("finding was published %d times, want with each of the 4 notifications", n)
+	}
+
+	send("textDocument/didSave", map[string]any{"textDocument": doc})
+	if runs != 2 {
+		t.Errorf("lint ran %d times after a save, want once more", runs)
+	}
+}
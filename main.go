@@ -1,84 +1,341 @@
 package main
 
 import (
-	"context"
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/dominikbraun/graph"
-	"github.com/go-task/task/v3/experiments"
-	"github.com/go-task/task/v3/taskfile"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
-func main() {
-	// Command line flags
-	var (
-		taskfileURL = flag.String("taskfile", "https://raw.githubusercontent.com/gkwa/ringgem/refs/heads/master/Taskfile.yaml", "Taskfile URL or path")
-		startTask   = flag.String("start", "default", "Task to start dependency tree from")
-		noCache     = flag.Bool("no-cache", false, "Force download without using cache")
-	)
-	flag.Parse()
+// commands maps subcommand names to their entry points. Running without a
+// subcommand prints the full analysis report. It is filled in init, since
+// the config file checks its keys against the flags of every command.
+var commands map[string]func(args []string) error
 
-	// Enable remote Taskfiles experiment - need to parse experiments first
-	os.Setenv("TASK_X_REMOTE_TASKFILES", "1")
+func init() {
+	commands = map[string]func(args []string) error{
+		"badges":      runBadges,
+		"bench":       runBench,
+		"blame":       runBlame,
+		"bundle":      runBundle,
+		"cache":       runCache,
+		"ci":          runCI,
+		"describe":    runDescribe,
+		"diff":        runDiff,
+		"docs":        runDocs,
+		"env-diff":    runEnvDiff,
+		"export":      runExport,
+		"fingerprint": runFingerprint,
+		"hubs":        runHubs,
+		"list":        runList,
+		"order":       runOrder,
+		"inventory":   runInventory,
+		"lint":        runLint,
+		"lsp":         runLSP,
+		"policy":      runPolicy,
+		"prune":       runPrune,
+		"refactor":    runRefactor,
+		"rpc":         runRPC,
+		"serve":       runServe,
+		"similar":     runSimilar,
+		"split":       runSplit,
+		"vendor":      runVendor,
+		"track":       runTrack,
+		"verify":      runVerify,
+	}
+}
 
-	// Parse experiments with current directory
-	experiments.Parse(".")
+// taskNames collects a repeatable flag of task names, each use taking one
+// name or a comma-separated list
+type taskNames []string
+
+func (t *taskNames) String() string {
+	return strings.Join(*t, ",")
+}
 
-	// Validate experiments
-	if err := experiments.Validate(); err != nil {
-		panic(fmt.Sprintf("Failed to validate experiments: %v", err))
+func (t *taskNames) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			*t = append(*t, name)
+		}
 	}
+	return nil
+}
 
-	// Create a root node for the Taskfile
-	node, err := taskfile.NewRootNode(*taskfileURL, "", false, 30*time.Second)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to create root node: %v", err))
-	}
-
-	// Create a reader with remote-specific options
-	reader := taskfile.NewReader(
-		taskfile.WithInsecure(false),    // Don't allow HTTP (only HTTPS)
-		taskfile.WithDownload(*noCache), // Force download if no-cache is set
-		taskfile.WithOffline(false),     // Allow network requests
-		taskfile.WithTempDir(os.TempDir()),
-		taskfile.WithCacheExpiryDuration(24*time.Hour),
-		taskfile.WithDebugFunc(func(msg string) {
-			fmt.Printf("DEBUG: %s\n", msg)
-		}),
-		taskfile.WithPromptFunc(func(prompt string) error {
-			fmt.Printf("PROMPT: %s\n", prompt)
-			// Auto-accept prompts for demo purposes
-			// In production, you'd want to prompt the user
-			return nil
-		}),
-	)
-
-	// Read the Taskfile graph (including remote includes)
-	taskfileGraph, err := reader.Read(context.Background(), node)
+// quoteTasks lists task names the way the report headings quote one
+func quoteTasks(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + name + "'"
+	}
+	if len(names) == 1 {
+		return quoted[0] + " task"
+	}
+	return strings.Join(quoted, ", ") + " tasks"
+}
+
+// failure is returned by a command that ran fine but whose results call for
+// a non-zero exit status, such as findings matching --fail-on
+type failure struct {
+	msg string
+}
+
+func (f *failure) Error() string {
+	return f.msg
+}
+
+func main() {
+	// Dispatch to a subcommand when one is named first
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				if jsonErrors(os.Args[2:]) {
+					writeErrorJSON(os.Stderr, err)
+				} else {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+				}
+				os.Exit(describeError(err).ExitCode)
+			}
+			return
+		}
+	}
+
+	if err := runReport(os.Args[1:]); err != nil {
+		if jsonErrors(os.Args[1:]) {
+			writeErrorJSON(os.Stderr, err)
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(describeError(err).ExitCode)
+	}
+}
+
+// runReport prints the inclusion graph, every task and the dependency tree
+func runReport(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("meerkat", flag.ExitOnError)
+	opts.register(fs)
+	var starts taskNames
+	fs.Var(&starts, "start", "Task to start dependency tree from (repeatable or comma-separated; default \"default\")")
+	format := fs.String("format", "text", "Output format: text, json, dot, mermaid, graphml, gexf, cytoscape, json-tree for the dependency tree of --start, or template")
+	subgraph := fs.Bool("subgraph", false, "Only report the tasks reachable from --start, reading only the root includes they call into")
+	maxDepth := fs.Int("max-depth", 0, "With --format json-tree or template, levels to expand below --start (0 is unlimited)")
+	graphKind := fs.String("graph", "tasks", "Graph to report: tasks, or includes for the Taskfile inclusion graph alone")
+	allRoots := fs.Bool("all-roots", false, "Start from every task no other task calls, instead of --start")
+	var selected selectors
+	fs.Var(&selected, "select", "Only report tasks whose labels match, as in tier=critical, owner!=web or flaky (repeatable)")
+	groupBy := fs.String("group-by", "", "Group the task listing and statistics by the value of this label")
+	hierarchy := fs.Bool("hierarchy", false, "Also print the tasks as a tree of their name prefixes")
+	noPager := fs.Bool("no-pager", false, "Print straight to the terminal instead of through $PAGER")
+	collapse := fs.Int("collapse", 10, "With --hierarchy, fold flat families larger than this into one line (0 never folds)")
+	treeStyle := fs.String("tree", "indent", "How to draw the dependency tree of --start: indent, or dag to draw each task once with converging lines")
+	render := fs.String("render", "", "Draw the graph as png, svg or pdf with the local Graphviz dot instead of reporting it")
+	out := fs.String("out", "", "With --render, the image file to write (default tasks.FORMAT or includes.FORMAT)")
+	open := fs.Bool("open", false, "Render the graph (as svg unless --render says otherwise) and open it in the default browser")
+	templateFile := fs.String("template-file", "", "With --format template, the Go template to execute against the Taskfile, graph and tree")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if *allRoots && len(starts) > 0 {
+		return fmt.Errorf("--all-roots and --start cannot be combined")
+	}
+	if len(starts) == 0 {
+		starts = taskNames{"default"}
+	}
+
+	l, err := newLoader(&opts)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to read Taskfile: %v", err))
+		return err
+	}
+	defer l.Close()
+	if *graphKind != "tasks" && *graphKind != "includes" {
+		return fmt.Errorf("unknown graph %q", *graphKind)
+	}
+	if *treeStyle != "indent" && *treeStyle != "dag" {
+		return fmt.Errorf("unknown tree style %q", *treeStyle)
+	}
+	if err := checkRender(render, out, *open, *graphKind, *format); err != nil {
+		return err
+	}
+	var reportTemplate *template.Template
+	if (*format == "template") != (*templateFile != "") {
+		return fmt.Errorf("--format template and --template-file go together")
+	}
+	if *templateFile != "" {
+		if reportTemplate, err = parseReportTemplate(*templateFile); err != nil {
+			return err
+		}
+	}
+	if *format == "text" {
+		l.logOut = os.Stdout
 	}
 
-	// Get the merged Taskfile
-	mergedTaskfile, err := taskfileGraph.Merge()
+	load := l.load
+	if *subgraph && !*allRoots {
+		load = func() (*ast.TaskfileGraph, *ast.Taskfile, error) { return l.loadSubgraph(starts...) }
+	}
+	taskfileGraph, mergedTaskfile, err := load()
 	if err != nil {
-		panic(fmt.Sprintf("Failed to merge Taskfile: %v", err))
+		return err
+	}
+	if *allRoots {
+		if starts = rootTasks(mergedTaskfile); len(starts) == 0 {
+			return fmt.Errorf("every task is called by another, so there is no root to start from")
+		}
+	}
+
+	// Paging starts after loading, so trust prompts for remote Taskfiles
+	// still reach the terminal
+	defer pageOutput(*noPager)()
+
+	idx := newSourceIndex(l)
+
+	// --select and --subgraph narrow every view of the task graph
+	reachable := func(string) bool { return true }
+	if *subgraph {
+		ws, err := newWorkspace(l, taskfileGraph, mergedTaskfile)
+		if err != nil {
+			return err
+		}
+		for i, start := range starts {
+			starts[i], _, _ = ws.resolve(start)
+		}
+		tg, err := buildTaskGraph(taskfileGraph, mergedTaskfile, idx)
+		if err != nil {
+			return err
+		}
+		names := make(map[string]bool)
+		for _, n := range tg.subgraph(starts...).Nodes {
+			names[n.Name] = true
+		}
+		reachable = func(name string) bool { return names[name] }
+	}
+	scope := func(tg *taskGraph) *taskGraph {
+		tg = tg.selectNodes(selected)
+		if *subgraph {
+			tg = tg.subgraph(starts...)
+		}
+		return tg
+	}
+
+	// --render hands the DOT export to Graphviz
+	if *render != "" {
+		var dot bytes.Buffer
+		if *graphKind == "includes" {
+			ig, err := buildIncludeGraph(taskfileGraph)
+			if err != nil {
+				return err
+			}
+			if err := writeIncludeGraph(&dot, ig, "dot"); err != nil {
+				return err
+			}
+		} else {
+			tg, err := buildTaskGraph(taskfileGraph, mergedTaskfile, idx)
+			if err != nil {
+				return err
+			}
+			if err := writeTaskGraph(&dot, scope(tg), "dot"); err != nil {
+				return err
+			}
+		}
+		if err := renderDOT(dot.Bytes(), *render, *out); err != nil {
+			return err
+		}
+		if *open {
+			return openBrowser(*out)
+		}
+		return nil
+	}
+
+	// The inclusion graph is exported on its own, in every format
+	if *graphKind == "includes" {
+		ig, err := buildIncludeGraph(taskfileGraph)
+		if err != nil {
+			return err
+		}
+		ig.Skipped = l.skipped
+		return writeIncludeGraph(os.Stdout, ig, *format)
+	}
+
+	// A user's template sees everything the built-in formats draw on
+	if reportTemplate != nil {
+		ws, err := newWorkspace(l, taskfileGraph, mergedTaskfile)
+		if err != nil {
+			return err
+		}
+		tg, err := buildTaskGraph(taskfileGraph, mergedTaskfile, idx)
+		if err != nil {
+			return err
+		}
+		ig, err := buildIncludeGraph(taskfileGraph)
+		if err != nil {
+			return err
+		}
+		data := templateData{
+			Start:    starts[0],
+			Starts:   starts,
+			Taskfile: mergedTaskfile,
+			Graph:    scope(tg),
+			Includes: ig,
+		}
+		for _, start := range starts {
+			data.Trees = append(data.Trees, ws.tree(start, *maxDepth, make(map[string]bool)))
+		}
+		data.Tree = data.Trees[0]
+		return writeTemplate(os.Stdout, reportTemplate, data)
+	}
+
+	// The dependency tree of one task, nested, for clients that expand it
+	// lazily; several starts give an array of trees
+	if *format == "json-tree" {
+		ws, err := newWorkspace(l, taskfileGraph, mergedTaskfile)
+		if err != nil {
+			return err
+		}
+		var trees []taskTree
+		for _, start := range starts {
+			if _, _, ok := ws.resolve(start); !ok {
+				return fmt.Errorf("task %q not found%s", start, didYouMean(start, mergedTaskfile.Tasks))
+			}
+			trees = append(trees, ws.tree(start, *maxDepth, make(map[string]bool)))
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if len(trees) == 1 {
+			return enc.Encode(trees[0])
+		}
+		return enc.Encode(trees)
+	}
+
+	// Graph exports replace the text report entirely
+	if *format != "text" {
+		tg, err := buildTaskGraph(taskfileGraph, mergedTaskfile, idx)
+		if err != nil {
+			return err
+		}
+		return writeTaskGraph(os.Stdout, scope(tg), *format)
 	}
 
 	fmt.Printf("=== Taskfile Graph Analysis ===\n")
 	fmt.Printf("Location: %s\n", mergedTaskfile.Location)
 	fmt.Printf("Version: %s\n", mergedTaskfile.Version.String())
+	if on := enabledExperiments(); len(on) > 0 {
+		fmt.Printf("Experiments: %s\n", strings.Join(on, ", "))
+	}
 	fmt.Printf("\n")
 
 	// Traverse the Taskfile inclusion graph
 	fmt.Printf("=== Taskfile Inclusion Graph ===\n")
-	hashes, err := graph.TopologicalSort(taskfileGraph.Graph)
+	hashes, err := graph.StableTopologicalSort(taskfileGraph.Graph, func(a, b string) bool { return a < b })
 	if err != nil {
-		panic(fmt.Sprintf("Failed to sort graph: %v", err))
+		return fmt.Errorf("failed to sort graph: %w", err)
 	}
 
 	for i, hash := range hashes {
@@ -93,61 +350,182 @@ func main() {
 			fmt.Printf("   Includes:\n")
 			for namespace, include := range vertex.Taskfile.Includes.All() {
 				fmt.Printf("     - %s: %s\n", namespace, include.Taskfile)
+				if include.Vars.Len() > 0 {
+					r := newVarResolver("")
+					r.add(vertex.Taskfile.Env)
+					r.add(vertex.Taskfile.Vars)
+					var vars []includeVar
+					for _, v := range r.add(include.Vars) {
+						vars = append(vars, includeVar{resolvedVar: v})
+					}
+					fmt.Printf("       Vars: %s\n", formatIncludeVars(vars))
+				}
 			}
 		}
 	}
 	fmt.Printf("\n")
 
+	// Optional includes that could not be read are left out, as task leaves
+	// them out, but not silently; so are required ones under --keep-going
+	if len(l.skipped) > 0 {
+		fmt.Printf("=== Skipped Includes ===\n")
+		for _, s := range l.skipped {
+			if s.Required {
+				fmt.Printf("- %s: %s (required, skipped by --keep-going)\n", s.Namespace, s.URI)
+			} else {
+				fmt.Printf("- %s: %s\n", s.Namespace, s.URI)
+			}
+			fmt.Printf("  Included by: %s\n", s.From)
+			fmt.Printf("  Reason: %s\n", s.Reason)
+		}
+		fmt.Printf("\n")
+	}
+
 	// Analyze task dependencies
 	fmt.Printf("=== Task Dependencies ===\n")
+	analyzeStart := time.Now()
 	buildTaskDependencyGraph(mergedTaskfile)
+	l.dlog.Phase("analyze", analyzeStart, nil)
 
-	for taskName, task := range mergedTaskfile.Tasks.All(nil) {
-		fmt.Printf("Task: %s", taskName)
-		if task.Desc != "" {
-			fmt.Printf(" - %s", task.Desc)
+	var listed []string
+	for taskName, task := range mergedTaskfile.Tasks.All(byName) {
+		if selected.matches(idx.labels(task)) && reachable(taskName) {
+			listed = append(listed, taskName)
+		}
+	}
+	if *groupBy == "" {
+		for _, taskName := range listed {
+			printTask(mergedTaskfile, taskName, idx)
+		}
+	} else {
+		labelsOf := func(name string) map[string]string {
+			task, _ := mergedTaskfile.Tasks.Get(name)
+			return idx.labels(task)
 		}
+		values, groups := groupByLabel(listed, labelsOf, *groupBy)
+		for _, v := range values {
+			fmt.Printf("--- %s: %s ---\n", *groupBy, v)
+			for _, taskName := range groups[v] {
+				printTask(mergedTaskfile, taskName, idx)
+			}
+		}
+	}
+
+	// Summarize the task graph, calling out references made more than once
+	fmt.Printf("=== Statistics ===\n")
+	tg, err := buildTaskGraph(taskfileGraph, mergedTaskfile, idx)
+	if err != nil {
+		return err
+	}
+	tg = scope(tg)
+	printStats(os.Stdout, tg)
+	if *groupBy != "" {
+		printGroups(tg, *groupBy)
+	}
+	fmt.Printf("\n")
+
+	if *hierarchy {
+		fmt.Printf("=== Task Hierarchy ===\n")
+		writePrefixTree(os.Stdout, buildPrefixTree(listed), *collapse)
 		fmt.Printf("\n")
+	}
 
-		if len(task.Deps) > 0 {
-			fmt.Printf("  Dependencies:\n")
-			for _, dep := range task.Deps {
-				fmt.Printf("    - %s\n", dep.Task)
-			}
+	// Show complete dependency tree from starting task. The DAG draws every
+	// start in one graph, the indented tree each start on its own.
+	var found []string
+	for _, start := range starts {
+		if _, exists := mergedTaskfile.Tasks.Get(start); exists {
+			found = append(found, start)
+		}
+	}
+	if *treeStyle == "dag" && len(found) > 0 {
+		fmt.Printf("=== Complete Dependency Tree from %s ===\n", quoteTasks(found))
+		if err := writeDAG(os.Stdout, tg.subgraph(found...), found...); err != nil {
+			return err
+		}
+	}
+	drawn := *treeStyle == "dag" && len(found) > 0
+	for _, start := range starts {
+		_, exists := mergedTaskfile.Tasks.Get(start)
+		if exists && *treeStyle == "dag" {
+			continue
+		}
+		if drawn {
+			fmt.Printf("\n")
 		}
+		drawn = true
+		fmt.Printf("=== Complete Dependency Tree from '%s' task ===\n", start)
+		if exists {
+			showDependencyTree(mergedTaskfile, start, 0)
+			continue
+		}
+		fmt.Printf("Task '%s' not found\n", start)
+		if suggestions := suggestTasks(start, mergedTaskfile.Tasks); len(suggestions) > 0 {
+			fmt.Printf("Did you mean: %s?\n", strings.Join(suggestions, ", "))
+		} else {
+			fmt.Printf("Run list to see all %s\n", plural(mergedTaskfile.Tasks.Len(), "task"))
+		}
+	}
 
-		if len(task.Cmds) > 0 {
-			fmt.Printf("  Commands:\n")
-			for _, cmd := range task.Cmds {
-				if cmd.Cmd != "" {
-					fmt.Printf("    - cmd: %s\n", cmd.Cmd)
-				}
-				if cmd.Task != "" {
-					fmt.Printf("    - task: %s\n", cmd.Task)
-				}
-			}
+	return nil
+}
+
+// printTask prints one task of the report with its dependencies and commands
+func printTask(tf *ast.Taskfile, taskName string, idx *sourceIndex) {
+	task, _ := tf.Tasks.Get(taskName)
+	fmt.Printf("Task: %s", taskName)
+	if task.Desc != "" {
+		fmt.Printf(" - %s", task.Desc)
+	}
+	fmt.Printf(" (%s)\n", taskPos(task))
+	if labels := idx.labels(task); len(labels) > 0 {
+		fmt.Printf("  Labels: %s\n", formatLabels(labels))
+	}
+	if reqs := taskRequirements(task); len(reqs) > 0 {
+		fmt.Printf("  Requires: %s\n", strings.Join(requirementClasses(reqs), ", "))
+	}
+	if vars := includeVars(tf, taskName, task); len(vars) > 0 {
+		fmt.Printf("  Include vars: %s\n", formatIncludeVars(vars))
+	}
+
+	if len(task.Deps) > 0 {
+		fmt.Printf("  Dependencies:\n")
+		for i, dep := range task.Deps {
+			fmt.Printf("    - %s (%s)%s\n", dep.Task, idx.depPos(task, i), unresolvedNote(tf, dep.Task, idx))
 		}
-		fmt.Printf("\n")
 	}
 
-	// Show complete dependency tree from starting task
-	fmt.Printf("=== Complete Dependency Tree from '%s' task ===\n", *startTask)
-	if _, exists := mergedTaskfile.Tasks.Get(*startTask); exists {
-		showDependencyTree(mergedTaskfile, *startTask, 0)
-	} else {
-		fmt.Printf("Task '%s' not found\n", *startTask)
-		fmt.Printf("Available tasks:\n")
-		for taskName := range mergedTaskfile.Tasks.All(nil) {
-			fmt.Printf("  - %s\n", taskName)
+	if len(task.Cmds) > 0 {
+		fmt.Printf("  Commands:\n")
+		for i, cmd := range task.Cmds {
+			if cmd.Cmd != "" {
+				fmt.Printf("    - cmd: %s (%s)\n", cmd.Cmd, idx.cmdPos(task, i))
+			}
+			if cmd.Task != "" {
+				fmt.Printf("    - task: %s (%s)%s\n", cmd.Task, idx.cmdPos(task, i), unresolvedNote(tf, cmd.Task, idx))
+			}
 		}
 	}
+	fmt.Printf("\n")
+}
+
+// unresolvedNote marks a reference of a task that does not exist because the
+// include that would define it could not be read
+func unresolvedNote(tf *ast.Taskfile, name string, idx *sourceIndex) string {
+	if _, ok := tf.Tasks.Get(name); ok {
+		return ""
+	}
+	if s, ok := idx.unresolved(name); ok {
+		return fmt.Sprintf(" [unresolved: include %s was skipped]", s.Namespace)
+	}
+	return ""
 }
 
 // buildTaskDependencyGraph creates a dependency map for tasks
 func buildTaskDependencyGraph(tf *ast.Taskfile) map[string][]string {
 	deps := make(map[string][]string)
 
-	for taskName, task := range tf.Tasks.All(nil) {
+	for taskName, task := range tf.Tasks.All(byName) {
 		var taskDeps []string
 
 		// Add explicit dependencies
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// makeTarget escapes the colons of a namespaced task name, which GNU make
+// accepts in a target written as site\:deploy and run as make site:deploy
+func makeTarget(name string) string {
+	return strings.ReplaceAll(name, ":", `\:`)
+}
+
+// shellQuote quotes s as one word for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// makeEnv is NAME=value exporting a variable, its value quoted for the
+// shell and its dollars for make; a dynamic variable runs its command
+func makeEnv(v resolvedVar) string {
+	value := shellQuote(v.Value)
+	if v.Dynamic {
+		value = `"$(` + v.Value + `)"`
+	}
+	return v.Name + "=" + strings.ReplaceAll(value, "$", "$$")
+}
+
+// writeMakefile writes the recipes as GNU make rules, deps as prerequisites.
+// Every recipe runs in one shell that stops at the first failure, as a
+// task's commands do, so multi-line commands and a cd into the task's dir
+// carry over; deferred commands become an EXIT trap.
+func writeMakefile(w io.Writer, plan exportPlan) error {
+	recipes := plan.Recipes
+	var b strings.Builder
+	b.WriteString("# Generated by meerkat export from the Taskfile; regenerate rather than edit.\n")
+	b.WriteString("SHELL := /bin/sh\n.SHELLFLAGS := -ec\n.ONESHELL:\n")
+	b.WriteString("TASK ?= task\n")
+	// Global env as make variables, which make exports to every recipe
+	for _, v := range plan.Env {
+		value := strings.ReplaceAll(v.Value, "$", "$$")
+		if v.Dynamic {
+			value = "$(shell " + v.Value + ")"
+		}
+		fmt.Fprintf(&b, "export %s := %s\n", v.Name, value)
+	}
+	b.WriteString("\n")
+	names := make([]string, len(recipes))
+	for i, r := range recipes {
+		names[i] = makeTarget(r.Name)
+	}
+	if slices.ContainsFunc(recipes, func(r exportRecipe) bool { return r.Name == "default" }) {
+		b.WriteString(".DEFAULT_GOAL := default\n")
+	}
+	fmt.Fprintf(&b, ".PHONY: %s\n", strings.Join(names, " "))
+
+	for _, r := range recipes {
+		b.WriteString("\n")
+		if r.Desc != "" {
+			fmt.Fprintf(&b, "# %s\n", r.Desc)
+		}
+		// A delegated task runs its own deps, so they are only noted
+		if r.Delegate {
+			fmt.Fprintf(&b, "# Runs through task: %s\n", r.Why)
+			if len(r.Deps) > 0 {
+				fmt.Fprintf(&b, "# deps: %s\n", strings.Join(r.Deps, " "))
+			}
+			fmt.Fprintf(&b, "%s:\n\t$(TASK) %s\n", makeTarget(r.Name), shellQuote(r.Name))
+			continue
+		}
+		deps := make([]string, len(r.Deps))
+		for i, dep := range r.Deps {
+			deps[i] = makeTarget(dep)
+		}
+		fmt.Fprintf(&b, "%s:%s\n", makeTarget(r.Name), strings.TrimRight(" "+strings.Join(deps, " "), " "))
+
+		if len(r.Steps) == 0 {
+			continue
+		}
+		var lines, body, deferred []string
+		if r.Dir != "" {
+			lines = append(lines, "cd "+shellQuote(r.Dir))
+		}
+		for _, v := range r.Env {
+			lines = append(lines, "export "+makeEnv(v))
+		}
+		for _, s := range r.Steps {
+			line := strings.ReplaceAll(s.Cmd, "$", "$$")
+			if s.Task != "" {
+				line = "$(MAKE) --no-print-directory " + shellQuote(s.Task)
+			}
+			if s.Defer {
+				deferred = append(deferred, line)
+			} else {
+				body = append(body, line)
+			}
+		}
+		// Task runs deferred commands last in first out, whatever fails
+		if len(deferred) > 0 {
+			slices.Reverse(deferred)
+			lines = append(lines, "trap "+shellQuote(strings.Join(deferred, "\n"))+" EXIT")
+		}
+		lines = append(lines, body...)
+		for _, line := range lines {
+			b.WriteString("\t" + strings.ReplaceAll(line, "\n", "\n\t") + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// makeDirectives are the words starting a Makefile line that is neither a
+// rule nor a recipe
+var makeDirectives = []string{"define", "endef", "ifeq", "ifneq", "ifdef", "ifndef", "else", "endif", "include", "-include", "sinclude", "export", "unexport", "override", "vpath", "undefine"}
+
+// parseMakefile reads the explicit rules of a Makefile as tasks: each target
+// on the line of its first rule, its prerequisites that are targets as deps
+// and the rest as sources, and its recipe as commands, with a $(MAKE) of
+// other targets as task calls. A "## text" after the prerequisites, or a
+// comment right above the rule, is the description. Variables and pattern
+// rules are left out and every branch of a conditional is read; the default
+// goal becomes the default task unless there is one.
+func parseMakefile(src []byte) []importedTask {
+	var tasks []*importedTask
+	byName := make(map[string]*importedTask)
+	var rule []*importedTask // the tasks the recipe lines being read belong to
+	inRule, inDefine := false, false
+	var comment, goal string
+
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		start := i + 1
+		line := lines[i]
+		recipe := strings.HasPrefix(line, "\t") && inRule
+		// A backslash continues the line; the shell gets recipe lines whole
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			i++
+			next := lines[i]
+			if recipe {
+				line += "\n" + strings.TrimPrefix(next, "\t")
+			} else {
+				line = strings.TrimSuffix(line, `\`) + " " + strings.TrimSpace(next)
+			}
+		}
+
+		trimmed := strings.TrimSpace(line)
+		word, _, _ := strings.Cut(trimmed, " ")
+		switch {
+		case inDefine:
+			inDefine = word != "endef"
+			continue
+		case recipe:
+			cmd := strings.TrimSpace(line)
+			if cmd == "" || strings.HasPrefix(cmd, "#") {
+				continue
+			}
+			c := makeCommand(cmd)
+			c.Line = start
+			c.Column = len(lines[start-1]) - len(strings.TrimLeft(lines[start-1], " \t@+-")) + 1
+			for _, t := range rule {
+				t.Cmds = append(t.Cmds, c)
+			}
+			continue
+		case trimmed == "":
+			comment = ""
+			continue
+		case strings.HasPrefix(trimmed, "#"):
+			comment = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			continue
+		case slices.Contains(makeDirectives, word):
+			inDefine = word == "define"
+			inRule, comment = false, ""
+			continue
+		}
+
+		at, kind := makeSeparator(trimmed)
+		if kind != ':' {
+			// An assignment, or a line make would reject
+			if name := strings.TrimSpace(trimmed[:max(at, 0)]); kind != 0 && strings.TrimRight(name, "?+:!") == ".DEFAULT_GOAL" {
+				goal = strings.TrimSpace(strings.TrimLeft(trimmed[at:], "=:"))
+			}
+			inRule, comment = false, ""
+			continue
+		}
+		targets := strings.Fields(trimmed[:at])
+		rest := strings.TrimLeft(trimmed[at:], ":")
+		rest, inline, _ := strings.Cut(rest, ";")
+		desc := comment
+		if before, help, ok := strings.Cut(rest, "##"); ok {
+			rest, desc = before, strings.TrimSpace(help)
+		} else {
+			rest, _, _ = strings.Cut(rest, "#")
+		}
+		comment = ""
+		// target: VAR = value sets a variable for the target's recipes
+		if _, kind := makeSeparator(rest); kind == '=' {
+			inRule = false
+			continue
+		}
+
+		inRule, rule = true, nil
+		for _, name := range targets {
+			// Special targets such as .PHONY, pattern rules and computed names
+			if strings.HasPrefix(name, ".") || strings.ContainsAny(name, "%$") {
+				continue
+			}
+			t, ok := byName[name]
+			if !ok {
+				t = &importedTask{Name: name, Line: start}
+				byName[name] = t
+				tasks = append(tasks, t)
+				if goal == "" && len(tasks) == 1 {
+					goal = name
+				}
+			}
+			if desc != "" {
+				t.Desc = desc
+			}
+			for _, prereq := range strings.Fields(rest) {
+				if prereq != "|" && !slices.Contains(t.Deps, prereq) {
+					t.Deps = append(t.Deps, prereq)
+				}
+			}
+			if cmd := strings.TrimSpace(inline); cmd != "" {
+				t.Cmds = append(t.Cmds, makeCommand(cmd))
+			}
+			rule = append(rule, t)
+		}
+	}
+
+	// Prerequisites only targets make are deps; files are sources
+	out := make([]importedTask, 0, len(tasks)+1)
+	for _, t := range tasks {
+		prereqs := t.Deps
+		t.Deps = nil
+		for _, p := range prereqs {
+			if byName[p] != nil {
+				t.Deps = append(t.Deps, p)
+			} else {
+				t.Sources = append(t.Sources, p)
+			}
+		}
+		for i, c := range t.Cmds {
+			t.Cmds[i] = makeCall(c, byName)
+		}
+		out = append(out, *t)
+	}
+	if goal != "" && byName["default"] == nil && byName[goal] != nil {
+		// make runs the default goal when given no target, as task runs default
+		def := importedTask{Name: "default", Line: byName[goal].Line, Desc: "Default goal of the Makefile", Deps: []string{goal}}
+		at := slices.IndexFunc(out, func(t importedTask) bool { return t.Name == goal })
+		out = slices.Insert(out, at, def)
+	}
+	return out
+}
+
+// makeSeparator finds what makes a Makefile line a rule or an assignment:
+// the first : or = outside $(...), returning its index and ':' for a rule,
+// '=' for an assignment (including :=, ::=, ?=, += and !=) or 0 for neither
+func makeSeparator(line string) (int, byte) {
+	depth := 0
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '(' || c == '{':
+			depth++
+		case c == ')' || c == '}':
+			depth--
+		case depth > 0:
+		case c == '=':
+			at := i
+			for at > 0 && strings.IndexByte("?+!:", line[at-1]) >= 0 {
+				at--
+			}
+			return at, '='
+		case c == ':':
+			rest := strings.TrimLeft(line[i:], ":")
+			if strings.HasPrefix(rest, "=") {
+				return i, '='
+			}
+			return i, ':'
+		}
+	}
+	return -1, 0
+}
+
+// makeCommand is a recipe line as a command: make's @ (silent), + (run
+// under -n) and - (ignore errors) prefixes come off, the last as
+// ignore_error
+func makeCommand(line string) importedCmd {
+	var c importedCmd
+	for len(line) > 0 && strings.IndexByte("@+-", line[0]) >= 0 {
+		c.IgnoreError = c.IgnoreError || line[0] == '-'
+		line = strings.TrimSpace(line[1:])
+	}
+	c.Cmd = line
+	return c
+}
+
+// makeCall turns a recipe running make on a target of the same Makefile into
+// a call of its task; a make with flags or several targets stays a
+// command, keeping its order and arguments
+func makeCall(c importedCmd, targets map[string]*importedTask) importedCmd {
+	fields := strings.Fields(c.Cmd)
+	if len(fields) != 2 || c.IgnoreError {
+		return c
+	}
+	switch fields[0] {
+	case "$(MAKE)", "${MAKE}", "make":
+		if targets[fields[1]] != nil {
+			c.Cmd, c.Task = "", fields[1]
+		}
+	}
+	return c
+}
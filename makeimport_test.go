@@ -0,0 +1,130 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMakefile(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []importedTask
+	}{
+		{"empty", "", []importedTask{}},
+		{
+			"rule with recipe",
+			"build: main.go\n\tgo build -o app .\n",
+			[]importedTask{
+				{Name: "default", Line: 1, Desc: "Default goal of the Makefile", Deps: []string{"build"}},
+				{Name: "build", Line: 1, Sources: []string{"main.go"}, Cmds: []importedCmd{{Cmd: "go build -o app .", Line: 2, Column: 2}}},
+			},
+		},
+		{
+			"deps and sources",
+			"test: build go.sum\n\tgo test ./...\nbuild:\n\tgo build\n",
+			[]importedTask{
+				{Name: "default", Line: 1, Desc: "Default goal of the Makefile", Deps: []string{"test"}},
+				{Name: "test", Line: 1, Deps: []string{"build"}, Sources: []string{"go.sum"}, Cmds: []importedCmd{{Cmd: "go test ./...", Line: 2, Column: 2}}},
+				{Name: "build", Line: 3, Cmds: []importedCmd{{Cmd: "go build", Line: 4, Column: 2}}},
+			},
+		},
+		{
+			"descriptions",
+			"# Build it\nbuild:\n\tgo build\nlint: ## Lint it\n\tgolangci-lint run\n",
+			[]importedTask{
+				{Name: "default", Line: 2, Desc: "Default goal of the Makefile", Deps: []string{"build"}},
+				{Name: "build", Line: 2, Desc: "Build it", Cmds: []importedCmd{{Cmd: "go build", Line: 3, Column: 2}}},
+				{Name: "lint", Line: 4, Desc: "Lint it", Cmds: []importedCmd{{Cmd: "golangci-lint run", Line: 5, Column: 2}}},
+			},
+		},
+		{
+			"prefixes and make calls",
+			"all:\n\t@echo start\n\t-rm -f out\n\t$(MAKE) clean\n\t$(MAKE) -j4 clean\nclean:\n\trm -rf dist\n",
+			[]importedTask{
+				{Name: "default", Line: 1, Desc: "Default goal of the Makefile", Deps: []string{"all"}},
+				{Name: "all", Line: 1, Cmds: []importedCmd{
+					{Cmd: "echo start", Line: 2, Column: 3},
+					{Cmd: "rm -f out", IgnoreError: true, Line: 3, Column: 3},
+					{Task: "clean", Line: 4, Column: 2},
+					{Cmd: "$(MAKE) -j4 clean", Line: 5, Column: 2},
+				}},
+				{Name: "clean", Line: 6, Cmds: []importedCmd{{Cmd: "rm -rf dist", Line: 7, Column: 2}}},
+			},
+		},
+		{
+			"variables, special targets and patterns left out",
+			"CC := gcc\n.PHONY: app\n%.o: %.c\n\t$(CC) -c $<\napp: CFLAGS = -O2\napp:\n\tcc app.c\n",
+			[]importedTask{
+				{Name: "default", Line: 6, Desc: "Default goal of the Makefile", Deps: []string{"app"}},
+				{Name: "app", Line: 6, Cmds: []importedCmd{{Cmd: "cc app.c", Line: 7, Column: 2}}},
+			},
+		},
+		{
+			"default goal and existing default",
+			".DEFAULT_GOAL := b\na:\n\techo a\nb:\n\techo b\n",
+			[]importedTask{
+				{Name: "a", Line: 2, Cmds: []importedCmd{{Cmd: "echo a", Line: 3, Column: 2}}},
+				{Name: "default", Line: 4, Desc: "Default goal of the Makefile", Deps: []string{"b"}},
+				{Name: "b", Line: 4, Cmds: []importedCmd{{Cmd: "echo b", Line: 5, Column: 2}}},
+			},
+		},
+		{
+			"default target kept",
+			"default: build\nbuild:\n\tgo build\n",
+			[]importedTask{
+				{Name: "default", Line: 1, Deps: []string{"build"}},
+				{Name: "build", Line: 2, Cmds: []importedCmd{{Cmd: "go build", Line: 3, Column: 2}}},
+			},
+		},
+		{
+			"continued lines and defines",
+			"define HELP\nnot: a rule\nendef\nbuild: a \\\n  b\n\techo one \\\n\t  two\na:\nb:\n",
+			[]importedTask{
+				{Name: "default", Line: 4, Desc: "Default goal of the Makefile", Deps: []string{"build"}},
+				{Name: "build", Line: 4, Deps: []string{"a", "b"}, Cmds: []importedCmd{{Cmd: "echo one \\\n  two", Line: 6, Column: 2}}},
+				{Name: "a", Line: 8},
+				{Name: "b", Line: 9},
+			},
+		},
+		{
+			"several targets and inline recipe",
+			"x y: ; touch $@\n",
+			[]importedTask{
+				{Name: "default", Line: 1, Desc: "Default goal of the Makefile", Deps: []string{"x"}},
+				{Name: "x", Line: 1, Cmds: []importedCmd{{Cmd: "touch $@"}}},
+				{Name: "y", Line: 1, Cmds: []importedCmd{{Cmd: "touch $@"}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMakefile([]byte(tt.src)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMakefile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeSeparator(t *testing.T) {
+	tests := []struct {
+		line string
+		at   int
+		kind byte
+	}{
+		{"build: main.go", 5, ':'},
+		{"a::b", 1, ':'},
+		{"CC = gcc", 3, '='},
+		{"CC := gcc", 3, '='},
+		{"CC ::= gcc", 3, '='},
+		{"CC ?= gcc", 3, '='},
+		{"CC += -O2", 3, '='},
+		{"$(OUT:.c=.o): x", 12, ':'},
+		{"echo hi", -1, 0},
+	}
+	for _, tt := range tests {
+		if at, kind := makeSeparator(tt.line); at != tt.at || kind != tt.kind {
+			t.Errorf("makeSeparator(%q) = %d, %q, want %d, %q", tt.line, at, kind, tt.at, tt.kind)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// netrcLogin is the login and password of a machine in .netrc
+type netrcLogin struct {
+	login, password string
+}
+
+// netrc holds the logins of .netrc by machine, "" being the default entry
+type netrc map[string]netrcLogin
+
+// readNetrc reads the file NETRC names, or else ~/.netrc (or _netrc on
+// Windows), as curl and git do. A missing or unreadable file has no logins.
+func readNetrc() netrc {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		name := ".netrc"
+		if runtime.GOOS == "windows" {
+			name = "_netrc"
+		}
+		path = filepath.Join(home, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return parseNetrc(string(data))
+}
+
+// parseNetrc reads machine, default, login and password tokens, skipping
+// account and the bodies of macdef, which end at a blank line. The first
+// entry for a machine wins, and default applies to the machines after none.
+// As for curl, a token may be double-quoted, with \ escaping the next
+// character, and # starts a comment running to the end of the line.
+func parseNetrc(src string) netrc {
+	n := make(netrc)
+	var machine string
+	var current *netrcLogin
+	done := func() {
+		if current != nil {
+			if _, ok := n[machine]; !ok {
+				n[machine] = *current
+			}
+		}
+		current = nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := netrcTokens(lines[i])
+		for j := 0; j < len(fields); j++ {
+			next := func() string {
+				if j+1 >= len(fields) {
+					return ""
+				}
+				j++
+				return fields[j]
+			}
+			switch fields[j] {
+			case "machine":
+				done()
+				machine, current = next(), &netrcLogin{}
+			case "default":
+				done()
+				machine, current = "", &netrcLogin{}
+			case "login":
+				if v := next(); current != nil {
+					current.login = v
+				}
+			case "password":
+				if v := next(); current != nil {
+					current.password = v
+				}
+			case "account":
+				next()
+			case "macdef":
+				done()
+				for i++; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				}
+				j = len(fields)
+			}
+		}
+	}
+	done()
+	return n
+}
+
+// netrcTokens splits a line of .netrc into its tokens, up to a comment
+func netrcTokens(line string) []string {
+	var tokens []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" || line[0] == '#' {
+			return tokens
+		}
+		if line[0] != '"' {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			tokens = append(tokens, line[:end])
+			line = line[end:]
+			continue
+		}
+		var b strings.Builder
+		i := 1
+		for ; i < len(line) && line[i] != '"'; i++ {
+			if line[i] == '\\' && i+1 < len(line) {
+				i++
+			}
+			b.WriteByte(line[i])
+		}
+		tokens = append(tokens, b.String())
+		line = line[min(i+1, len(line)):]
+	}
+}
+
+// lookup is the login for a host, without its port, falling back to the
+// default entry unless only an entry for the host itself will do
+func (n netrc) lookup(host string, exact bool) (netrcLogin, bool) {
+	if l, ok := n[host]; ok {
+		return l, true
+	}
+	if exact {
+		return netrcLogin{}, false
+	}
+	l, ok := n[""]
+	return l, ok
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseNetrc(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want netrc
+	}{
+		{"empty", "", netrc{}},
+		{"one line", "machine h login u password p\n", netrc{"h": {"u", "p"}}},
+		{"multi line", "machine h\n  login u\n  password p\n", netrc{"h": {"u", "p"}}},
+		{"crlf", "machine h\r\nlogin u\r\npassword p\r\n", netrc{"h": {"u", "p"}}},
+		{"first wins", "machine h login a password 1\nmachine h login b password 2\n", netrc{"h": {"a", "1"}}},
+		{"default", "machine h login u password p\ndefault login anon password x\n", netrc{"h": {"u", "p"}, "": {"anon", "x"}}},
+		{"account skipped", "machine h login u account acct password p\n", netrc{"h": {"u", "p"}}},
+		{"comment line", "# machine x login no password no\nmachine h login u password p\n", netrc{"h": {"u", "p"}}},
+		{"inline comment", "machine h login u password p # work account\n", netrc{"h": {"u", "p"}}},
+		{"hash inside token", "machine h login u password p#ss\n", netrc{"h": {"u", "p#ss"}}},
+		{"quoted", `machine h login "a user" password "p w#d"` + "\n", netrc{"h": {"a user", "p w#d"}}},
+		{"escaped quote", `machine h login u password "say \"hi\" \\ok"` + "\n", netrc{"h": {"u", `say "hi" \ok`}}},
+		{"macdef skipped", "macdef init\nmachine evil login x password y\n\nmachine h login u password p\n", netrc{"h": {"u", "p"}}},
+		{"missing value", "machine h login u password", netrc{"h": {"u", ""}}},
+		{"login before machine", "login u password p\nmachine h login v password q\n", netrc{"h": {"v", "q"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseNetrc(tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNetrc() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetrcLookup(t *testing.T) {
+	n := netrc{"h": {"u", "p"}, "": {"anon", "x"}}
+	tests := []struct {
+		host  string
+		exact bool
+		want  netrcLogin
+		ok    bool
+	}{
+		{"h", false, netrcLogin{"u", "p"}, true},
+		{"h", true, netrcLogin{"u", "p"}, true},
+		{"other", false, netrcLogin{"anon", "x"}, true},
+		{"other", true, netrcLogin{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := n.lookup(tt.host, tt.exact)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("lookup(%q, %v) = %v, %v, want %v, %v", tt.host, tt.exact, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFetchTransportAuthorization(t *testing.T) {
+	basic := func(user, password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	}
+	request := func(url string, from ...string) *http.Request {
+		req, err := http.NewRequestWithContext(withTaskfileFetch(context.Background()), http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range from {
+			prev, err := http.NewRequest(http.MethodGet, f, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Response = &http.Response{Request: prev}
+		}
+		return req
+	}
+	tr := &fetchTransport{netrc: netrc{"tasks.example.com": {"u", "p"}, "": {"anon", "x"}}}
+	tests := []struct {
+		name string
+		req  *http.Request
+		want string
+	}{
+		{"machine", request("https://tasks.example.com/Taskfile.yml"), basic("u", "p")},
+		{"machine with port", request("https://tasks.example.com:8443/Taskfile.yml"), basic("u", "p")},
+		{"default", request("https://other.example.com/Taskfile.yml"), basic("anon", "x")},
+		{"plain http", request("http://tasks.example.com/Taskfile.yml"), ""},
+		{"redirect within host", request("https://other.example.com/b.yml", "https://other.example.com/a.yml"), basic("anon", "x")},
+		{"redirect to machine", request("https://tasks.example.com/b.yml", "https://other.example.com/a.yml"), basic("u", "p")},
+		{"redirect to other host", request("https://evil.example.com/b.yml", "https://tasks.example.com/a.yml"), ""},
+		{"not a Taskfile fetch", httptest.NewRequest(http.MethodPost, "https://hooks.example.com/notify", nil), ""},
+		{"machine outside a Taskfile fetch", httptest.NewRequest(http.MethodGet, "https://tasks.example.com/token", nil), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tr.authorization(tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("authorization() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
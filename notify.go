@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// slackItemLimit caps the lines listed in a Slack message, which has to
+// stay readable in a channel
+const slackItemLimit = 20
+
+// webhookClient posts notifications. The default client is the one remote
+// Taskfiles are fetched with, with their logins, download limits, redirect
+// policy and --timeout, none of which are meant for a webhook.
+var webhookClient = &http.Client{Timeout: 30 * time.Second}
+
+// notifier posts a summary of a command's results to a webhook
+type notifier struct {
+	url    string
+	format string
+}
+
+// registerNotify adds the webhook flags to a command that produces results
+// worth alerting on
+func registerNotify(fs *flag.FlagSet) *notifier {
+	n := &notifier{}
+	fs.StringVar(&n.url, "notify-webhook", "", "POST a summary to this URL when there is something to report")
+	fs.StringVar(&n.format, "notify-format", "auto", "Webhook payload: json, slack, or auto for slack on hooks.slack.com and json elsewhere")
+	return n
+}
+
+// notification is what a command reports: a one-line summary, the lines
+// behind it, and its full results for JSON consumers
+type notification struct {
+	Source   string   `json:"source"`
+	Command  string   `json:"command"`
+	Taskfile string   `json:"taskfile"`
+	Summary  string   `json:"summary"`
+	Items    []string `json:"items,omitempty"`
+	Results  any      `json:"results"`
+}
+
+// notify posts n, unless no webhook was given
+func (nt *notifier) notify(n notification) error {
+	if nt.url == "" {
+		return nil
+	}
+	n.Source = "meerkat"
+
+	format := nt.format
+	if format == "auto" {
+		format = "json"
+		if u, err := url.Parse(nt.url); err == nil && u.Host == "hooks.slack.com" {
+			format = "slack"
+		}
+	}
+	var payload any
+	switch format {
+	case "json":
+		payload = n
+	case "slack":
+		payload = slackMessage(n)
+	default:
+		return fmt.Errorf("unknown --notify-format %q", nt.format)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(nt.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to notify webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// slackMessage formats a notification as an incoming-webhook message, with
+// a plain text fallback for notifications
+func slackMessage(n notification) any {
+	type text struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	type block struct {
+		Type string `json:"type"`
+		Text text   `json:"text"`
+	}
+
+	headline := fmt.Sprintf("*meerkat %s* on `%s`: %s", n.Command, n.Taskfile, n.Summary)
+	blocks := []block{{Type: "section", Text: text{Type: "mrkdwn", Text: headline}}}
+	if len(n.Items) > 0 {
+		var b strings.Builder
+		for i, item := range n.Items {
+			if i == slackItemLimit {
+				fmt.Fprintf(&b, "• … and %d more\n", len(n.Items)-i)
+				break
+			}
+			fmt.Fprintf(&b, "• %s\n", item)
+		}
+		blocks = append(blocks, block{Type: "section", Text: text{Type: "mrkdwn", Text: b.String()}})
+	}
+	return struct {
+		Text   string  `json:"text"`
+		Blocks []block `json:"blocks"`
+	}{fmt.Sprintf("meerkat %s on %s: %s", n.Command, n.Taskfile, n.Summary), blocks}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// failingTransport fails every request, standing in for a fetch transport
+// that must not be used
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("went through the default client")
+}
+
+func TestNotifyUsesItsOwnClient(t *testing.T) {
+	var got notification
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	transport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = failingTransport{}
+	defer func() { http.DefaultClient.Transport = transport }()
+
+	nt := &notifier{url: srv.URL, format: "auto"}
+	if err := nt.notify(notification{Command: "lint", Summary: "2 findings"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Source != "meerkat" || got.Command != "lint" || got.Summary != "2 findings" {
+		t.Errorf("webhook got %+v", got)
+	}
+	if auth != "" {
+		t.Errorf("webhook got Authorization %q", auth)
+	}
+}
+
+func TestNotifyFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		nt   notifier
+		err  bool
+	}{
+		{"no webhook", notifier{format: "json"}, false},
+		{"error status", notifier{url: srv.URL, format: "json"}, true},
+		{"unknown format", notifier{url: srv.URL, format: "xml"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.nt.notify(notification{}); (err != nil) != tt.err {
+			t.Errorf("%s: notify() = %v, want error %v", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// npmRunners are the shorthands npm, yarn, pnpm and bun have for running a
+// script, as the words before the script name
+var npmRunners = [][]string{
+	{"npm", "run"}, {"npm", "run-script"}, {"yarn", "run"}, {"pnpm", "run"}, {"bun", "run"},
+	{"yarn"}, {"pnpm"},
+}
+
+// npmLifecycle are the scripts npm runs under their own name, as npm test
+var npmLifecycle = []string{"test", "start", "stop", "restart"}
+
+// parsePackageJSON reads the scripts of a package.json as tasks. npm runs
+// preX and postX around X, so they are internal tasks X calls first and
+// last. Scripts run as commands split at &&, and a command running scripts
+// through npm run and the like, or npm-run-all, run-s and run-p with their
+// globs, is calls of them; a script that is one run-p of several has them as
+// deps, which run in parallel.
+func parsePackageJSON(src []byte) []importedTask {
+	type script struct {
+		name, cmd          string
+		line               int // of the key
+		cmdLine, cmdColumn int
+	}
+	var scripts []script
+
+	// The decoder only gives offsets, so positions are found in the source
+	dec := json.NewDecoder(bytes.NewReader(src))
+	at := func(offset int64) (int, int) {
+		offset += int64(len(src[offset:]) - len(bytes.TrimLeft(src[offset:], " \t\r\n,:")))
+		line := bytes.Count(src[:offset], []byte("\n")) + 1
+		return line, int(offset) - bytes.LastIndexByte(src[:offset], '\n')
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		if key != "scripts" {
+			var skip json.RawMessage
+			if dec.Decode(&skip) != nil {
+				return nil
+			}
+			continue
+		}
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil
+		}
+		for dec.More() {
+			var s script
+			s.line, _ = at(dec.InputOffset())
+			name, err := dec.Token()
+			if err != nil {
+				return nil
+			}
+			s.cmdLine, s.cmdColumn = at(dec.InputOffset())
+			var cmd any
+			if dec.Decode(&cmd) != nil {
+				return nil
+			}
+			s.name, _ = name.(string)
+			s.cmd, _ = cmd.(string)
+			// Keys such as "//" are comments
+			if !strings.HasPrefix(s.name, "//") {
+				scripts = append(scripts, s)
+			}
+		}
+		break
+	}
+
+	names := make([]string, len(scripts))
+	for i, s := range scripts {
+		names[i] = s.name
+	}
+	hook := func(name string) bool {
+		for _, prefix := range []string{"pre", "post"} {
+			if base, ok := strings.CutPrefix(name, prefix); ok && slices.Contains(names, base) {
+				return true
+			}
+		}
+		return false
+	}
+
+	tasks := make([]importedTask, 0, len(scripts))
+	for _, s := range scripts {
+		t := importedTask{Name: s.name, Line: s.line, Internal: hook(s.name)}
+		if slices.Contains(names, "pre"+s.name) {
+			t.Cmds = append(t.Cmds, importedCmd{Task: "pre" + s.name})
+		}
+		pieces := splitAndList(s.cmd)
+		// Only a whole script of parallel runs can be deps
+		if len(pieces) == 1 {
+			if parallel, ok := npmRunAll(pieces[0]); ok && parallel {
+				if calls, ok := npmCalls(pieces[0], names); ok {
+					for _, c := range calls {
+						t.Deps = append(t.Deps, c.Task)
+					}
+					pieces = nil
+				}
+			}
+		}
+		for i, piece := range pieces {
+			calls, ok := npmCalls(piece, names)
+			if !ok {
+				calls = []importedCmd{{Cmd: piece}}
+			}
+			if i == 0 {
+				calls[0].Line, calls[0].Column = s.cmdLine, s.cmdColumn
+			}
+			t.Cmds = append(t.Cmds, calls...)
+		}
+		if slices.Contains(names, "post"+s.name) {
+			t.Cmds = append(t.Cmds, importedCmd{Task: "post" + s.name})
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// splitAndList splits a shell command at the && outside quotes, each part
+// running only when the last succeeded, as a task's commands do
+func splitAndList(line string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '\\':
+			i++
+		case strings.HasPrefix(line[i:], "&&"):
+			parts = append(parts, strings.TrimSpace(line[start:i]))
+			start = i + 2
+			i++
+		}
+	}
+	parts = append(parts, strings.TrimSpace(line[start:]))
+	return slices.DeleteFunc(parts, func(p string) bool { return p == "" })
+}
+
+// npmCalls reads a command that only runs scripts as calls of them, in
+// order, reporting false for any other command
+func npmCalls(cmd string, scripts []string) ([]importedCmd, bool) {
+	commands := splitCommands(cmd)
+	if len(commands) != 1 {
+		return nil, false
+	}
+	words := commands[0]
+	var run []string
+	switch {
+	case len(words) == 2 && words[0] == "npm" && slices.Contains(npmLifecycle, words[1]):
+		run = words[1:]
+	default:
+		if _, ok := npmRunAll(cmd); ok {
+			for _, w := range words[1:] {
+				if !strings.HasPrefix(w, "-") {
+					run = append(run, npmGlob(w, scripts)...)
+				}
+			}
+			break
+		}
+		for _, runner := range npmRunners {
+			// A script given arguments is more than a call
+			if len(words) == len(runner)+1 && slices.Equal(words[:len(runner)], runner) {
+				run = words[len(runner):]
+				break
+			}
+		}
+	}
+	if len(run) == 0 {
+		return nil, false
+	}
+	calls := make([]importedCmd, len(run))
+	for i, name := range run {
+		if !slices.Contains(scripts, name) {
+			return nil, false
+		}
+		calls[i] = importedCmd{Task: name}
+	}
+	return calls, true
+}
+
+// npmRunAll reports whether a command is npm-run-all, run-s or run-p, and
+// whether all it runs runs in parallel
+func npmRunAll(cmd string) (parallel, ok bool) {
+	commands := splitCommands(cmd)
+	if len(commands) != 1 {
+		return false, false
+	}
+	words := commands[0]
+	switch words[0] {
+	case "run-p":
+		return true, true
+	case "run-s":
+		return false, true
+	case "npm-run-all":
+		// Scripts run in sequence until a -p, and in groups after each
+		mode, sequential, named := false, false, false
+		for _, w := range words[1:] {
+			switch w {
+			case "-p", "--parallel":
+				mode = true
+			case "-s", "--sequential", "--serial":
+				mode = false
+			default:
+				if !strings.HasPrefix(w, "-") {
+					named = true
+					sequential = sequential || !mode
+				}
+			}
+		}
+		return named && !sequential, true
+	}
+	return false, false
+}
+
+// npmGlob matches the scripts npm-run-all runs for a pattern: * within a
+// colon-separated segment, ** across them
+func npmGlob(pattern string, scripts []string) []string {
+	if !strings.Contains(pattern, "*") {
+		return []string{pattern}
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\*`, "[^:]*")
+	re := regexp.MustCompile("^" + expr + "$")
+	var matched []string
+	for _, s := range scripts {
+		if re.MatchString(s) {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePackageJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []importedTask
+	}{
+		{"not an object", `[]`, nil},
+		{"invalid", `{"scripts": {"a": `, nil},
+		{"no scripts", `{"name": "app", "version": "1.0.0"}`, []importedTask{}},
+		{
+			"commands and positions",
+			"{\n  \"name\": \"app\",\n  \"scripts\": {\n    \"build\": \"tsc && vite build\",\n    \"//\": \"a comment\"\n  }\n}\n",
+			[]importedTask{
+				{Name: "build", Line: 4, Cmds: []importedCmd{{Cmd: "tsc", Line: 4, Column: 14}, {Cmd: "vite build"}}},
+			},
+		},
+		{
+			"hooks",
+			`{"scripts": {"pretest": "lint", "test": "jest", "posttest": "rm -rf tmp"}}`,
+			[]importedTask{
+				{Name: "pretest", Line: 1, Internal: true, Cmds: []importedCmd{{Cmd: "lint", Line: 1, Column: 25}}},
+				{Name: "test", Line: 1, Cmds: []importedCmd{{Task: "pretest"}, {Cmd: "jest", Line: 1, Column: 41}, {Task: "posttest"}}},
+				{Name: "posttest", Line: 1, Internal: true, Cmds: []importedCmd{{Cmd: "rm -rf tmp", Line: 1, Column: 61}}},
+			},
+		},
+		{
+			"calls",
+			`{"scripts": {"a": "echo a", "b": "npm run a && yarn a && npm test", "test": "pnpm run a --watch"}}`,
+			[]importedTask{
+				{Name: "a", Line: 1, Cmds: []importedCmd{{Cmd: "echo a", Line: 1, Column: 19}}},
+				{Name: "b", Line: 1, Cmds: []importedCmd{{Task: "a", Line: 1, Column: 34}, {Task: "a"}, {Task: "test"}}},
+				{Name: "test", Line: 1, Cmds: []importedCmd{{Cmd: "pnpm run a --watch", Line: 1, Column: 77}}},
+			},
+		},
+		{
+			"run-p as deps",
+			`{"scripts": {"lint:js": "eslint", "lint:css": "stylelint", "lint": "run-p lint:*"}}`,
+			[]importedTask{
+				{Name: "lint:js", Line: 1, Cmds: []importedCmd{{Cmd: "eslint", Line: 1, Column: 25}}},
+				{Name: "lint:css", Line: 1, Cmds: []importedCmd{{Cmd: "stylelint", Line: 1, Column: 47}}},
+				{Name: "lint", Line: 1, Deps: []string{"lint:js", "lint:css"}},
+			},
+		},
+		{
+			"npm-run-all in sequence",
+			`{"scripts": {"a": "x", "b": "y", "all": "npm-run-all a -p b"}}`,
+			[]importedTask{
+				{Name: "a", Line: 1, Cmds: []importedCmd{{Cmd: "x", Line: 1, Column: 19}}},
+				{Name: "b", Line: 1, Cmds: []importedCmd{{Cmd: "y", Line: 1, Column: 29}}},
+				{Name: "all", Line: 1, Cmds: []importedCmd{{Task: "a", Line: 1, Column: 41}, {Task: "b"}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePackageJSON([]byte(tt.src)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePackageJSON() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitAndList(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"a", []string{"a"}},
+		{"a && b&&c", []string{"a", "b", "c"}},
+		{`echo "a && b" && c`, []string{`echo "a && b"`, "c"}},
+		{`echo 'a && b'`, []string{`echo 'a && b'`}},
+		{`echo a \&\& b`, []string{`echo a \&\& b`}},
+		{"a || b", []string{"a || b"}},
+		{" && a && ", []string{"a"}},
+	}
+	for _, tt := range tests {
+		if got := splitAndList(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitAndList(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestNpmRunAll(t *testing.T) {
+	tests := []struct {
+		cmd          string
+		parallel, ok bool
+	}{
+		{"run-p a b", true, true},
+		{"run-s a b", false, true},
+		{"npm-run-all -p a b", true, true},
+		{"npm-run-all a b", false, true},
+		{"npm-run-all a -p b", false, true},
+		{"npm-run-all --parallel", false, true},
+		{"npm run a", false, false},
+		{"run-p a && run-p b", false, false},
+	}
+	for _, tt := range tests {
+		if parallel, ok := npmRunAll(tt.cmd); parallel != tt.parallel || ok != tt.ok {
+			t.Errorf("npmRunAll(%q) = %v, %v, want %v, %v", tt.cmd, parallel, ok, tt.parallel, tt.ok)
+		}
+	}
+}
+
+func TestNpmGlob(t *testing.T) {
+	scripts := []string{"lint", "lint:js", "lint:css", "lint:js:fix", "test"}
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"test", []string{"test"}},
+		{"missing", []string{"missing"}},
+		{"lint:*", []string{"lint:js", "lint:css"}},
+		{"lint:**", []string{"lint:js", "lint:css", "lint:js:fix"}},
+		{"*", []string{"lint", "test"}},
+		{"build:*", nil},
+	}
+	for _, tt := range tests {
+		if got := npmGlob(tt.pattern, scripts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("npmGlob(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/go-task/task/v3/taskfile"
+	"go.yaml.in/yaml/v3"
+)
+
+// objectScheme returns s3 or gs for object storage URLs, oci for registry
+// artifacts, and "" otherwise
+func objectScheme(uri string) string {
+	scheme, _, ok := strings.Cut(uri, "://")
+	if ok && (scheme == "s3" || scheme == "gs" || scheme == "oci") {
+		return scheme
+	}
+	return ""
+}
+
+// objectMirror copies Taskfiles out of S3, GCS and OCI registries into a
+// local tree laid out as scheme/bucket/key, so the reader can load them as
+// files. Relative includes keep working because the layout mirrors the keys
+// (an OCI artifact is pulled whole); absolute object URLs in includes are
+// rewritten to point into the mirror. The read limits apply as objects are
+// fetched, since the mirror follows includes before the reader sees them.
+type objectMirror struct {
+	dir      string
+	timeout  time.Duration
+	maxDepth int
+	budget   *downloadBudget
+	fetched  map[string]string // object URL -> local path
+	sign     *signatureVerifier
+	s3       *s3.Client
+	gcs      *storage.Client
+}
+
+// mirrorObjects fetches the Taskfile at an object URL, and every object
+// Taskfile it includes, using ambient cloud credentials. It returns the
+// local path of the root and the directory holding the mirror.
+func mirrorObjects(uri string, timeout time.Duration, limits readLimits, sign *signatureVerifier) (string, string, error) {
+	tmp, err := os.MkdirTemp("", "meerkat-objects-")
+	if err != nil {
+		return "", "", err
+	}
+	m := &objectMirror{dir: tmp, timeout: timeout, maxDepth: limits.maxDepth, budget: &downloadBudget{limits: limits}, fetched: make(map[string]string), sign: sign}
+	defer m.close()
+
+	root, err := m.fetch(uri, 0)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return "", "", err
+	}
+	return root, tmp, nil
+}
+
+func (m *objectMirror) close() {
+	if m.gcs != nil {
+		m.gcs.Close()
+	}
+}
+
+// fetch mirrors one object Taskfile, depth includes below the root, and,
+// recursively, its object includes. A key naming a directory is looked up
+// by go-task's default file names.
+func (m *objectMirror) fetch(uri string, depth int) (string, error) {
+	if local, ok := m.fetched[uri]; ok {
+		return local, nil
+	}
+	if m.maxDepth > 0 && depth > m.maxDepth {
+		return "", fmt.Errorf("%s is included %d levels deep, more than %d (--max-include-depth)", uri, depth, m.maxDepth)
+	}
+	if err := m.budget.admit(uri); err != nil {
+		return "", err
+	}
+	if objectScheme(uri) == "oci" {
+		return m.fetchArtifact(uri, depth)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if err := checkObjectKey(bucket, key); err != nil {
+		return "", fmt.Errorf("%s: %w", uri, err)
+	}
+
+	candidates := []string{key}
+	if key == "" || strings.HasSuffix(key, "/") || path.Ext(key) == "" {
+		candidates = nil
+		for _, name := range taskfile.DefaultTaskfiles {
+			candidates = append(candidates, path.Join(key, name))
+		}
+		if path.Ext(key) == "" && key != "" && !strings.HasSuffix(key, "/") {
+			candidates = append([]string{key}, candidates...)
+		}
+	}
+
+	var src []byte
+	var firstErr error
+	for _, k := range candidates {
+		src, err = m.get(u.Scheme, bucket, k)
+		if err == nil {
+			key = k
+			break
+		}
+		// Another name will not fit in the budget either
+		if exceeded := m.budget.err(); exceeded != nil {
+			return "", exceeded
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if src == nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", uri, firstErr)
+	}
+	if m.sign != nil {
+		// The signature is a sibling object; only its absence means none
+		sig, err := m.get(u.Scheme, bucket, key+".sig")
+		if exceeded := m.budget.err(); exceeded != nil {
+			return "", exceeded
+		}
+		if err != nil && !objectNotFound(err) {
+			return "", fmt.Errorf("failed to fetch the signature of %s: %w", uri, err)
+		}
+		if err := m.sign.verify(uri, src, sig); err != nil {
+			return "", err
+		}
+	}
+
+	local := filepath.Join(m.dir, u.Scheme, bucket, filepath.FromSlash(key))
+	if !strings.HasPrefix(local, filepath.Clean(m.dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%s escapes the object mirror", uri)
+	}
+	m.fetched[uri] = local
+	src, err = m.followIncludes(u.Scheme, bucket, key, local, src, depth)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		return "", err
+	}
+	return local, os.WriteFile(local, src, 0o644)
+}
+
+// fetchArtifact pulls an OCI artifact into the mirror and returns the
+// Taskfile among its files, or the one named after //
+func (m *objectMirror) fetchArtifact(uri string, depth int) (string, error) {
+	artifact, inner := splitArchive(uri)
+	name := strings.NewReplacer(":", "_", "@", "_").Replace(strings.TrimPrefix(artifact, "oci://"))
+	dir := filepath.Join(m.dir, "oci", filepath.FromSlash(name))
+	if err := pullArtifact(artifact, dir, os.Stderr, m.sign); err != nil {
+		return "", fmt.Errorf("failed to pull %s: %w", artifact, err)
+	}
+	local := filepath.Join(dir, filepath.FromSlash(inner))
+	if info, err := os.Stat(local); err == nil && info.IsDir() {
+		if local, err = findTaskfile(local); err != nil {
+			return "", fmt.Errorf("%s: %w", artifact, err)
+		}
+	}
+	m.fetched[uri] = local
+
+	src, err := os.ReadFile(local)
+	if err != nil {
+		return "", err
+	}
+	if src, err = m.followIncludes("oci", "", "", local, src, depth); err != nil {
+		return "", err
+	}
+	return local, os.WriteFile(local, src, 0o644)
+}
+
+// followIncludes mirrors the object Taskfiles a fetched Taskfile, depth
+// includes below the root, includes and returns its source with absolute
+// object URLs made local
+func (m *objectMirror) followIncludes(scheme, bucket, key, local string, src []byte, depth int) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		// Left for the reader to report with its own message
+		return src, nil
+	}
+	includes := mappingValue(documentRoot(&doc), "includes")
+	if includes == nil || includes.Kind != yaml.MappingNode {
+		return src, nil
+	}
+
+	var edits []scalarEdit
+	for i := 1; i < len(includes.Content); i += 2 {
+		ref := includes.Content[i]
+		if ref.Kind == yaml.MappingNode {
+			ref = mappingValue(ref, "taskfile")
+		}
+		if ref == nil || ref.Kind != yaml.ScalarNode || strings.Contains(ref.Value, "{{") {
+			continue
+		}
+
+		switch {
+		case objectScheme(ref.Value) != "":
+			target, err := m.fetch(ref.Value, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			rel, err := filepath.Rel(filepath.Dir(local), target)
+			if err != nil {
+				return nil, err
+			}
+			edits = append(edits, scalarEdit{node: ref, value: filepath.ToSlash(rel)})
+		case taskfile.IsRemoteEntrypoint(ref.Value) || path.IsAbs(ref.Value) || scheme == "oci":
+			// Fetched by the reader itself, outside the mirror, or already
+			// pulled with the rest of the artifact
+		default:
+			target := scheme + "://" + bucket + "/" + path.Join(path.Dir(key), ref.Value)
+			if _, err := m.fetch(target, depth+1); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return applyScalarEdits(src, edits), nil
+}
+
+// checkObjectKey refuses a bucket or key that would lead out of its place
+// in the mirror, such as a relative include climbing above the bucket with
+// ../, since the layout follows the key
+func checkObjectKey(bucket, key string) error {
+	if bucket == "" || bucket == "." || bucket == ".." || strings.ContainsAny(bucket, `/\`) {
+		return fmt.Errorf("invalid bucket %q", bucket)
+	}
+	if key == "" {
+		return nil
+	}
+	clean := path.Clean(key)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("object key %q leads outside its bucket", key)
+	}
+	return nil
+}
+
+// objectNotFound reports whether an object download failed because there is
+// no such object, rather than for access or the network
+func objectNotFound(err error) bool {
+	var noKey *types.NoSuchKey
+	return errors.As(err, &noKey) || errors.Is(err, storage.ErrObjectNotExist)
+}
+
+// get downloads one object, within the download budget
+func (m *objectMirror) get(scheme, bucket, key string) ([]byte, error) {
+	ctx := context.Background()
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+
+	var body io.ReadCloser
+	length := int64(-1)
+	switch scheme {
+	case "s3":
+		if m.s3 == nil {
+			// Clients outlive this request, so they get their own context
+			cfg, err := config.LoadDefaultConfig(context.Background())
+			if err != nil {
+				return nil, fmt.Errorf("failed to load AWS credentials: %w", err)
+			}
+			if cfg.Region == "" {
+				cfg.Region = "us-east-1"
+			}
+			m.s3 = s3.NewFromConfig(cfg, func(o *s3.Options) {
+				// Objects uploaded without a checksum are normal here
+				o.DisableLogOutputChecksumValidationSkipped = true
+			})
+		}
+		out, err := m.s3.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+		if err != nil {
+			return nil, err
+		}
+		body = out.Body
+		if out.ContentLength != nil {
+			length = *out.ContentLength
+		}
+	case "gs":
+		if m.gcs == nil {
+			client, err := storage.NewClient(context.Background())
+			if err != nil {
+				return nil, fmt.Errorf("failed to load Google Cloud credentials: %w", err)
+			}
+			m.gcs = client
+		}
+		r, err := m.gcs.Bucket(bucket).Object(key).NewReader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		body, length = r, r.Attrs.Size
+	default:
+		return nil, errors.New("unsupported object storage scheme " + scheme)
+	}
+	body, err := m.budget.meter(scheme+"://"+bucket+"/"+key, body, length)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestCheckObjectKey(t *testing.T) {
+	tests := []struct {
+		bucket, key string
+		wantErr     bool
+	}{
+		{"b", "", false},
+		{"b", "Taskfile.yml", false},
+		{"b", "team/Taskfile.yml", false},
+		{"b", "team/../Taskfile.yml", false},
+		{"b", "..", true},
+		{"b", "../x.yml", true},
+		{"b", "team/../../x.yml", true},
+		{"b", "/x.yml", true},
+		{"..", "x.yml", true},
+		{"", "x.yml", true},
+	}
+	for _, tt := range tests {
+		err := checkObjectKey(tt.bucket, tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkObjectKey(%q, %q) error = %v, want error %v", tt.bucket, tt.key, err, tt.wantErr)
+		}
+	}
+}
+
+func TestObjectMirrorRefusesEscapingKeys(t *testing.T) {
+	m := &objectMirror{dir: t.TempDir(), budget: &downloadBudget{}, fetched: make(map[string]string)}
+	for _, uri := range []string{"s3://b/../../x.yml", "gs://b/team/../../../x.yml"} {
+		if _, err := m.fetch(uri, 0); err == nil {
+			t.Errorf("fetch(%q) succeeded, want it refused", uri)
+		}
+	}
+}
+
+func TestObjectNotFound(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&types.NoSuchKey{}, true},
+		{fmt.Errorf("get: %w", &types.NoSuchKey{}), true},
+		{storage.ErrObjectNotExist, true},
+		{errors.New("403 Forbidden"), false},
+	}
+	for _, tt := range tests {
+		if got := objectNotFound(tt.err); got != tt.want {
+			t.Errorf("objectNotFound(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// fakeGCS serves objects, by bucket/key, as the Cloud Storage emulator
+// would, and points the storage client at it
+func fakeGCS(t *testing.T, objects map[string]string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := objects[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("STORAGE_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+}
+
+func TestObjectMirrorLimits(t *testing.T) {
+	// A chain of includes, each Taskfile including the next
+	objects := map[string]string{"b/t/Taskfile.yml": "version: '3'\nincludes:\n  a: ./a.yml\n"}
+	for i, name := range []string{"a", "b", "c"} {
+		next := "includes:\n  next: ./" + string(rune('b'+i)) + ".yml\n"
+		if name == "c" {
+			next = "tasks:\n  big: echo " + strings.Repeat("x", 2000) + "\n"
+		}
+		objects["b/t/"+name+".yml"] = "version: '3'\n" + next
+	}
+	fakeGCS(t, objects)
+
+	tests := []struct {
+		name   string
+		limits readLimits
+		err    string
+	}{
+		{"unlimited", readLimits{}, ""},
+		{"depth", readLimits{maxDepth: 2}, "t/c.yml is included 3 levels deep, more than 2 (--max-include-depth)"},
+		{"files", readLimits{maxFiles: 3}, "gs://b/t/c.yml would exceed 3 remote Taskfiles (--max-files)"},
+		{"bytes", readLimits{maxBytes: 1000}, "downloading gs://b/t/c.yml would exceed 1000 bytes of remote Taskfiles (--max-download-bytes)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, dir, err := mirrorObjects("gs://b/t/Taskfile.yml", 0, tt.limits, nil)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.RemoveAll(dir)
+				if _, err := os.Stat(filepath.Join(filepath.Dir(root), "c.yml")); err != nil {
+					t.Errorf("the last include was not mirrored: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				os.RemoveAll(dir)
+				t.Fatalf("mirrorObjects() succeeded, want %q", tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("mirrorObjects() = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// noteName names the note of a task, with colons kept out of file names as
+// for the HTML site
+func noteName(task string) string {
+	return strings.ReplaceAll(task, ":", "__")
+}
+
+// wikilink links the note of a task under the task's own name
+func wikilink(task string) string {
+	if note := noteName(task); note != task {
+		return "[[" + note + "|" + task + "]]"
+	}
+	return "[[" + task + "]]"
+}
+
+// writeNotes writes the graph as a vault of Markdown notes for Obsidian,
+// Dendron and other wikis: one note per task linking the tasks it depends
+// on and the ones depending on it, and an index note listing them by
+// namespace
+func writeNotes(dir, root string, ws *workspace, tg *taskGraph) (int, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+	out := make(map[string][]taskEdge)
+	in := make(map[string][]taskEdge)
+	for _, e := range tg.Edges {
+		out[e.From] = append(out[e.From], e)
+		in[e.To] = append(in[e.To], e)
+	}
+
+	notes := 0
+	var index strings.Builder
+	fmt.Fprintf(&index, "# %s\n", root)
+	for _, ns := range append([]string{""}, tg.namespaces()...) {
+		heading := ns
+		if ns == "" {
+			heading = rootNamespace
+		}
+		listed := false
+		for _, n := range tg.Nodes {
+			if n.Missing || n.Namespace != ns {
+				continue
+			}
+			if !listed {
+				fmt.Fprintf(&index, "\n## %s\n\n", heading)
+				listed = true
+			}
+			fmt.Fprintf(&index, "- %s", wikilink(n.Name))
+			if n.Desc != "" {
+				fmt.Fprintf(&index, " - %s", n.Desc)
+			}
+			index.WriteString("\n")
+
+			name, task, _ := ws.resolve(n.Name)
+			note := taskNote(n, ws.describe(name, task), out[n.Name], in[n.Name])
+			if err := os.WriteFile(filepath.Join(dir, noteName(n.Name)+".md"), []byte(note), 0o644); err != nil {
+				return notes, err
+			}
+			notes++
+		}
+	}
+	return notes, os.WriteFile(filepath.Join(dir, "index.md"), []byte(index.String()), 0o644)
+}
+
+// taskNote is the note of one task. Its front matter carries the task's
+// name as an alias, so the note is found by the name with colons, and its
+// namespace and requirement classes as tags.
+func taskNote(n taskNode, d taskDescription, out, in []taskEdge) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "task: %s\n", strconv.Quote(n.Name))
+	fmt.Fprintf(&b, "aliases: [%s]\n", strconv.Quote(n.Name))
+	tags := []string{"task"}
+	if n.Namespace != "" {
+		tags = append(tags, "namespace/"+strings.ReplaceAll(n.Namespace, ":", "/"))
+	}
+	for _, r := range n.Requires {
+		tags = append(tags, "requires/"+r)
+	}
+	fmt.Fprintf(&b, "tags: [%s]\n", strings.Join(tags, ", "))
+	if n.Location != "" {
+		fmt.Fprintf(&b, "location: %s\n", strconv.Quote(n.Location))
+	}
+	b.WriteString("---\n\n")
+
+	fmt.Fprintf(&b, "# %s\n", n.Name)
+	if n.Desc != "" {
+		fmt.Fprintf(&b, "\n%s\n", n.Desc)
+	}
+	section := func(title string, edges []taskEdge, other func(taskEdge) string) {
+		if len(edges) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", title)
+		for _, e := range edges {
+			fmt.Fprintf(&b, "- %s (%s", wikilink(other(e)), e.Kind)
+			if e.Weight > 1 {
+				fmt.Fprintf(&b, " x%d", e.Weight)
+			}
+			b.WriteString(")\n")
+		}
+	}
+	section("Depends on", out, func(e taskEdge) string { return e.To })
+	section("Used by", in, func(e taskEdge) string { return e.From })
+	if len(d.Cmds) > 0 {
+		b.WriteString("\n## Commands\n\n```sh\n")
+		for _, cmd := range d.Cmds {
+			b.WriteString(strings.TrimRight(cmd, "\n") + "\n")
+		}
+		b.WriteString("```\n")
+	}
+	return b.String()
+}
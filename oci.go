@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// OCI media types of the manifests an artifact reference may resolve to
+const (
+	ociManifest    = "application/vnd.oci.image.manifest.v1+json"
+	ociIndex       = "application/vnd.oci.image.index.v1+json"
+	dockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// ociTitle is the annotation ORAS stores each pushed file's name in, and
+// ociUnpack marks a layer that is a tarball of a pushed directory
+const (
+	ociTitle  = "org.opencontainers.image.title"
+	ociUnpack = "io.deis.oras.content.unpack"
+)
+
+// ociRef is a parsed oci://registry/repository[:tag|@digest] reference
+type ociRef struct {
+	registry, repository, reference string
+}
+
+func parseOCIRef(uri string) (ociRef, error) {
+	rest := strings.TrimPrefix(uri, "oci://")
+	registry, repository, ok := strings.Cut(rest, "/")
+	if !ok || repository == "" {
+		return ociRef{}, fmt.Errorf("invalid OCI reference %s: want oci://registry/repository:tag", uri)
+	}
+	ref := ociRef{registry: registry, repository: repository, reference: "latest"}
+	if name, digest, ok := strings.Cut(repository, "@"); ok {
+		ref.repository, ref.reference = name, digest
+	} else if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		ref.repository, ref.reference = repository[:i], repository[i+1:]
+	}
+	if ref.registry == "docker.io" {
+		ref.registry = "registry-1.docker.io"
+	}
+	return ref, nil
+}
+
+// pinned reports whether the reference names a digest rather than a tag
+func (r ociRef) pinned() bool {
+	return strings.HasPrefix(r.reference, "sha256:")
+}
+
+// ociManifestDoc is the part of an image manifest or index the puller reads
+type ociManifestDoc struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// registryClient talks to one registry, holding the token it was last
+// granted
+type registryClient struct {
+	registry string
+	token    string
+}
+
+// pullArtifact downloads the files of an OCI artifact, as pushed by ORAS,
+// into dir. A digest reference is verified against the manifest; a tag is
+// reported with the digest it resolved to, so it can be pinned. The
+// manifest's signature is checked before any file is downloaded.
+func pullArtifact(uri, dir string, log io.Writer, sign *signatureVerifier) error {
+	ref, err := parseOCIRef(uri)
+	if err != nil {
+		return err
+	}
+	c := &registryClient{registry: ref.registry}
+
+	manifest, digest, err := c.manifest(ref.repository, ref.reference)
+	if err != nil {
+		return fmt.Errorf("%s: %w", uri, err)
+	}
+	if !ref.pinned() {
+		fmt.Fprintf(log, "%s resolved to %s\n", uri, digest)
+	}
+	if err := sign.verifyArtifact(c, uri, ref.repository, digest); err != nil {
+		return err
+	}
+
+	for i, layer := range manifest.Layers {
+		blob, err := c.blob(ref.repository, layer.Digest)
+		if err != nil {
+			return err
+		}
+		name := layer.Annotations[ociTitle]
+		if name == "" {
+			name = fmt.Sprintf("layer-%d", i)
+		}
+		target, err := extractPath(dir, name)
+		if err != nil {
+			return err
+		}
+		if layer.Annotations[ociUnpack] == "true" || strings.HasSuffix(layer.MediaType, ".tar+gzip") {
+			if err := extractArchive(blob, ".tar.gz", target); err != nil {
+				return fmt.Errorf("failed to unpack %s: %w", name, err)
+			}
+			continue
+		}
+		if err := writeFile(target, bytes.NewReader(blob), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manifest fetches the manifest a reference points to, following an index
+// to its first entry. It returns the digest of what the reference names
+// itself, the index if it is one, which a digest reference is checked
+// against before anything it lists is fetched.
+func (c *registryClient) manifest(repository, reference string) (*ociManifestDoc, string, error) {
+	body, err := c.get(fmt.Sprintf("/v2/%s/manifests/%s", repository, reference), ociManifest+", "+ociIndex+", "+dockerManifest)
+	if err != nil {
+		return nil, "", err
+	}
+	digest := sha256Digest(body)
+	if (ociRef{reference: reference}).pinned() && digest != reference {
+		return nil, "", fmt.Errorf("manifest %s has digest %s", reference, digest)
+	}
+	var m ociManifestDoc
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, "", fmt.Errorf("invalid manifest for %s:%s: %w", repository, reference, err)
+	}
+	if m.MediaType == ociIndex || len(m.Manifests) > 0 {
+		if len(m.Manifests) == 0 {
+			return nil, "", fmt.Errorf("empty index for %s:%s", repository, reference)
+		}
+		child, _, err := c.manifest(repository, m.Manifests[0].Digest)
+		return child, digest, err
+	}
+	return &m, digest, nil
+}
+
+// blob fetches a layer and checks it against its digest
+func (c *registryClient) blob(repository, digest string) ([]byte, error) {
+	body, err := c.get(fmt.Sprintf("/v2/%s/blobs/%s", repository, digest), "")
+	if err != nil {
+		return nil, err
+	}
+	if got := sha256Digest(body); got != digest {
+		return nil, fmt.Errorf("blob %s has digest %s", digest, got)
+	}
+	return body, nil
+}
+
+// get performs a registry request, answering a 401 challenge once with a
+// bearer token or basic credentials
+func (c *registryClient) get(path, accept string) ([]byte, error) {
+	u := "https://" + c.registry + path
+	resp, err := c.do(u, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := c.authenticate(challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(u, accept); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &registryStatusError{url: u, status: resp.Status, code: resp.StatusCode}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// registryStatusError is a registry request answered with an error status
+type registryStatusError struct {
+	url, status string
+	code        int
+}
+
+func (e *registryStatusError) Error() string {
+	return fmt.Sprintf("GET %s: %s", e.url, e.status)
+}
+
+func (c *registryClient) do(u, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", c.token)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// authenticate answers a WWW-Authenticate challenge with the credentials
+// docker login stored for the registry, if any. Bearer challenges trade
+// them, or nothing for anonymous pulls, for a token at the realm.
+func (c *registryClient) authenticate(challenge string) error {
+	user, secret := registryCredentials(c.registry)
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		if user == "" {
+			return fmt.Errorf("%s requires credentials; run docker login %s", c.registry, c.registry)
+		}
+		c.token = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+secret))
+		return nil
+	}
+
+	fields := challengeParams(params)
+	realm, err := url.Parse(fields["realm"])
+	if err != nil || fields["realm"] == "" {
+		return fmt.Errorf("%s sent an unusable auth challenge: %s", c.registry, challenge)
+	}
+	// The realm is sent the docker login secret, so never in the clear
+	if realm.Scheme != "https" {
+		return fmt.Errorf("%s sent a token realm that is not https: %s", c.registry, realm.Redacted())
+	}
+	q := realm.Query()
+	for _, k := range []string{"service", "scope"} {
+		if v := fields[k]; v != "" {
+			q.Set(k, v)
+		}
+	}
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if user != "" {
+		req.SetBasicAuth(user, secret)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("token request to %s: %s", realm.Host, resp.Status)
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return err
+	}
+	if tok.Token == "" {
+		tok.Token = tok.AccessToken
+	}
+	c.token = "Bearer " + tok.Token
+	return nil
+}
+
+// challengeParams parses the key="value" list of a WWW-Authenticate header
+func challengeParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s != "" {
+		key, rest, ok := strings.Cut(strings.TrimLeft(s, ", "), "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value, s = rest[1:end+1], rest[end+2:]
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return params
+}
+
+// dockerHubConfigKey is the server docker login stores Docker Hub
+// credentials under, for auths and when asking credential helpers
+const dockerHubConfigKey = "https://index.docker.io/v1/"
+
+// registryCredentials looks a registry up in the Docker CLI config: a
+// credential helper for it, the global credential store, or a stored auth
+func registryCredentials(registry string) (string, string) {
+	switch registry {
+	case "registry-1.docker.io", "index.docker.io", "docker.io":
+		registry = dockerHubConfigKey
+	}
+
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return "", ""
+	}
+	var cfg struct {
+		Auths       map[string]struct{ Auth string } `json:"auths"`
+		CredHelpers map[string]string                `json:"credHelpers"`
+		CredsStore  string                           `json:"credsStore"`
+	}
+	if json.Unmarshal(data, &cfg) != nil {
+		return "", ""
+	}
+
+	helper := cfg.CredHelpers[registry]
+	if helper == "" {
+		helper = cfg.CredsStore
+	}
+	if helper != "" {
+		cmd := exec.Command("docker-credential-"+helper, "get")
+		cmd.Stdin = strings.NewReader(registry)
+		if out, err := cmd.Output(); err == nil {
+			var cred struct{ Username, Secret string }
+			if json.Unmarshal(out, &cred) == nil {
+				return cred.Username, cred.Secret
+			}
+		}
+	}
+
+	if a, ok := cfg.Auths[registry]; ok {
+		if decoded, err := base64.StdEncoding.DecodeString(a.Auth); err == nil {
+			user, secret, _ := strings.Cut(string(decoded), ":")
+			return user, secret
+		}
+	}
+	return "", ""
+}
+
+func sha256Digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeRegistry serves manifests, by tag or digest, and blobs, by digest,
+// over TLS, and routes the default client to it; it returns the registry's
+// host
+func fakeRegistry(t *testing.T, manifests map[string][]byte, blobs [][]byte) string {
+	t.Helper()
+	byDigest := make(map[string][]byte)
+	for _, b := range blobs {
+		byDigest[sha256Digest(b)] = b
+	}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ref, _ := strings.Cut(r.URL.Path, "/manifests/")
+		if body, ok := manifests[ref]; ok {
+			w.Write(body)
+			return
+		}
+		for _, body := range manifests {
+			if sha256Digest(body) == ref {
+				w.Write(body)
+				return
+			}
+		}
+		_, digest, _ := strings.Cut(r.URL.Path, "/blobs/")
+		if body, ok := byDigest[digest]; ok {
+			w.Write(body)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	transport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = srv.Client().Transport
+	t.Cleanup(func() { http.DefaultClient.Transport = transport })
+	return strings.TrimPrefix(srv.URL, "https://")
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestPullArtifact(t *testing.T) {
+	taskfile := []byte("version: '3'\ntasks:\n  build: go build\n")
+	manifest := mustJSON(t, ociManifestDoc{
+		MediaType: ociManifest,
+		Layers:    []ociDescriptor{{MediaType: "application/yaml", Digest: sha256Digest(taskfile), Annotations: map[string]string{ociTitle: "Taskfile.yml"}}},
+	})
+	index := mustJSON(t, ociManifestDoc{
+		MediaType: ociIndex,
+		Manifests: []ociDescriptor{{MediaType: ociManifest, Digest: sha256Digest(manifest)}},
+	})
+	registry := fakeRegistry(t, map[string][]byte{"v1": manifest, "multi": index}, [][]byte{taskfile})
+	repo := "oci://" + registry + "/org/taskfiles"
+
+	tests := []struct {
+		name     string
+		uri      string
+		resolved string // reported for a tag
+		err      string
+	}{
+		{"tag", repo + ":v1", sha256Digest(manifest), ""},
+		{"tag of an index", repo + ":multi", sha256Digest(index), ""},
+		{"manifest digest", repo + "@" + sha256Digest(manifest), "", ""},
+		{"index digest", repo + "@" + sha256Digest(index), "", ""},
+		{"wrong digest", repo + "@sha256:" + strings.Repeat("0", 64), "", "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var log strings.Builder
+			err := pullArtifact(tt.uri, dir, &log, nil)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("pullArtifact() = %v, want an error with %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(filepath.Join(dir, "Taskfile.yml"))
+			if err != nil || string(got) != string(taskfile) {
+				t.Errorf("Taskfile.yml = %q, %v, want the pushed file", got, err)
+			}
+			if tt.resolved != "" && !strings.Contains(log.String(), "resolved to "+tt.resolved) {
+				t.Errorf("log = %q, want the tag resolved to %s", log.String(), tt.resolved)
+			}
+		})
+	}
+}
+
+func TestRegistryManifestChecksPinnedDigest(t *testing.T) {
+	manifest := mustJSON(t, ociManifestDoc{MediaType: ociManifest})
+	changed := mustJSON(t, ociManifestDoc{MediaType: ociManifest, Layers: []ociDescriptor{{Digest: "sha256:00"}}})
+	// A registry answering a digest with other content than it names
+	registry := fakeRegistry(t, map[string][]byte{sha256Digest(manifest): changed}, nil)
+
+	c := &registryClient{registry: registry}
+	if _, _, err := c.manifest("org/taskfiles", sha256Digest(manifest)); err == nil || !strings.Contains(err.Error(), "has digest "+sha256Digest(changed)) {
+		t.Errorf("manifest() = %v, want a digest mismatch", err)
+	}
+}
+
+func TestRegistryCredentials(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	writeFiles(t, dir, map[string]string{"config.json": `{"auths": {
+		"https://index.docker.io/v1/": {"auth": "aHViOmh1YnNlY3JldA=="},
+		"ghcr.io": {"auth": "Z2g6Z2hzZWNyZXQ="}
+	}}`})
+
+	tests := []struct {
+		registry, user, secret string
+	}{
+		{"registry-1.docker.io", "hub", "hubsecret"},
+		{"index.docker.io", "hub", "hubsecret"},
+		{"ghcr.io", "gh", "ghsecret"},
+		{"quay.io", "", ""},
+	}
+	for _, tt := range tests {
+		user, secret := registryCredentials(tt.registry)
+		if user != tt.user || secret != tt.secret {
+			t.Errorf("registryCredentials(%q) = %q, %q, want %q, %q", tt.registry, user, secret, tt.user, tt.secret)
+		}
+	}
+}
+
+func TestRegistryCredentialsHelper(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	// The helper answers only for the server docker login used
+	writeFiles(t, dir, map[string]string{
+		"config.json": `{"credHelpers": {"https://index.docker.io/v1/": "fake"}}`,
+		"docker-credential-fake": `#!/bin/sh
+read server
+[ "$server" = "https://index.docker.io/v1/" ] || exit 1
+echo '{"Username": "hub", "Secret": "fromhelper"}'
+`,
+	})
+	if err := os.Chmod(filepath.Join(dir, "docker-credential-fake"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if user, secret := registryCredentials("registry-1.docker.io"); user != "hub" || secret != "fromhelper" {
+		t.Errorf("registryCredentials() = %q, %q, want the helper's credentials", user, secret)
+	}
+}
+
+func TestRegistryAuthenticate(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	writeFiles(t, dir, map[string]string{"config.json": `{"auths": {"registry.example.com": {"auth": "aHViOmh1YnNlY3JldA=="}}}`})
+
+	var gotAuth, gotScope string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotScope = r.Header.Get("Authorization"), r.URL.Query().Get("scope")
+		w.Write([]byte(`{"token": "tok"}`))
+	}))
+	defer srv.Close()
+	transport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = srv.Client().Transport
+	defer func() { http.DefaultClient.Transport = transport }()
+
+	tests := []struct {
+		name      string
+		challenge string
+		token     string
+		err       string
+	}{
+		{"bearer", `Bearer realm="` + srv.URL + `/token",service="registry",scope="repository:org/taskfiles:pull"`, "Bearer tok", ""},
+		{"basic", `Basic realm="registry"`, "Basic aHViOmh1YnNlY3JldA==", ""},
+		{"plain http realm", `Bearer realm="http://auth.example.com/token"`, "", "not https"},
+		{"no realm", `Bearer service="registry"`, "", "unusable auth challenge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAuth = ""
+			c := &registryClient{registry: "registry.example.com"}
+			err := c.authenticate(tt.challenge)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("authenticate() = %v, want an error with %q", err, tt.err)
+				}
+				if gotAuth != "" {
+					t.Errorf("credentials were sent: %q", gotAuth)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.token != tt.token {
+				t.Errorf("token = %q, want %q", c.token, tt.token)
+			}
+			if strings.HasPrefix(tt.token, "Bearer") && (gotAuth != "Basic aHViOmh1YnNlY3JldA==" || gotScope != "repository:org/taskfiles:pull") {
+				t.Errorf("realm got Authorization %q and scope %q", gotAuth, gotScope)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	rcast "github.com/go-task/task/v3/taskrc/ast"
+)
+
+// defaultTaskfileURL is analyzed when --taskfile is not given
+const defaultTaskfileURL = "https://raw.githubusercontent.com/gkwa/ringgem/refs/heads/master/Taskfile.yaml"
+
+// options holds the flags shared by every command that loads a Taskfile
+type options struct {
+	taskfileURL  string
+	noCache      bool
+	timeout      time.Duration
+	readTimeout  time.Duration
+	cacheExpiry  time.Duration
+	cacheDir     string
+	configFile   string
+	debugFile    string
+	progress     string
+	rateLimit    float64
+	maxPerHost   int
+	maxRedirects int
+	redirects    string
+	gitRef       string
+	baseDir      string
+	cpuProfile   string
+	memProfile   string
+	limits       readLimits
+	cosignKey    string
+	clientCert   string
+	clientKey    string
+	caFile       string
+	credHelper   string
+	requireSig   bool
+	with         buildFiles
+	keepGoing    bool
+	strict       bool
+
+	// rc holds the Task settings (.taskrc.yml) that apply to the Taskfile
+	rc *rcast.TaskRC
+}
+
+// register adds the shared flags to fs
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.taskfileURL, "taskfile", defaultTaskfileURL, "Taskfile URL or path, or - to read it from stdin")
+	fs.BoolVar(&o.noCache, "no-cache", false, "Force download without using cache")
+	fs.BoolVar(&o.strict, "strict", false, "Fail instead of warning on skipped includes, Taskfiles over plain HTTP or redirected to another host, calls of unknown tasks and unsigned Taskfiles")
+	fs.BoolVar(&o.keepGoing, "keep-going", false, "Analyze the rest of the graph when a remote include fails to download or parse, marking the tasks it would have had unresolved")
+	fs.DurationVar(&o.timeout, "timeout", 30*time.Second, "Timeout for each remote Taskfile fetch (root and includes)")
+	fs.DurationVar(&o.readTimeout, "read-timeout", 0, "Overall deadline for reading the whole Taskfile graph (0 disables)")
+	fs.DurationVar(&o.cacheExpiry, "cache-expiry", 24*time.Hour, "How long cached remote Taskfiles stay fresh (0 always revalidates)")
+	fs.StringVar(&o.cacheDir, "cache-dir", defaultCacheDir(), "Directory of the content-addressed remote Taskfile cache")
+	fs.StringVar(&o.configFile, "config", defaultConfigFile, "Config file whose keys provide defaults for any flag")
+	fs.StringVar(&o.debugFile, "debug-log", "", "Write reader debug messages and phase timings as JSONL to this file")
+	fs.StringVar(&o.progress, "progress", "auto", "Download progress on stderr: auto, bar, log or off")
+	fs.Float64Var(&o.rateLimit, "rate-limit", 0, "Maximum remote requests per second (0 is unlimited)")
+	fs.IntVar(&o.maxPerHost, "max-per-host", 0, "Maximum concurrent downloads per host (0 is unlimited)")
+	fs.IntVar(&o.maxRedirects, "max-redirects", 10, "Fail a remote fetch that redirects more times than this (0 is unlimited)")
+	fs.StringVar(&o.redirects, "redirects", "follow", "Redirects remote fetches may follow: follow, same-host or none")
+	fs.StringVar(&o.gitRef, "git-ref", "", "Read the local Taskfile and its local includes as of this git ref")
+	fs.IntVar(&o.limits.maxDepth, "max-include-depth", 32, "Fail when includes nest deeper than this below the root Taskfile (0 is unlimited)")
+	fs.IntVar(&o.limits.maxFiles, "max-files", 1000, "Fail when the graph has more Taskfiles than this (0 is unlimited)")
+	fs.Int64Var(&o.limits.maxBytes, "max-download-bytes", 64<<20, "Fail when remote Taskfiles add up to more bytes than this (0 is unlimited)")
+	fs.StringVar(&o.clientCert, "client-cert", "", "Client certificate (PEM) to present to services that require mutual TLS, with --client-key")
+	fs.StringVar(&o.clientKey, "client-key", "", "Private key (PEM) of --client-cert")
+	fs.StringVar(&o.caFile, "ca-file", "", "PEM bundle of CAs to trust for remote fetches besides the system ones (default $MEERKAT_CA_FILE)")
+	fs.StringVar(&o.credHelper, "credential-helper", "", "Command run per host for the credentials of remote fetches, speaking git's credential protocol")
+	fs.StringVar(&o.cosignKey, "cosign-key", "", "Verify remote Taskfiles against cosign signatures made with this public key")
+	fs.BoolVar(&o.requireSig, "require-signed", false, "Refuse remote Taskfiles without a signature from --cosign-key")
+	fs.StringVar(&o.cpuProfile, "cpuprofile", "", "Write a CPU profile of the run to this file")
+	fs.StringVar(&o.memProfile, "memprofile", "", "Write a heap profile to this file when the run ends")
+	fs.Var(&o.with, "with", "Add the tasks of a Makefile, package.json or justfile, as NS=PATH or PATH under its format's name (repeatable)")
+	fs.StringVar(&o.baseDir, "base-dir", "", "Directory that relative includes of a Taskfile read from stdin resolve against (default the working directory)")
+}
+
+// parseFlags parses args into fs and then fills unset flags from the config
+// file and Task's own settings
+func parseFlags(fs *flag.FlagSet, o *options, args []string) error {
+	if collectFlags != nil {
+		collectFlags(fs)
+		return errFlagsCollected
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := applyConfigFile(fs, o.configFile); err != nil {
+		return err
+	}
+	return applyTaskRC(fs, o)
+}
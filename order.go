@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// taskOrder is a schedule of the graph: every task after all the tasks it
+// depends on, and the loops that cannot be put in any order
+type taskOrder struct {
+	Order  []string   `json:"order"`
+	Cycles [][]string `json:"cycles,omitempty"`
+}
+
+// runOrder prints the tasks in an order they can run in, dependencies
+// first, so the same work can be scheduled outside Task
+func runOrder(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("order", flag.ExitOnError)
+	opts.register(fs)
+	start := fs.String("start", "", "Only order the tasks reachable from this task")
+	format := fs.String("format", "text", "Output format: text or json")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	g, merged, err := l.load()
+	if err != nil {
+		return err
+	}
+	tg, err := buildTaskGraph(g, merged, nil)
+	if err != nil {
+		return err
+	}
+	if *start != "" {
+		ws, err := newWorkspace(l, g, merged)
+		if err != nil {
+			return err
+		}
+		name, _, ok := ws.resolve(*start)
+		if !ok {
+			return fmt.Errorf("task %q not found%s", *start, didYouMean(*start, merged.Tasks))
+		}
+		tg = tg.subgraph(name)
+	}
+
+	order := tg.topologicalOrder()
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(order)
+	}
+	for _, name := range order.Order {
+		fmt.Println(name)
+	}
+	if len(order.Cycles) > 0 {
+		fmt.Fprintf(os.Stderr, "%s left out of the order:\n", plural(len(order.Cycles), "cycle"))
+		for _, loop := range order.Cycles {
+			fmt.Fprintf(os.Stderr, "  %s\n", strings.Join(loop, ", "))
+		}
+	}
+	return nil
+}
+
+// topologicalOrder orders the tasks dependencies first, picking the first
+// task by name whenever several are ready so the order is the same on every
+// run. The tasks of a cycle are reported as one group instead, and the
+// tasks depending on them are ordered as if the cycle had run. Inferred
+// edges and missing tasks are left out, as in the dependency tree.
+func (tg *taskGraph) topologicalOrder() taskOrder {
+	var names []string
+	index := make(map[string]int)
+	for _, n := range tg.Nodes {
+		if !n.Missing {
+			index[n.Name] = len(names)
+			names = append(names, n.Name)
+		}
+	}
+	deps := make([][]int, len(names))
+	selfLoop := make([]bool, len(names))
+	for _, e := range tg.Edges {
+		from, ok := index[e.From]
+		to, ok2 := index[e.To]
+		if e.Kind == edgeInferred || !ok || !ok2 {
+			continue
+		}
+		if from == to {
+			selfLoop[from] = true
+		} else if !slices.Contains(deps[from], to) {
+			deps[from] = append(deps[from], to)
+		}
+	}
+
+	comp := stronglyConnected(deps)
+	members := make(map[int][]int)
+	for v, c := range comp {
+		members[c] = append(members[c], v)
+	}
+	// Components wait on the distinct components their tasks depend on
+	waiting := make(map[int]int)
+	dependents := make(map[int][]int)
+	for v, ds := range deps {
+		for _, d := range ds {
+			if comp[v] != comp[d] && !slices.Contains(dependents[comp[d]], comp[v]) {
+				dependents[comp[d]] = append(dependents[comp[d]], comp[v])
+				waiting[comp[v]]++
+			}
+		}
+	}
+	// A component is named, and ordered among the ready ones, by its first task
+	first := func(c int) string { return names[slices.Min(members[c])] }
+	var ready []int
+	for c := range members {
+		if waiting[c] == 0 {
+			ready = append(ready, c)
+		}
+	}
+
+	order := taskOrder{Order: []string{}}
+	for len(ready) > 0 {
+		slices.SortFunc(ready, func(a, b int) int { return strings.Compare(first(a), first(b)) })
+		c := ready[0]
+		ready = ready[1:]
+		if vs := members[c]; len(vs) > 1 || selfLoop[vs[0]] {
+			var loop []string
+			for _, v := range vs {
+				loop = append(loop, names[v])
+			}
+			slices.Sort(loop)
+			order.Cycles = append(order.Cycles, loop)
+		} else {
+			order.Order = append(order.Order, names[vs[0]])
+		}
+		for _, d := range dependents[c] {
+			if waiting[d]--; waiting[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	return order
+}
+
+// stronglyConnected numbers the strongly connected components of a graph by
+// Tarjan's algorithm, returning the component of every node
+func stronglyConnected(adj [][]int) []int {
+	n := len(adj)
+	comp := make([]int, n)
+	low := make([]int, n)
+	num := make([]int, n)
+	for i := range num {
+		num[i] = -1
+	}
+	var stack []int
+	onStack := make([]bool, n)
+	counter, components := 0, 0
+	var visit func(v int)
+	visit = func(v int) {
+		num[v], low[v] = counter, counter
+		counter++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range adj[v] {
+			if num[w] < 0 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], num[w])
+			}
+		}
+		if low[v] == num[v] {
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				comp[w] = components
+				if w == v {
+					break
+				}
+			}
+			components++
+		}
+	}
+	for v := range adj {
+		if num[v] < 0 {
+			visit(v)
+		}
+	}
+	return comp
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// packageUse is one package a command installs or runs, with the version it
+// asks for. Version is empty when the command takes whatever is current.
+type packageUse struct {
+	Task     string   `json:"task"`
+	Manager  string   `json:"manager"`
+	Name     string   `json:"name"`
+	Version  string   `json:"version,omitempty"`
+	OS       []string `json:"os"` // where the command may run and the manager exists
+	Location string   `json:"location"`
+}
+
+// osPackage is a package installed on one OS, with every task installing it
+type osPackage struct {
+	Manager string   `json:"manager"`
+	Name    string   `json:"name"`
+	Version string   `json:"version,omitempty"`
+	Tasks   []string `json:"tasks"`
+}
+
+// versionConflict is a package that tasks pin to different exact versions
+type versionConflict struct {
+	Manager  string              `json:"manager"`
+	Name     string              `json:"name"`
+	Versions map[string][]string `json:"versions"` // version to the tasks pinning it
+}
+
+// packageInstalls recognizes the package manager commands that fetch a
+// package: go install/run/get, pip and pipx install, npm/pnpm/yarn global
+// installs, npx, cargo install and gem install, and the system package
+// managers of each OS
+func packageInstalls(c taskCmd, tool string, args []string) []packageUse {
+	var uses []packageUse
+	targets := packageOS(c, tool)
+	add := func(manager, name, version string) {
+		if name == "" || strings.Contains(name, "{{") {
+			return
+		}
+		uses = append(uses, packageUse{Task: c.name, Manager: manager, Name: name, Version: version, OS: targets, Location: c.pos.String()})
+	}
+	sub, rest := "", []string(nil)
+	if len(args) > 0 {
+		sub, rest = args[0], args[1:]
+	}
+
+	switch tool {
+	case "go":
+		if sub == "install" || sub == "run" || sub == "get" {
+			for _, a := range positional(rest) {
+				// Only module-qualified paths; go run main.go is not a package
+				if name, version, ok := strings.Cut(a, "@"); ok || sub == "get" {
+					add("go", name, version)
+				}
+			}
+		}
+	case "python", "python3":
+		if sub == "-m" && len(rest) > 0 && strings.HasPrefix(rest[0], "pip") {
+			return packageInstalls(c, "pip", rest[1:])
+		}
+	case "pip", "pip3", "pipx", "uv":
+		if tool == "uv" && sub == "pip" && len(rest) > 0 {
+			sub, rest = rest[0], rest[1:]
+		}
+		manager := "pip"
+		if tool == "pipx" {
+			// pipx installs each application into a virtualenv of its own
+			manager = "pipx"
+		}
+		if sub == "install" {
+			for _, a := range positional(rest, "-r", "--requirement", "-c", "--constraint", "-i", "--index-url", "-e", "--editable", "--python", "--suffix") {
+				name, version := pipSpec(a)
+				add(manager, name, version)
+			}
+		}
+	case "npm", "pnpm", "yarn":
+		if tool == "yarn" && sub == "global" && len(rest) > 0 {
+			sub, rest = rest[0], rest[1:]
+		}
+		if sub == "install" || sub == "i" || sub == "add" {
+			for _, a := range positional(rest) {
+				name, version := npmSpec(a)
+				add("npm", name, version)
+			}
+		}
+	case "npx", "bunx":
+		if pkgs := positional(args, "-p", "--package"); len(pkgs) > 0 {
+			name, version := npmSpec(pkgs[0])
+			add("npm", name, version)
+		}
+	case "cargo":
+		if sub == "install" {
+			version := flagValue(rest, "--version")
+			for _, a := range positional(rest, "--version", "--git", "--branch", "--tag", "--rev", "--root", "--features") {
+				name, v, _ := strings.Cut(a, "@")
+				if v == "" {
+					v = version
+				}
+				add("cargo", name, v)
+			}
+		}
+	case "gem":
+		if sub == "install" {
+			version := flagValue(rest, "-v", "--version")
+			for _, a := range positional(rest, "-v", "--version", "-i", "--install-dir", "-n", "--bindir", "--source") {
+				name, v, _ := strings.Cut(a, ":")
+				if v == "" {
+					v = version
+				}
+				add("gem", name, v)
+			}
+		}
+
+	// System package managers take global options before the subcommand, as
+	// in apt-get -y install, so the subcommand is the first positional word
+	case "apt", "apt-get", "aptitude":
+		if words := positional(args, "-o", "--option", "-t", "--target-release", "-c", "--config-file"); len(words) > 0 && words[0] == "install" {
+			for _, a := range words[1:] {
+				if !packageFile(a) {
+					name, version, _ := strings.Cut(a, "=")
+					add("apt", name, version)
+				}
+			}
+		}
+	case "dnf", "yum", "microdnf":
+		if words := positional(args, "--enablerepo", "--disablerepo", "--repo", "--setopt", "-c", "--config", "--releasever", "--installroot"); len(words) > 0 && words[0] == "install" {
+			for _, a := range words[1:] {
+				// RPM names carry no separate version: foo-1.2 is a name too
+				if !packageFile(a) {
+					add(tool, a, "")
+				}
+			}
+		}
+	case "zypper":
+		if words := positional(args, "-r", "--repo", "--from", "--root"); len(words) > 0 && (words[0] == "install" || words[0] == "in") {
+			for _, a := range words[1:] {
+				if !packageFile(a) {
+					name, version := pipSpec(a)
+					add("zypper", name, strings.TrimPrefix(version, "="))
+				}
+			}
+		}
+	case "apk":
+		if words := positional(args, "-X", "--repository", "-t", "--virtual", "-p", "--root", "--arch"); len(words) > 0 && words[0] == "add" {
+			for _, a := range words[1:] {
+				if !packageFile(a) {
+					name, version := pipSpec(a)
+					add("apk", name, strings.TrimPrefix(version, "="))
+				}
+			}
+		}
+	case "pacman":
+		// pacman -S installs, -Sy and -Syu refresh first; -Ss and -Si only look
+		sync := slices.ContainsFunc(args, func(a string) bool {
+			op, ok := strings.CutPrefix(a, "-S")
+			return a == "--sync" || ok && !strings.HasPrefix(a, "--") && strings.Trim(op, "yu") == ""
+		})
+		if sync {
+			for _, a := range positional(args, "--config", "--root", "--dbpath", "--cachedir", "--overwrite", "--ignore") {
+				add("pacman", a, "")
+			}
+		}
+	case "brew":
+		if sub == "install" || sub == "reinstall" {
+			manager := "brew"
+			if slices.Contains(rest, "--cask") {
+				// Casks are macOS applications, even where Homebrew runs on linux
+				manager, targets = "brew-cask", []string{"darwin"}
+			}
+			// A versioned formula such as python@3.12 is a formula of its own
+			for _, a := range positional(rest) {
+				add(manager, a, "")
+			}
+		}
+	case "port":
+		if words := positional(args); len(words) > 0 && words[0] == "install" {
+			for _, a := range words[1:] {
+				// Variants such as +universal select a build, not a package
+				if !strings.HasPrefix(a, "+") && !strings.Contains(a, "=") {
+					add("port", a, "")
+				}
+			}
+		}
+	case "snap":
+		if sub == "install" {
+			for _, a := range positional(rest, "--channel", "--revision") {
+				add("snap", a, flagValue(rest, "--revision"))
+			}
+		}
+	case "choco":
+		if sub == "install" || sub == "upgrade" {
+			version := flagValue(rest, "--version")
+			for _, a := range positional(rest, "--version", "-s", "--source", "--params", "--package-parameters", "--install-arguments") {
+				add("choco", a, version)
+			}
+		}
+	case "winget":
+		if sub == "install" || sub == "add" {
+			version := flagValue(rest, "-v", "--version")
+			if id := flagValue(rest, "--id"); id != "" {
+				add("winget", id, version)
+				break
+			}
+			for _, a := range positional(rest, "-v", "--version", "-s", "--source", "--scope", "-l", "--location", "--override", "--name", "--moniker", "-n") {
+				add("winget", a, version)
+			}
+		}
+	case "scoop":
+		if sub == "install" {
+			for _, a := range positional(rest, "-a", "--arch") {
+				name, version, _ := strings.Cut(a, "@")
+				add("scoop", name, version)
+			}
+		}
+	}
+	return uses
+}
+
+// packageFile reports whether an install argument is a package file or URL
+// rather than a package of the manager's repositories
+func packageFile(a string) bool {
+	for _, ext := range []string{".deb", ".rpm", ".apk"} {
+		if strings.HasSuffix(a, ext) {
+			return true
+		}
+	}
+	return strings.Contains(a, "://")
+}
+
+// packageOS is where a package manager command can install: the OS its
+// command may run on, narrowed to those the manager exists on. A manager
+// missing from every OS the task declares is counted where it exists, as
+// the portability check already reports the contradiction.
+func packageOS(c taskCmd, tool string) []string {
+	targets, _ := targetOS(c.task, c.cmd)
+	supported, ok := toolOS[tool]
+	if !ok {
+		return targets
+	}
+	var in []string
+	for _, target := range targets {
+		if slices.Contains(supported, target) {
+			in = append(in, target)
+		}
+	}
+	if len(in) == 0 {
+		return supported
+	}
+	return in
+}
+
+// packagesByOS consolidates the installs of every task into what each OS
+// gets, a package once per manager and version, sorted by manager and name
+func packagesByOS(uses []packageUse) map[string][]osPackage {
+	type key struct{ os, manager, name, version string }
+	index := make(map[key]int)
+	byOS := make(map[string][]osPackage)
+	for _, u := range uses {
+		for _, target := range u.OS {
+			k := key{target, u.Manager, u.Name, u.Version}
+			i, ok := index[k]
+			if !ok {
+				i = len(byOS[target])
+				index[k] = i
+				byOS[target] = append(byOS[target], osPackage{Manager: u.Manager, Name: u.Name, Version: u.Version})
+			}
+			if p := &byOS[target][i]; !slices.Contains(p.Tasks, u.Task) {
+				p.Tasks = append(p.Tasks, u.Task)
+			}
+		}
+	}
+	for _, pkgs := range byOS {
+		sort.SliceStable(pkgs, func(i, j int) bool {
+			if pkgs[i].Manager != pkgs[j].Manager {
+				return pkgs[i].Manager < pkgs[j].Manager
+			}
+			if pkgs[i].Name != pkgs[j].Name {
+				return pkgs[i].Name < pkgs[j].Name
+			}
+			return pkgs[i].Version < pkgs[j].Version
+		})
+	}
+	return byOS
+}
+
+// positional returns the arguments that are not options, skipping the
+// values of the given options that take one
+func positional(args []string, valueFlags ...string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if slices.Contains(valueFlags, a) {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(a, "-") {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// flagValue returns the value given to any of the named options, as in
+// --version 1.2 or --version=1.2
+func flagValue(args []string, names ...string) string {
+	for i, a := range args {
+		if slices.Contains(names, a) && i+1 < len(args) {
+			return args[i+1]
+		}
+		if name, v, ok := strings.Cut(a, "="); ok && slices.Contains(names, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// pipSpec splits a requirement such as bar==2.0 or bar>=1,<2. An exact ==
+// pin is returned as the bare version, any other specifier as written.
+func pipSpec(spec string) (string, string) {
+	i := strings.IndexAny(spec, "=<>!~")
+	if i < 0 {
+		return spec, ""
+	}
+	name, version := spec[:i], spec[i:]
+	if strings.HasPrefix(version, "==") && !strings.ContainsAny(version[2:], "=<>!~,*") {
+		version = version[2:]
+	}
+	// Extras such as bar[cli]==2.0 belong to the same package
+	name, _, _ = strings.Cut(name, "[")
+	return name, version
+}
+
+// npmSpec splits pkg@1.2 and @scope/pkg@1.2; the @ of a scope is not a
+// version separator
+func npmSpec(spec string) (string, string) {
+	if i := strings.LastIndex(spec, "@"); i > 0 {
+		return spec[:i], spec[i+1:]
+	}
+	return spec, ""
+}
+
+// exactVersion reports whether a version names one release rather than a
+// range or a moving target like latest
+func exactVersion(v string) bool {
+	return v != "" && v != "latest" && !strings.ContainsAny(v, "=<>!~^*, ")
+}
+
+// versionConflicts finds packages pinned to more than one exact version
+func versionConflicts(uses []packageUse) []versionConflict {
+	type key struct{ manager, name string }
+	pins := make(map[key]map[string][]string)
+	for _, u := range uses {
+		if !exactVersion(u.Version) {
+			continue
+		}
+		k := key{u.Manager, u.Name}
+		if pins[k] == nil {
+			pins[k] = make(map[string][]string)
+		}
+		if !slices.Contains(pins[k][u.Version], u.Task) {
+			pins[k][u.Version] = append(pins[k][u.Version], u.Task)
+		}
+	}
+
+	conflicts := []versionConflict{}
+	for k, versions := range pins {
+		if len(versions) > 1 {
+			conflicts = append(conflicts, versionConflict{Manager: k.manager, Name: k.name, Versions: versions})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Manager != conflicts[j].Manager {
+			return conflicts[i].Manager < conflicts[j].Manager
+		}
+		return conflicts[i].Name < conflicts[j].Name
+	})
+	return conflicts
+}
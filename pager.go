@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+
+	"golang.org/x/term"
+)
+
+// defaultPager is run when $PAGER is unset
+const defaultPager = "less -R"
+
+// pageOutput captures everything printed to stdout from here on. The
+// returned function, called once the report is complete, shows it through
+// $PAGER when it is taller than the terminal and prints it directly
+// otherwise. Nothing is captured unless stdout is a terminal.
+func pageOutput(disabled bool) func() error {
+	out := os.Stdout
+	if disabled || !term.IsTerminal(int(out.Fd())) {
+		return func() error { return nil }
+	}
+	_, height, err := term.GetSize(int(out.Fd()))
+	if err != nil {
+		return func() error { return nil }
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return func() error { return nil }
+	}
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&buf, r)
+		close(done)
+	}()
+	os.Stdout = w
+
+	return func() error {
+		os.Stdout = out
+		w.Close()
+		<-done
+		r.Close()
+
+		if bytes.Count(buf.Bytes(), []byte("\n")) < height {
+			_, err := out.Write(buf.Bytes())
+			return err
+		}
+		pager := os.Getenv("PAGER")
+		if pager == "" {
+			pager = defaultPager
+		}
+		cmd := exec.Command("sh", "-c", pager)
+		cmd.Stdin = &buf
+		cmd.Stdout = out
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			// A missing or broken pager must not lose the report
+			if _, ok := err.(*exec.ExitError); !ok {
+				_, err = out.Write(buf.Bytes())
+				return err
+			}
+		}
+		return nil
+	}
+}
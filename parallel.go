@@ -0,0 +1,38 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parallelMap calls fn for every index below n on a pool of GOMAXPROCS
+// workers and returns the results in index order, so what the analysis
+// passes print does not depend on scheduling
+func parallelMap[T any](n int, fn func(i int) T) []T {
+	results := make([]T, n)
+	workers := min(runtime.GOMAXPROCS(0), n)
+	if workers <= 1 {
+		for i := range n {
+			results[i] = fn(i)
+		}
+		return results
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	regoloader "github.com/open-policy-agent/opa/v1/loader"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+)
+
+// policyRules are the rules read from every package of a policy, with the
+// severity their results are reported at. A result is a message, or an
+// object with a msg and the task it concerns:
+//
+//	package taskfile.sudo
+//
+//	deny contains {"msg": "runs sudo outside the root namespace", "task": t.name} if {
+//		some t in input.tasks
+//		t.namespace != ""
+//		some cmd in t.cmds
+//		contains(cmd, "sudo ")
+//	}
+var policyRules = []struct{ name, severity string }{
+	{"deny", severityError},
+	{"violation", severityError},
+	{"warn", severityWarning},
+}
+
+// runPolicy evaluates Rego policies against the graph. The input is the
+// document external analyzers read, and any deny or violation fails the run.
+func runPolicy(args []string) error {
+	if len(args) == 0 || args[0] != "eval" {
+		return fmt.Errorf("usage: policy eval [flags]")
+	}
+	var opts options
+	fs := flag.NewFlagSet("policy eval", flag.ExitOnError)
+	opts.register(fs)
+	paths := fs.String("policy", "policies", "Comma-separated Rego files or directories, with any JSON or YAML data beside them")
+	format := fs.String("format", "text", "Output format: text or json")
+	if err := parseFlags(fs, &opts, args[1:]); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	// Policies are compiled before the graph is read, so a broken one fails fast
+	p, err := loadPolicies(strings.Split(*paths, ","))
+	if err != nil {
+		return err
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	ws, err := openWorkspace(l)
+	if err != nil {
+		return err
+	}
+	input, err := ws.analyzerInput()
+	if err != nil {
+		return err
+	}
+	findings, err := p.eval(ws, input)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		type located struct {
+			finding
+			Location string `json:"location,omitempty"`
+		}
+		results := []located{}
+		for _, f := range findings {
+			results = append(results, located{f, f.Pos.String()})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		writeFindings(os.Stdout, findings)
+	}
+	denied := 0
+	for _, f := range findings {
+		if f.Severity == severityError {
+			denied++
+		}
+	}
+	if denied > 0 {
+		return &failure{msg: fmt.Sprintf("%s denied by policy", plural(denied, "finding"))}
+	}
+	return nil
+}
+
+// policySet is the compiled policies with the packages they define
+type policySet struct {
+	compiler *ast.Compiler
+	data     map[string]any
+	packages []string // data.x.y paths, sorted
+}
+
+func loadPolicies(paths []string) (*policySet, error) {
+	result, err := regoloader.NewFileLoader().WithRegoVersion(ast.RegoV1).All(paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policies: %w", err)
+	}
+	modules := result.ParsedModules()
+	if len(modules) == 0 {
+		return nil, fmt.Errorf("no Rego policies in %s", strings.Join(paths, ", "))
+	}
+	compiler := ast.NewCompiler()
+	if compiler.Compile(modules); compiler.Failed() {
+		return nil, fmt.Errorf("failed to compile policies: %w", compiler.Errors)
+	}
+	p := &policySet{compiler: compiler, data: result.Documents}
+	for _, m := range modules {
+		if pkg := m.Package.Path.String(); !slices.Contains(p.packages, pkg) {
+			p.packages = append(p.packages, pkg)
+		}
+	}
+	slices.Sort(p.packages)
+	return p, nil
+}
+
+// eval queries the rules of every package and turns their results into
+// findings, placed at the task a result names
+func (p *policySet) eval(ws *workspace, input *analyzerInput) ([]finding, error) {
+	// The input goes through JSON so policies see the field names analyzers do
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	store := inmem.NewFromObject(p.data)
+	var findings []finding
+	for _, pkg := range p.packages {
+		for _, rule := range policyRules {
+			query := pkg + "." + rule.name
+			rs, err := rego.New(
+				rego.Query(query),
+				rego.Compiler(p.compiler),
+				rego.Store(store),
+				rego.Input(doc),
+			).Eval(context.Background())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", query, err)
+			}
+			name := strings.TrimPrefix(query, "data.")
+			for _, r := range rs {
+				for _, e := range r.Expressions {
+					results, ok := e.Value.([]any)
+					if !ok {
+						// A rule of another shape, such as a boolean, is not a finding
+						continue
+					}
+					for _, v := range results {
+						findings = append(findings, policyFinding(ws, name, rule.severity, v))
+					}
+				}
+			}
+		}
+	}
+	sortFindings(findings)
+	return findings, nil
+}
+
+// policyFinding reads one result of a rule. Results without a task are
+// placed at the root Taskfile.
+func policyFinding(ws *workspace, rule, severity string, v any) finding {
+	f := finding{Rule: rule, Severity: severity}
+	switch v := v.(type) {
+	case string:
+		f.Message = v
+	case map[string]any:
+		f.Message, _ = v["msg"].(string)
+		if f.Message == "" {
+			f.Message, _ = v["message"].(string)
+		}
+		f.Task, _ = v["task"].(string)
+	}
+	if f.Message == "" {
+		data, _ := json.Marshal(v)
+		f.Message = string(data)
+	}
+	if _, task, ok := ws.resolve(f.Task); ok && f.Task != "" {
+		f.Pos = taskPos(task)
+	} else {
+		root, _ := rootURI(ws.graph)
+		f.Pos = sourcePos{File: root}
+	}
+	return f
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// allOS are the operating systems a task without platforms may run on
+var allOS = []string{"darwin", "linux", "windows"}
+
+// toolOS lists tools that only exist on some operating systems
+var toolOS = map[string][]string{
+	// Linux package managers and system tools
+	"apt": {"linux"}, "apt-get": {"linux"}, "aptitude": {"linux"}, "dpkg": {"linux"},
+	"yum": {"linux"}, "dnf": {"linux"}, "zypper": {"linux"}, "pacman": {"linux"},
+	"apk": {"linux"}, "snap": {"linux"}, "systemctl": {"linux"}, "journalctl": {"linux"},
+	"update-alternatives": {"linux"},
+	// macOS
+	"brew": {"darwin", "linux"}, "port": {"darwin"}, "launchctl": {"darwin"},
+	"defaults": {"darwin"}, "pbcopy": {"darwin"}, "pbpaste": {"darwin"},
+	"osascript": {"darwin"}, "xcode-select": {"darwin"}, "softwareupdate": {"darwin"},
+	"hdiutil": {"darwin"},
+	// Windows
+	"choco": {"windows"}, "winget": {"windows"}, "scoop": {"windows"},
+	"powershell": {"windows"}, "powershell.exe": {"windows"}, "cmd": {"windows"},
+	"cmd.exe": {"windows"}, "reg": {"windows"}, "msiexec": {"windows"},
+}
+
+// flagRule is a flag that only one implementation of a tool understands
+type flagRule struct {
+	tool   string
+	match  func(args []string) bool
+	only   string // the flavor that accepts it
+	breaks string // the OS whose default tool rejects it
+}
+
+// flagRules are GNU-only and BSD-only flags of common tools. GNU tools are
+// the default on linux, BSD ones on darwin.
+var flagRules = []flagRule{
+	{"sed", func(a []string) bool { return sedInPlaceSuffix(a) == "gnu" }, "GNU sed -i without a suffix", "darwin"},
+	{"sed", func(a []string) bool { return sedInPlaceSuffix(a) == "bsd" }, "BSD sed -i ''", "linux"},
+	{"date", hasFlag("-d", "--date"), "GNU date -d", "darwin"},
+	{"date", hasFlag("-j"), "BSD date -j", "linux"},
+	{"stat", hasFlag("-c", "--format"), "GNU stat -c", "darwin"},
+	{"stat", hasFlag("-f"), "BSD stat -f", "linux"},
+	{"grep", hasFlag("-P", "--perl-regexp"), "GNU grep -P", "darwin"},
+	{"find", hasFlag("-printf"), "GNU find -printf", "darwin"},
+	{"base64", hasFlag("-w", "--wrap"), "GNU base64 -w", "darwin"},
+}
+
+// hasFlag matches commands passing any of the given flags
+func hasFlag(flags ...string) func([]string) bool {
+	return func(args []string) bool {
+		for _, a := range args {
+			if slices.Contains(flags, a) || strings.HasPrefix(a, "--") && slices.Contains(flags, strings.SplitN(a, "=", 2)[0]) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// sedInPlaceSuffix tells which sed accepts an in-place edit as written: a
+// bare -i followed by the script is GNU-only, -i followed by an empty
+// suffix is BSD-only, and -i.bak works with both
+func sedInPlaceSuffix(args []string) string {
+	for i, a := range args {
+		if a != "-i" {
+			continue
+		}
+		if i+1 < len(args) && args[i+1] == "" {
+			return "bsd"
+		}
+		return "gnu"
+	}
+	return ""
+}
+
+// targetOS is where a command may run: its own platforms, else its task's,
+// else everywhere. Platforms naming only an architecture allow every OS.
+func targetOS(task *ast.Task, cmd *ast.Cmd) ([]string, bool) {
+	platforms := cmd.Platforms
+	if len(platforms) == 0 {
+		platforms = task.Platforms
+	}
+	if len(platforms) == 0 {
+		return allOS, false
+	}
+	var targets []string
+	for _, p := range platforms {
+		if p.OS == "" {
+			return allOS, true
+		}
+		if !slices.Contains(targets, p.OS) {
+			targets = append(targets, p.OS)
+		}
+	}
+	return targets, true
+}
+
+// checkPortability flags commands that cannot work on every OS their task
+// may run on. Tasks that declare platforms contradicting their commands get
+// errors; tasks that declare none get warnings.
+func checkPortability(ws *workspace) []finding {
+	var findings []finding
+	for _, c := range ws.shellCmds() {
+		targets, declared := targetOS(c.task, c.cmd)
+		severity := severityWarning
+		advice := "; declare platforms or guard the command"
+		if declared {
+			severity = severityError
+			advice = ""
+		}
+		report := func(message string) {
+			findings = append(findings, finding{
+				Rule:     "portability",
+				Severity: severity,
+				Task:     c.name,
+				Message:  message + advice,
+				Pos:      c.pos,
+			})
+		}
+
+		for _, words := range splitCommands(c.cmd.Cmd) {
+			tool, args := commandTool(words)
+			if supported, ok := toolOS[tool]; ok {
+				var broken []string
+				for _, target := range targets {
+					if !slices.Contains(supported, target) {
+						broken = append(broken, target)
+					}
+				}
+				if len(broken) > 0 {
+					report(fmt.Sprintf("%s only exists on %s but the task may run on %s",
+						tool, strings.Join(supported, ", "), strings.Join(broken, ", ")))
+				}
+			}
+			for _, rule := range flagRules {
+				if rule.tool == tool && slices.Contains(targets, rule.breaks) && rule.match(args) {
+					report(fmt.Sprintf("%s fails with the default %s on %s, where the task may run", rule.only, tool, rule.breaks))
+				}
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// sourcePos is a position in a Taskfile, printed as file:line:col so
+// editors and terminals can jump to it
+type sourcePos struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p sourcePos) String() string {
+	switch {
+	case p.File == "":
+		return ""
+	case p.Line == 0:
+		return displayPath(p.File)
+	default:
+		return fmt.Sprintf("%s:%d:%d", displayPath(p.File), p.Line, p.Column)
+	}
+}
+
+// MarshalText makes the position a file:line:col string in JSON, as tools
+// annotating a change read it
+func (p sourcePos) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// taskPos is where a merged task is defined
+func taskPos(task *ast.Task) sourcePos {
+	if task.Location == nil {
+		return sourcePos{}
+	}
+	return sourcePos{File: task.Location.Taskfile, Line: task.Location.Line, Column: task.Location.Column}
+}
+
+// sourceIndex finds the YAML nodes behind merged tasks, which only carry the
+// position of their key, so deps and commands can be located as well. Files
+// are parsed once, on first use, and the index is safe for concurrent use.
+type sourceIndex struct {
+	l     *loader
+	mu    sync.Mutex
+	tasks map[string]map[[2]int]taskSource // file URI -> key line and column -> task
+}
+
+// taskSource is the key and value of one task in its Taskfile
+type taskSource struct {
+	key, value *yaml.Node
+}
+
+func newSourceIndex(l *loader) *sourceIndex {
+	return &sourceIndex{l: l, tasks: make(map[string]map[[2]int]taskSource)}
+}
+
+// node returns the YAML value defining a merged task, or nil if its file
+// cannot be read
+func (x *sourceIndex) node(task *ast.Task) *yaml.Node {
+	return x.source(task).value
+}
+
+// source returns the YAML key and value of a merged task; both are nil if
+// its file cannot be read
+func (x *sourceIndex) source(task *ast.Task) taskSource {
+	if x == nil || task.Location == nil {
+		return taskSource{}
+	}
+	uri := task.Location.Taskfile
+	x.mu.Lock()
+	byKey, ok := x.tasks[uri]
+	if !ok {
+		byKey = x.l.parseTasks(uri)
+		x.tasks[uri] = byKey
+	}
+	x.mu.Unlock()
+	return byKey[[2]int{task.Location.Line, task.Location.Column}]
+}
+
+// parseTasks indexes the task keys of a file by line and column, which
+// tell apart the keys of a flow mapping on one line. Parses are kept by
+// content hash, so a file that has not changed is parsed once per process
+// however often the graph is reloaded.
+func (l *loader) parseTasks(uri string) map[[2]int]taskSource {
+	src, err := l.readSource(uri)
+	if err != nil {
+		return nil
+	}
+	hash := sha256.Sum256(src)
+	l.sourcesMu.Lock()
+	defer l.sourcesMu.Unlock()
+	if byKey, ok := l.sources[hash]; ok {
+		return byKey
+	}
+
+	byKey := make(map[[2]int]taskSource)
+	var doc yaml.Node
+	if yaml.Unmarshal(src, &doc) == nil {
+		if tasks := mappingValue(documentRoot(&doc), "tasks"); tasks != nil {
+			for i := 0; i+1 < len(tasks.Content); i += 2 {
+				key := tasks.Content[i]
+				byKey[[2]int{key.Line, key.Column}] = taskSource{key, tasks.Content[i+1]}
+			}
+		}
+	}
+	if l.sources == nil {
+		l.sources = make(map[[sha256.Size]byte]map[[2]int]taskSource)
+	}
+	l.sources[hash] = byKey
+	return byKey
+}
+
+// depPos is where the i-th dependency of a task is written
+func (x *sourceIndex) depPos(task *ast.Task, i int) sourcePos {
+	node := x.node(task)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return taskPos(task)
+	}
+	return x.itemPos(task, mappingValue(node, "deps"), i)
+}
+
+// cmdPos is where the i-th command of a task is written. Shorthand tasks
+// are their own command list.
+func (x *sourceIndex) cmdPos(task *ast.Task, i int) sourcePos {
+	node := x.node(task)
+	if node == nil {
+		return taskPos(task)
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return sourcePos{File: task.Location.Taskfile, Line: node.Line, Column: node.Column}
+	case yaml.MappingNode:
+		cmds := mappingValue(node, "cmds")
+		if cmds == nil {
+			cmds = mappingValue(node, "cmd")
+		}
+		if cmds != nil && cmds.Kind == yaml.ScalarNode {
+			return sourcePos{File: task.Location.Taskfile, Line: cmds.Line, Column: cmds.Column}
+		}
+		node = cmds
+	}
+	return x.itemPos(task, node, i)
+}
+
+// itemPos is the position of the i-th entry of a sequence, falling back to
+// the task itself when the sequence does not line up
+func (x *sourceIndex) itemPos(task *ast.Task, seq *yaml.Node, i int) sourcePos {
+	if seq == nil || seq.Kind != yaml.SequenceNode || i >= len(seq.Content) {
+		return taskPos(task)
+	}
+	item := seq.Content[i]
+	return sourcePos{File: task.Location.Taskfile, Line: item.Line, Column: item.Column}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// prefixGroup is one segment of the task name hierarchy: install in
+// install:go:gopls, containing install:go and its tasks. Task is set when a
+// task is named exactly by the group's path.
+type prefixGroup struct {
+	Path     string
+	Task     bool
+	Count    int // tasks at or below this group
+	Children []*prefixGroup
+}
+
+// buildPrefixTree arranges task names by their namespace-separated segments
+func buildPrefixTree(names []string) *prefixGroup {
+	root := &prefixGroup{}
+	for _, name := range names {
+		g := root
+		g.Count++
+		segments := strings.Split(name, ast.NamespaceSeparator)
+		for i := range segments {
+			path := strings.Join(segments[:i+1], ast.NamespaceSeparator)
+			var child *prefixGroup
+			for _, c := range g.Children {
+				if c.Path == path {
+					child = c
+					break
+				}
+			}
+			if child == nil {
+				child = &prefixGroup{Path: path}
+				g.Children = append(g.Children, child)
+			}
+			child.Count++
+			g = child
+		}
+		g.Task = true
+	}
+	root.sort()
+	return root
+}
+
+func (g *prefixGroup) sort() {
+	sort.Slice(g.Children, func(i, j int) bool { return g.Children[i].Path < g.Children[j].Path })
+	for _, c := range g.Children {
+		c.sort()
+	}
+}
+
+// leaves reports whether every child of a group is a plain task
+func (g *prefixGroup) leaves() bool {
+	for _, c := range g.Children {
+		if len(c.Children) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// writePrefixTree prints the hierarchy indented by depth. A family of more
+// than collapse tasks with no subfamilies of its own is shown as one
+// prefix:* line with its size, so the structure above stays visible; zero
+// expands everything.
+func writePrefixTree(w io.Writer, root *prefixGroup, collapse int) {
+	var walk func(g *prefixGroup, depth int)
+	walk = func(g *prefixGroup, depth int) {
+		indent := strings.Repeat("  ", depth)
+		family := len(g.Children) > 0
+		switch {
+		case family && collapse > 0 && g.Count > collapse && g.leaves():
+			fmt.Fprintf(w, "%s%s%s* (%d tasks, folded)\n", indent, g.Path, ast.NamespaceSeparator, g.Count)
+			return
+		case family && g.Task:
+			fmt.Fprintf(w, "%s%s (+%d below)\n", indent, g.Path, g.Count-1)
+		case family:
+			fmt.Fprintf(w, "%s%s%s* (%d tasks)\n", indent, g.Path, ast.NamespaceSeparator, g.Count)
+		default:
+			fmt.Fprintf(w, "%s%s\n", indent, g.Path)
+		}
+		for _, c := range g.Children {
+			walk(c, depth+1)
+		}
+	}
+	for _, c := range root.Children {
+		walk(c, 0)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	rpprof "runtime/pprof"
+)
+
+// profiler records the --cpuprofile and --memprofile of one run, for go
+// tool pprof. A nil *profiler records nothing.
+type profiler struct {
+	cpu     *os.File
+	memPath string
+}
+
+// startProfiles starts CPU profiling when cpuPath is set. The heap profile
+// is only written on Stop, so it shows what the run still holds at the end.
+func startProfiles(cpuPath, memPath string) (*profiler, error) {
+	if cpuPath == "" && memPath == "" {
+		return nil, nil
+	}
+	p := &profiler{memPath: memPath}
+	if cpuPath != "" {
+		f, err := os.Create(cpuPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		if err := rpprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		p.cpu = f
+	}
+	return p, nil
+}
+
+// Stop finishes the CPU profile and writes the heap profile
+func (p *profiler) Stop() error {
+	if p == nil {
+		return nil
+	}
+	if p.cpu != nil {
+		rpprof.StopCPUProfile()
+		if err := p.cpu.Close(); err != nil {
+			return err
+		}
+	}
+	if p.memPath == "" {
+		return nil
+	}
+	f, err := os.Create(p.memPath)
+	if err != nil {
+		return fmt.Errorf("failed to create memory profile: %w", err)
+	}
+	defer f.Close()
+	// Collect first, so the profile has up-to-date allocation statistics
+	runtime.GC()
+	return rpprof.WriteHeapProfile(f)
+}
+
+// registerPprof serves the runtime profiles under /debug/pprof/, as the
+// net/http/pprof package does on the default mux
+func registerPprof(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
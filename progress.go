@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// progressLogInterval is how often in-flight downloads are logged in non-TTY mode
+const progressLogInterval = 5 * time.Second
+
+// progressReporter reports per-file download progress, either as a redrawn
+// status line on a terminal or as periodic log lines (e.g. in CI)
+type progressReporter struct {
+	mu  sync.Mutex
+	out io.Writer
+	tty bool
+}
+
+// newProgressReporter builds a reporter for mode "auto", "bar", "log" or
+// "off". It returns nil when progress is disabled.
+func newProgressReporter(mode string, out *os.File) (*progressReporter, error) {
+	switch mode {
+	case "off":
+		return nil, nil
+	case "auto":
+		return &progressReporter{out: out, tty: term.IsTerminal(int(out.Fd()))}, nil
+	case "bar":
+		return &progressReporter{out: out, tty: true}, nil
+	case "log":
+		return &progressReporter{out: out, tty: false}, nil
+	default:
+		return nil, fmt.Errorf("unknown progress mode %q (want auto, bar, log or off)", mode)
+	}
+}
+
+// track wraps body so that reading it reports progress for the named file
+func (p *progressReporter) track(name string, size int64, body io.ReadCloser, start time.Time) io.ReadCloser {
+	return &progressBody{ReadCloser: body, p: p, name: name, size: size, start: start, lastLog: start}
+}
+
+func (p *progressReporter) update(b *progressBody) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tty {
+		fmt.Fprintf(p.out, "\r\033[K%s", b.status())
+		return
+	}
+	if time.Since(b.lastLog) >= progressLogInterval {
+		b.lastLog = time.Now()
+		fmt.Fprintf(p.out, "fetching %s\n", b.status())
+	}
+}
+
+func (p *progressReporter) finish(b *progressBody) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tty {
+		fmt.Fprintf(p.out, "\r\033[K%s\n", b.status())
+		return
+	}
+	fmt.Fprintf(p.out, "fetched %s\n", b.status())
+}
+
+// progressBody counts bytes as the reader consumes a response body
+type progressBody struct {
+	io.ReadCloser
+	p       *progressReporter
+	name    string
+	size    int64
+	read    int64
+	start   time.Time
+	lastLog time.Time
+	done    bool
+}
+
+func (b *progressBody) Read(buf []byte) (int, error) {
+	n, err := b.ReadCloser.Read(buf)
+	b.read += int64(n)
+	if err == io.EOF {
+		b.markDone()
+	} else if n > 0 {
+		b.p.update(b)
+	}
+	return n, err
+}
+
+func (b *progressBody) Close() error {
+	b.markDone()
+	return b.ReadCloser.Close()
+}
+
+func (b *progressBody) markDone() {
+	if b.done {
+		return
+	}
+	b.done = true
+	b.p.finish(b)
+}
+
+// status renders name, size and elapsed time, with a bar when the size is known
+func (b *progressBody) status() string {
+	elapsed := time.Since(b.start).Round(time.Millisecond)
+	if b.size <= 0 {
+		return fmt.Sprintf("%s %s %s", b.name, formatBytes(b.read), elapsed)
+	}
+	const width = 20
+	filled := int(int64(width) * b.read / b.size)
+	filled = min(filled, width)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", width-filled)
+	return fmt.Sprintf("%s [%s] %s/%s %s", b.name, bar, formatBytes(b.read), formatBytes(b.size), elapsed)
+}
+
+// formatBytes renders a byte count using binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
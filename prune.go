@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+	"golang.org/x/term"
+)
+
+// runPrune walks through unreferenced tasks, lets the user pick the ones to
+// delete and emits a unified diff removing them from their local Taskfiles
+func runPrune(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	opts.register(fs)
+	yes := fs.Bool("yes", false, "Select every unreferenced task without prompting")
+	patchFile := fs.String("patch", "-", "Where to write the removal patch (- for stdout)")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	_, merged, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	orphans := findOrphanTasks(merged)
+	if len(orphans) == 0 {
+		fmt.Fprintf(os.Stderr, "No unreferenced tasks found\n")
+		return nil
+	}
+
+	interactive := !*yes && term.IsTerminal(int(os.Stdin.Fd()))
+	if !*yes && !interactive {
+		return fmt.Errorf("stdin is not a terminal; pass --yes to select every unreferenced task")
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	selected := make(map[string][]*ast.Task)
+	for _, task := range orphans {
+		uri := task.Location.Taskfile
+		describeOrphan(os.Stderr, task)
+		if taskfile.IsRemoteEntrypoint(uri) {
+			fmt.Fprintf(os.Stderr, "  (defined in a remote Taskfile, cannot be removed)\n\n")
+			continue
+		}
+		if interactive {
+			fmt.Fprintf(os.Stderr, "Remove %s? [y/N] ", task.Task)
+			answer, _ := in.ReadString('\n')
+			if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+				fmt.Fprintf(os.Stderr, "\n")
+				continue
+			}
+		}
+		fmt.Fprintf(os.Stderr, "\n")
+		selected[uri] = append(selected[uri], task)
+	}
+
+	out := io.Writer(os.Stdout)
+	if *patchFile != "-" {
+		f, err := os.Create(*patchFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	uris := make([]string, 0, len(selected))
+	for uri := range selected {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		src, err := os.ReadFile(uri)
+		if err != nil {
+			return err
+		}
+		ranges, err := taskLineRanges(src, selected[uri])
+		if err != nil {
+			return fmt.Errorf("%s: %w", uri, err)
+		}
+		fmt.Fprint(out, deletionPatch(displayPath(uri), src, ranges))
+	}
+
+	return nil
+}
+
+// findOrphanTasks returns tasks that no other task references and that do
+// not look like entry points: they are internal or have no description.
+// The default task is never an orphan, nor the default of an include, which
+// task lib runs as lib:default.
+func findOrphanTasks(tf *ast.Taskfile) []*ast.Task {
+	referenced := referencedTasks(tf)
+	var orphans []*ast.Task
+	for name, task := range tf.Tasks.All(byName) {
+		if name == "default" || strings.HasSuffix(name, ast.NamespaceSeparator+"default") || referenced[name] {
+			continue
+		}
+		if task.Internal || task.Desc == "" {
+			orphans = append(orphans, task)
+		}
+	}
+	return orphans
+}
+
+// referencedTasks returns the set of tasks called by some other task, with
+// references made through aliases resolved to the real task name
+func referencedTasks(tf *ast.Taskfile) map[string]bool {
+	aliases := make(map[string]string)
+	for name, task := range tf.Tasks.All(byName) {
+		for _, alias := range task.Aliases {
+			aliases[alias] = name
+		}
+	}
+
+	referenced := make(map[string]bool)
+	for name, deps := range buildTaskDependencyGraph(tf) {
+		for _, dep := range deps {
+			if real, ok := aliases[dep]; ok {
+				dep = real
+			}
+			if dep != name {
+				referenced[dep] = true
+			}
+		}
+	}
+	return referenced
+}
+
+// rootTasks returns the tasks no other task calls that can be run directly,
+// the entry points of the workflows in tf
+func rootTasks(tf *ast.Taskfile) []string {
+	referenced := referencedTasks(tf)
+	var roots []string
+	for name, task := range tf.Tasks.All(byName) {
+		if !referenced[name] && !task.Internal {
+			roots = append(roots, name)
+		}
+	}
+	return roots
+}
+
+// describeOrphan prints a task's location, commands and last-modified date
+func describeOrphan(w io.Writer, task *ast.Task) {
+	fmt.Fprintf(w, "%s (%s)\n", task.Task, taskPos(task))
+	if modified := lastModified(task.Location.Taskfile, task.Location.Line); modified != "" {
+		fmt.Fprintf(w, "  Last modified: %s\n", modified)
+	}
+	for _, cmd := range task.Cmds {
+		if cmd.Cmd != "" {
+			fmt.Fprintf(w, "  - cmd: %s\n", cmd.Cmd)
+		}
+		if cmd.Task != "" {
+			fmt.Fprintf(w, "  - task: %s\n", cmd.Task)
+		}
+	}
+}
+
+// lastModified reports when a line was last changed according to git blame,
+// falling back to the file's modification time outside a repository
+func lastModified(path string, line int) string {
+	if taskfile.IsRemoteEntrypoint(path) {
+		return ""
+	}
+	cmd := exec.Command("git", "blame", "--porcelain", "-L", fmt.Sprintf("%d,%d", line, line), "--", filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+	if out, err := cmd.Output(); err == nil {
+		var author, when string
+		for _, l := range strings.Split(string(out), "\n") {
+			if v, ok := strings.CutPrefix(l, "author "); ok {
+				author = v
+			}
+			if v, ok := strings.CutPrefix(l, "author-time "); ok {
+				if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+					when = time.Unix(secs, 0).Format("2006-01-02")
+				}
+			}
+		}
+		if when != "" {
+			return fmt.Sprintf("%s by %s", when, author)
+		}
+	}
+	if info, err := os.Stat(path); err == nil {
+		return info.ModTime().Format("2006-01-02") + " (file mtime)"
+	}
+	return ""
+}
+
+// lineRange is an inclusive, 1-based range of lines
+type lineRange struct {
+	start, end int
+}
+
+// taskLineRanges finds the block of lines that defines each task in src.
+// Trailing blank and comment lines are left in place since they usually
+// belong to whatever follows.
+func taskLineRanges(src []byte, tasks []*ast.Task) ([]lineRange, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, err
+	}
+	top := documentRoot(&doc)
+	mapping := mappingValue(top, "tasks")
+	if mapping == nil || mapping.Style&yaml.FlowStyle != 0 {
+		return nil, fmt.Errorf("tasks must be a block mapping to be edited")
+	}
+
+	lines := strings.Split(string(src), "\n")
+
+	// The tasks section ends where the next top-level key starts
+	sectionEnd := len(lines)
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		if top.Content[i+1] == mapping && i+2 < len(top.Content) {
+			sectionEnd = top.Content[i+2].Line - 1
+		}
+	}
+
+	// Merged tasks carry the line of their key, which identifies them even
+	// when the namespaces have renamed them
+	wanted := make(map[int]bool)
+	for _, task := range tasks {
+		wanted[task.Location.Line] = true
+	}
+
+	var ranges []lineRange
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key := mapping.Content[i]
+		if !wanted[key.Line] {
+			continue
+		}
+		end := sectionEnd
+		if i+2 < len(mapping.Content) {
+			end = mapping.Content[i+2].Line - 1
+		}
+		for end > key.Line {
+			trimmed := strings.TrimSpace(lines[end-1])
+			if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				break
+			}
+			end--
+		}
+		ranges = append(ranges, lineRange{start: key.Line, end: end})
+	}
+	return ranges, nil
+}
+
+// deletionPatch renders a unified diff that deletes the given line ranges
+func deletionPatch(name string, src []byte, ranges []lineRange) string {
+	const context = 3
+	lines := strings.Split(strings.TrimSuffix(string(src), "\n"), "\n")
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", name, name)
+
+	removed := 0
+	for i := 0; i < len(ranges); {
+		// Group deletions whose context overlaps into one hunk
+		group := []lineRange{ranges[i]}
+		for i++; i < len(ranges) && ranges[i].start-group[len(group)-1].end <= 2*context+1; i++ {
+			group = append(group, ranges[i])
+		}
+
+		from := max(group[0].start-context, 1)
+		to := min(group[len(group)-1].end+context, len(lines))
+		deleted := 0
+		for _, r := range group {
+			deleted += r.end - r.start + 1
+		}
+		oldLen := to - from + 1
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", from, oldLen, from-removed, oldLen-deleted)
+
+		g := 0
+		for n := from; n <= to; n++ {
+			for g < len(group) && n > group[g].end {
+				g++
+			}
+			if g < len(group) && n >= group[g].start {
+				fmt.Fprintf(&b, "-%s\n", lines[n-1])
+			} else {
+				fmt.Fprintf(&b, " %s\n", lines[n-1])
+			}
+		}
+		removed += deleted
+	}
+	return b.String()
+}
+
+// displayPath shows local paths relative to the working directory
+func displayPath(uri string) string {
+	if uri == stdinLocation {
+		return "<stdin>"
+	}
+	if taskfile.IsRemoteEntrypoint(uri) {
+		return uri
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return uri
+	}
+	if rel, err := filepath.Rel(wd, uri); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return uri
+}
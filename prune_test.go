@@ -0,0 +1,38 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFindOrphanTasks(t *testing.T) {
+	_, _, merged := loadTest(t, map[string]string{
+		"Taskfile.yml": `version: '3'
+includes:
+  lib: ./sub
+tasks:
+  default:
+    cmds: [task: build]
+  build: echo build
+  unused: echo unused
+  documented:
+    desc: Run by hand
+    cmds: [echo documented]
+`,
+		"sub/Taskfile.yml": `version: '3'
+tasks:
+  default: echo lib
+  helper:
+    internal: true
+    cmds: [echo helper]
+`,
+	})
+	var got []string
+	for _, task := range findOrphanTasks(merged) {
+		got = append(got, task.Task)
+	}
+	slices.Sort(got)
+	if want := []string{"lib:helper", "unused"}; !slices.Equal(got, want) {
+		t.Errorf("findOrphanTasks() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// exportStep is one entry of a task's cmds as another runner runs it: a
+// shell command with its templates expanded, or a call of another task
+type exportStep struct {
+	Cmd   string
+	Task  string
+	Defer bool
+}
+
+// exportRecipe is a task as the runner exporters write it. A task whose
+// commands cannot be written out faithfully, because a template needs a
+// value only known at runtime or a call passes vars, loops or runs on some
+// platforms only, is delegated: its recipe runs it through task, which runs
+// its deps too.
+type exportRecipe struct {
+	Name     string
+	Desc     string
+	Internal bool
+	Dir      string        // relative to the root Taskfile, "" for its own
+	Env      []resolvedVar // beyond the global env
+	Deps     []string
+	Steps    []exportStep
+	Delegate bool
+	Why      string // what made it delegated
+}
+
+// exportPlan is what the exporters write: the starts it was made for, the
+// Taskfile's global env, which every recipe sees, and the recipes
+type exportPlan struct {
+	Starts  []string
+	Env     []resolvedVar
+	Recipes []exportRecipe
+}
+
+// runExport writes the task graph as the build file of another runner, for
+// environments that cannot install Task or for teams trying a migration
+func runExport(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	opts.register(fs)
+	makefile := fs.String("makefile", "", "Write a Makefile to this path (- for stdout)")
+	justfile := fs.String("justfile", "", "Write a justfile to this path (- for stdout)")
+	gha := fs.String("gha", "", "Write a GitHub Actions workflow to this path (- for stdout)")
+	runsOn := fs.String("runs-on", "ubuntu-latest", "Runner label of the workflow's jobs")
+	var starts taskNames
+	fs.Var(&starts, "start", "Only export the tasks reachable from this task (repeatable or comma-separated)")
+	delegate := fs.Bool("delegate", false, "Have every recipe run its task through task instead of embedding the commands")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	var path string
+	var write func(w io.Writer, plan exportPlan) error
+	chosen := 0
+	for _, p := range []string{*makefile, *justfile, *gha} {
+		if p != "" {
+			chosen++
+		}
+	}
+	switch {
+	case chosen != 1:
+		return fmt.Errorf("usage: export --makefile PATH|--justfile PATH|--gha PATH [flags]")
+	case *makefile != "":
+		path, write = *makefile, writeMakefile
+	case *justfile != "":
+		path, write = *justfile, writeJustfile
+	default:
+		path = *gha
+		write = func(w io.Writer, plan exportPlan) error { return writeWorkflow(w, plan, *runsOn) }
+		// A workflow is the graph under its starts
+		if len(starts) == 0 {
+			starts = taskNames{"default"}
+		}
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	ws, err := openWorkspace(l)
+	if err != nil {
+		return err
+	}
+	for i, start := range starts {
+		name, _, ok := ws.resolve(start)
+		if !ok {
+			return fmt.Errorf("task %q not found%s", start, didYouMean(start, ws.merged.Tasks))
+		}
+		starts[i] = name
+	}
+	plan := ws.exportPlan(starts, *delegate)
+
+	if path == "-" {
+		return write(os.Stdout, plan)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f, plan); err != nil {
+		f.Close()
+		return err
+	}
+	delegated := 0
+	for _, r := range plan.Recipes {
+		if r.Delegate {
+			delegated++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s to %s, %d run through task\n", plural(len(plan.Recipes), "task"), path, delegated)
+	return f.Close()
+}
+
+// exportPlan translates the tasks reachable from the starts, or every task
+// when there are none, in name order. Internal tasks are exported too, since
+// the others call them.
+func (ws *workspace) exportPlan(starts []string, delegate bool) exportPlan {
+	keep := func(string) bool { return true }
+	if len(starts) > 0 {
+		reached := make(map[string]bool)
+		var visit func(name string)
+		visit = func(name string) {
+			name, task, ok := ws.resolve(name)
+			if !ok || reached[name] {
+				return
+			}
+			reached[name] = true
+			for _, dep := range task.Deps {
+				visit(dep.Task)
+			}
+			for _, cmd := range task.Cmds {
+				if cmd.Task != "" {
+					visit(cmd.Task)
+				}
+			}
+		}
+		for _, start := range starts {
+			visit(start)
+		}
+		keep = func(name string) bool { return reached[name] }
+	}
+
+	root, _ := rootURI(ws.graph)
+	plan := exportPlan{Starts: starts, Env: newVarResolver("").add(ws.merged.Env)}
+	for name, task := range ws.merged.Tasks.All(byName) {
+		if !keep(name) {
+			continue
+		}
+		r := ws.recipe(name, task, filepath.Dir(root), plan.Env)
+		if delegate && !r.Delegate {
+			r.Delegate, r.Why = true, "--delegate"
+		}
+		plan.Recipes = append(plan.Recipes, r)
+	}
+	return plan
+}
+
+// recipe translates one task, marking it delegated at the first thing that
+// keeps its commands from being written out
+func (ws *workspace) recipe(name string, task *ast.Task, rootDir string, global []resolvedVar) exportRecipe {
+	r := exportRecipe{Name: name, Desc: task.Desc, Internal: task.Internal}
+	resolver, _, env := ws.taskVars(name, task)
+	delegateFor := func(why string) {
+		if !r.Delegate {
+			r.Delegate, r.Why = true, why
+		}
+	}
+	resolved := func(s string) string {
+		s = resolver.expand(s)
+		if strings.Contains(s, "{{") {
+			delegateFor("templates needing runtime values")
+		}
+		return s
+	}
+	call := func(ref string, vars *ast.Vars) string {
+		target, _, ok := ws.resolve(strings.TrimPrefix(ref, ast.NamespaceSeparator))
+		switch {
+		case strings.Contains(ref, "{{"):
+			delegateFor("a templated task name")
+		case !ok:
+			delegateFor("a call of missing task " + ref)
+		case vars != nil && vars.Len() > 0:
+			delegateFor("a call passing vars")
+		}
+		return target
+	}
+
+	if task.Dir != "" {
+		dir := resolved(task.Dir)
+		if rel, err := filepath.Rel(rootDir, dir); err == nil && filepath.IsAbs(dir) {
+			dir = rel
+		}
+		if dir != "." {
+			r.Dir = filepath.ToSlash(dir)
+		}
+	}
+	for _, v := range env {
+		if strings.Contains(v.Value, "{{") {
+			delegateFor("templates needing runtime values")
+		}
+		if !slices.Contains(global, v) {
+			r.Env = append(r.Env, v)
+		}
+	}
+	if len(task.Platforms) > 0 {
+		delegateFor("platforms")
+	}
+	for _, dep := range task.Deps {
+		if dep.For != nil {
+			delegateFor("a looping dep")
+		}
+		if target := call(dep.Task, dep.Vars); !slices.Contains(r.Deps, target) {
+			r.Deps = append(r.Deps, target)
+		}
+	}
+	for _, cmd := range task.Cmds {
+		if cmd.For != nil {
+			delegateFor("a looping command")
+		}
+		if len(cmd.Platforms) > 0 {
+			delegateFor("platforms")
+		}
+		switch {
+		case cmd.Task != "":
+			r.Steps = append(r.Steps, exportStep{Task: call(cmd.Task, cmd.Vars), Defer: cmd.Defer})
+		case cmd.Cmd != "":
+			r.Steps = append(r.Steps, exportStep{Cmd: strings.TrimRight(resolved(cmd.Cmd), "\n"), Defer: cmd.Defer})
+		}
+	}
+	return r
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// redirectModes are the values --redirects accepts
+var redirectModes = []string{"follow", "same-host", "none"}
+
+// redirectPolicy limits and records the redirects of remote fetches as the
+// default HTTP client's CheckRedirect. The reader reports a refused
+// redirect as a failed download, so the refusal is kept to say why.
+type redirectPolicy struct {
+	max  int
+	mode string
+
+	mu       sync.Mutex
+	followed map[string]string // requested URL -> where it ended up
+	refused  error
+}
+
+func newRedirectPolicy(max int, mode string) (*redirectPolicy, error) {
+	if !slices.Contains(redirectModes, mode) {
+		return nil, fmt.Errorf("unknown --redirects %q (want %s)", mode, strings.Join(redirectModes, ", "))
+	}
+	return &redirectPolicy{max: max, mode: mode}, nil
+}
+
+func (p *redirectPolicy) check(req *http.Request, via []*http.Request) error {
+	first := via[0].URL
+	var err error
+	switch {
+	case p.mode == "none":
+		err = fmt.Errorf("%s redirects to %s, and --redirects none forbids redirects", first.Redacted(), req.URL.Redacted())
+	case p.mode == "same-host" && req.URL.Host != first.Host:
+		err = fmt.Errorf("%s redirects to %s, and --redirects same-host forbids other hosts", first.Redacted(), req.URL.Redacted())
+	case p.max > 0 && len(via) > p.max:
+		err = fmt.Errorf("%s redirects more than --max-redirects %d times", first.Redacted(), p.max)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		if p.refused == nil {
+			p.refused = err
+		}
+		return err
+	}
+	if p.followed == nil {
+		p.followed = make(map[string]string)
+	}
+	p.followed[first.String()] = req.URL.String()
+	return nil
+}
+
+// reset forgets the refusal of the last read
+func (p *redirectPolicy) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refused = nil
+}
+
+func (p *redirectPolicy) err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.refused
+}
+
+// targets are the redirects followed so far, by requested URL
+func (p *redirectPolicy) targets() map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	targets := make(map[string]string, len(p.followed))
+	for from, to := range p.followed {
+		targets[from] = to
+	}
+	return targets
+}
+
+// checkTransport reports includes fetched over plain HTTP, which anyone on
+// the way can read and change, and ones whose URL redirects, as of the
+// download in the cache: to another host as a warning, since the include
+// no longer says where its tasks come from, and within the host as info
+func checkTransport(ws *workspace) []finding {
+	l := ws.idx.l
+	edges, err := includeEdges(ws.graph)
+	if err != nil {
+		return nil
+	}
+	redirects := l.store.redirects()
+	var findings []finding
+	for _, e := range edges {
+		pos := l.includePos(e.Parent, e.Include.Namespace)
+		u, err := url.Parse(e.Child)
+		if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+			continue
+		}
+		if u.Scheme == "http" {
+			findings = append(findings, finding{
+				Rule:     "transport",
+				Severity: severityWarning,
+				Task:     e.Include.Namespace,
+				Message:  fmt.Sprintf("includes %s over plain HTTP; use https so it cannot be read or changed in transit", e.Child),
+				Pos:      pos,
+			})
+		}
+		to, ok := redirects[e.Child]
+		if !ok {
+			continue
+		}
+		f := finding{Rule: "transport", Task: e.Include.Namespace, Pos: pos}
+		if crossHostRedirect(e.Child, to) {
+			f.Severity = severityWarning
+			f.Message = fmt.Sprintf("includes %s, which redirects to another host, %s; include the final URL", e.Child, to)
+		} else {
+			f.Severity = severityInfo
+			f.Message = fmt.Sprintf("includes %s, which redirects to %s", e.Child, to)
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// crossHostRedirect reports whether a redirect leads to another host
+func crossHostRedirect(from, to string) bool {
+	a, err := url.Parse(from)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(to)
+	return err == nil && a.Host != b.Host
+}
+
+// includePos is where a Taskfile declares the include of a namespace, or
+// just the file when that can't be read
+func (l *loader) includePos(uri, namespace string) sourcePos {
+	pos := sourcePos{File: uri}
+	src, err := l.readSource(uri)
+	if err != nil {
+		return pos
+	}
+	var doc yaml.Node
+	if yaml.Unmarshal(src, &doc) != nil {
+		return pos
+	}
+	includes := mappingValue(documentRoot(&doc), "includes")
+	if includes == nil || includes.Kind != yaml.MappingNode {
+		return pos
+	}
+	for i := 0; i+1 < len(includes.Content); i += 2 {
+		if key := includes.Content[i]; key.Value == namespace {
+			pos.Line, pos.Column = key.Line, key.Column
+		}
+	}
+	return pos
+}
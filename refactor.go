@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// runRefactor dispatches to the named refactoring
+func runRefactor(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: refactor rename --from OLD --to NEW")
+	}
+	switch args[0] {
+	case "rename":
+		return runRename(args[1:])
+	default:
+		return fmt.Errorf("unknown refactoring %q", args[0])
+	}
+}
+
+// runRename renames a task and rewrites every reference to it in the local
+// Taskfiles of the graph
+func runRename(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("refactor rename", flag.ExitOnError)
+	opts.register(fs)
+	from := fs.String("from", "", "Current full name of the task")
+	to := fs.String("to", "", "New full name of the task")
+	dryRun := fs.Bool("dry-run", false, "Print the changes without writing any files")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return fmt.Errorf("both --from and --to are required")
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	g, merged, err := l.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := merged.Tasks.Get(*from); !ok {
+		return fmt.Errorf("task %q not found%s", *from, didYouMean(*from, merged.Tasks))
+	}
+	if _, ok := merged.Tasks.Get(*to); ok {
+		return fmt.Errorf("task %q already exists", *to)
+	}
+
+	scopes, err := includeScopes(g)
+	if err != nil {
+		return err
+	}
+	uris := make([]string, 0, len(scopes))
+	for uri := range scopes {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	for _, uri := range uris {
+		src, err := l.readSource(uri)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", uri, err)
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(src, &doc); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", uri, err)
+		}
+
+		edits, err := renameEdits(&doc, scopes[uri], *from, *to)
+		if err != nil {
+			return fmt.Errorf("%s: %w", uri, err)
+		}
+		if len(edits) == 0 {
+			continue
+		}
+
+		if taskfile.IsRemoteEntrypoint(uri) {
+			fmt.Printf("%s: %d reference(s) in a remote Taskfile left unchanged\n", uri, len(edits))
+			continue
+		}
+		for _, e := range edits {
+			fmt.Printf("%s:%d:%d: %s -> %s\n", uri, e.node.Line, e.node.Column, e.node.Value, e.value)
+		}
+		if *dryRun {
+			continue
+		}
+		if err := os.WriteFile(uri, applyScalarEdits(src, edits), 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renameEdits finds the edits renaming from to to in one Taskfile. A file
+// included under several scopes must need the same edits in each of them.
+func renameEdits(doc *yaml.Node, scopes []includeScope, from, to string) ([]scalarEdit, error) {
+	var result []scalarEdit
+	for i, scope := range scopes {
+		edits, err := renameEditsInScope(doc, scope, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && !sameEdits(result, edits) {
+			return nil, fmt.Errorf("included under several namespaces that need different renames")
+		}
+		result = edits
+	}
+	return result, nil
+}
+
+func renameEditsInScope(doc *yaml.Node, scope includeScope, from, to string) ([]scalarEdit, error) {
+	tasks := mappingValue(documentRoot(doc), "tasks")
+	if tasks == nil || tasks.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	// newRef is how the file must spell the new name: relative to its own
+	// namespace when possible, otherwise anchored at the root
+	prefix := scope.prefix()
+	newRef := func(old string) string {
+		if !strings.HasPrefix(old, ast.NamespaceSeparator) && strings.HasPrefix(to, prefix) {
+			return strings.TrimPrefix(to, prefix)
+		}
+		return ast.NamespaceSeparator + to
+	}
+
+	var edits []scalarEdit
+	for i := 0; i+1 < len(tasks.Content); i += 2 {
+		key, task := tasks.Content[i], tasks.Content[i+1]
+		if scope.qualify(key.Value) == from {
+			if !strings.HasPrefix(to, prefix) {
+				return nil, fmt.Errorf("cannot rename %q to %q: the task would leave namespace %q", from, to, strings.TrimSuffix(prefix, ast.NamespaceSeparator))
+			}
+			edits = append(edits, scalarEdit{node: key, value: strings.TrimPrefix(to, prefix)})
+		}
+		for _, ref := range taskRefNodes(task) {
+			if scope.qualify(ref.Value) == from {
+				edits = append(edits, scalarEdit{node: ref, value: newRef(ref.Value)})
+			}
+		}
+	}
+	return edits, nil
+}
+
+func sameEdits(a, b []scalarEdit) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].node != b[i].node || a[i].value != b[i].value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// renderFormats are the image formats --render has Graphviz produce
+var renderFormats = []string{"png", "svg", "pdf"}
+
+// renderDOT pipes a DOT export through the locally installed Graphviz dot
+// and writes the image to out
+func renderDOT(dot []byte, format, out string) error {
+	bin, err := exec.LookPath("dot")
+	if err != nil {
+		return fmt.Errorf("--render needs Graphviz, but dot is not on PATH; install graphviz, or write the graph with --format dot and render it elsewhere")
+	}
+	cmd := exec.Command(bin, "-T"+format, "-o", out)
+	cmd.Stdin = bytes.NewReader(dot)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("dot -T%s: %s", format, msg)
+		}
+		return fmt.Errorf("dot -T%s: %w", format, err)
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s\n", out)
+	return nil
+}
+
+// checkRender validates --render and picks the default --out, named after
+// the graph being drawn. --open renders an SVG unless told otherwise, into
+// a temporary file unless given --out.
+func checkRender(format, out *string, open bool, graphKind, outputFormat string) error {
+	if open && *format == "" {
+		*format = "svg"
+	}
+	if *format == "" {
+		return nil
+	}
+	if !slices.Contains(renderFormats, *format) {
+		return fmt.Errorf("unknown render format %q; want %s", *format, strings.Join(renderFormats, ", "))
+	}
+	if outputFormat != "text" && outputFormat != "dot" {
+		return fmt.Errorf("--render draws the DOT export and cannot be combined with --format %s", outputFormat)
+	}
+	switch {
+	case *out != "":
+	case open:
+		f, err := os.CreateTemp("", "meerkat-"+graphKind+"-*."+*format)
+		if err != nil {
+			return err
+		}
+		f.Close()
+		*out = f.Name()
+	default:
+		*out = graphKind + "." + *format
+	}
+	return nil
+}
+
+// openBrowser shows a file or URL with the platform's default handler,
+// without waiting for it
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", target, err)
+	}
+	return cmd.Process.Release()
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// Requirement classes a task's commands can have, for auditing what running
+// it takes
+const (
+	reqRoot    = "root"           // runs a command as root through sudo or doas
+	reqNetwork = "network"        // downloads, uploads or talks to a remote service
+	reqWrites  = "outside-writes" // writes to the filesystem outside the repository
+)
+
+// requirement is one class a task needs with the first command showing it
+type requirement struct {
+	Class   string `json:"class"`
+	Command string `json:"command"`
+}
+
+// rootWrappers run the rest of the command as another user, root unless
+// told otherwise
+var rootWrappers = map[string]bool{"sudo": true, "doas": true, "pkexec": true, "su": true, "run0": true}
+
+// networkTools always reach the network
+var networkTools = map[string]bool{
+	"curl": true, "wget": true, "ssh": true, "scp": true, "sftp": true, "ftp": true,
+	"nc": true, "ncat": true, "telnet": true, "aria2c": true, "http": true, "https": true,
+	"gh": true, "aws": true, "gcloud": true, "az": true, "kubectl": true, "helm": true,
+}
+
+// networkSubcommands are the subcommands of other tools that reach the
+// network, mostly package managers fetching what they install
+var networkSubcommands = map[string][]string{
+	"git":     {"clone", "fetch", "pull", "push", "ls-remote", "submodule"},
+	"go":      {"get", "install", "mod"},
+	"npm":     {"install", "i", "ci", "add", "publish", "update"},
+	"pnpm":    {"install", "i", "add", "publish", "update"},
+	"yarn":    {"install", "add", "global", "publish", "upgrade"},
+	"pip":     {"install", "download"},
+	"pip3":    {"install", "download"},
+	"uv":      {"pip", "sync", "add"},
+	"cargo":   {"install", "fetch", "publish", "update"},
+	"gem":     {"install", "push", "update"},
+	"bundle":  {"install", "update"},
+	"docker":  {"pull", "push", "login"},
+	"podman":  {"pull", "push", "login"},
+	"apt":     {"install", "update", "upgrade", "dist-upgrade"},
+	"apt-get": {"install", "update", "upgrade", "dist-upgrade"},
+	"yum":     {"install", "update", "upgrade"},
+	"dnf":     {"install", "update", "upgrade"},
+	"zypper":  {"install", "in", "update", "up", "refresh"},
+	"apk":     {"add", "update", "upgrade"},
+	"brew":    {"install", "update", "upgrade", "tap"},
+	"snap":    {"install", "refresh"},
+	"choco":   {"install", "upgrade"},
+	"winget":  {"install", "upgrade"},
+	"scoop":   {"install", "update"},
+}
+
+// systemInstalls are the package manager commands that install into the
+// system or the user's home rather than the repository
+var systemInstalls = map[string][]string{
+	"apt": {"install"}, "apt-get": {"install"}, "yum": {"install"}, "dnf": {"install"},
+	"zypper": {"install", "in"}, "apk": {"add"}, "brew": {"install"}, "snap": {"install"},
+	"choco": {"install"}, "winget": {"install"}, "scoop": {"install"}, "pacman": {"-S"},
+	"go": {"install"}, "cargo": {"install"}, "gem": {"install"}, "pipx": {"install"},
+}
+
+// writeTools change the paths given as their arguments; for copyTools it is
+// only the last one
+var (
+	writeTools = map[string]bool{"tee": true, "mkdir": true, "touch": true, "rm": true, "rmdir": true, "chmod": true, "chown": true, "truncate": true}
+	copyTools  = map[string]bool{"cp": true, "mv": true, "install": true, "ln": true, "rsync": true}
+)
+
+// remotePath is a host:path argument of rsync or scp
+var remotePath = regexp.MustCompile(`^([\w.-]+@)?[\w.-]+:`)
+
+// taskRequirements classifies the commands of a task, in the order of the
+// classes above
+func taskRequirements(task *ast.Task) []requirement {
+	found := make(map[string]string)
+	for _, cmd := range task.Cmds {
+		if cmd.Cmd == "" {
+			continue
+		}
+		for _, words := range splitCommands(cmd.Cmd) {
+			for _, words := range expandCommand(words) {
+				for _, class := range commandRequirements(words) {
+					if _, ok := found[class]; !ok {
+						found[class] = cmd.Cmd
+					}
+				}
+			}
+		}
+	}
+	var reqs []requirement
+	for _, class := range []string{reqRoot, reqNetwork, reqWrites} {
+		if command, ok := found[class]; ok {
+			reqs = append(reqs, requirement{Class: class, Command: command})
+		}
+	}
+	return reqs
+}
+
+// requirementClasses lists just the classes, as graph exports carry them
+func requirementClasses(reqs []requirement) []string {
+	var classes []string
+	for _, r := range reqs {
+		classes = append(classes, r.Class)
+	}
+	return classes
+}
+
+// commandRequirements classifies one simple command
+func commandRequirements(words []string) []string {
+	var classes []string
+	for _, w := range words {
+		if assignmentPattern.MatchString(w) {
+			continue
+		}
+		if rootWrappers[w] {
+			classes = append(classes, reqRoot)
+		}
+		break
+	}
+
+	tool, args := commandTool(words)
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	network := networkTools[tool] || slices.Contains(networkSubcommands[tool], sub) ||
+		tool == "pacman" && strings.HasPrefix(sub, "-S")
+	if (tool == "rsync" || tool == "scp") && slices.ContainsFunc(args, remotePath.MatchString) {
+		network = true
+	}
+	if network {
+		classes = append(classes, reqNetwork)
+	}
+
+	writes := slices.ContainsFunc(systemInstalls[tool], func(s string) bool { return strings.HasPrefix(sub, s) }) ||
+		(tool == "npm" || tool == "pnpm") && slices.Contains(args, "-g") || tool == "yarn" && sub == "global"
+	for i := range words {
+		// Redirections, as in > /etc/hosts, 2>>~/log or &>/var/log/x
+		if target, ok := redirectTarget(words, i); ok && outsideRepo(target) {
+			writes = true
+		}
+	}
+	targets := positional(args)
+	switch {
+	case writeTools[tool]:
+		if tool == "chown" && len(targets) > 0 {
+			targets = targets[1:]
+		}
+		writes = writes || slices.ContainsFunc(targets, outsideRepo)
+	case copyTools[tool] && len(targets) > 1:
+		dest := targets[len(targets)-1]
+		if t := flagValue(args, "-t", "--target-directory"); t != "" {
+			dest = t
+		}
+		writes = writes || outsideRepo(dest)
+	case tool == "dd":
+		for _, a := range args {
+			if of, ok := strings.CutPrefix(a, "of="); ok && outsideRepo(of) {
+				writes = true
+			}
+		}
+	}
+	if writes {
+		classes = append(classes, reqWrites)
+	}
+	return classes
+}
+
+// redirectTarget returns the file words[i] redirects output to, either in
+// the same word or the next
+func redirectTarget(words []string, i int) (string, bool) {
+	w := strings.TrimLeft(words[i], "0123456789&")
+	if !strings.HasPrefix(w, ">") {
+		return "", false
+	}
+	target := strings.TrimPrefix(strings.TrimPrefix(w, ">"), ">")
+	if strings.HasPrefix(target, "&") {
+		// Duplicating a descriptor, as in 2>&1
+		return "", false
+	}
+	if target == "" && i+1 < len(words) {
+		target = words[i+1]
+	}
+	return target, target != ""
+}
+
+// outsideRepo reports whether a path leaves the repository: absolute paths
+// other than scratch and device files, the home directory and parents.
+// Tasks normally run from their Taskfile's directory, so relative paths
+// stay inside.
+func outsideRepo(path string) bool {
+	switch {
+	case path == "" || strings.Contains(path, "{{"):
+		return false
+	case strings.HasPrefix(path, "/"):
+		for _, scratch := range []string{"/dev/", "/tmp/", "/proc/self/"} {
+			if strings.HasPrefix(path, scratch) || path+"/" == scratch {
+				return false
+			}
+		}
+		return true
+	case path == "~" || strings.HasPrefix(path, "~/"), strings.HasPrefix(path, "$HOME"), strings.HasPrefix(path, "${HOME}"):
+		return true
+	case path == ".." || strings.HasPrefix(path, "../"):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidParams  = -32602
+	rpcMethodNotFound = -32601
+	rpcInternalError  = -32603
+)
+
+// rpcRequest is an incoming request, or a notification when ID is empty
+type rpcRequest struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcError is the error member of a JSON-RPC response
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// rpcConn reads and writes JSON-RPC messages framed with Content-Length
+// headers, the framing LSP uses, so both the language server and the plain
+// editor backend share it
+type rpcConn struct {
+	r  *textproto.Reader
+	w  io.Writer
+	mu sync.Mutex // serializes writes
+}
+
+func newRPCConn(r io.Reader, w io.Writer) *rpcConn {
+	return &rpcConn{r: textproto.NewReader(bufio.NewReader(r)), w: w}
+}
+
+// read returns the next message. io.EOF means the client went away.
+func (c *rpcConn) read() (*rpcRequest, error) {
+	header, err := c.r.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r.R, body); err != nil {
+		return nil, err
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return &rpcRequest{}, &rpcError{Code: rpcParseError, Message: err.Error()}
+	}
+	return &req, nil
+}
+
+// reply answers a request with either its result or an error
+func (c *rpcConn) reply(id json.RawMessage, result any, err error) error {
+	msg := map[string]any{"jsonrpc": "2.0", "id": id}
+	if err != nil {
+		rpcErr, ok := err.(*rpcError)
+		if !ok {
+			rpcErr = &rpcError{Code: rpcInternalError, Message: err.Error()}
+		}
+		msg["error"] = rpcErr
+	} else {
+		msg["result"] = result
+	}
+	return c.write(msg)
+}
+
+// notify sends a notification to the client
+func (c *rpcConn) notify(method string, params any) error {
+	return c.write(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (c *rpcConn) write(msg any) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+// decodeParams unmarshals request params, reporting failures as invalid params
+func decodeParams(raw json.RawMessage, v any) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
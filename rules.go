@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"go.yaml.in/yaml/v3"
+)
+
+// customRule is a house policy from the rules: list of the config file. Expr
+// is a CEL expression over one task that is true when the task breaks it:
+//
+//	rules:
+//	  - name: sudo-needs-label
+//	    expr: task.cmds.exists(c, c.cmd.contains("sudo")) && !task.labels.has("privileged")
+//	    message: runs sudo without the privileged label
+//	    severity: error
+type customRule struct {
+	Name     string `yaml:"name"`
+	Expr     string `yaml:"expr"`
+	Message  string `yaml:"message"`
+	Severity string `yaml:"severity"`
+}
+
+// loadChecks returns the built-in checks followed by the custom rules and
+// external analyzers of the config file
+func loadChecks(configFile string) ([]lintCheck, error) {
+	data, err := os.ReadFile(configFile)
+	if errors.Is(err, os.ErrNotExist) {
+		// applyConfigFile has already complained about a missing --config
+		return lintChecks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg struct {
+		Rules     []customRule       `yaml:"rules"`
+		Analyzers []externalAnalyzer `yaml:"analyzers"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configFile, err)
+	}
+	if len(cfg.Rules) == 0 && len(cfg.Analyzers) == 0 {
+		return lintChecks, nil
+	}
+
+	env, err := ruleEnv()
+	if err != nil {
+		return nil, err
+	}
+	checks := slices.Clone(lintChecks)
+	defined := func(name string) bool {
+		return slices.ContainsFunc(checks, func(c lintCheck) bool { return c.name == name })
+	}
+	for _, r := range cfg.Rules {
+		if r.Name == "" || r.Expr == "" {
+			return nil, fmt.Errorf("config file %s: every rule needs a name and an expr", configFile)
+		}
+		if defined(r.Name) {
+			return nil, fmt.Errorf("config file %s: rule %q is already defined", configFile, r.Name)
+		}
+		check, err := r.compile(env)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: rule %q: %w", configFile, r.Name, err)
+		}
+		checks = append(checks, check)
+	}
+	for _, a := range cfg.Analyzers {
+		if a.Name == "" || a.Command == "" {
+			return nil, fmt.Errorf("config file %s: every analyzer needs a name and a command", configFile)
+		}
+		if defined(a.Name) {
+			return nil, fmt.Errorf("config file %s: analyzer %q is already defined", configFile, a.Name)
+		}
+		checks = append(checks, a.check())
+	}
+	return checks, nil
+}
+
+// ruleEnv declares the task variable rules are written against, plus a
+// has method on maps for testing label and var names
+func ruleEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("task", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Function("has",
+			cel.MemberOverload("map_has_string", []*cel.Type{cel.MapType(cel.StringType, cel.DynType), cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(func(m, key ref.Val) ref.Val {
+					mapper, ok := m.(traits.Mapper)
+					if !ok {
+						return types.MaybeNoSuchOverloadErr(m)
+					}
+					_, found := mapper.Find(key)
+					return types.Bool(found)
+				}))),
+	)
+}
+
+func (r customRule) compile(env *cel.Env) (lintCheck, error) {
+	ast, issues := env.Compile(r.Expr)
+	if issues != nil && issues.Err() != nil {
+		return lintCheck{}, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return lintCheck{}, fmt.Errorf("expr is %s, want bool", ast.OutputType())
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		return lintCheck{}, err
+	}
+
+	severity := r.Severity
+	if severity == "" {
+		severity = severityWarning
+	}
+	if !slices.Contains([]string{severityError, severityWarning, severityInfo}, severity) {
+		return lintCheck{}, fmt.Errorf("unknown severity %q", r.Severity)
+	}
+	message := r.Message
+	if message == "" {
+		message = "breaks rule " + r.Name
+	}
+	summary := r.Message
+	if summary == "" {
+		summary = r.Expr
+	}
+
+	return lintCheck{name: r.Name, summary: summary, run: func(ws *workspace) []finding {
+		var names []string
+		for name := range ws.merged.Tasks.All(byName) {
+			names = append(names, name)
+		}
+		// Programs are safe to evaluate concurrently, one task per worker
+		results := parallelMap(len(names), func(i int) *finding {
+			task, _ := ws.merged.Tasks.Get(names[i])
+			out, _, err := prg.Eval(map[string]any{"task": ws.ruleTask(names[i])})
+			switch {
+			case err != nil:
+				return &finding{Rule: r.Name, Severity: severityError, Task: names[i],
+					Message: "rule failed to evaluate: " + err.Error(), Pos: taskPos(task)}
+			case out == types.True:
+				return &finding{Rule: r.Name, Severity: severity, Task: names[i],
+					Message: message, Pos: taskPos(task)}
+			}
+			return nil
+		})
+		var findings []finding
+		for _, f := range results {
+			if f != nil {
+				findings = append(findings, *f)
+			}
+		}
+		return findings
+	}}, nil
+}
+
+// ruleTask is the task object rules see. Commands keep their templates as
+// written; vars and env hold the values describe resolves.
+func (ws *workspace) ruleTask(name string) map[string]any {
+	name, task, _ := ws.resolve(name)
+	d := ws.describe(name, task)
+
+	cmds := []map[string]any{}
+	for _, c := range task.Cmds {
+		cmds = append(cmds, map[string]any{"cmd": c.Cmd, "task": c.Task, "defer": c.Defer, "silent": c.Silent})
+	}
+	values := func(vars []resolvedVar) map[string]string {
+		m := make(map[string]string, len(vars))
+		for _, v := range vars {
+			m[v.Name] = v.Value
+		}
+		return m
+	}
+	labels := d.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return map[string]any{
+		"name":          name,
+		"desc":          task.Desc,
+		"namespace":     d.Namespace,
+		"internal":      task.Internal,
+		"dir":           task.Dir,
+		"location":      d.Location,
+		"aliases":       nonNil(d.Aliases),
+		"platforms":     nonNil(d.Platforms),
+		"labels":        labels,
+		"deps":          nonNil(d.Deps),
+		"preconditions": nonNil(d.Preconditions),
+		"cmds":          cmds,
+		"vars":          values(d.Vars),
+		"env":           values(d.Env),
+		"dependents":    nonNil(d.Dependents),
+	}
+}
+
+// nonNil gives rules an empty list to call exists and size on rather than
+// null
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// cdxBOM is the subset of a CycloneDX 1.5 JSON document the inventory fills
+type cdxBOM struct {
+	BOMFormat    string         `json:"bomFormat"`
+	SpecVersion  string         `json:"specVersion"`
+	SerialNumber string         `json:"serialNumber"`
+	Version      int            `json:"version"`
+	Metadata     cdxMetadata    `json:"metadata"`
+	Components   []cdxComponent `json:"components"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Type       string        `json:"type"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	Purl       string        `json:"purl,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// purlTypes maps package managers to their package URL types
+var purlTypes = map[string]string{
+	"go": "golang", "pip": "pypi", "pipx": "pypi", "npm": "npm", "cargo": "cargo", "gem": "gem",
+	"apt": "deb", "apk": "apk", "dnf": "rpm", "yum": "rpm", "microdnf": "rpm", "zypper": "rpm",
+	"pacman": "alpm",
+}
+
+// writeCycloneDX writes the inventory as a CycloneDX SBOM describing the
+// Taskfile at root: every external tool, versioned package and container
+// image its tasks need, each with the tasks that use it
+func (inv *inventory) writeCycloneDX(w io.Writer, root string) error {
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools:     cdxTools{Components: []cdxComponent{{Type: "application", Name: "mysteriousmeerkat"}}},
+			Component: cdxComponent{Type: "application", Name: displayPath(root)},
+		},
+		Components: []cdxComponent{},
+	}
+
+	// A component seen from several tasks is listed once, with one task
+	// property per user
+	index := make(map[string]int)
+	add := func(c cdxComponent, task string) {
+		i, ok := index[c.BOMRef]
+		if !ok {
+			i = len(bom.Components)
+			index[c.BOMRef] = i
+			bom.Components = append(bom.Components, c)
+		}
+		for _, p := range bom.Components[i].Properties {
+			if p.Name == "meerkat:task" && p.Value == task {
+				return
+			}
+		}
+		bom.Components[i].Properties = append(bom.Components[i].Properties, cdxProperty{"meerkat:task", task})
+	}
+
+	for _, t := range inv.Tools {
+		for _, task := range t.Tasks {
+			add(cdxComponent{BOMRef: "tool:" + t.Name, Type: "application", Name: t.Name}, task)
+		}
+	}
+	for _, p := range inv.Packages {
+		purl := packageURL(p)
+		c := cdxComponent{BOMRef: purl, Type: "library", Name: p.Name, Purl: purl}
+		if exactVersion(p.Version) {
+			c.Version = p.Version
+		} else if p.Version != "" {
+			c.Properties = []cdxProperty{{"meerkat:constraint", p.Version}}
+		}
+		add(c, p.Task)
+	}
+	for _, u := range inv.Containers {
+		if u.Image == "" {
+			continue
+		}
+		name, version := splitImage(u.Image)
+		add(cdxComponent{BOMRef: "image:" + u.Image, Type: "container", Name: name, Version: version}, u.Task)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bom)
+}
+
+// packageURL builds the purl of a package, as in pkg:npm/%40scope/cli@1.2.3.
+// Only exact versions go in the purl; ranges are left out.
+func packageURL(p packageUse) string {
+	typ, ok := purlTypes[p.Manager]
+	if !ok {
+		typ = "generic"
+	}
+	var segments []string
+	for _, s := range strings.Split(p.Name, "/") {
+		segments = append(segments, strings.ReplaceAll(url.PathEscape(s), "@", "%40"))
+	}
+	purl := "pkg:" + typ + "/" + strings.Join(segments, "/")
+	if exactVersion(p.Version) {
+		purl += "@" + url.PathEscape(p.Version)
+	}
+	return purl
+}
+
+// splitImage separates an image reference into its name and its digest or
+// tag; a colon before the last slash is a registry port
+func splitImage(image string) (string, string) {
+	if name, digest, ok := strings.Cut(image, "@"); ok {
+		return name, digest
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, ""
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
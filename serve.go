@@ -0,0 +1,341 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-task/task/v3/taskfile"
+)
+
+// runServe serves a live view of the task graph over HTTP. Local Taskfiles
+// of the graph are watched, and open pages reload when one changes.
+func runServe(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	opts.register(fs)
+	addr := fs.String("addr", "localhost:8080", "Address to listen on")
+	poll := fs.Duration("poll", time.Second, "How often to check the local Taskfiles for changes")
+	profiling := fs.Bool("pprof", false, "Also serve the Go runtime profiles under /debug/pprof/")
+	open := fs.Bool("open", false, "Open the graph in the default browser once listening")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if opts.taskfileURL == "-" {
+		return fmt.Errorf("--taskfile - cannot be used here: stdin cannot be watched for changes")
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	s := &graphServer{l: l, subscribers: make(map[chan int]bool)}
+	s.reload()
+	go s.watch(*poll)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/graph.json", s.handleJSON)
+	mux.HandleFunc("/cytoscape.json", s.handleCytoscape)
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.HandleFunc("/badges/", s.handleBadge)
+	if *profiling {
+		registerPprof(mux)
+	}
+	// Listen first so the browser finds the server up
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	url := "http://" + ln.Addr().String()
+	log.Printf("serving %s on %s", opts.taskfileURL, url)
+	if *open {
+		if err := openBrowser(url); err != nil {
+			log.Print(err)
+		}
+	}
+	return http.Serve(ln, mux)
+}
+
+// graphServer holds the latest analysis and the pages waiting to hear of
+// the next one
+type graphServer struct {
+	l *loader
+
+	mu          sync.Mutex
+	tg          *taskGraph
+	badges      map[string]badge
+	loadErr     error
+	loaded      time.Time
+	version     int
+	files       map[string]time.Time // local Taskfile -> modification time
+	subscribers map[chan int]bool
+}
+
+// reload analyzes the Taskfile again and tells every open page. A failed
+// load keeps the last good graph and shows the error alongside it.
+func (s *graphServer) reload() {
+	g, merged, err := s.l.load()
+	var tg *taskGraph
+	var badges map[string]badge
+	files := make(map[string]time.Time)
+	if err == nil {
+		var ws *workspace
+		if ws, err = newWorkspace(s.l, g, merged); err == nil {
+			if tg, err = buildTaskGraph(g, merged, ws.idx); err == nil {
+				var checks []lintCheck
+				if checks, err = loadChecks(s.l.opts.configFile); err == nil {
+					badges = taskfileBadges(ws, tg, checks)
+				}
+			}
+		}
+		if adjacency, aerr := g.AdjacencyMap(); aerr == nil {
+			for uri := range adjacency {
+				if !taskfile.IsRemoteEntrypoint(uri) {
+					files[uri] = modTime(uri)
+				}
+			}
+		}
+	} else {
+		files = s.failedFiles(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadErr = err
+	if err == nil {
+		s.tg, s.badges = tg, badges
+	} else {
+		log.Printf("reload failed: %v", err)
+	}
+	s.files = files
+	s.loaded = time.Now()
+	s.version++
+	for ch := range s.subscribers {
+		select {
+		case ch <- s.version:
+		default:
+		}
+	}
+}
+
+// failedFiles are the local Taskfiles to watch after a failed load, so a fix
+// reloads the page: the root Taskfile, the one the error is about, and the
+// ones they include as far as they still parse, along with those of the
+// last good graph
+func (s *graphServer) failedFiles(err error) map[string]time.Time {
+	var queue []string
+	if node, nerr := taskfile.NewRootNode(s.l.opts.taskfileURL, "", s.l.opts.insecure(), s.l.opts.timeout); nerr == nil {
+		queue = append(queue, node.Location())
+	}
+	if uri, ok := failedURI(err); ok {
+		queue = append(queue, uri)
+	}
+	s.mu.Lock()
+	for uri := range s.files {
+		queue = append(queue, uri)
+	}
+	s.mu.Unlock()
+
+	files := make(map[string]time.Time)
+	for len(queue) > 0 {
+		uri := queue[0]
+		queue = queue[1:]
+		if _, seen := files[uri]; seen || taskfile.IsRemoteEntrypoint(uri) {
+			continue
+		}
+		files[uri] = modTime(uri)
+		if src, err := s.l.readSource(uri); err == nil {
+			for _, step := range s.l.rawIncludes(uri, src) {
+				if step.err == nil {
+					queue = append(queue, step.uri)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// watch polls the local Taskfiles and reloads when any of them changes
+func (s *graphServer) watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		s.mu.Lock()
+		changed := false
+		for uri, seen := range s.files {
+			if !modTime(uri).Equal(seen) {
+				changed = true
+				break
+			}
+		}
+		s.mu.Unlock()
+		if changed {
+			s.reload()
+		}
+	}
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func (s *graphServer) handleJSON(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	tg := s.tg
+	s.mu.Unlock()
+	if tg == nil {
+		http.Error(w, "no graph loaded", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, tg)
+}
+
+// handleCytoscape serves the graph as Cytoscape.js elements, for pages that
+// draw it with Cytoscape instead of Mermaid
+func (s *graphServer) handleCytoscape(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	tg := s.tg
+	s.mu.Unlock()
+	if tg == nil {
+		http.Error(w, "no graph loaded", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeCytoscape(w, tg)
+}
+
+// handleBadge serves /badges/NAME.svg for the latest good analysis
+func (s *graphServer) handleBadge(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/badges/"), ".svg")
+	s.mu.Lock()
+	b, ok := s.badges[name]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(b.svg())
+}
+
+// handleEvents streams a server-sent event each time the graph is reloaded
+func (s *graphServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan int, 1)
+	s.mu.Lock()
+	s.subscribers[ch] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprintf(w, ": connected\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case version := <-ch:
+			fmt.Fprintf(w, "event: reload\ndata: %d\n\n", version)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *graphServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.Lock()
+	page := servePage{
+		Root:    displayPath(s.l.opts.taskfileURL),
+		Loaded:  s.loaded.Format(time.RFC3339),
+		Version: s.version,
+	}
+	if s.loadErr != nil {
+		page.Error = s.loadErr.Error()
+	}
+	if s.tg != nil {
+		var b strings.Builder
+		writeMermaid(&b, s.tg)
+		page.Mermaid = b.String()
+		page.Nodes = s.tg.Nodes
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := servePageTemplate.Execute(w, page); err != nil {
+		log.Printf("render failed: %v", err)
+	}
+}
+
+// servePage is what the HTML view shows
+type servePage struct {
+	Root    string
+	Loaded  string
+	Version int
+	Error   string
+	Mermaid string
+	Nodes   []taskNode
+}
+
+// pageHead is the styling and diagram script shared by the HTML views
+const pageHead = `<meta charset="utf-8">
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; }
+.error { background: #fdecea; border: 1px solid #f5c2c0; padding: 1em; white-space: pre-wrap; }
+table { border-collapse: collapse; }
+td, th { border-bottom: 1px solid #ddd; padding: 0.3em 0.8em; text-align: left; }
+.missing { color: #c62828; }
+</style>
+<script type="module">
+import mermaid from "https://cdn.jsdelivr.net/npm/mermaid@11/dist/mermaid.esm.min.mjs";
+mermaid.initialize({ startOnLoad: true });
+</script>
+`
+
+var servePageTemplate = template.Must(template.New("serve").Funcs(template.FuncMap{
+	"labels": formatLabels,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+` + pageHead + `<title>{{.Root}} - task graph</title>
+</head>
+<body>
+<h1>{{.Root}}</h1>
+<p>Loaded {{.Loaded}} (revision {{.Version}}). This page reloads when a local Taskfile changes.</p>
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+{{if .Mermaid}}<pre class="mermaid">{{.Mermaid}}</pre>{{end}}
+<table>
+<tr><th>Task</th><th>Description</th><th>Namespace</th><th>Location</th><th>Labels</th></tr>
+{{range .Nodes}}<tr{{if .Missing}} class="missing"{{end}}><td>{{.Name}}</td><td>{{.Desc}}</td><td>{{.Namespace}}</td><td>{{if .Missing}}missing{{else}}{{.Location}}{{end}}</td><td>{{labels .Labels}}</td></tr>
+{{end}}</table>
+<script>
+new EventSource("/events").addEventListener("reload", () => location.reload());
+</script>
+</body>
+</html>
+`))
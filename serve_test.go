@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReloadWatchesFilesOfFailedLoad(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		watch []string
+	}{
+		{"broken root", map[string]string{"Taskfile.yml": "version: '3'\ntasks:\n  a: [\n"}, []string{"Taskfile.yml"}},
+		{"broken include", map[string]string{
+			"Taskfile.yml":     "version: '3'\nincludes:\n  lib: ./lib/Taskfile.yml\n",
+			"lib/Taskfile.yml": "version: '3'\ntasks:\n  a: [\n",
+		}, []string{"Taskfile.yml", "lib/Taskfile.yml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLoader(t, tt.files)
+			s := &graphServer{l: l, subscribers: make(map[chan int]bool)}
+			s.reload()
+			if s.loadErr == nil {
+				t.Fatal("reload() succeeded on a broken Taskfile")
+			}
+			dir := filepath.Dir(l.opts.taskfileURL)
+			for _, name := range tt.watch {
+				if _, ok := s.files[filepath.Join(dir, filepath.FromSlash(name))]; !ok {
+					t.Errorf("%s is not watched after the failed load; watching %v", name, s.files)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// assignmentPattern matches a leading VAR=value word of a shell command
+var assignmentPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// commandWrappers run the command that follows them, so the tool a command
+// needs is the word after them
+var commandWrappers = map[string]bool{
+	"sudo": true, "doas": true, "env": true, "command": true, "exec": true,
+	"nohup": true, "time": true, "nice": true,
+}
+
+// splitCommands breaks a shell command line into its simple commands, each
+// as a list of words with quotes removed. It understands quoting and the
+// usual separators (; && || | & newlines, subshells and command
+// substitution) but is not a full shell parser.
+func splitCommands(line string) [][]string {
+	var (
+		commands [][]string
+		words    []string
+		word     strings.Builder
+		inWord   bool
+		quote    rune
+	)
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' && i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			if runes[i] != '\n' {
+				word.WriteRune(runes[i])
+				inWord = true
+			}
+		case r == '&' && (i > 0 && runes[i-1] == '>' || i+1 < len(runes) && runes[i+1] == '>'):
+			// Part of a redirection such as 2>&1 or &>file
+			word.WriteRune(r)
+			inWord = true
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '(':
+			endCommand()
+			i++
+		case strings.ContainsRune(";&|\n()`", r):
+			endCommand()
+		case r == ' ' || r == '\t':
+			endWord()
+		case r == '#' && !inWord:
+			// A comment runs to the end of the line
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands
+}
+
+// commandTool strips leading variable assignments and wrappers such as sudo
+// from a simple command and returns the tool it runs with its arguments
+func commandTool(words []string) (string, []string) {
+	for len(words) > 0 {
+		w := words[0]
+		switch {
+		case assignmentPattern.MatchString(w):
+			words = words[1:]
+		case commandWrappers[w]:
+			words = words[1:]
+			// Options of the wrapper itself, as in sudo -E or sudo -u root
+			for len(words) > 0 && strings.HasPrefix(words[0], "-") {
+				if (w == "sudo" || w == "doas") && (words[0] == "-u" || words[0] == "-g") && len(words) > 1 {
+					words = words[1:]
+				}
+				words = words[1:]
+			}
+		default:
+			return w, words[1:]
+		}
+	}
+	return "", nil
+}
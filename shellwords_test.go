@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommands(t *testing.T) {
+	tests := []struct {
+		line string
+		want [][]string
+	}{
+		{"", nil},
+		{"go build ./...", [][]string{{"go", "build", "./..."}}},
+		{"a && b || c; d | e & f", [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}, {"f"}}},
+		{"a\nb", [][]string{{"a"}, {"b"}}},
+		{`echo "a b" 'c d' e\ f`, [][]string{{"echo", "a b", "c d", "e f"}}},
+		{`echo "say \"hi\"" 'no \escape'`, [][]string{{"echo", `say "hi"`, `no \escape`}}},
+		{`echo "a;b" 'c&&d'`, [][]string{{"echo", "a;b", "c&&d"}}},
+		{"echo $(date +%s) done", [][]string{{"echo"}, {"date", "+%s"}, {"done"}}},
+		{"(cd web && npm ci)", [][]string{{"cd", "web"}, {"npm", "ci"}}},
+		{"echo `whoami`", [][]string{{"echo"}, {"whoami"}}},
+		{"make 2>&1 &>log", [][]string{{"make", "2>&1", "&>log"}}},
+		{"echo hi # a comment; rm -rf /\nls", [][]string{{"echo", "hi"}, {"ls"}}},
+		{"echo a#b", [][]string{{"echo", "a#b"}}},
+		{"go \\\n  test", [][]string{{"go", "test"}}},
+		{`echo ""`, [][]string{{"echo", ""}}},
+	}
+	for _, tt := range tests {
+		if got := splitCommands(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommands(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestCommandTool(t *testing.T) {
+	tests := []struct {
+		words []string
+		tool  string
+		args  []string
+	}{
+		{nil, "", nil},
+		{[]string{"go", "test"}, "go", []string{"test"}},
+		{[]string{"CGO_ENABLED=0", "GOOS=linux", "go", "build"}, "go", []string{"build"}},
+		{[]string{"sudo", "-E", "apt-get", "install"}, "apt-get", []string{"install"}},
+		{[]string{"sudo", "-u", "root", "make"}, "make", []string{}},
+		{[]string{"env", "A=1", "nice", "node", "app.js"}, "node", []string{"app.js"}},
+		{[]string{"time", "exec", "docker", "ps"}, "docker", []string{"ps"}},
+		{[]string{"A=1"}, "", nil},
+	}
+	for _, tt := range tests {
+		tool, args := commandTool(tt.words)
+		if tool != tt.tool || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("commandTool(%q) = %q, %q, want %q, %q", tt.words, tool, args, tt.tool, tt.args)
+		}
+	}
+}
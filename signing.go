@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// Cosign stores an OCI artifact's signatures as layers of a manifest tagged
+// after the artifact's digest, each with its signature in an annotation
+const (
+	cosignSignature  = "dev.cosignproject.cosign/signature"
+	cosignSimpleSign = "application/vnd.dev.cosign.simplesigning.v1+json"
+)
+
+// errUnsigned is returned for content published without a signature
+var errUnsigned = errors.New("not signed")
+
+// signatureVerifier checks remote Taskfiles against a cosign public key
+// before they are analyzed or vendored. HTTP and object storage Taskfiles
+// are signed with cosign sign-blob, their signature published beside them
+// with a .sig suffix; OCI artifacts carry the signature cosign sign
+// attaches. Keyless signatures cannot be checked.
+//
+// A nil *signatureVerifier accepts everything.
+type signatureVerifier struct {
+	key     crypto.PublicKey
+	require bool
+	warn    io.Writer
+}
+
+// newSignatureVerifier reads the --cosign-key public key. Without one
+// nothing is verified, and --require-signed has nothing to verify with.
+func newSignatureVerifier(keyPath string, require bool) (*signatureVerifier, error) {
+	if keyPath == "" {
+		if require {
+			return nil, fmt.Errorf("--require-signed needs --cosign-key")
+		}
+		return nil, nil
+	}
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s is not a PEM public key", keyPath)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key in %s: %w", keyPath, err)
+	}
+	return &signatureVerifier{key: key, require: require, warn: os.Stderr}, nil
+}
+
+// verify checks sig, as cosign writes it in base64, against content. A
+// missing signature is only an error with --require-signed.
+func (v *signatureVerifier) verify(uri string, content, sig []byte) error {
+	if v == nil {
+		return nil
+	}
+	if sig == nil {
+		if v.require {
+			return fmt.Errorf("%s is %w; --require-signed and --strict refuse it", uri, errUnsigned)
+		}
+		fmt.Fprintf(v.warn, "warning: %s is %v\n", uri, errUnsigned)
+		return nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		raw = sig
+	}
+	if !v.valid(content, raw) {
+		return fmt.Errorf("%s has a signature that does not match --cosign-key", uri)
+	}
+	return nil
+}
+
+// valid reports whether raw signs content, the way cosign signs with each
+// kind of key: ECDSA and RSA sign the SHA-256 digest, Ed25519 the content
+func (v *signatureVerifier) valid(content, raw []byte) bool {
+	digest := sha256.Sum256(content)
+	switch key := v.key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest[:], raw)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], raw) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, content, raw)
+	}
+	return false
+}
+
+// checkGraph verifies every HTTP Taskfile of the graph, as read into the
+// cache, against the signature published beside it
+func (v *signatureVerifier) checkGraph(l *loader, g *ast.TaskfileGraph) error {
+	if v == nil {
+		return nil
+	}
+	adjacency, err := g.AdjacencyMap()
+	if err != nil {
+		return err
+	}
+	for _, uri := range slices.Sorted(maps.Keys(adjacency)) {
+		if !taskfile.IsRemoteEntrypoint(uri) {
+			continue
+		}
+		content, err := l.readSource(uri)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", uri, err)
+		}
+		var sig []byte
+		if node, err := taskfile.NewNode(uri, "", l.opts.insecure()); err == nil {
+			if _, ok := node.(*taskfile.HTTPNode); ok {
+				if sig, err = fetchSignature(uri); err != nil {
+					return err
+				}
+			}
+		}
+		// Git includes have nowhere to publish a signature, so they count
+		// as unsigned
+		if err := v.verify(uri, content, sig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchSignature downloads the .sig published beside an HTTP Taskfile, or
+// returns nil when there is none
+func fetchSignature(uri string) ([]byte, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	u.Path += ".sig"
+	req, err := http.NewRequestWithContext(withTaskfileFetch(context.Background()), http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signature of %s: %w", uri, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound, http.StatusForbidden:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("failed to fetch signature of %s: GET %s: %s", uri, u.Redacted(), resp.Status)
+}
+
+// verifyArtifact checks the signatures cosign attached to the manifest
+// with the given digest. One valid signature is enough.
+func (v *signatureVerifier) verifyArtifact(c *registryClient, uri, repository, digest string) error {
+	if v == nil {
+		return nil
+	}
+	tag := strings.Replace(digest, ":", "-", 1) + ".sig"
+	manifest, _, err := c.manifest(repository, tag)
+	var status *registryStatusError
+	if errors.As(err, &status) && status.code == http.StatusNotFound {
+		return v.verify(uri, nil, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch signatures of %s: %w", uri, err)
+	}
+
+	signed := false
+	for _, layer := range manifest.Layers {
+		sig, ok := layer.Annotations[cosignSignature]
+		if !ok || layer.MediaType != cosignSimpleSign {
+			continue
+		}
+		signed = true
+		payload, err := c.blob(repository, layer.Digest)
+		if err != nil {
+			return err
+		}
+		if v.verify(uri, payload, []byte(sig)) != nil {
+			continue
+		}
+		// The payload names the manifest it signs, so a signature cannot
+		// be copied onto another artifact
+		var p struct {
+			Critical struct {
+				Image struct {
+					Digest string `json:"docker-manifest-digest"`
+				} `json:"image"`
+			} `json:"critical"`
+		}
+		if json.Unmarshal(payload, &p) == nil && p.Critical.Image.Digest == digest {
+			return nil
+		}
+	}
+	if !signed {
+		return v.verify(uri, nil, nil)
+	}
+	return fmt.Errorf("%s has no signature from --cosign-key for %s", uri, digest)
+}
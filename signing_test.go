@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePublicKey writes the public half of key as a PEM file, as cosign
+// generate-key-pair does
+func writePublicKey(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "cosign.pub")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// sign signs content the way cosign sign-blob does with each kind of key
+func sign(t *testing.T, key crypto.Signer, content []byte) []byte {
+	t.Helper()
+	digest := sha256.Sum256(content)
+	var sig []byte
+	var err error
+	switch key.(type) {
+	case ed25519.PrivateKey:
+		sig, err = key.Sign(rand.Reader, content, crypto.Hash(0))
+	default:
+		sig, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func TestSignatureVerifier(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("version: '3'\ntasks:\n  build: go build\n")
+
+	keys := map[string]crypto.Signer{"ecdsa": ecKey, "rsa": rsaKey, "ed25519": edKey}
+	for name, key := range keys {
+		t.Run(name, func(t *testing.T) {
+			v, err := newSignatureVerifier(writePublicKey(t, key), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw := sign(t, key, content)
+			tests := []struct {
+				name    string
+				content []byte
+				sig     []byte
+				ok      bool
+			}{
+				{"base64", content, []byte(base64.StdEncoding.EncodeToString(raw) + "\n"), true},
+				{"raw", content, raw, true},
+				{"changed content", append([]byte("# evil\n"), content...), []byte(base64.StdEncoding.EncodeToString(raw)), false},
+				{"other key", content, []byte(base64.StdEncoding.EncodeToString(sign(t, otherKey, content))), false},
+				{"garbage", content, []byte("not a signature"), false},
+			}
+			for _, tt := range tests {
+				err := v.verify("https://example.com/Taskfile.yml", tt.content, tt.sig)
+				if tt.ok && err != nil {
+					t.Errorf("%s: verify() = %v, want nil", tt.name, err)
+				}
+				if !tt.ok && (err == nil || !strings.Contains(err.Error(), "does not match --cosign-key")) {
+					t.Errorf("%s: verify() = %v, want a mismatch", tt.name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSignatureVerifierUnsigned(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writePublicKey(t, key)
+
+	v, err := newSignatureVerifier(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warn bytes.Buffer
+	v.warn = &warn
+	if err := v.verify("https://example.com/Taskfile.yml", nil, nil); err != nil {
+		t.Errorf("verify() unsigned = %v, want only a warning", err)
+	}
+	if !strings.Contains(warn.String(), "https://example.com/Taskfile.yml is not signed") {
+		t.Errorf("warning = %q", warn.String())
+	}
+
+	v, err = newSignatureVerifier(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.verify("https://example.com/Taskfile.yml", nil, nil); !errors.Is(err, errUnsigned) {
+		t.Errorf("verify() unsigned with --require-signed = %v, want %v", err, errUnsigned)
+	}
+
+	var none *signatureVerifier
+	if err := none.verify("https://example.com/Taskfile.yml", nil, []byte("anything")); err != nil {
+		t.Errorf("nil verify() = %v, want nil", err)
+	}
+}
+
+func TestNewSignatureVerifier(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "key.txt")
+	private := filepath.Join(dir, "cosign.key")
+	badKey := filepath.Join(dir, "bad.pub")
+	for path, data := range map[string][]byte{
+		notPEM:  []byte("hello"),
+		private: pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED SIGSTORE PRIVATE KEY", Bytes: []byte("x")}),
+		badKey:  pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("x")}),
+	} {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		require bool
+		want    string // in the error, "" for none
+	}{
+		{"no key", "", false, ""},
+		{"require without key", "", true, "--require-signed needs --cosign-key"},
+		{"missing file", filepath.Join(dir, "missing.pub"), false, "no such file"},
+		{"not PEM", notPEM, false, "is not a PEM public key"},
+		{"private key", private, false, "is not a PEM public key"},
+		{"bad key", badKey, false, "invalid public key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := newSignatureVerifier(tt.path, tt.require)
+			if tt.want == "" {
+				if err != nil || v != nil {
+					t.Errorf("newSignatureVerifier() = %v, %v, want no verifier", v, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("newSignatureVerifier() = %v, want an error with %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// taskBody is a task's commands as similarity compares them
+type taskBody struct {
+	name     string
+	lines    []string          // normalized commands, in order
+	original map[string]string // normalized command -> the first command as written
+	shingles map[string]bool
+	tokens   int
+}
+
+// similarPair is two tasks whose bodies score at least the threshold, with
+// the commands each has that the other does not
+type similarPair struct {
+	A          string   `json:"a"`
+	B          string   `json:"b"`
+	Similarity float64  `json:"similarity"`
+	OnlyA      []string `json:"only_a,omitempty"`
+	OnlyB      []string `json:"only_b,omitempty"`
+}
+
+// runSimilar reports pairs of tasks whose commands are nearly the same, the
+// copies that drifted apart after being pasted from one task into another
+func runSimilar(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("similar", flag.ExitOnError)
+	opts.register(fs)
+	threshold := fs.Float64("threshold", 0.7, "Report pairs whose Jaccard similarity is at least this, from 0 to 1")
+	minTokens := fs.Int("min-tokens", 4, "Skip tasks whose commands have fewer words than this")
+	format := fs.String("format", "text", "Output format: text or json")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if *threshold <= 0 || *threshold > 1 {
+		return fmt.Errorf("--threshold must be above 0 and at most 1")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	ws, err := openWorkspace(l)
+	if err != nil {
+		return err
+	}
+
+	var bodies []*taskBody
+	for name := range ws.merged.Tasks.All(byName) {
+		if b := ws.taskBody(name); b.tokens >= *minTokens {
+			bodies = append(bodies, b)
+		}
+	}
+	pairs := similarPairs(bodies, *threshold)
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(pairs)
+	}
+	if len(pairs) == 0 {
+		fmt.Printf("No tasks are at least %.0f%% similar\n", *threshold*100)
+		return nil
+	}
+	for i, p := range pairs {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%.0f%% %s ~ %s\n", p.Similarity*100, p.A, p.B)
+		for _, cmd := range p.OnlyA {
+			fmt.Printf("  - %s\n", cmd)
+		}
+		for _, cmd := range p.OnlyB {
+			fmt.Printf("  + %s\n", cmd)
+		}
+	}
+	return nil
+}
+
+// taskBody normalizes a task's commands the way duplicate detection does and
+// shingles them into word pairs, so a renamed variable costs nothing and a
+// changed flag only a little similarity. Calls of other tasks count as
+// commands.
+func (ws *workspace) taskBody(name string) *taskBody {
+	b := &taskBody{name: name, original: make(map[string]string), shingles: make(map[string]bool)}
+	task, _ := ws.merged.Tasks.Get(name)
+	for _, cmd := range task.Cmds {
+		written := cmd.Cmd
+		if cmd.Task != "" {
+			written = "task " + cmd.Task
+		}
+		if written == "" {
+			continue
+		}
+		words := strings.Fields(shellVar.ReplaceAllString(templateVar.ReplaceAllString(written, "$"), "$"))
+		if len(words) == 0 {
+			continue
+		}
+		line := strings.Join(words, " ")
+		b.lines = append(b.lines, line)
+		if _, ok := b.original[line]; !ok {
+			b.original[line] = strings.Join(strings.Fields(written), " ")
+		}
+		b.tokens += len(words)
+		// Boundaries make a command's first and last words shingles too
+		words = append(append([]string{"\x00"}, words...), "\x00")
+		for i := range len(words) - 1 {
+			b.shingles[words[i]+" "+words[i+1]] = true
+		}
+	}
+	return b
+}
+
+// similarPairs scores every pair of bodies, most similar first
+func similarPairs(bodies []*taskBody, threshold float64) []similarPair {
+	found := parallelMap(len(bodies), func(i int) []similarPair {
+		var pairs []similarPair
+		for _, other := range bodies[i+1:] {
+			if s := jaccard(bodies[i].shingles, other.shingles); s >= threshold {
+				pairs = append(pairs, similarPair{
+					A:          bodies[i].name,
+					B:          other.name,
+					Similarity: s,
+					OnlyA:      missingLines(bodies[i], other),
+					OnlyB:      missingLines(other, bodies[i]),
+				})
+			}
+		}
+		return pairs
+	})
+	pairs := []similarPair{}
+	for _, p := range found {
+		pairs = append(pairs, p...)
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Similarity > pairs[j].Similarity })
+	return pairs
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	shared := 0
+	for s := range a {
+		if b[s] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// missingLines lists the commands of a that b does not have, as a wrote them
+func missingLines(a, b *taskBody) []string {
+	var lines []string
+	for _, line := range a.lines {
+		if !slices.Contains(b.lines, line) && !slices.Contains(lines, a.original[line]) {
+			lines = append(lines, a.original[line])
+		}
+	}
+	return lines
+}
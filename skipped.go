@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	taskerrors "github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// skippedInclude is an include left out of the graph because it could not
+// be read: an optional one, or any remote one under --keep-going. Prefixes
+// are what the names of its tasks would start with, as in web:docs:.
+type skippedInclude struct {
+	From      string   `json:"from"`
+	Namespace string   `json:"namespace"`
+	URI       string   `json:"uri"`
+	Reason    string   `json:"reason"`
+	Required  bool     `json:"required,omitempty"`
+	Prefixes  []string `json:"prefixes,omitempty"`
+}
+
+// placeholderTaskfile stands in for an optional include that failed to
+// download, so the reader can finish the graph around it
+var placeholderTaskfile = []byte("version: '3'\n")
+
+// failedURI is the Taskfile a read failed to fetch or parse, when the error
+// says
+func failedURI(err error) (string, bool) {
+	var fetch taskerrors.TaskfileFetchFailedError
+	var notFound taskerrors.TaskfileNotFoundError
+	var uncached *taskerrors.TaskfileCacheNotFoundError
+	var decode *taskerrors.TaskfileDecodeError
+	var invalid *taskerrors.TaskfileInvalidError
+	var version *taskerrors.TaskfileVersionCheckError
+	switch {
+	case errors.As(err, &decode):
+		return decode.Location, true
+	case errors.As(err, &invalid):
+		return invalid.URI, true
+	case errors.As(err, &version):
+		return version.URI, true
+	case errors.As(err, &fetch):
+		return fetch.URI, true
+	case errors.As(err, &notFound):
+		return notFound.URI, true
+	case errors.As(err, &uncached):
+		return uncached.URI, true
+	}
+	return "", false
+}
+
+// skipFailed lets the next read go past a remote Taskfile the last one
+// failed to fetch, when every include of it is optional, or parse, with
+// --keep-going any of them: task skips an optional include it cannot
+// resolve, but fails on one that resolves and then 404s or is missing from
+// the cache offline. The reader gets an empty Taskfile for it, from its
+// cache and from the transport, and dropSkipped takes it out of the graph
+// again.
+func (l *loader) skipFailed(root string, err error) bool {
+	uri, ok := failedURI(err)
+	if !ok {
+		return false
+	}
+	if _, done := l.placeholders[uri]; done {
+		return false
+	}
+	from := l.includers(root, uri)
+	required := slices.ContainsFunc(from, func(s cycleStep) bool { return !s.optional })
+	if len(from) == 0 || required && !l.opts.keepGoing {
+		return false
+	}
+	node, nerr := taskfile.NewNode(uri, "", l.opts.insecure())
+	if nerr != nil {
+		return false
+	}
+	remote, ok := node.(taskfile.RemoteNode)
+	if !ok {
+		return false
+	}
+	key := remote.CacheKey()
+	files := map[string][]byte{
+		"yaml":      placeholderTaskfile,
+		"timestamp": []byte(time.Now().UTC().Format(time.RFC3339)),
+	}
+	for suffix, content := range files {
+		if os.WriteFile(l.store.workFile(key, suffix), content, 0o644) != nil {
+			return false
+		}
+	}
+	l.fetch.stub(uri, placeholderTaskfile)
+	if l.placeholders == nil {
+		l.placeholders = make(map[string]string)
+	}
+	l.placeholders[uri] = key
+	reason := skipReason(err)
+	for _, step := range from {
+		l.skipped = append(l.skipped, skippedInclude{From: step.uri, Namespace: step.namespace, URI: uri, Reason: reason, Required: !step.optional})
+		if !step.optional {
+			fmt.Fprintf(os.Stderr, "warning: --keep-going: skipped include %s (%s) of %s: %s\n", step.namespace, uri, displayPath(step.uri), reason)
+		}
+	}
+	return true
+}
+
+// skipReason is a reader error on one line, without task's prefix
+func skipReason(err error) string {
+	return strings.Join(strings.Fields(strings.TrimPrefix(err.Error(), "task: ")), " ")
+}
+
+// includers finds the Taskfiles including uri, and whether each include is
+// optional, by reading them directly, since the reader discards its partial
+// graph on error
+func (l *loader) includers(root, uri string) []cycleStep {
+	var from []cycleStep
+	queue := []string{root}
+	seen := map[string]bool{root: true}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		src, err := l.readSource(current)
+		if err != nil {
+			continue
+		}
+		for _, step := range l.rawIncludes(current, src) {
+			switch {
+			case step.uri == uri:
+				from = append(from, cycleStep{uri: current, namespace: step.namespace, optional: step.optional})
+			case step.err == nil && !seen[step.uri]:
+				seen[step.uri] = true
+				queue = append(queue, step.uri)
+			}
+		}
+	}
+	return from
+}
+
+// dropSkipped takes the placeholders of skipped includes out of a graph just
+// read, and records the optional includes the reader skipped on its own
+// because it couldn't resolve them, such as a local file that is missing
+func (l *loader) dropSkipped(g *ast.TaskfileGraph) error {
+	predecessors, err := g.PredecessorMap()
+	if err != nil {
+		return err
+	}
+	for uri := range l.placeholders {
+		for from := range predecessors[uri] {
+			if err := g.RemoveEdge(from, uri); err != nil {
+				return err
+			}
+		}
+		if err := g.RemoveVertex(uri); err != nil {
+			return err
+		}
+		delete(predecessors, uri)
+	}
+	for _, uri := range slices.Sorted(maps.Keys(predecessors)) {
+		src, err := l.readSource(uri)
+		if err != nil {
+			continue
+		}
+		for _, step := range l.rawIncludes(uri, src) {
+			if step.optional && step.err != nil {
+				l.skipped = append(l.skipped, skippedInclude{From: uri, Namespace: step.namespace, URI: step.uri, Reason: skipReason(step.err)})
+			}
+		}
+	}
+	slices.SortFunc(l.skipped, func(a, b skippedInclude) int {
+		return strings.Compare(a.From+"\x00"+a.Namespace, b.From+"\x00"+b.Namespace)
+	})
+
+	scopes, err := includeScopes(g)
+	if err != nil {
+		return err
+	}
+	for i, s := range l.skipped {
+		for _, scope := range scopes[s.From] {
+			l.skipped[i].Prefixes = append(l.skipped[i].Prefixes, scope.prefix()+s.Namespace+ast.NamespaceSeparator)
+		}
+	}
+	return nil
+}
+
+// unresolved finds the skipped include a task name belongs under, such as a
+// call of a task the failed Taskfile was expected to define
+func (l *loader) unresolved(name string) (skippedInclude, bool) {
+	if l == nil {
+		return skippedInclude{}, false
+	}
+	for _, s := range l.skipped {
+		for _, prefix := range s.Prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return s, true
+			}
+		}
+	}
+	return skippedInclude{}, false
+}
+
+// unresolved finds the skipped include a task name belongs under, when the
+// index has a loader to ask; the commands that build their task graph
+// without an index have none
+func (x *sourceIndex) unresolved(name string) (skippedInclude, bool) {
+	if x == nil {
+		return skippedInclude{}, false
+	}
+	return x.l.unresolved(name)
+}
+
+// clearPlaceholders removes the placeholders from the reader's cache and the
+// transport, so they are neither stored nor served after the read
+func (l *loader) clearPlaceholders() {
+	for uri, key := range l.placeholders {
+		for _, suffix := range []string{"yaml", "timestamp", "checksum"} {
+			os.Remove(l.store.workFile(key, suffix))
+		}
+		l.fetch.stub(uri, nil)
+	}
+	l.placeholders = nil
+}
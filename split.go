@@ -0,0 +1,303 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// runSplit proposes, and with --write performs, a decomposition of the root
+// Taskfile into one included file per namespace, or with --by communities
+// per group of tightly connected tasks
+func runSplit(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("split", flag.ExitOnError)
+	opts.register(fs)
+	write := fs.Bool("write", false, "Write the split files instead of only printing the proposal")
+	dest := fs.String("dest", "taskfiles", "Directory to write per-namespace Taskfiles into")
+	out := fs.String("out", "Taskfile.split.yml", "Path of the rewritten root Taskfile")
+	by := fs.String("by", "prefix", "Group tasks by: prefix, their first name segment, or communities of the dependency graph")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if *by != "prefix" && *by != "communities" {
+		return fmt.Errorf("unknown grouping %q", *by)
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	g, _, err := l.load()
+	if err != nil {
+		return err
+	}
+	root, err := rootURI(g)
+	if err != nil {
+		return err
+	}
+	src, err := l.readSource(root)
+	if err != nil {
+		return err
+	}
+
+	plan, err := planSplit(src, *by == "communities")
+	if err != nil {
+		return err
+	}
+	plan.print()
+
+	if !*write {
+		return nil
+	}
+	return plan.write(*dest, *out)
+}
+
+// splitPlan assigns every task of a Taskfile to a namespace group. The root
+// group has the empty name and stays in the root file.
+type splitPlan struct {
+	doc     yaml.Node
+	order   []string          // task names in file order
+	group   map[string]string // task name -> group
+	deps    map[string][]string
+	moved   map[string]bool // unprefixed tasks pulled into a group
+	blocked map[string]bool // groups that collide with an existing include
+
+	communities bool
+	modularity  float64 // of the communities found, when grouping by them
+}
+
+// planSplit groups tasks by their first name segment, then moves unprefixed
+// tasks whose every edge stays within a single group into that group. With
+// communities, the dependency graph alone decides the groups.
+func planSplit(src []byte, communities bool) (*splitPlan, error) {
+	p := &splitPlan{
+		group:       make(map[string]string),
+		moved:       make(map[string]bool),
+		blocked:     make(map[string]bool),
+		communities: communities,
+	}
+	if err := yaml.Unmarshal(src, &p.doc); err != nil {
+		return nil, err
+	}
+	var tf ast.Taskfile
+	if err := yaml.Unmarshal(src, &tf); err != nil {
+		return nil, err
+	}
+	p.deps = buildTaskDependencyGraph(&tf)
+
+	includes := mappingValue(documentRoot(&p.doc), "includes")
+	if communities {
+		for name := range tf.Tasks.All(byName) {
+			p.order = append(p.order, name)
+		}
+		p.groupByCommunity(func(ns string) bool { return mappingValue(includes, ns) != nil })
+		return p, nil
+	}
+	for name := range tf.Tasks.All(byName) {
+		p.order = append(p.order, name)
+		if ns, _, ok := strings.Cut(name, ast.NamespaceSeparator); ok {
+			if mappingValue(includes, ns) != nil {
+				p.blocked[ns] = true
+				continue
+			}
+			p.group[name] = ns
+		}
+	}
+
+	// Edges in both directions decide where an unprefixed task belongs
+	neighbours := make(map[string][]string)
+	for from, targets := range p.deps {
+		for _, to := range targets {
+			neighbours[from] = append(neighbours[from], to)
+			neighbours[to] = append(neighbours[to], from)
+		}
+	}
+	for _, name := range p.order {
+		if strings.Contains(name, ast.NamespaceSeparator) {
+			continue
+		}
+		groups := make(map[string]bool)
+		for _, n := range neighbours[name] {
+			if _, known := p.deps[n]; known {
+				groups[p.group[n]] = true
+			}
+		}
+		if len(groups) == 1 {
+			for g := range groups {
+				if g != "" {
+					p.group[name] = g
+					p.moved[name] = true
+				}
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// groups returns the non-root group names in sorted order
+func (p *splitPlan) groups() []string {
+	seen := make(map[string]bool)
+	var groups []string
+	for _, g := range p.group {
+		if g != "" && !seen[g] {
+			seen[g] = true
+			groups = append(groups, g)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// newName is the name a task will have once the split Taskfile is merged
+func (p *splitPlan) newName(task string) string {
+	if p.moved[task] {
+		return p.group[task] + ast.NamespaceSeparator + task
+	}
+	return task
+}
+
+// localName is a task's name inside its own group file
+func (p *splitPlan) localName(task string) string {
+	if p.moved[task] {
+		return task
+	}
+	return strings.TrimPrefix(task, p.group[task]+ast.NamespaceSeparator)
+}
+
+func (p *splitPlan) print() {
+	fmt.Printf("=== Split Proposal ===\n")
+	if p.communities {
+		fmt.Printf("Communities of the dependency graph (modularity %.2f)\n", p.modularity)
+	}
+	for _, g := range append([]string{""}, p.groups()...) {
+		internal, external := 0, 0
+		var members []string
+		for _, name := range p.order {
+			if p.group[name] != g {
+				continue
+			}
+			members = append(members, name)
+			for _, dep := range p.deps[name] {
+				if p.group[dep] == g {
+					internal++
+				} else {
+					external++
+				}
+			}
+		}
+		if g == "" {
+			fmt.Printf("Root Taskfile (%d tasks)\n", len(members))
+		} else {
+			fmt.Printf("Namespace %s (%d tasks, %d internal edges, %d external edges)\n", g, len(members), internal, external)
+		}
+		for _, name := range members {
+			if p.moved[name] {
+				fmt.Printf("  - %s (moved, becomes %s)\n", name, p.newName(name))
+			} else {
+				fmt.Printf("  - %s\n", name)
+			}
+		}
+	}
+	for _, ns := range slices.Sorted(maps.Keys(p.blocked)) {
+		fmt.Printf("Namespace %s kept in root: an include with that name already exists\n", ns)
+	}
+}
+
+// write emits one Taskfile per group into dest and a rewritten root Taskfile
+// at out whose includes reference them
+func (p *splitPlan) write(dest, out string) error {
+	top := documentRoot(&p.doc)
+	tasks := mappingValue(top, "tasks")
+	if tasks == nil {
+		return nil
+	}
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+
+	groupTasks := make(map[string]*yaml.Node)
+	rootTasks := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i+1 < len(tasks.Content); i += 2 {
+		name := tasks.Content[i].Value
+		task := tasks.Content[i+1]
+		g := p.group[name]
+		if g == "" {
+			if task.Kind == yaml.MappingNode {
+				rewriteTaskRefs(task, p.newName)
+			}
+			rootTasks.Content = append(rootTasks.Content, tasks.Content[i], task)
+			continue
+		}
+
+		if task.Kind == yaml.MappingNode {
+			rewriteTaskRefs(task, func(ref string) string {
+				if p.group[ref] == g {
+					return p.localName(ref)
+				}
+				return ast.NamespaceSeparator + p.newName(ref)
+			})
+			// A root-anchored alias keeps the old name of a moved task working
+			if p.moved[name] {
+				aliases := sequenceOrNew(mappingValue(task, "aliases"))
+				aliases.Content = append(aliases.Content, scalarNode(ast.NamespaceSeparator+name))
+				setMappingValue(task, "aliases", aliases)
+			}
+		}
+		if groupTasks[g] == nil {
+			groupTasks[g] = &yaml.Node{Kind: yaml.MappingNode}
+		}
+		groupTasks[g].Content = append(groupTasks[g].Content, scalarNode(p.localName(name)), task)
+	}
+
+	includes := mappingOrNew(mappingValue(top, "includes"))
+	outDir, err := filepath.Abs(filepath.Dir(out))
+	if err != nil {
+		return err
+	}
+	for _, g := range p.groups() {
+		file := &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(file, "version", mappingValue(top, "version"))
+		setMappingValue(file, "tasks", groupTasks[g])
+		data, err := encodeYAML(file)
+		if err != nil {
+			return err
+		}
+		target, err := filepath.Abs(filepath.Join(dest, g+".yml"))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0o644); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(outDir, target)
+		if err != nil {
+			return err
+		}
+		setMappingValue(includes, g, scalarNode("./"+filepath.ToSlash(rel)))
+		fmt.Printf("wrote %s\n", target)
+	}
+
+	setMappingValue(top, "includes", includes)
+	setMappingValue(top, "tasks", rootTasks)
+	data, err := encodeYAML(&p.doc)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", out)
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/go-task/task/v3/taskfile"
+)
+
+// stdinLocation is the URI go-task gives a Taskfile read from stdin
+const stdinLocation = "__stdin__"
+
+// stdinNode is the root Taskfile given as --taskfile -. Stdin can only be
+// read once, so the node serves the copy the loader keeps, which also lets
+// later passes over the source (positions, cycle paths) see it.
+type stdinNode struct {
+	*taskfile.StdinNode
+	src []byte
+}
+
+func (n *stdinNode) Read() ([]byte, error) {
+	return n.src, nil
+}
+
+// newStdinNode reads stdin, once, into a root node whose relative includes
+// resolve against --base-dir, or the working directory when it is unset
+func (l *loader) newStdinNode() (taskfile.Node, error) {
+	if l.stdin == nil {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Taskfile from stdin: %w", err)
+		}
+		l.stdin = src
+	}
+
+	dir := l.opts.baseDir
+	if dir == "" {
+		dir = "."
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	node, err := taskfile.NewStdinNode(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &stdinNode{StdinNode: node, src: l.stdin}, nil
+}
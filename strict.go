@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// checkStrict fails a load under --strict on anything meerkat would
+// otherwise warn about and carry on from: skipped includes, Taskfiles
+// fetched over plain HTTP or redirected to another host, and calls of tasks
+// that do not exist. Unsigned
+// Taskfiles are refused as with --require-signed.
+func (l *loader) checkStrict(g *ast.TaskfileGraph, merged *ast.Taskfile) error {
+	if !l.opts.strict {
+		return nil
+	}
+	var problems []string
+	for _, s := range l.skipped {
+		problems = append(problems, fmt.Sprintf("include %s of %s was skipped: %s", s.Namespace, displayPath(s.From), s.Reason))
+	}
+
+	adjacency, err := g.AdjacencyMap()
+	if err != nil {
+		return err
+	}
+	redirects := l.store.redirects()
+	for _, uri := range slices.Sorted(maps.Keys(adjacency)) {
+		if strings.HasPrefix(uri, "http://") {
+			problems = append(problems, fmt.Sprintf("%s is fetched over plain HTTP", uri))
+		}
+		if to, ok := redirects[uri]; ok && crossHostRedirect(uri, to) {
+			problems = append(problems, fmt.Sprintf("%s redirects to another host, %s", uri, to))
+		}
+	}
+
+	ws, err := newWorkspace(l, g, merged)
+	if err != nil {
+		return err
+	}
+	for name, task := range merged.Tasks.All(byName) {
+		var calls []string
+		for _, dep := range task.Deps {
+			calls = append(calls, dep.Task)
+		}
+		for _, cmd := range task.Cmds {
+			if cmd.Task != "" {
+				calls = append(calls, cmd.Task)
+			}
+		}
+		for _, call := range calls {
+			if strings.Contains(call, "{{") {
+				continue
+			}
+			if _, _, ok := ws.resolve(call); !ok {
+				problems = append(problems, fmt.Sprintf("%s (%s) calls unknown task %s", name, taskPos(task), call))
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &failure{fmt.Sprintf("--strict: %s:\n  - %s", plural(len(problems), "problem"), strings.Join(problems, "\n  - "))}
+}
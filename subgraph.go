@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// rootInclude is one entry of the root Taskfile's includes, with the lines
+// it spans so it can be left out of what the reader sees
+type rootInclude struct {
+	namespace string
+	aliases   []string
+	flatten   bool
+	first     int // first line of the entry
+	last      int // last line, including comments up to the next entry
+}
+
+// names are the namespace and aliases a reference may call the include by
+func (inc rootInclude) names() []string {
+	return append([]string{inc.namespace}, inc.aliases...)
+}
+
+// prunedNode is the root Taskfile with some of its includes blanked out. The
+// lines are kept, so positions read from the real file still line up.
+type prunedNode struct {
+	taskfile.Node
+	src []byte
+}
+
+func (n *prunedNode) Read() ([]byte, error) {
+	return n.src, nil
+}
+
+// loadSubgraph loads only the includes of the root Taskfile that the trees
+// of the starts can reach. It starts from the namespaces they are in, and
+// loads again with an include added whenever the tree calls into one left
+// out, so a namespace is only skipped once nothing reachable refers to it.
+// Includes of included Taskfiles are read as usual, and anything that rules
+// out knowing which namespaces are called, such as templated task names,
+// falls back to the full graph.
+func (l *loader) loadSubgraph(starts ...string) (*ast.TaskfileGraph, *ast.Taskfile, error) {
+	uri := l.opts.taskfileURL
+	if uri == "-" || taskfile.IsRemoteEntrypoint(uri) {
+		return l.load()
+	}
+	node, err := taskfile.NewRootNode(uri, "", l.opts.insecure(), l.opts.timeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create root node: %w", err)
+	}
+	src, err := os.ReadFile(node.Location())
+	if err != nil {
+		return nil, nil, err
+	}
+	includes, ok := rootIncludes(src)
+	if !ok || len(includes) == 0 {
+		return l.load()
+	}
+
+	needed := make(map[string]bool)
+	for _, inc := range includes {
+		// Flattened tasks keep their names, so any of them may be called
+		if inc.flatten {
+			needed[inc.namespace] = true
+		}
+	}
+	for _, start := range starts {
+		if inc, ok := includeOf(includes, start); ok {
+			needed[inc.namespace] = true
+		}
+	}
+	defer func() { l.root = nil }()
+	for {
+		l.root = &prunedNode{Node: node, src: pruneIncludes(src, includes, needed)}
+		g, merged, err := l.load()
+		if err != nil {
+			return nil, nil, err
+		}
+		missing, templated := subtreeMissing(merged, starts...)
+		if templated {
+			l.dlog.Debug("subgraph: " + strings.Join(starts, ", ") + " calls a templated task name, reading every include")
+			l.root = nil
+			return l.load()
+		}
+		added := false
+		for _, name := range missing {
+			if inc, ok := includeOf(includes, name); ok && !needed[inc.namespace] {
+				needed[inc.namespace] = true
+				added = true
+			}
+		}
+		if !added {
+			l.dlog.Debug(fmt.Sprintf("subgraph: read %d of %d includes of %s", len(needed), len(includes), uri))
+			return g, merged, nil
+		}
+	}
+}
+
+// rootIncludes finds the entries of a block-style includes mapping. It
+// reports false when the includes cannot be cut out line by line.
+func rootIncludes(src []byte) ([]rootInclude, bool) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, false
+	}
+	root := documentRoot(&doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil, false
+	}
+	at := -1
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "includes" {
+			at = i
+		}
+	}
+	if at < 0 {
+		return nil, true
+	}
+	key, value := root.Content[at], root.Content[at+1]
+	if value.Kind != yaml.MappingNode || value.Style&yaml.FlowStyle != 0 {
+		return nil, false
+	}
+
+	// The last entry runs up to the next top-level key
+	end := strings.Count(string(src), "\n") + 1
+	if at+2 < len(root.Content) {
+		end = root.Content[at+2].Line - 1
+	}
+	var includes []rootInclude
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		k := value.Content[i]
+		if k.Line <= key.Line {
+			return nil, false
+		}
+		var inc ast.Include
+		if err := value.Content[i+1].Decode(&inc); err != nil {
+			return nil, false
+		}
+		last := end
+		if i+2 < len(value.Content) {
+			last = value.Content[i+2].Line - 1
+		}
+		includes = append(includes, rootInclude{namespace: k.Value, aliases: inc.Aliases, flatten: inc.Flatten, first: k.Line, last: last})
+	}
+	return includes, true
+}
+
+// pruneIncludes blanks the lines of every include not needed, and the
+// includes key itself when none is
+func pruneIncludes(src []byte, includes []rootInclude, needed map[string]bool) []byte {
+	lines := strings.Split(string(src), "\n")
+	blank := func(first, last int) {
+		for n := first; n <= last && n <= len(lines); n++ {
+			lines[n-1] = ""
+		}
+	}
+	kept := 0
+	for _, inc := range includes {
+		if needed[inc.namespace] {
+			kept++
+			continue
+		}
+		blank(inc.first, inc.last)
+	}
+	if kept == 0 {
+		// The key sits on the line above the first entry
+		blank(includes[0].first-1, includes[0].first-1)
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// includeOf finds the include a task name is qualified with
+func includeOf(includes []rootInclude, name string) (rootInclude, bool) {
+	name = strings.TrimPrefix(name, ":")
+	for _, inc := range includes {
+		for _, ns := range inc.names() {
+			if name == ns || strings.HasPrefix(name, ns+":") {
+				return inc, true
+			}
+		}
+	}
+	return rootInclude{}, false
+}
+
+// subtreeMissing walks the calls reachable from the starts and lists the names
+// that no merged task or alias answers to. templated is set when a call's
+// name is only known once its template runs.
+func subtreeMissing(merged *ast.Taskfile, starts ...string) (missing []string, templated bool) {
+	aliases := make(map[string]string)
+	for name, task := range merged.Tasks.All(byName) {
+		for _, alias := range task.Aliases {
+			aliases[alias] = name
+		}
+	}
+	seen := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if seen[name] || templated {
+			return
+		}
+		seen[name] = true
+		if strings.Contains(name, "{{") {
+			templated = true
+			return
+		}
+		task, ok := merged.Tasks.Get(strings.TrimPrefix(name, ":"))
+		if !ok {
+			if real, isAlias := aliases[name]; isAlias {
+				task, ok = merged.Tasks.Get(real)
+			}
+		}
+		if !ok {
+			missing = append(missing, name)
+			return
+		}
+		for _, dep := range task.Deps {
+			visit(dep.Task)
+		}
+		for _, cmd := range task.Cmds {
+			if cmd.Task != "" {
+				visit(cmd.Task)
+			}
+		}
+	}
+	for _, start := range starts {
+		visit(start)
+	}
+	slices.Sort(missing)
+	return missing, templated
+}
+
+// subgraph keeps the tasks reachable from any of the starts through deps
+// and calls, and the references between them
+func (tg *taskGraph) subgraph(starts ...string) *taskGraph {
+	next := make(map[string][]string)
+	for _, e := range tg.Edges {
+		if e.Kind != edgeInferred {
+			next[e.From] = append(next[e.From], e.To)
+		}
+	}
+	keep := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if keep[name] {
+			return
+		}
+		keep[name] = true
+		for _, to := range next[name] {
+			visit(to)
+		}
+	}
+	for _, start := range starts {
+		visit(start)
+	}
+
+	kept := &taskGraph{}
+	for _, n := range tg.Nodes {
+		if keep[n.Name] {
+			kept.Nodes = append(kept.Nodes, n)
+		}
+	}
+	for _, e := range tg.Edges {
+		if keep[e.From] && keep[e.To] {
+			kept.Edges = append(kept.Edges, e)
+		}
+	}
+	return kept
+}
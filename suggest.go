@@ -0,0 +1,73 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// maxSuggestions caps how many names a typo hint offers
+const maxSuggestions = 5
+
+// suggestTasks lists the tasks a mistyped name may have meant: first those
+// that carry it, or its last part, under another namespace, then the names
+// within a few edits of it, closest first
+func suggestTasks(name string, tasks *ast.Tasks) []string {
+	leaf := name[strings.LastIndex(name, ast.NamespaceSeparator)+1:]
+	limit := max(2, len(name)/3)
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var qualified []string
+	var close []candidate
+	for task := range tasks.All(byName) {
+		if task == name {
+			continue
+		}
+		if strings.HasSuffix(task, ast.NamespaceSeparator+leaf) {
+			qualified = append(qualified, task)
+		} else if d := levenshtein(name, task); d <= limit {
+			close = append(close, candidate{task, d})
+		}
+	}
+	slices.SortStableFunc(close, func(a, b candidate) int { return a.distance - b.distance })
+	for _, c := range close {
+		qualified = append(qualified, c.name)
+	}
+	return qualified[:min(len(qualified), maxSuggestions)]
+}
+
+// didYouMean is the hint appended to a message about an unknown task, empty
+// when nothing comes close
+func didYouMean(name string, tasks *ast.Tasks) string {
+	suggestions := suggestTasks(name, tasks)
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return "; did you mean: " + strings.Join(suggestions, ", ") + "?"
+}
+
+// levenshtein counts the single-rune insertions, deletions and substitutions
+// turning a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := range ra {
+		cur[0] = i + 1
+		for j := range rb {
+			cost := 1
+			if ra[i] == rb[j] {
+				cost = 0
+			}
+			cur[j+1] = min(prev[j+1]+1, cur[j]+1, prev[j]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
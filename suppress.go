@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ignoreMarker starts a comment that silences findings of some checks for
+// the task or command it sits above or after:
+//
+//	build:
+//	  cmds:
+//	    # meerkat:ignore portability CI images are always Debian
+//	    - sudo apt-get install -y jq
+//
+// Several checks may be named with commas; everything after them is the
+// reason, kept for --list-suppressions.
+const ignoreMarker = labelMarker + "ignore"
+
+// suppression is one meerkat:ignore comment. Pos is the task or command it
+// applies to; Used counts the findings it silenced in this run.
+type suppression struct {
+	Rules   []string
+	Reason  string
+	Task    string
+	Command bool // applies to one command, not the whole task
+	Pos     sourcePos
+	Used    int
+}
+
+// parseIgnores reads the meerkat:ignore lines of a comment
+func parseIgnores(comment string) []suppression {
+	var found []suppression
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		rest, ok := strings.CutPrefix(line, ignoreMarker)
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		found = append(found, suppression{
+			Rules:  strings.Split(fields[0], ","),
+			Reason: strings.Join(fields[1:], " "),
+		})
+	}
+	return found
+}
+
+// suppressions collects the meerkat:ignore comments on every merged task
+// and on each of its commands
+func (ws *workspace) suppressions() []*suppression {
+	var names []string
+	for name := range ws.merged.Tasks.All(byName) {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var all []*suppression
+	add := func(task string, command bool, pos sourcePos, comments ...string) {
+		for _, s := range parseIgnores(strings.Join(comments, "\n")) {
+			s.Task, s.Command, s.Pos = task, command, pos
+			all = append(all, &s)
+		}
+	}
+	for _, name := range names {
+		task, _ := ws.merged.Tasks.Get(name)
+		src := ws.idx.source(task)
+		if src.key == nil {
+			continue
+		}
+		add(name, false, taskPos(task), src.key.HeadComment, src.key.LineComment)
+
+		cmds := src.value
+		if cmds.Kind == yaml.MappingNode {
+			cmds = mappingValue(cmds, "cmds")
+		}
+		if cmds == nil || cmds.Kind != yaml.SequenceNode {
+			continue
+		}
+		for i, item := range cmds.Content {
+			comments := []string{item.HeadComment, item.LineComment}
+			// A comment after the first line of a mapping command belongs to
+			// its first value
+			if item.Kind == yaml.MappingNode && len(item.Content) >= 2 {
+				comments = append(comments, item.Content[0].HeadComment, item.Content[0].LineComment, item.Content[1].LineComment)
+			}
+			add(name, true, ws.idx.cmdPos(task, i), comments...)
+		}
+	}
+	return all
+}
+
+// suppress drops the findings a suppression covers and counts, on each
+// suppression, the findings it silenced
+func suppress(findings []finding, suppressions []*suppression) []finding {
+	var kept []finding
+	for _, f := range findings {
+		silenced := false
+		for _, s := range suppressions {
+			if s.Task != f.Task || !slices.Contains(s.Rules, f.Rule) || (s.Command && s.Pos != f.Pos) {
+				continue
+			}
+			s.Used++
+			silenced = true
+		}
+		if !silenced {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// writeSuppressions lists every suppression with what it silenced, so
+// stale ones and ones naming unknown checks can be cleaned up
+func writeSuppressions(w io.Writer, suppressions []*suppression, checks []lintCheck) {
+	for _, s := range suppressions {
+		target := "task " + s.Task
+		if s.Command {
+			target = "command of " + s.Task
+		}
+		reason := s.Reason
+		if reason == "" {
+			reason = "(no reason given)"
+		}
+		unknown := slices.IndexFunc(s.Rules, func(rule string) bool {
+			return !slices.ContainsFunc(checks, func(c lintCheck) bool { return c.name == rule })
+		})
+		var status string
+		switch {
+		case unknown >= 0:
+			status = fmt.Sprintf("unknown check %q", s.Rules[unknown])
+		case s.Used == 0:
+			status = "unused"
+		default:
+			status = "silenced " + plural(s.Used, "finding")
+		}
+		fmt.Fprintf(w, "%s: %s: ignore %s: %s [%s]\n", s.Pos, target, strings.Join(s.Rules, ","), reason, status)
+	}
+	fmt.Fprintf(w, "%s\n", plural(len(suppressions), "suppression"))
+}
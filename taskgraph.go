@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// taskGraph is the task-level dependency graph shared by the exporters
+type taskGraph struct {
+	Nodes []taskNode
+	Edges []taskEdge
+}
+
+// taskNode is one task, or a referenced task that does not exist
+type taskNode struct {
+	Name       string            `json:"name"`
+	Desc       string            `json:"desc,omitempty"`
+	Namespace  string            `json:"namespace,omitempty"` // include namespace the task was merged under, "" for root
+	Taskfile   string            `json:"taskfile,omitempty"`  // URI of the file that defines the task
+	Location   string            `json:"location,omitempty"`  // file:line:col of the task's key
+	Labels     map[string]string `json:"labels,omitempty"`    // meerkat: comment annotations
+	Requires   []string          `json:"requires,omitempty"`  // requirement classes of its commands
+	Commands   int               `json:"commands,omitempty"`  // entries in cmds, task calls included
+	Missing    bool              `json:"missing,omitempty"`
+	Unresolved bool              `json:"unresolved,omitempty"` // missing under an include that could not be read
+}
+
+// Edge kinds, from the strongest declaration to the loosest inference
+const (
+	edgeDep      = "dep"      // listed in deps
+	edgeCall     = "call"     // task: entry in cmds
+	edgeDefer    = "defer"    // deferred task: entry in cmds
+	edgeInferred = "inferred" // sources of one task match generates of another
+)
+
+// taskEdge is a reference from one task to another. Weight counts how many
+// times the reference is made, including static for-loop iterations, and
+// Location is where the first of them is written.
+type taskEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Kind     string `json:"kind"`
+	Weight   int    `json:"weight"`
+	Location string `json:"location,omitempty"`
+}
+
+// byName is the order tasks are visited in. The merged Taskfile keeps tasks
+// in the order includes happened to be merged, which can change from one
+// run to the next, so every listing and export walks them by name instead.
+func byName(names, _ []string) []string {
+	slices.Sort(names)
+	return names
+}
+
+// buildTaskGraph builds the exportable task graph from the merged Taskfile,
+// using the inclusion graph to recover each task's include namespace and the
+// source index, when given, to locate each reference
+func buildTaskGraph(g *ast.TaskfileGraph, tf *ast.Taskfile, idx *sourceIndex) (*taskGraph, error) {
+	scopes, err := includeScopes(g)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range tf.Tasks.All(byName) {
+		names = append(names, name)
+	}
+	tg := &taskGraph{}
+	tg.Nodes = parallelMap(len(names), func(i int) taskNode {
+		task, _ := tf.Tasks.Get(names[i])
+		return taskNode{
+			Name:      names[i],
+			Desc:      task.Desc,
+			Namespace: taskNamespace(names[i], task, scopes),
+			Taskfile:  task.Location.Taskfile,
+			Location:  taskPos(task).String(),
+			Labels:    idx.labels(task),
+			Requires:  requirementClasses(taskRequirements(task)),
+			Commands:  len(task.Cmds),
+		}
+	})
+	known := make(map[string]bool)
+	for _, n := range tg.Nodes {
+		known[n.Name] = true
+	}
+
+	for _, e := range taskEdges(tf, idx) {
+		if !known[e.To] {
+			known[e.To] = true
+			_, unresolved := idx.unresolved(e.To)
+			tg.Nodes = append(tg.Nodes, taskNode{Name: e.To, Missing: true, Unresolved: unresolved})
+		}
+		tg.Edges = append(tg.Edges, e)
+	}
+
+	sort.Slice(tg.Nodes, func(i, j int) bool { return tg.Nodes[i].Name < tg.Nodes[j].Name })
+	sort.Slice(tg.Edges, func(i, j int) bool {
+		if tg.Edges[i].From != tg.Edges[j].From {
+			return tg.Edges[i].From < tg.Edges[j].From
+		}
+		if tg.Edges[i].To != tg.Edges[j].To {
+			return tg.Edges[i].To < tg.Edges[j].To
+		}
+		return tg.Edges[i].Kind < tg.Edges[j].Kind
+	})
+	return tg, nil
+}
+
+// taskEdges lists every task reference in the merged Taskfile with its kind,
+// plus edges inferred from one task's sources matching another's generates
+func taskEdges(tf *ast.Taskfile, idx *sourceIndex) []taskEdge {
+	var names []string
+	var tasks []*ast.Task
+	for name, task := range tf.Tasks.All(byName) {
+		names = append(names, name)
+		tasks = append(tasks, task)
+	}
+
+	// Each task's references are located, and its sources matched against
+	// every other task's generates, on its own worker
+	var edges []taskEdge
+	for _, found := range parallelMap(len(tasks), func(i int) []taskEdge {
+		return taskEdgesFrom(names, tasks, i, idx)
+	}) {
+		edges = append(edges, found...)
+	}
+	return collapseEdges(edges)
+}
+
+// taskEdgesFrom lists the references of the i-th task and the edges
+// inferred from its sources
+func taskEdgesFrom(names []string, tasks []*ast.Task, i int, idx *sourceIndex) []taskEdge {
+	name, task := names[i], tasks[i]
+	var edges []taskEdge
+	for k, dep := range task.Deps {
+		edges = append(edges, taskEdge{
+			From: name, To: dep.Task, Kind: edgeDep, Weight: loopCount(dep.For),
+			Location: idx.depPos(task, k).String(),
+		})
+	}
+	for k, cmd := range task.Cmds {
+		if cmd.Task == "" {
+			continue
+		}
+		kind := edgeCall
+		if cmd.Defer {
+			kind = edgeDefer
+		}
+		edges = append(edges, taskEdge{
+			From: name, To: cmd.Task, Kind: kind, Weight: loopCount(cmd.For),
+			Location: idx.cmdPos(task, k).String(),
+		})
+	}
+
+	// A task whose sources match another task's generates consumes its output
+	for j, p := range tasks {
+		if j != i && globsOverlap(task.Dir, task.Sources, p.Dir, p.Generates) {
+			edges = append(edges, taskEdge{From: name, To: names[j], Kind: edgeInferred, Weight: 1, Location: taskPos(task).String()})
+		}
+	}
+	return edges
+}
+
+// loopCount is how many times a for-loop runs its body, when that is known
+// statically; dynamic loops and plain references count once
+func loopCount(f *ast.For) int {
+	switch {
+	case f == nil:
+		return 1
+	case len(f.List) > 0:
+		return len(f.List)
+	case f.Matrix.Len() > 0:
+		count := 1
+		for _, row := range f.Matrix.All() {
+			if len(row.Value) > 0 {
+				count *= len(row.Value)
+			}
+		}
+		return count
+	default:
+		return 1
+	}
+}
+
+// collapseEdges merges repeated references of the same kind into one edge
+// whose weight is the sum of theirs
+func collapseEdges(edges []taskEdge) []taskEdge {
+	type key struct{ from, to, kind string }
+	index := make(map[key]int)
+	var collapsed []taskEdge
+	for _, e := range edges {
+		k := key{e.From, e.To, e.Kind}
+		if i, ok := index[k]; ok {
+			collapsed[i].Weight += e.Weight
+			continue
+		}
+		index[k] = len(collapsed)
+		collapsed = append(collapsed, e)
+	}
+	return collapsed
+}
+
+// globsOverlap reports whether any source glob matches any generated path.
+// Globs are compared relative to their task directories.
+func globsOverlap(sourceDir string, sources []*ast.Glob, generateDir string, generates []*ast.Glob) bool {
+	for _, s := range sources {
+		if s.Negate {
+			continue
+		}
+		source := path.Join(sourceDir, s.Glob)
+		for _, g := range generates {
+			if g.Negate {
+				continue
+			}
+			generated := path.Join(generateDir, g.Glob)
+			if source == generated {
+				return true
+			}
+			if ok, _ := path.Match(source, generated); ok {
+				return true
+			}
+			if ok, _ := path.Match(generated, source); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// taskNamespace finds the include namespace of a merged task
+func taskNamespace(name string, task *ast.Task, scopes map[string][]includeScope) string {
+	scope, _ := taskScope(name, task, scopes)
+	return strings.TrimSuffix(scope.prefix(), ast.NamespaceSeparator)
+}
+
+// taskScope finds the scope a merged task came from: among the scopes its
+// defining file was merged under, the one with the longest prefix that the
+// task's name carries
+func taskScope(name string, task *ast.Task, scopes map[string][]includeScope) (includeScope, bool) {
+	var best includeScope
+	found := false
+	for _, scope := range scopes[task.Location.Taskfile] {
+		prefix := scope.prefix()
+		if strings.HasPrefix(name, prefix) && (!found || len(prefix) > len(best.prefix())) {
+			best, found = scope, true
+		}
+	}
+	return best, found
+}
+
+// namespaces returns the distinct non-root namespaces in sorted order
+func (tg *taskGraph) namespaces() []string {
+	seen := make(map[string]bool)
+	var namespaces []string
+	for _, n := range tg.Nodes {
+		if n.Namespace != "" && !seen[n.Namespace] {
+			seen[n.Namespace] = true
+			namespaces = append(namespaces, n.Namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// printStats prints task and edge counts and every edge with a weight above
+// one. Tasks that are referenced but not defined are counted apart.
+func printStats(w io.Writer, tg *taskGraph) {
+	tasks, missing := 0, 0
+	for _, n := range tg.Nodes {
+		if n.Missing {
+			missing++
+		} else {
+			tasks++
+		}
+	}
+	references := 0
+	var repeated []taskEdge
+	for _, e := range tg.Edges {
+		references += e.Weight
+		if e.Weight > 1 {
+			repeated = append(repeated, e)
+		}
+	}
+
+	fmt.Fprintf(w, "Tasks: %d\n", tasks)
+	if missing > 0 {
+		fmt.Fprintf(w, "Missing tasks: %d (referenced but not defined)\n", missing)
+	}
+	fmt.Fprintf(w, "Edges: %d (%d references)\n", len(tg.Edges), references)
+	if len(repeated) > 0 {
+		fmt.Fprintf(w, "Repeated references:\n")
+		for _, e := range repeated {
+			fmt.Fprintf(w, "  - %s -> %s (%s) x%d\n", e.From, e.To, e.Kind, e.Weight)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildTaskGraphMissingTask(t *testing.T) {
+	l, g, merged := loadTest(t, map[string]string{
+		"Taskfile.yml": `version: '3'
+tasks:
+  build:
+    cmds:
+      - task: missing-task
+`,
+	})
+	for name, idx := range map[string]*sourceIndex{"without index": nil, "with index": newSourceIndex(l)} {
+		t.Run(name, func(t *testing.T) {
+			tg, err := buildTaskGraph(g, merged, idx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var missing *taskNode
+			for i, n := range tg.Nodes {
+				if n.Name == "missing-task" {
+					missing = &tg.Nodes[i]
+				}
+			}
+			if missing == nil || !missing.Missing || missing.Unresolved {
+				t.Errorf("missing-task node = %+v, want missing and not unresolved", missing)
+			}
+		})
+	}
+}
+
+func TestPrintStats(t *testing.T) {
+	_, g, merged := loadTest(t, map[string]string{
+		"Taskfile.yml": `version: '3'
+tasks:
+  build:
+    deps: [gen]
+    cmds:
+      - task: lint
+      - task: lint
+`,
+	})
+	tg, err := buildTaskGraph(g, merged, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	printStats(&b, tg)
+	want := `Tasks: 1
+Missing tasks: 2 (referenced but not defined)
+Edges: 2 (3 references)
+Repeated references:
+  - build -> lint (call) x2
+`
+	if b.String() != want {
+		t.Errorf("printStats() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
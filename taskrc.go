@@ -0,0 +1,224 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-task/task/v3/experiments"
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskrc"
+	rcast "github.com/go-task/task/v3/taskrc/ast"
+)
+
+// taskRCDir is where Task would look for .taskrc.yml when run on the
+// Taskfile: its directory for a local one, and the working directory, or
+// --base-dir for stdin, otherwise
+func taskRCDir(o *options) string {
+	switch {
+	case o.taskfileURL == "-":
+		if o.baseDir != "" {
+			return o.baseDir
+		}
+		return "."
+	case taskfile.IsRemoteEntrypoint(o.taskfileURL):
+		return "."
+	}
+	if info, err := os.Stat(o.taskfileURL); err == nil && info.IsDir() {
+		return o.taskfileURL
+	}
+	return filepath.Dir(o.taskfileURL)
+}
+
+// readTaskRC reads the Task settings that apply to the Taskfile, merged the
+// way Task merges them: $XDG_CONFIG_HOME/task/taskrc.yml, then ~/.taskrc.yml,
+// then every .taskrc.yml from the root of the filesystem down to its
+// directory, the nearest winning, and Task's environment variables over all
+// of them
+func readTaskRC(o *options) (*rcast.TaskRC, error) {
+	rc, err := taskrc.GetConfig(taskRCDir(o))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .taskrc.yml: %w", err)
+	}
+	if rc == nil {
+		rc = &rcast.TaskRC{}
+	}
+	for _, s := range taskEnvSettings {
+		if value, ok := os.LookupEnv("TASK_" + s.name); ok && value != "" {
+			if err := s.set(rc, value); err != nil {
+				return nil, fmt.Errorf("invalid TASK_%s: %w", s.name, err)
+			}
+		}
+	}
+	return rc, nil
+}
+
+// taskEnvSettings are the TASK_ environment variables task reads in place of
+// a setting of .taskrc.yml
+var taskEnvSettings = []struct {
+	name string
+	set  func(rc *rcast.TaskRC, value string) error
+}{
+	{"TEMP_DIR", func(rc *rcast.TaskRC, v string) error { rc.TempDir = &v; return nil }},
+	{"REMOTE_INSECURE", func(rc *rcast.TaskRC, v string) error { return parseSetting(v, strconv.ParseBool, &rc.Remote.Insecure) }},
+	{"REMOTE_OFFLINE", func(rc *rcast.TaskRC, v string) error { return parseSetting(v, strconv.ParseBool, &rc.Remote.Offline) }},
+	{"REMOTE_TIMEOUT", func(rc *rcast.TaskRC, v string) error { return parseSetting(v, time.ParseDuration, &rc.Remote.Timeout) }},
+	{"REMOTE_CACHE_EXPIRY", func(rc *rcast.TaskRC, v string) error {
+		return parseSetting(v, time.ParseDuration, &rc.Remote.CacheExpiry)
+	}},
+	{"REMOTE_TRUSTED_HOSTS", func(rc *rcast.TaskRC, v string) error { rc.Remote.TrustedHosts = strings.Split(v, ","); return nil }},
+	{"REMOTE_CACERT", func(rc *rcast.TaskRC, v string) error { rc.Remote.CACert = &v; return nil }},
+	{"REMOTE_CERT", func(rc *rcast.TaskRC, v string) error { rc.Remote.Cert = &v; return nil }},
+	{"REMOTE_CERT_KEY", func(rc *rcast.TaskRC, v string) error { rc.Remote.CertKey = &v; return nil }},
+}
+
+// parseSetting parses a setting's value into the field it overrides
+func parseSetting[T any](value string, parse func(string) (T, error), field **T) error {
+	v, err := parse(value)
+	if err != nil {
+		return err
+	}
+	*field = &v
+	return nil
+}
+
+// taskTempDir is where Task keeps its temporary files, and meerkat the
+// reader's cache while it runs: TASK_TEMP_DIR or temp-dir, relative to the
+// Taskfile's directory, or "" for the system's temporary directory
+func taskTempDir(o *options) (string, error) {
+	if o.rc == nil || o.rc.TempDir == nil || *o.rc.TempDir == "" {
+		return "", nil
+	}
+	dir := *o.rc.TempDir
+	if rest, ok := strings.CutPrefix(dir, "~"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, rest)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(taskRCDir(o), dir)
+	}
+	return dir, os.MkdirAll(dir, 0o755)
+}
+
+// setupExperiments reads Task's experiments from TASK_X_ variables, .env and
+// the settings. meerkat reads remote Taskfiles whether or not task would,
+// unless the experiment is turned off explicitly.
+func setupExperiments(o *options) error {
+	dir := taskRCDir(o)
+	experiments.ParseWithConfig(dir, o.rc)
+	if err := experiments.Validate(); err != nil {
+		return fmt.Errorf("failed to validate experiments: %w", err)
+	}
+	_, inEnv := os.LookupEnv("TASK_X_REMOTE_TASKFILES")
+	inRC := false
+	if o.rc != nil {
+		_, inRC = o.rc.Experiments["REMOTE_TASKFILES"]
+	}
+	if !inEnv && !inRC {
+		experiments.RemoteTaskfiles.Value = 1
+		impliedRemote = true
+	}
+	return nil
+}
+
+// impliedRemote is set when remote Taskfiles are read without the
+// experiment being turned on
+var impliedRemote bool
+
+// enabledExperiments lists the experiments on, as in REMOTE_TASKFILES=1
+func enabledExperiments() []string {
+	var on []string
+	for _, x := range []experiments.Experiment{experiments.GentleForce, experiments.RemoteTaskfiles, experiments.EnvPrecedence} {
+		if !x.Enabled() {
+			continue
+		}
+		s := fmt.Sprintf("%s=%d", x.Name, x.Value)
+		if x.Name == experiments.RemoteTaskfiles.Name && impliedRemote {
+			s += " (implied)"
+		}
+		on = append(on, s)
+	}
+	return on
+}
+
+// applyTaskRC reads the Task settings into o and makes their remote
+// timeout, cache expiry, client certificate and CA the defaults of
+// --timeout, --cache-expiry, --client-cert, --client-key and --ca-file, so
+// the command line and the config file still win over them; for the CA,
+// MEERKAT_CA_FILE does too
+func applyTaskRC(fs *flag.FlagSet, o *options) error {
+	rc, err := readTaskRC(o)
+	if err != nil {
+		return err
+	}
+	o.rc = rc
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	if rc.Remote.Timeout != nil && !explicit["timeout"] {
+		o.timeout = *rc.Remote.Timeout
+	}
+	if rc.Remote.CacheExpiry != nil && !explicit["cache-expiry"] {
+		o.cacheExpiry = *rc.Remote.CacheExpiry
+	}
+	if rc.Remote.Cert != nil && !explicit["client-cert"] {
+		o.clientCert = *rc.Remote.Cert
+	}
+	if rc.Remote.CertKey != nil && !explicit["client-key"] {
+		o.clientKey = *rc.Remote.CertKey
+	}
+	if o.caFile == "" {
+		o.caFile = os.Getenv("MEERKAT_CA_FILE")
+	}
+	if rc.Remote.CACert != nil && o.caFile == "" {
+		o.caFile = *rc.Remote.CACert
+	}
+	return nil
+}
+
+// insecure reports whether the Task settings allow Taskfiles over plain HTTP
+func (o *options) insecure() bool {
+	return o.rc != nil && o.rc.Remote.Insecure != nil && *o.rc.Remote.Insecure
+}
+
+// readerOptions are the reader options the Task settings have a say in:
+// HTTP Taskfiles, offline reads and hosts trusted without a prompt. The
+// reader gets the CA and client certificate only when it builds an HTTP
+// client of its own, for insecure fetches; otherwise the default client's
+// transport uses them, so fetches keep going through fetchTransport.
+func readerOptions(o *options) []taskfile.ReaderOption {
+	opts := []taskfile.ReaderOption{
+		taskfile.WithInsecure(false), // Don't allow HTTP (only HTTPS)
+		taskfile.WithOffline(false),  // Allow network requests
+	}
+	if o.insecure() {
+		if o.caFile != "" {
+			opts = append(opts, taskfile.WithReaderCACert(o.caFile))
+		}
+		if o.clientCert != "" {
+			opts = append(opts, taskfile.WithReaderCert(o.clientCert), taskfile.WithReaderCertKey(o.clientKey))
+		}
+	}
+	rc := o.rc
+	if rc == nil {
+		return opts
+	}
+	if rc.Remote.Insecure != nil {
+		opts = append(opts, taskfile.WithInsecure(*rc.Remote.Insecure))
+	}
+	if rc.Remote.Offline != nil {
+		opts = append(opts, taskfile.WithOffline(*rc.Remote.Offline))
+	}
+	if len(rc.Remote.TrustedHosts) > 0 {
+		opts = append(opts, taskfile.WithTrustedHosts(rc.Remote.TrustedHosts))
+	}
+	return opts
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	sprig "github.com/go-task/slim-sprig/v3"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// templateData is what a --template-file sees: the merged Taskfile as go-task
+// parsed it, the task and inclusion graphs, and the dependency trees of the
+// --start tasks. Start and Tree are the first of them.
+type templateData struct {
+	Start    string
+	Starts   []string
+	Taskfile *ast.Taskfile
+	Graph    *taskGraph
+	Includes *includeGraph
+	Tree     taskTree
+	Trees    []taskTree
+}
+
+// parseReportTemplate reads a user's template with the sprig functions
+// Taskfiles themselves can use. Missing keys are errors so a typo in a field
+// name does not print "<no value>".
+func parseReportTemplate(path string) (*template.Template, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := template.New(filepath.Base(path)).Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return t, nil
+}
+
+func writeTemplate(w io.Writer, t *template.Template, data templateData) error {
+	if err := t.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+	return nil
+}
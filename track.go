@@ -0,0 +1,240 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-task/task/v3/taskfile"
+	_ "modernc.org/sqlite"
+)
+
+// trackRun is the summary of one analysis as track records it
+type trackRun struct {
+	Time        time.Time `json:"time"`
+	Commit      string    `json:"commit,omitempty"`
+	Taskfile    string    `json:"taskfile"`
+	Tasks       int       `json:"tasks"`
+	Edges       int       `json:"edges"`
+	Depth       int       `json:"depth"`
+	Cycles      int       `json:"cycles"`
+	Errors      int       `json:"errors"`
+	Warnings    int       `json:"warnings"`
+	Info        int       `json:"info"`
+	Fingerprint string    `json:"fingerprint"`
+}
+
+const trackSchema = `CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY,
+	recorded_at TEXT NOT NULL,
+	git_sha TEXT NOT NULL,
+	taskfile TEXT NOT NULL,
+	tasks INTEGER NOT NULL,
+	edges INTEGER NOT NULL,
+	depth INTEGER NOT NULL,
+	cycles INTEGER NOT NULL,
+	errors INTEGER NOT NULL,
+	warnings INTEGER NOT NULL,
+	info INTEGER NOT NULL,
+	fingerprint TEXT NOT NULL
+)`
+
+// runTrack records the health of the Taskfile in a SQLite database, one row
+// per run, or with report shows how it has moved over the recorded runs
+func runTrack(args []string) error {
+	report := len(args) > 0 && args[0] == "report"
+	name := "track"
+	if report {
+		name, args = "track report", args[1:]
+	}
+	var opts options
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	opts.register(fs)
+	dbPath := fs.String("db", filepath.Join(".meerkat", "metrics.db"), "SQLite database the runs are recorded in")
+	last := fs.Int("last", 20, "With report, the most recent runs to show (0 shows all)")
+	format := fs.String("format", "text", "With report, output format: text or json")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	root := displayPath(opts.taskfileURL)
+
+	if report {
+		if _, err := os.Stat(*dbPath); err != nil {
+			return fmt.Errorf("no runs recorded in %s yet: %w", *dbPath, err)
+		}
+	} else if err := os.MkdirAll(filepath.Dir(*dbPath), 0o755); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite", *dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec(trackSchema); err != nil {
+		return fmt.Errorf("failed to prepare %s: %w", *dbPath, err)
+	}
+
+	if report {
+		runs, err := trackedRuns(db, root, *last)
+		if err != nil {
+			return err
+		}
+		if *format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(runs)
+		}
+		return writeTrend(runs)
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	run, err := measureRun(l, &opts)
+	if err != nil {
+		return err
+	}
+	run.Taskfile = root
+	_, err = db.Exec(`INSERT INTO runs (recorded_at, git_sha, taskfile, tasks, edges, depth, cycles, errors, warnings, info, fingerprint)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.Time.Format(time.RFC3339), run.Commit, run.Taskfile, run.Tasks, run.Edges, run.Depth, run.Cycles,
+		run.Errors, run.Warnings, run.Info, run.Fingerprint)
+	if err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "recorded %s, %s and %s in %s\n",
+		plural(run.Tasks, "task"), plural(run.Cycles, "cycle"), plural(run.Errors+run.Warnings+run.Info, "finding"), *dbPath)
+	return nil
+}
+
+// measureRun analyzes the Taskfile once for the metrics track records,
+// running every lint check and honouring suppressions as lint does
+func measureRun(l *loader, opts *options) (trackRun, error) {
+	run := trackRun{Time: time.Now().UTC()}
+	ws, err := openWorkspace(l)
+	if err != nil {
+		return run, err
+	}
+	tg, err := buildTaskGraph(ws.graph, ws.merged, ws.idx)
+	if err != nil {
+		return run, err
+	}
+	checks, err := loadChecks(opts.configFile)
+	if err != nil {
+		return run, err
+	}
+
+	for _, n := range tg.Nodes {
+		if !n.Missing {
+			run.Tasks++
+		}
+	}
+	run.Edges = len(tg.Edges)
+	for _, r := range tg.listRows() {
+		run.Depth = max(run.Depth, r.Depth)
+	}
+	run.Cycles = len(tg.topologicalOrder().Cycles)
+	for _, f := range suppress(ws.lint(checks), ws.suppressions()) {
+		switch f.Severity {
+		case severityError:
+			run.Errors++
+		case severityWarning:
+			run.Warnings++
+		default:
+			run.Info++
+		}
+	}
+	run.Fingerprint = tg.fingerprint()
+
+	// The commit of the repository holding the Taskfile, if any
+	dir := "."
+	if uri := opts.taskfileURL; uri != "-" && !taskfile.IsRemoteEntrypoint(uri) {
+		if info, err := os.Stat(uri); err == nil && !info.IsDir() {
+			dir = filepath.Dir(uri)
+		} else if err == nil {
+			dir = uri
+		}
+	}
+	if commit, err := gitHead(dir); err == nil {
+		run.Commit = commit
+	}
+	return run, nil
+}
+
+// trackedRuns reads the last runs recorded for a Taskfile, oldest first
+func trackedRuns(db *sql.DB, root string, last int) ([]trackRun, error) {
+	limit := -1
+	if last > 0 {
+		limit = last
+	}
+	rows, err := db.Query(`SELECT recorded_at, git_sha, taskfile, tasks, edges, depth, cycles, errors, warnings, info, fingerprint
+		FROM runs WHERE taskfile = ? ORDER BY id DESC LIMIT ?`, root, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	runs := []trackRun{}
+	for rows.Next() {
+		var r trackRun
+		var recorded string
+		if err := rows.Scan(&recorded, &r.Commit, &r.Taskfile, &r.Tasks, &r.Edges, &r.Depth, &r.Cycles,
+			&r.Errors, &r.Warnings, &r.Info, &r.Fingerprint); err != nil {
+			return nil, err
+		}
+		if r.Time, err = time.Parse(time.RFC3339, recorded); err != nil {
+			return nil, fmt.Errorf("bad time %q in run: %w", recorded, err)
+		}
+		runs = append(runs, r)
+	}
+	slices.Reverse(runs)
+	return runs, rows.Err()
+}
+
+// writeTrend prints the runs oldest first, each metric with its change since
+// the run before, and marks the runs where the graph's structure changed
+func writeTrend(runs []trackRun) error {
+	if len(runs) == 0 {
+		fmt.Println("No runs recorded for this Taskfile")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "TIME\tCOMMIT\tTASKS\tEDGES\tDEPTH\tCYCLES\tERRORS\tWARNINGS\tINFO\tGRAPH\n")
+	for i, r := range runs {
+		prev := r
+		if i > 0 {
+			prev = runs[i-1]
+		}
+		graph := "same"
+		if i == 0 {
+			graph = strings.TrimPrefix(r.Fingerprint, "sha256:")[:12]
+		} else if r.Fingerprint != prev.Fingerprint {
+			graph = "changed"
+		}
+		fmt.Fprintf(tw, "%s\t%.12s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Time.Local().Format(time.DateTime), r.Commit,
+			trend(r.Tasks, prev.Tasks), trend(r.Edges, prev.Edges), trend(r.Depth, prev.Depth),
+			trend(r.Cycles, prev.Cycles), trend(r.Errors, prev.Errors), trend(r.Warnings, prev.Warnings),
+			trend(r.Info, prev.Info), graph)
+	}
+	return tw.Flush()
+}
+
+// trend is a metric followed by its change, when it changed
+func trend(value, prev int) string {
+	if value == prev {
+		return fmt.Sprint(value)
+	}
+	return fmt.Sprintf("%d (%+d)", value, value-prev)
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// fetchTransport wraps the transport used by the default HTTP client, which
+// the Taskfile reader uses for every remote fetch. It is the hook for
+// observing and shaping remote downloads.
+type fetchTransport struct {
+	base     http.RoundTripper
+	progress *progressReporter
+	limiter  *rate.Limiter
+	perHost  int
+	budget   *downloadBudget
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+
+	// stubs answer for URLs that are not fetched, by URL
+	stubs map[string][]byte
+
+	// creds, from --credential-helper, and then netrc log in to the hosts
+	// they have credentials for
+	creds *credentialHelper
+	netrc netrc
+
+	// fetches counts completed requests and fetchTime sums how long each
+	// took, up to its body being closed
+	fetches   int
+	fetchTime time.Duration
+}
+
+// installFetchTransport routes the default HTTP client through a fetchTransport
+func installFetchTransport(t *fetchTransport) {
+	if t.base == nil {
+		t.base = http.DefaultTransport
+	}
+	http.DefaultClient.Transport = t
+}
+
+// newRateLimiter allows rps requests per second, or returns nil for no limit
+func newRateLimiter(rps float64) *rate.Limiter {
+	if rps <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(rps), 1)
+}
+
+func (t *fetchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	stub, stubbed := t.stubs[req.URL.String()]
+	t.mu.Unlock()
+	if stubbed {
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"application/x-yaml"}},
+			Body:          io.NopCloser(bytes.NewReader(stub)),
+			ContentLength: int64(len(stub)),
+			Request:       req,
+		}, nil
+	}
+
+	if req.Header.Get("Authorization") == "" {
+		auth, err := t.authorization(req)
+		if err != nil {
+			return nil, err
+		}
+		if auth != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", auth)
+		}
+	}
+
+	if t.limiter != nil {
+		if err := t.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Method == http.MethodGet && t.budget != nil {
+		if err := t.budget.admit(req.URL.Redacted()); err != nil {
+			return nil, err
+		}
+	}
+
+	release, err := t.acquireHost(req)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if req.Method == http.MethodGet && t.budget != nil {
+		if resp.Body, err = t.budget.meter(req.URL.Redacted(), resp.Body, resp.ContentLength); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	finish := sync.OnceFunc(func() {
+		release()
+		t.mu.Lock()
+		t.fetches++
+		t.fetchTime += time.Since(start)
+		t.mu.Unlock()
+	})
+
+	// Keep the host slot until a downloaded body has been read and closed.
+	// HEAD probes carry no body and may be left open by the caller.
+	if req.Method == http.MethodHead {
+		finish()
+	} else {
+		resp.Body = &releaseBody{ReadCloser: resp.Body, release: finish}
+	}
+
+	// Only report bodies that are actually downloaded; HEAD probes are noise
+	if req.Method == http.MethodGet && t.progress != nil {
+		resp.Body = t.progress.track(req.URL.Redacted(), resp.ContentLength, resp.Body, start)
+	}
+
+	return resp, nil
+}
+
+// taskfileFetchKey marks the context of requests that fetch Taskfiles
+type taskfileFetchKey struct{}
+
+// withTaskfileFetch is ctx for fetching Taskfiles, and what is published
+// beside them, the only requests the fetch transport logs in for
+func withTaskfileFetch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, taskfileFetchKey{}, true)
+}
+
+func isTaskfileFetch(ctx context.Context) bool {
+	return ctx.Value(taskfileFetchKey{}) != nil
+}
+
+// authorization is what to log in to the request's host with: from the
+// credential helper, or else .netrc, where the default entry only covers
+// hosts the Taskfiles name, not ones a redirect leads to. Neither goes out
+// over plain HTTP, where anyone on the way could read it, nor with requests
+// other than Taskfile fetches, such as webhooks or registry token services.
+func (t *fetchTransport) authorization(req *http.Request) (string, error) {
+	if req.URL.Scheme != "https" || !isTaskfileFetch(req.Context()) {
+		return "", nil
+	}
+	auth, err := t.creds.authorization(req.URL.Scheme, req.URL.Host)
+	if err != nil || auth != "" {
+		return auth, err
+	}
+	if l, ok := t.netrc.lookup(req.URL.Hostname(), redirectedHere(req)); ok {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(l.login+":"+l.password)), nil
+	}
+	return "", nil
+}
+
+// redirectedHere reports whether a redirect from another host led to the
+// request
+func redirectedHere(req *http.Request) bool {
+	for resp := req.Response; resp != nil && resp.Request != nil; resp = resp.Request.Response {
+		if resp.Request.URL.Host != req.URL.Host {
+			return true
+		}
+	}
+	return false
+}
+
+// acquireHost blocks until a concurrency slot for the request's host is free
+func (t *fetchTransport) acquireHost(req *http.Request) (func(), error) {
+	if t.perHost <= 0 {
+		return func() {}, nil
+	}
+
+	t.mu.Lock()
+	if t.hosts == nil {
+		t.hosts = make(map[string]chan struct{})
+	}
+	slots, ok := t.hosts[req.URL.Host]
+	if !ok {
+		slots = make(chan struct{}, t.perHost)
+		t.hosts[req.URL.Host] = slots
+	}
+	t.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-slots }) }, nil
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+}
+
+// stub answers requests for url with body from now on, or fetches it again
+// for a nil body
+func (t *fetchTransport) stub(url string, body []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if body == nil {
+		delete(t.stubs, url)
+		return
+	}
+	if t.stubs == nil {
+		t.stubs = make(map[string][]byte)
+	}
+	t.stubs[url] = body
+}
+
+// fetched reports the requests completed so far and their summed duration
+func (t *fetchTransport) fetched() (int, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.fetches, t.fetchTime
+}
+
+// releaseBody runs release once the response body is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// tlsTransport is the default transport trusting the CAs of --ca-file
+// besides the system ones, for hosts with a private CA, and presenting the
+// client certificate of --client-cert, for services that require mutual
+// TLS; or nil for the default transport when there are neither
+func tlsTransport(o *options) (http.RoundTripper, error) {
+	if o.clientCert == "" && o.clientKey == "" && o.caFile == "" {
+		return nil, nil
+	}
+	config := &tls.Config{}
+	if o.clientCert != "" || o.clientKey != "" {
+		if o.clientCert == "" || o.clientKey == "" {
+			return nil, fmt.Errorf("--client-cert and --client-key go together")
+		}
+		cert, err := tls.LoadX509KeyPair(o.clientCert, o.clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if o.caFile != "" {
+		pem, err := os.ReadFile(o.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --ca-file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("--ca-file %s has no PEM certificates", o.caFile)
+		}
+		config.RootCAs = pool
+	}
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.TLSClientConfig = config
+	return base, nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// runVendor downloads every remote include into a local directory and writes
+// a root Taskfile whose includes point at the vendored copies
+func runVendor(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("vendor", flag.ExitOnError)
+	opts.register(fs)
+	dest := fs.String("dest", "vendor/taskfiles", "Directory to write vendored Taskfiles into")
+	out := fs.String("out", "Taskfile.vendored.yml", "Path of the rewritten root Taskfile")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	g, _, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	v, err := newVendorPlan(g, *dest, *out)
+	if err != nil {
+		return err
+	}
+
+	for _, uri := range v.order {
+		src, err := l.readSource(uri)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", uri, err)
+		}
+		rewritten, err := v.rewrite(uri, src)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite %s: %w", uri, err)
+		}
+		target := v.paths[uri]
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, rewritten, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("vendored %s -> %s\n", uri, target)
+	}
+
+	return nil
+}
+
+// vendorPlan decides where each Taskfile in the graph is written and how its
+// includes must be rewritten to reference the local copies
+type vendorPlan struct {
+	edges map[string][]includeEdge // includes keyed by parent URI
+	paths map[string]string        // absolute output path of each written URI
+	order []string                 // URIs to write, root first
+}
+
+func newVendorPlan(g *ast.TaskfileGraph, dest, out string) (*vendorPlan, error) {
+	edges, err := includeEdges(g)
+	if err != nil {
+		return nil, err
+	}
+	root, err := rootURI(g)
+	if err != nil {
+		return nil, err
+	}
+	dest, err = filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+	out, err = filepath.Abs(out)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &vendorPlan{
+		edges: make(map[string][]includeEdge),
+		paths: map[string]string{root: out},
+	}
+	for _, e := range edges {
+		v.edges[e.Parent] = append(v.edges[e.Parent], e)
+	}
+
+	// A file must be written if it is remote or transitively includes a
+	// remote file; local files that only include local files stay in place
+	needs := make(map[string]bool)
+	var visit func(uri string) bool
+	visit = func(uri string) bool {
+		if done, ok := needs[uri]; ok {
+			return done
+		}
+		needs[uri] = taskfile.IsRemoteEntrypoint(uri)
+		for _, e := range v.edges[uri] {
+			if visit(e.Child) {
+				needs[uri] = true
+			}
+		}
+		return needs[uri]
+	}
+	visit(root)
+
+	v.order = append(v.order, root)
+	seen := map[string]bool{root: true}
+	for _, e := range edges {
+		if !needs[e.Child] || seen[e.Child] {
+			continue
+		}
+		seen[e.Child] = true
+		if v.paths[e.Child], err = vendorPath(dest, e.Child); err != nil {
+			return nil, err
+		}
+		v.order = append(v.order, e.Child)
+	}
+
+	return v, nil
+}
+
+// rewrite points the includes of the Taskfile at uri to their local copies
+func (v *vendorPlan) rewrite(uri string, src []byte) ([]byte, error) {
+	targets := make(map[string]string)
+	from := filepath.Dir(v.paths[uri])
+	for _, e := range v.edges[uri] {
+		to, ok := v.paths[e.Child]
+		if !ok {
+			to = e.Child
+		}
+		rel, err := filepath.Rel(from, to)
+		if err != nil {
+			return nil, err
+		}
+		targets[e.Include.Namespace] = filepath.ToSlash(rel)
+	}
+	if len(targets) == 0 {
+		return src, nil
+	}
+	return rewriteIncludes(src, targets)
+}
+
+// rewriteIncludes replaces the taskfile of each namespace in the includes
+// section of the YAML document src
+func rewriteIncludes(src []byte, targets map[string]string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, err
+	}
+
+	if includes := mappingValue(documentRoot(&doc), "includes"); includes != nil && includes.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(includes.Content); i += 2 {
+			target, ok := targets[includes.Content[i].Value]
+			if !ok {
+				continue
+			}
+			value := includes.Content[i+1]
+			switch value.Kind {
+			case yaml.ScalarNode:
+				value.SetString(target)
+			case yaml.MappingNode:
+				if tf := mappingValue(value, "taskfile"); tf != nil {
+					tf.SetString(target)
+				}
+			}
+		}
+	}
+
+	return encodeYAML(&doc)
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._/-]+`)
+
+// vendorPath maps a remote Taskfile URI to a stable path under dest that
+// mirrors its host and path. The path is cleaned as a server would, so ..
+// in the URI, its ref or a local path cannot climb out of dest.
+func vendorPath(dest, uri string) (string, error) {
+	var p string
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" {
+		p = unsafePathChars.ReplaceAllString(uri, "_")
+	} else {
+		p = u.Host + path.Clean("/"+u.Path)
+		if ref := u.Query().Get("ref"); ref != "" {
+			p += "@" + ref
+		}
+		p = unsafePathChars.ReplaceAllString(p, "_")
+	}
+	if !strings.HasSuffix(p, ".yml") && !strings.HasSuffix(p, ".yaml") {
+		p += "/Taskfile.yml"
+	}
+	dest = filepath.Clean(dest)
+	target := filepath.Join(dest, filepath.FromSlash(strings.TrimPrefix(path.Clean("/"+p), "/")))
+	if !strings.HasPrefix(target, dest+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%s would be vendored outside %s", uri, dest)
+	}
+	return target, nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVendorPath(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "vendor")
+	tests := []struct {
+		uri, want string
+	}{
+		{"https://example.com/team/Taskfile.yml", "example.com/team/Taskfile.yml"},
+		{"https://example.com/team", "example.com/team/Taskfile.yml"},
+		{"https://example.com/a/../b.yml", "example.com/b.yml"},
+		{"https://example.com/a/../../../x.yml", "example.com/x.yml"},
+		{"https://example.com/%2e%2e/%2e%2e/x.yml", "example.com/x.yml"},
+		{"https://example.com//team//x.yml", "example.com/team/x.yml"},
+		{"https://example.com/x.yml?ref=v1.2", "example.com/x.yml_v1.2/Taskfile.yml"},
+		{"https://example.com/x.yml?ref=../../../..", "Taskfile.yml"},
+		{"git::https://example.com/repo.git//Taskfile.yml?ref=v1", "git_https_/example.com/repo.git/Taskfile.yml_ref_v1/Taskfile.yml"},
+		{"../../outside.yml", "outside.yml"},
+	}
+	for _, tt := range tests {
+		got, err := vendorPath(dest, tt.uri)
+		if err != nil {
+			t.Errorf("vendorPath(%q) error = %v", tt.uri, err)
+			continue
+		}
+		if !strings.HasPrefix(got, dest+string(filepath.Separator)) {
+			t.Errorf("vendorPath(%q) = %s, outside %s", tt.uri, got, dest)
+		}
+		if want := filepath.Join(dest, filepath.FromSlash(tt.want)); got != want {
+			t.Errorf("vendorPath(%q) = %s, want %s", tt.uri, got, want)
+		}
+	}
+}
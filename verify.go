@@ -0,0 +1,275 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+	"github.com/pmezard/go-difflib/difflib"
+	"go.yaml.in/yaml/v3"
+)
+
+// lockVersion is written to every lockfile, so the format can change later
+// without misreading old files
+const lockVersion = 1
+
+// lockfile pins the content of every remote Taskfile in a graph. The hash
+// is the SHA-256 go-task uses for an include's checksum, so a locked hash
+// can be copied into the include to have go-task enforce it too.
+type lockfile struct {
+	Version  int         `json:"version"`
+	Includes []lockEntry `json:"includes"`
+}
+
+type lockEntry struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+func readLockfile(path string) (*lockfile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("lockfile %s does not exist; create it with verify --update", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lock lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s: %w", path, err)
+	}
+	if lock.Version != lockVersion {
+		return nil, fmt.Errorf("lockfile %s has version %d, want %d", path, lock.Version, lockVersion)
+	}
+	return &lock, nil
+}
+
+func writeLockfile(path string, entries []lockEntry) error {
+	data, err := json.MarshalIndent(lockfile{Version: lockVersion, Includes: entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// runVerify fetches every remote Taskfile of the graph again and compares
+// it with meerkat.lock, reporting each file that changed upstream, what
+// changed in its tasks and, with --diff, the change itself
+func runVerify(args []string) error {
+	var opts options
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	opts.register(fs)
+	lockPath := fs.String("lock", "meerkat.lock", "Lockfile recording the content of each remote Taskfile")
+	update := fs.Bool("update", false, "Record the remote Taskfiles as they are now in the lockfile and exit")
+	cached := fs.Bool("cached", false, "Compare the cached copies instead of fetching every remote Taskfile again")
+	showDiff := fs.Bool("diff", false, "Show a unified diff of each changed Taskfile whose locked content is still cached")
+	if err := parseFlags(fs, &opts, args); err != nil {
+		return err
+	}
+	var lock *lockfile
+	if !*update {
+		var err error
+		if lock, err = readLockfile(*lockPath); err != nil {
+			return err
+		}
+	}
+	// Verifying against a copy cached from before the change would pass
+	opts.noCache = !*cached
+
+	l, err := newLoader(&opts)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	g, _, err := l.load()
+	if err != nil {
+		return err
+	}
+	current, err := remoteHashes(l, g)
+	if err != nil {
+		return err
+	}
+
+	if *update {
+		var entries []lockEntry
+		for _, url := range slices.Sorted(maps.Keys(current)) {
+			entries = append(entries, lockEntry{URL: url, SHA256: current[url]})
+		}
+		if err := writeLockfile(*lockPath, entries); err != nil {
+			return err
+		}
+		fmt.Printf("locked %s in %s\n", plural(len(entries), "remote Taskfile"), *lockPath)
+		return nil
+	}
+
+	namespaces, err := includedAs(g)
+	if err != nil {
+		return err
+	}
+	locked := make(map[string]string)
+	for _, e := range lock.Includes {
+		locked[e.URL] = e.SHA256
+	}
+	var problems int
+	for _, e := range lock.Includes {
+		sum, ok := current[e.URL]
+		switch {
+		case !ok:
+			problems++
+			fmt.Printf("removed %s: locked but no longer included\n", e.URL)
+		case sum != e.SHA256:
+			problems++
+			fmt.Printf("changed %s%s\n", e.URL, namespaces[e.URL])
+			fmt.Printf("  sha256 %.12s -> %.12s\n", e.SHA256, sum)
+			if err := explainChange(l, e, *showDiff); err != nil {
+				return err
+			}
+		}
+	}
+	for _, url := range slices.Sorted(maps.Keys(current)) {
+		if _, ok := locked[url]; !ok {
+			problems++
+			fmt.Printf("added %s%s: not in %s\n", url, namespaces[url], *lockPath)
+		}
+	}
+
+	if problems > 0 {
+		return &failure{msg: fmt.Sprintf("%s: %s out of date; run verify --update once the changes are reviewed", *lockPath, plural(problems, "remote Taskfile"))}
+	}
+	fmt.Printf("%s: %s verified\n", *lockPath, plural(len(lock.Includes), "remote Taskfile"))
+	return nil
+}
+
+// remoteHashes hashes the content of every remote Taskfile in the graph
+func remoteHashes(l *loader, g *ast.TaskfileGraph) (map[string]string, error) {
+	adjacency, err := g.AdjacencyMap()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string)
+	for uri := range adjacency {
+		if !taskfile.IsRemoteEntrypoint(uri) {
+			continue
+		}
+		src, err := l.readSource(uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", uri, err)
+		}
+		sum := sha256.Sum256(src)
+		hashes[uri] = hex.EncodeToString(sum[:])
+	}
+	return hashes, nil
+}
+
+// includedAs labels each Taskfile with the namespaces it is included under,
+// as in " (included as lib)"
+func includedAs(g *ast.TaskfileGraph) (map[string]string, error) {
+	edges, err := includeEdges(g)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string][]string)
+	for _, e := range edges {
+		if !slices.Contains(names[e.Child], e.Include.Namespace) {
+			names[e.Child] = append(names[e.Child], e.Include.Namespace)
+		}
+	}
+	labels := make(map[string]string)
+	for uri, ns := range names {
+		labels[uri] = " (included as " + strings.Join(ns, ", ") + ")"
+	}
+	return labels, nil
+}
+
+// explainChange lists the tasks a changed Taskfile added, removed and
+// changed, and its diff when asked. Both need the locked content, which is
+// only there while the cache still holds it.
+func explainChange(l *loader, e lockEntry, showDiff bool) error {
+	var before []byte
+	if _, err := hex.DecodeString(e.SHA256); err == nil && len(e.SHA256) == 2*sha256.Size {
+		before, _ = os.ReadFile(l.store.blobPath(e.SHA256))
+	}
+	if before == nil {
+		fmt.Println("  the locked content is no longer cached, so what changed is unknown")
+		return nil
+	}
+	after, err := l.readSource(e.URL)
+	if err != nil {
+		return err
+	}
+	added, removed, changed := taskChanges(before, after)
+	for _, c := range []struct {
+		what  string
+		names []string
+	}{{"added", added}, {"removed", removed}, {"changed", changed}} {
+		if len(c.names) > 0 {
+			fmt.Printf("  tasks %s: %s\n", c.what, strings.Join(c.names, ", "))
+		}
+	}
+	if len(added)+len(removed)+len(changed) == 0 {
+		fmt.Println("  no task changed")
+	}
+	if !showDiff {
+		return nil
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(before)),
+		B:        difflib.SplitLines(string(after)),
+		FromFile: fmt.Sprintf("%s@%.12s", e.URL, e.SHA256),
+		ToFile:   e.URL,
+		Context:  3,
+	})
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if line != "" {
+			fmt.Print("  " + line)
+		}
+	}
+	return nil
+}
+
+// taskChanges compares the tasks of two versions of a Taskfile by what they
+// decode to, so comments and formatting do not count as changes
+func taskChanges(before, after []byte) (added, removed, changed []string) {
+	old, cur := decodedTasks(before), decodedTasks(after)
+	for name, task := range cur {
+		prev, ok := old[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case !reflect.DeepEqual(prev, task):
+			changed = append(changed, name)
+		}
+	}
+	for name := range old {
+		if _, ok := cur[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	slices.Sort(changed)
+	return added, removed, changed
+}
+
+func decodedTasks(src []byte) map[string]any {
+	var doc struct {
+		Tasks map[string]any `yaml:"tasks"`
+	}
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil
+	}
+	return doc.Tasks
+}
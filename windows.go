@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// posixPathPattern matches hardcoded absolute paths that do not exist on
+// Windows. /tmp gets its own message.
+var posixPathPattern = regexp.MustCompile(`(^|[\s='"(:])(/(?:usr|etc|opt|var|bin|sbin|home|dev)/[^\s'"]*|~/[^\s'"]*)`)
+
+// bashisms are bash constructs that Task's built-in shell interpreter, the
+// one that runs commands on Windows, cannot provide there
+var bashisms = []struct {
+	pattern *regexp.Regexp
+	what    string
+}{
+	{regexp.MustCompile(`[<>]\(`), "process substitution"},
+}
+
+// shellTools are interpreters a command may hand a script to
+var shellTools = []string{"bash", "sh", "zsh", "ksh"}
+
+// checkWindows flags commands of tasks that may run on Windows and rely on
+// POSIX paths, shell scripts or bash-only syntax
+func checkWindows(ws *workspace) []finding {
+	var findings []finding
+	for _, c := range ws.shellCmds() {
+		targets, declared := targetOS(c.task, c.cmd)
+		if !slices.Contains(targets, "windows") {
+			continue
+		}
+		severity := severityWarning
+		advice := "; constrain the task with platforms if it is not meant for Windows"
+		if declared {
+			severity = severityError
+			advice = ""
+		}
+		report := func(message string) {
+			findings = append(findings, finding{
+				Rule:     "windows",
+				Severity: severity,
+				Task:     c.name,
+				Message:  message + advice,
+				Pos:      c.pos,
+			})
+		}
+
+		line := c.cmd.Cmd
+		if strings.Contains(line, "/tmp/") || strings.HasSuffix(line, "/tmp") {
+			report("hardcoded /tmp does not exist on Windows; use a directory relative to the task or one taken from the environment")
+		}
+		for _, m := range posixPathPattern.FindAllStringSubmatch(line, -1) {
+			report(fmt.Sprintf("absolute POSIX path %s does not exist on Windows", m[2]))
+		}
+		for _, b := range bashisms {
+			if b.pattern.MatchString(line) {
+				report(fmt.Sprintf("uses %s, which Task's built-in shell interpreter does not support on Windows", b.what))
+			}
+		}
+
+		for _, words := range splitCommands(line) {
+			tool, _ := commandTool(words)
+			switch {
+			case slices.Contains(shellTools, tool):
+				report(fmt.Sprintf("runs %s, which is not installed on Windows runners by default", tool))
+			case strings.HasSuffix(tool, ".sh"):
+				report(fmt.Sprintf("runs the shell script %s, which needs a POSIX shell on Windows", tool))
+			case strings.Contains(tool, "/") && !strings.Contains(tool, "exeExt") && !strings.HasSuffix(tool, ".exe"):
+				report(fmt.Sprintf("runs %s without {{exeExt}}, so the .exe binary is not found on Windows", tool))
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"github.com/go-task/task/v3/taskfile/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// workspace is a loaded Taskfile graph kept around by the long-running
+// editor modes, with what they need to answer questions about any file in it
+type workspace struct {
+	graph   *ast.TaskfileGraph
+	merged  *ast.Taskfile
+	scopes  map[string][]includeScope
+	idx     *sourceIndex
+	aliases map[string]string // alias -> merged task name
+}
+
+// openWorkspace loads the Taskfile graph named by the loader's options
+func openWorkspace(l *loader) (*workspace, error) {
+	g, merged, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	return newWorkspace(l, g, merged)
+}
+
+// newWorkspace wraps a Taskfile graph the loader has already read
+func newWorkspace(l *loader, g *ast.TaskfileGraph, merged *ast.Taskfile) (*workspace, error) {
+	scopes, err := includeScopes(g)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &workspace{
+		graph:   g,
+		merged:  merged,
+		scopes:  scopes,
+		idx:     newSourceIndex(l),
+		aliases: make(map[string]string),
+	}
+	for name, task := range merged.Tasks.All(byName) {
+		for _, alias := range task.Aliases {
+			ws.aliases[alias] = name
+		}
+	}
+	return ws, nil
+}
+
+// resolve finds a merged task by name or alias
+func (ws *workspace) resolve(name string) (string, *ast.Task, bool) {
+	if task, ok := ws.merged.Tasks.Get(name); ok {
+		return name, task, true
+	}
+	if real, ok := ws.aliases[name]; ok {
+		if task, ok := ws.merged.Tasks.Get(real); ok {
+			return real, task, true
+		}
+	}
+	return name, nil, false
+}
+
+// taskRef is a task name written in a file: a call in deps or cmds, or the
+// key defining a task. Name is the merged name it refers to.
+type taskRef struct {
+	node *yaml.Node
+	name string
+	key  bool
+}
+
+// fileRefs lists the task keys and task calls of one file of the graph, with
+// names qualified the way merging qualifies them. A file included under
+// several namespaces is read in its first scope.
+func (ws *workspace) fileRefs(uri string, src []byte) []taskRef {
+	scopes := ws.scopes[uri]
+	if len(scopes) == 0 {
+		return nil
+	}
+	scope := scopes[0]
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil
+	}
+	tasks := mappingValue(documentRoot(&doc), "tasks")
+	if tasks == nil || tasks.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var refs []taskRef
+	for i := 0; i+1 < len(tasks.Content); i += 2 {
+		key := tasks.Content[i]
+		refs = append(refs, taskRef{node: key, name: scope.qualify(key.Value), key: true})
+		for _, ref := range callRefNodes(tasks.Content[i+1]) {
+			refs = append(refs, taskRef{node: ref, name: scope.qualify(ref.Value)})
+		}
+	}
+	return refs
+}
+
+// refAt returns the task name under a 1-based line and column of a file
+func (ws *workspace) refAt(uri string, src []byte, line, col int) (taskRef, bool) {
+	for _, ref := range ws.fileRefs(uri, src) {
+		n := ref.node
+		start := n.Column
+		if n.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0 {
+			start++
+		}
+		if n.Line == line && col >= start && col <= start+len([]rune(n.Value)) {
+			return ref, true
+		}
+	}
+	return taskRef{}, false
+}
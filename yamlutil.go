@@ -0,0 +1,157 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// documentRoot returns the top-level node of a parsed YAML document
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// mappingValue returns the value stored under key in a YAML mapping node
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// encodeYAML serializes a YAML node with the two-space indent Taskfiles use
+func encodeYAML(node *yaml.Node) ([]byte, error) {
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+// setMappingValue stores value under key in a YAML mapping node, replacing any
+// existing entry
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, scalarNode(key), value)
+}
+
+// deleteMappingKey removes key from a YAML mapping node
+func deleteMappingKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// scalarNode builds a plain string YAML node
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// scalarEdit replaces the scalar node at a source position with a new value
+type scalarEdit struct {
+	node  *yaml.Node
+	value string
+}
+
+// applyScalarEdits rewrites scalars in place in src, leaving every other byte
+// of the file untouched so formatting and comments survive
+func applyScalarEdits(src []byte, edits []scalarEdit) []byte {
+	lines := strings.SplitAfter(string(src), "\n")
+	sort.Slice(edits, func(i, j int) bool {
+		a, b := edits[i].node, edits[j].node
+		if a.Line != b.Line {
+			return a.Line > b.Line
+		}
+		return a.Column > b.Column
+	})
+	for _, e := range edits {
+		n := e.node
+		if n.Line < 1 || n.Line > len(lines) {
+			continue
+		}
+		line := []rune(lines[n.Line-1])
+		start := n.Column - 1
+		if n.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0 {
+			start++
+		}
+		end := start + len([]rune(n.Value))
+		if start < 0 || end > len(line) || string(line[start:end]) != n.Value {
+			continue
+		}
+		lines[n.Line-1] = string(line[:start]) + e.value + string(line[end:])
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// taskRefNodes returns the scalar nodes of a task that name other tasks: deps,
+// task calls in cmds (including deferred ones) and aliases
+func taskRefNodes(task *yaml.Node) []*yaml.Node {
+	refs := callRefNodes(task)
+	if aliases := mappingValue(task, "aliases"); aliases != nil && aliases.Kind == yaml.SequenceNode {
+		for _, alias := range aliases.Content {
+			if alias.Kind == yaml.ScalarNode && alias.Value != "" {
+				refs = append(refs, alias)
+			}
+		}
+	}
+	return refs
+}
+
+// callRefNodes returns the scalar nodes of a task that call other tasks:
+// deps and task calls in cmds, including deferred ones
+func callRefNodes(task *yaml.Node) []*yaml.Node {
+	var refs []*yaml.Node
+	add := func(n *yaml.Node) {
+		if n != nil && n.Kind == yaml.ScalarNode && n.Value != "" {
+			refs = append(refs, n)
+		}
+	}
+
+	if deps := mappingValue(task, "deps"); deps != nil && deps.Kind == yaml.SequenceNode {
+		for _, dep := range deps.Content {
+			if dep.Kind == yaml.ScalarNode {
+				add(dep)
+			} else {
+				add(mappingValue(dep, "task"))
+			}
+		}
+	}
+
+	if cmds := mappingValue(task, "cmds"); cmds != nil && cmds.Kind == yaml.SequenceNode {
+		for _, cmd := range cmds.Content {
+			add(mappingValue(cmd, "task"))
+			add(mappingValue(mappingValue(cmd, "defer"), "task"))
+		}
+	}
+
+	return refs
+}
+
+// rewriteTaskRefs applies qualify to every task reference within a task
+func rewriteTaskRefs(task *yaml.Node, qualify func(string) string) {
+	for _, ref := range taskRefNodes(task) {
+		ref.SetString(qualify(ref.Value))
+	}
+}